@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,6 +31,13 @@ type AgentConfigSpec struct {
 	// MCP configuration (e.g., ~/.claude.json for Claude Code).
 	// +optional
 	MCPServers []MCPServerSpec `json:"mcpServers,omitempty"`
+
+	// Sidecars are service containers started alongside the agent of
+	// every Task that uses this AgentConfig. See TaskSpec.Sidecars.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
 }
 
 // PluginSpec defines a plugin bundle containing skills and agents.
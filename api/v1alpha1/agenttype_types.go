@@ -0,0 +1,133 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentTypeSpec defines the desired state of AgentType.
+type AgentTypeSpec struct {
+	// Image is the agent image for Tasks of this type that do not set
+	// spec.image. It must implement the Kelos agent image interface.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// ImagePullPolicy is the pull policy for Image.
+	// +optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// Credentials maps the Task credential types to the environment
+	// variables the agent reads them from. Each variable is read from the
+	// key of the same name in the credentials Secret.
+	// +optional
+	Credentials AgentTypeCredentials `json:"credentials,omitempty"`
+
+	// MCP describes where the agent reads its MCP server configuration.
+	// When set, the MCP servers of the Task's AgentConfig are written to
+	// this file in the given format before the agent starts.
+	// +optional
+	MCP *AgentTypeMCP `json:"mcp,omitempty"`
+
+	// Plugins describes where the agent expects AgentConfig plugins and
+	// skills.
+	// +optional
+	Plugins *AgentTypePlugins `json:"plugins,omitempty"`
+
+	// UsageFormat selects how kelos-capture reads token usage from the
+	// agent output, by the name of the built-in agent whose output format
+	// the agent shares. Token usage is not reported when it is empty or
+	// none.
+	// +optional
+	// +kubebuilder:validation:Enum=claude-code;codex;gemini;opencode;cursor;none
+	UsageFormat string `json:"usageFormat,omitempty"`
+}
+
+// AgentTypeCredentials maps credential types to environment variables.
+type AgentTypeCredentials struct {
+	// APIKeyEnv is the variable holding api-key credentials.
+	// Defaults to KELOS_API_KEY.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[A-Za-z_][A-Za-z0-9_]*$"
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+
+	// OAuthEnv is the variable holding oauth credentials.
+	// Defaults to KELOS_OAUTH_TOKEN.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[A-Za-z_][A-Za-z0-9_]*$"
+	OAuthEnv string `json:"oauthEnv,omitempty"`
+}
+
+// MCPConfigFormat is the file format of an agent's MCP configuration.
+// +kubebuilder:validation:Enum=json;toml
+type MCPConfigFormat string
+
+const (
+	// MCPConfigFormatJSON is a JSON document with an "mcpServers" object
+	// keyed by server name, as in .mcp.json.
+	MCPConfigFormatJSON MCPConfigFormat = "json"
+	// MCPConfigFormatTOML is a TOML document with an [mcp_servers.<name>]
+	// table per server, as in the Codex config.toml.
+	MCPConfigFormatTOML MCPConfigFormat = "toml"
+)
+
+// AgentTypeMCP describes an agent's MCP configuration file.
+type AgentTypeMCP struct {
+	// ConfigPath is the absolute path of the configuration file in the
+	// agent container.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern="^/.*[^/]$"
+	ConfigPath string `json:"configPath"`
+
+	// Format is the file format of the configuration. Defaults to json.
+	// +optional
+	// +kubebuilder:default=json
+	Format MCPConfigFormat `json:"format,omitempty"`
+}
+
+// AgentTypePlugins describes where an agent expects plugins and skills.
+type AgentTypePlugins struct {
+	// MountPath is where the plugin volume is mounted in the agent
+	// container, also exposed as KELOS_PLUGIN_DIR. Defaults to
+	// /kelos/plugin.
+	// +optional
+	// +kubebuilder:validation:Pattern="^/.*"
+	MountPath string `json:"mountPath,omitempty"`
+
+	// SkillsAgent is the agent name passed to "npx skills add --agent"
+	// when installing skills.sh packages. Defaults to the AgentType name.
+	// +optional
+	SkillsAgent string `json:"skillsAgent,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentType is the Schema for the agenttypes API. It registers an agent
+// that Tasks select by name in spec.type, or overrides the defaults of a
+// built-in agent type with the same name.
+type AgentType struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AgentTypeSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentTypeList contains a list of AgentType.
+type AgentTypeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentType `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentType{}, &AgentTypeList{})
+}
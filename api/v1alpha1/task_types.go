@@ -34,12 +34,145 @@ const (
 	TaskPhaseWaiting TaskPhase = "Waiting"
 )
 
+// AnnotationCancelRequested requests cancellation of a Task that has not yet
+// finished. The controller deletes the Task's Job and marks the Task Failed.
+// The annotation value is recorded in the status message as the reason.
+const AnnotationCancelRequested = "kelos.dev/cancel-requested"
+
+// AnnotationRerunOf records the name of the Task that a Task was rerun from.
+const AnnotationRerunOf = "kelos.dev/rerun-of"
+
 // SecretReference refers to a Secret containing credentials.
 type SecretReference struct {
 	// Name is the name of the secret.
 	Name string `json:"name"`
 }
 
+// ConfigMapReference refers to a ConfigMap in the same namespace.
+type ConfigMapReference struct {
+	// Name is the name of the ConfigMap.
+	Name string `json:"name"`
+}
+
+// ConfigMapKeyReference refers to a key of a ConfigMap in the same namespace.
+type ConfigMapKeyReference struct {
+	// Name is the name of the ConfigMap.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key is the key within the ConfigMap.
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+}
+
+// RepoFileReference refers to a file in the Workspace repository.
+type RepoFileReference struct {
+	// Path is the file path relative to the repository root.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Ref is the branch, tag or commit to read the file at. Defaults to the
+	// Workspace ref, or the repository's default branch when that is unset.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+}
+
+// PromptSource selects where a prompt or prompt template is loaded from.
+// +kubebuilder:validation:XValidation:rule="has(self.configMapKeyRef) != has(self.repoFile)",message="exactly one of configMapKeyRef or repoFile must be set"
+type PromptSource struct {
+	// ConfigMapKeyRef loads the prompt from a ConfigMap key.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeyReference `json:"configMapKeyRef,omitempty"`
+
+	// RepoFile loads the prompt from a file in the Workspace repository
+	// through the GitHub API. Only supported for github Workspaces.
+	// +optional
+	RepoFile *RepoFileReference `json:"repoFile,omitempty"`
+}
+
+// SessionSpec configures persistence of the agent's session state.
+type SessionSpec struct {
+	// PersistentVolumeClaimName is the name of an existing
+	// PersistentVolumeClaim that stores agent session state. Each Task
+	// stores its session in a subdirectory named after the Task, so one
+	// claim can be shared by many Tasks. Use a ReadWriteMany claim when
+	// Tasks sharing it may run on different nodes at the same time.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+}
+
+// TranscriptArchive configures where the agent's NDJSON transcript is
+// archived when the agent exits, so that it outlives the agent pod.
+// Exactly one store must be set.
+// +kubebuilder:validation:XValidation:rule="(has(self.persistentVolumeClaim) ? 1 : 0) + (has(self.s3) ? 1 : 0) + (has(self.configMap) ? 1 : 0) == 1",message="exactly one of persistentVolumeClaim, s3 or configMap must be set"
+type TranscriptArchive struct {
+	// PersistentVolumeClaim archives the transcript to an existing claim.
+	// +optional
+	PersistentVolumeClaim *PVCTranscriptStore `json:"persistentVolumeClaim,omitempty"`
+
+	// S3 archives the transcript to an S3-compatible bucket.
+	// +optional
+	S3 *S3TranscriptStore `json:"s3,omitempty"`
+
+	// ConfigMap archives the transcript, gzip-compressed, in the
+	// <task>-transcript ConfigMap. It suits small runs: when the compressed
+	// transcript exceeds the ConfigMap size limit only its end is kept.
+	// +optional
+	ConfigMap *ConfigMapTranscriptStore `json:"configMap,omitempty"`
+
+	// Retention is how long the <task>-transcript ConfigMap is kept after
+	// the Task finishes. It holds ConfigMap transcripts and records where
+	// the other stores put theirs, so that kelos logs finds them once the
+	// Task is deleted. Objects in S3 and files on the claim are not
+	// deleted; use a bucket lifecycle rule or clean the claim for those.
+	// Defaults to 720h (30 days).
+	// +optional
+	Retention *metav1.Duration `json:"retention,omitempty"`
+}
+
+// PVCTranscriptStore archives transcripts to a PersistentVolumeClaim.
+type PVCTranscriptStore struct {
+	// ClaimName is the name of an existing PersistentVolumeClaim. The
+	// transcript is written to <namespace>/<task>/<pod>.jsonl in the claim.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+}
+
+// S3TranscriptStore archives transcripts to an S3-compatible bucket.
+type S3TranscriptStore struct {
+	// Endpoint is the URL of the S3 API, such as
+	// https://s3.us-east-1.amazonaws.com or http://minio.minio:9000.
+	// Requests use path-style addressing.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern="^https?://"
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the name of the bucket.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Region is used to sign requests. Defaults to us-east-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Prefix is prepended to object keys. Objects are stored at
+	// <prefix>/<namespace>/<task>/<pod>.jsonl.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// SecretRef references a Secret with AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY keys.
+	// +kubebuilder:validation:Required
+	SecretRef SecretReference `json:"secretRef"`
+}
+
+// ConfigMapTranscriptStore archives transcripts in a ConfigMap.
+type ConfigMapTranscriptStore struct{}
+
 // Credentials defines how to authenticate with the AI agent.
 type Credentials struct {
 	// Type specifies the credential type.
@@ -80,18 +213,87 @@ type PodOverrides struct {
 	// Workload Identity, or Azure Workload Identity.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Tolerations allow agent pods to schedule onto nodes with matching
+	// taints, such as a dedicated node pool.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity sets node, pod and pod anti-affinity scheduling constraints
+	// for agent pods. It is left out of the CRD schema to keep the CRDs
+	// small, so an invalid affinity is only rejected when the agent Job is
+	// created.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// PriorityClassName sets the priority class of agent pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// RuntimeClassName selects the container runtime for agent pods, such
+	// as gVisor or Kata Containers.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+
+	// ImagePullSecrets references Secrets used to pull the agent and init
+	// container images.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Volumes are added to the agent pod. Names must not collide with the
+	// volumes Kelos creates: workspace, kelos-plugin, kelos-session,
+	// kelos-transcripts, kelos-git-cache, kelos-dependency-cache,
+	// kelos-mcp-config, kelos-ssh-key and the kelos-file-<n> volumes of
+	// Workspace files.
+	// +optional
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts are added to the agent container and all init
+	// containers, for example to mount a private CA bundle.
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// Labels are added to the agent pod. Labels set by Kelos take
+	// precedence.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the agent pod.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// SecurityContext is applied to the agent container and all init
+	// containers. Fields that are set override those chosen by Kelos, such
+	// as the UID shared by the workspace containers.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
 }
 
 // TaskSpec defines the desired state of Task.
+// +kubebuilder:validation:XValidation:rule="!has(self.promptFrom) || !has(self.prompt) || size(self.prompt) == 0",message="prompt and promptFrom are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.resumeFrom) || has(self.session)",message="session is required when resumeFrom is set"
+// +kubebuilder:validation:XValidation:rule="!(has(self.agentConfigRef) && has(self.agentConfigRefs))",message="agentConfigRef and agentConfigRefs are mutually exclusive"
 type TaskSpec struct {
-	// Type specifies the agent type (e.g., claude-code).
+	// Type specifies the agent type: one of the built-in types claude-code,
+	// codex, gemini, opencode and cursor, or the name of an AgentType.
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=claude-code;codex;gemini;opencode;cursor
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
 	Type string `json:"type"`
 
 	// Prompt is the task prompt to send to the agent.
-	// +kubebuilder:validation:Required
-	Prompt string `json:"prompt"`
+	// Mutually exclusive with promptFrom.
+	// +optional
+	Prompt string `json:"prompt,omitempty"`
+
+	// PromptFrom loads the prompt from a ConfigMap key or a file in the
+	// Workspace repository when the Job is created. Until then, changes to
+	// the source are picked up; a Task waits while its ConfigMap is missing.
+	// +optional
+	PromptFrom *PromptSource `json:"promptFrom,omitempty"`
 
 	// Credentials specifies how to authenticate with the agent.
 	// +kubebuilder:validation:Required
@@ -116,6 +318,15 @@ type TaskSpec struct {
 	// +optional
 	AgentConfigRef *AgentConfigReference `json:"agentConfigRef,omitempty"`
 
+	// AgentConfigRefs references an ordered list of AgentConfig resources
+	// that are merged into one. AgentsMD is concatenated in order;
+	// plugins, MCP servers and sidecars are merged by name with later
+	// entries winning; and skills.sh packages are deduplicated.
+	// Mutually exclusive with AgentConfigRef.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	AgentConfigRefs []AgentConfigReference `json:"agentConfigRefs,omitempty"`
+
 	// DependsOn lists Task names that must succeed before this Task starts.
 	// +optional
 	DependsOn []string `json:"dependsOn,omitempty"`
@@ -148,6 +359,36 @@ type TaskSpec struct {
 	// PodOverrides allows customizing the agent pod configuration.
 	// +optional
 	PodOverrides *PodOverrides `json:"podOverrides,omitempty"`
+
+	// Sidecars are service containers, such as databases, started before
+	// the agent and stopped after it exits. They run as native sidecar
+	// init containers (restartPolicy Always), which requires Kubernetes
+	// 1.29 or later. A sidecar's startupProbe, or its readinessProbe when
+	// no startupProbe is set, must pass before the agent starts. Sidecars
+	// with the same name as one from the AgentConfig replace it.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+
+	// Session persists the agent's session state when the agent exits so
+	// that a later Task can resume it with resumeFrom.
+	// +optional
+	Session *SessionSpec `json:"session,omitempty"`
+
+	// ResumeFrom is the name of a finished Task whose agent session this
+	// Task resumes, so that the agent keeps its earlier conversation
+	// context. Requires session with the same claim as the referenced Task.
+	// The Task waits while the referenced Task is still running. Agents that
+	// cannot resume sessions start a new one; currently only claude-code
+	// resumes.
+	// +optional
+	ResumeFrom string `json:"resumeFrom,omitempty"`
+
+	// TranscriptArchive archives the agent's transcript when the agent
+	// exits. The location is recorded in status.transcript.
+	// +optional
+	TranscriptArchive *TranscriptArchive `json:"transcriptArchive,omitempty"`
 }
 
 // TaskStatus defines the observed state of Task.
@@ -184,8 +425,29 @@ type TaskStatus struct {
 	// Results contains structured key-value outputs produced by the agent.
 	// +optional
 	Results map[string]string `json:"results,omitempty"`
+
+	// Transcript locates the archived agent transcript when
+	// spec.transcriptArchive is set: pvc://<claim>/<path>,
+	// s3://<bucket>/<key> or configmap://<name>.
+	// +optional
+	Transcript string `json:"transcript,omitempty"`
+
+	// Conditions provides detailed status information, such as the
+	// outcome of the workspace setup commands.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+const (
+	// TaskConditionWorkspaceSetup reports the outcome of the commands in
+	// the setup of the Task's Workspace.
+	TaskConditionWorkspaceSetup = "WorkspaceSetup"
+)
+
 // +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -18,6 +19,19 @@ const (
 	TaskSpawnerPhaseSuspended TaskSpawnerPhase = "Suspended"
 )
 
+// SourceClosedPolicy defines how the spawner treats an active Task whose
+// source item is no longer discovered.
+type SourceClosedPolicy string
+
+const (
+	// SourceClosedPolicyIgnore lets the Task run to completion.
+	SourceClosedPolicyIgnore SourceClosedPolicy = "ignore"
+	// SourceClosedPolicyCancel stops the agent and marks the Task Failed.
+	SourceClosedPolicyCancel SourceClosedPolicy = "cancel"
+	// SourceClosedPolicyDelete deletes the Task.
+	SourceClosedPolicyDelete SourceClosedPolicy = "delete"
+)
+
 // When defines the conditions that trigger task spawning.
 // Exactly one field must be set.
 type When struct {
@@ -313,10 +327,14 @@ type TaskTemplateMetadata struct {
 }
 
 // TaskTemplate defines the template for spawned Tasks.
+// +kubebuilder:validation:XValidation:rule="!(has(self.promptTemplate) && has(self.promptTemplateFrom))",message="promptTemplate and promptTemplateFrom are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!(has(self.agentConfigRef) && has(self.agentConfigRefs))",message="agentConfigRef and agentConfigRefs are mutually exclusive"
 type TaskTemplate struct {
-	// Type specifies the agent type (e.g., claude-code).
+	// Type specifies the agent type: one of the built-in types claude-code,
+	// codex, gemini, opencode and cursor, or the name of an AgentType.
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=claude-code;codex;gemini;opencode;cursor
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
 	Type string `json:"type"`
 
 	// Credentials specifies how to authenticate with the agent.
@@ -346,6 +364,15 @@ type TaskTemplate struct {
 	// +optional
 	AgentConfigRef *AgentConfigReference `json:"agentConfigRef,omitempty"`
 
+	// AgentConfigRefs references an ordered list of AgentConfig resources
+	// that are merged into one for spawned Tasks. AgentsMD is concatenated in order;
+	// plugins, MCP servers and sidecars are merged by name with later
+	// entries winning; and skills.sh packages are deduplicated.
+	// Mutually exclusive with AgentConfigRef.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	AgentConfigRefs []AgentConfigReference `json:"agentConfigRefs,omitempty"`
+
 	// DependsOn lists Task names that spawned Tasks depend on.
 	// +optional
 	DependsOn []string `json:"dependsOn,omitempty"`
@@ -364,9 +391,26 @@ type TaskTemplate struct {
 	// GitHub issue/Jira sources: {{.Number}}, {{.Body}}, {{.URL}}, {{.Labels}}, {{.Comments}}
 	// GitHub pull request sources additionally expose: {{.Branch}}, {{.ReviewState}}, {{.ReviewComments}}
 	// Cron sources: {{.Time}}, {{.Schedule}}
+	// Branch, promptTemplate and metadata templates can also use helper
+	// functions such as slugify, truncate, default, regexReplace and date.
 	// +optional
 	PromptTemplate string `json:"promptTemplate,omitempty"`
 
+	// PromptTemplateFrom loads promptTemplate from a ConfigMap key or a file
+	// in the repository the spawner watches. The source is re-read on every
+	// discovery cycle, and ConfigMap changes trigger a new cycle, so prompts
+	// can be versioned alongside the code they describe. Mutually exclusive
+	// with promptTemplate.
+	// +optional
+	PromptTemplateFrom *PromptSource `json:"promptTemplateFrom,omitempty"`
+
+	// PromptSnippetsRef references a ConfigMap whose keys are shared prompt
+	// snippets. promptTemplate renders a snippet with the include function,
+	// passing the ConfigMap key and the template data (usually "."). Snippets
+	// may use the same variables and functions as promptTemplate.
+	// +optional
+	PromptSnippetsRef *ConfigMapReference `json:"promptSnippetsRef,omitempty"`
+
 	// TTLSecondsAfterFinished limits the lifetime of a Task that has finished
 	// execution (either Succeeded or Failed). If set, spawned Tasks will be
 	// automatically deleted after the given number of seconds once they reach
@@ -382,6 +426,17 @@ type TaskTemplate struct {
 	// +optional
 	PodOverrides *PodOverrides `json:"podOverrides,omitempty"`
 
+	// Sidecars are service containers started alongside the agent of
+	// spawned Tasks. See TaskSpec.Sidecars.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+
+	// TranscriptArchive archives the agent transcripts of spawned Tasks.
+	// +optional
+	TranscriptArchive *TranscriptArchive `json:"transcriptArchive,omitempty"`
+
 	// Metadata holds optional labels and annotations for spawned Tasks.
 	// +optional
 	Metadata *TaskTemplateMetadata `json:"metadata,omitempty"`
@@ -435,6 +490,88 @@ type TaskSpawnerSpec struct {
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	MaxTotalTasks *int32 `json:"maxTotalTasks,omitempty"`
+
+	// OnSourceClosed controls what happens to a Pending, Waiting or Running
+	// Task when its source item is closed: the GitHub issue was closed, the
+	// pull request was closed or merged, or the Jira issue moved to a done
+	// status. Items that are only filtered out of discovery, or are past
+	// the page limit, do not count as closed. "ignore" lets the Task
+	// finish, "cancel" stops the agent and marks the Task Failed, and
+	// "delete" removes the Task. Only applies to githubIssues,
+	// githubPullRequests and jira sources. Defaults to "ignore".
+	// +kubebuilder:validation:Enum=ignore;cancel;delete
+	// +kubebuilder:default=ignore
+	// +optional
+	OnSourceClosed SourceClosedPolicy `json:"onSourceClosed,omitempty"`
+
+	// RetriggerOn lists work item changes that retrigger a finished Task, in
+	// addition to new trigger comments or reviews. "body" covers edits to the
+	// issue or pull request description, "labels" covers added or removed
+	// labels, "commits" covers a new head commit on a pull request, and
+	// "comments" covers new comments that are not trigger or exclude
+	// commands. A hash of the selected fields is stored on each Task. Changes
+	// made while the Task runs (for example by the agent itself) are folded
+	// into the stored hash, so only changes after the spawner observes the
+	// Task as finished cause a retrigger. When empty, content changes never
+	// retrigger.
+	// +kubebuilder:validation:Items:Enum=body;labels;commits;comments
+	// +optional
+	RetriggerOn []string `json:"retriggerOn,omitempty"`
+
+	// DryRun tells the spawner to discover items and evaluate retrigger,
+	// concurrency and budget rules without creating, deleting or cancelling
+	// any Tasks. The Tasks that would be created, retriggered or skipped are
+	// recorded in status.preview. Use "kelos get taskspawner <name> --preview"
+	// to inspect them. Defaults to false.
+	// +optional
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+// PreviewAction describes what the spawner would do with a discovered item.
+type PreviewAction string
+
+const (
+	// PreviewActionCreate means a new Task would be created for the item.
+	PreviewActionCreate PreviewAction = "Create"
+	// PreviewActionRetrigger means the finished Task for the item would be
+	// replaced by a new one.
+	PreviewActionRetrigger PreviewAction = "Retrigger"
+	// PreviewActionSkip means no Task would be created for the item.
+	PreviewActionSkip PreviewAction = "Skip"
+)
+
+// TaskSpawnerPreviewItem describes the outcome of a dry-run cycle for a
+// single discovered work item.
+type TaskSpawnerPreviewItem struct {
+	// ItemID is the source identifier of the work item (e.g., issue number).
+	ItemID string `json:"itemID"`
+
+	// TaskName is the name of the Task the item maps to.
+	TaskName string `json:"taskName"`
+
+	// Action is what the spawner would do with the item.
+	Action PreviewAction `json:"action"`
+
+	// Reason explains why the item would be retriggered or skipped.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Branch is the rendered branch for the Task, if any.
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// Prompt is the rendered prompt for the Task, truncated to a few
+	// kilobytes.
+	// +optional
+	Prompt string `json:"prompt,omitempty"`
+
+	// Labels are the rendered labels for the Task.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are the rendered annotations for the Task.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // TaskSpawnerStatus defines the observed state of TaskSpawner.
@@ -480,6 +617,19 @@ type TaskSpawnerStatus struct {
 	// +patchMergeKey=type
 	// +patchStrategy=merge
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Preview lists what the last dry-run cycle would have done for each
+	// discovered item, including items the source filtered out. Only set
+	// when spec.dryRun is true. At most 100 entries are kept, those that
+	// would create or retrigger a Task first.
+	// +optional
+	// +kubebuilder:validation:MaxItems=100
+	Preview []TaskSpawnerPreviewItem `json:"preview,omitempty"`
+
+	// PreviewOmitted is the number of dry-run outcomes left out of Preview
+	// because of its size limit.
+	// +optional
+	PreviewOmitted int `json:"previewOmitted,omitempty"`
 }
 
 // +genclient
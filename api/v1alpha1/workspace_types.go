@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -12,27 +14,187 @@ type GitRemote struct {
 	Name string `json:"name"`
 
 	// URL is the git remote URL.
-	// +kubebuilder:validation:Pattern="^(https?://|git://|git@).*"
+	// +kubebuilder:validation:Pattern="^(https?://|ssh://|git://|git@).*"
 	URL string `json:"url"`
 }
 
 // WorkspaceFile defines a file to write into the cloned repository before the
-// agent container starts.
+// agent container starts. The content is given inline, read from a
+// ConfigMap or Secret key, or, with DirectoryFrom, Path is a directory that
+// receives every key of a ConfigMap or Secret.
+// +kubebuilder:validation:XValidation:rule="!(has(self.valueFrom) && has(self.directoryFrom))",message="valueFrom and directoryFrom are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.content) || size(self.content) == 0 || (!has(self.valueFrom) && !has(self.directoryFrom))",message="content cannot be combined with valueFrom or directoryFrom"
 type WorkspaceFile struct {
 	// Path is the relative file path inside the repository (for example,
-	// ".claude/skills/reviewer/SKILL.md" or "CLAUDE.md").
+	// ".claude/skills/reviewer/SKILL.md" or "CLAUDE.md"), or the directory
+	// to copy into when DirectoryFrom is set.
 	// +kubebuilder:validation:MinLength=1
 	Path string `json:"path"`
 
 	// Content is the file content to write.
-	Content string `json:"content"`
+	// +optional
+	Content string `json:"content,omitempty"`
+
+	// ValueFrom reads the file content from a key of a ConfigMap or Secret
+	// in the Task's namespace.
+	// +optional
+	ValueFrom *WorkspaceFileSource `json:"valueFrom,omitempty"`
+
+	// DirectoryFrom copies every key of a ConfigMap or Secret in the Task's
+	// namespace into the directory Path, as a file named after the key.
+	// +optional
+	DirectoryFrom *WorkspaceDirectorySource `json:"directoryFrom,omitempty"`
+}
+
+// WorkspaceFileSource selects the key that holds the content of a
+// WorkspaceFile. Exactly one field must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.configMapKeyRef) != has(self.secretKeyRef)",message="exactly one of configMapKeyRef or secretKeyRef must be set"
+type WorkspaceFileSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// WorkspaceDirectorySource selects the ConfigMap or Secret whose keys are
+// copied into a directory of the repository. Exactly one field must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.configMapRef) != has(self.secretRef)",message="exactly one of configMapRef or secretRef must be set"
+type WorkspaceDirectorySource struct {
+	// ConfigMapRef references a ConfigMap.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// SecretRef references a Secret.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Optional allows the ConfigMap or Secret to be missing, in which case
+	// nothing is copied.
+	// +optional
+	Optional *bool `json:"optional,omitempty"`
+}
+
+// WorkspaceRepo defines an additional repository that is cloned next to the
+// main repository of a Workspace.
+type WorkspaceRepo struct {
+	// Name is the directory under /workspace that the repository is cloned
+	// into. "repo" is reserved for the main repository.
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	Name string `json:"name"`
+
+	// Repo is the git repository URL to clone.
+	// +kubebuilder:validation:Pattern="^(https?://|ssh://|git://|git@).*"
+	Repo string `json:"repo"`
+
+	// Ref is the git reference to checkout (branch, tag, or commit SHA).
+	// Defaults to the repository's default branch if not specified.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// SecretRef references a Secret with the provider credentials for this
+	// repository, with the same keys as the Workspace's secretRef, which
+	// it defaults to.
+	// +optional
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+}
+
+// WorkspaceSetup defines commands that prepare the cloned repository before
+// the agent starts, such as installing dependencies.
+type WorkspaceSetup struct {
+	// Commands are run in order by sh in the repository root. Setup stops
+	// at the first command that fails, which fails the Task.
+	// +kubebuilder:validation:MinItems=1
+	Commands []string `json:"commands"`
+
+	// Image runs the commands. Defaults to the agent image of the Task so
+	// that its toolchain is available. The image must provide sh and
+	// timeout.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// TimeoutSeconds limits how long the commands may run. Defaults to 600.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// Env sets additional environment variables for the commands.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
 }
 
+// CacheVolume describes a PersistentVolumeClaim created by the controller
+// for a Workspace cache.
+type CacheVolume struct {
+	// StorageClassName is the storage class of the claim. Defaults to the
+	// cluster's default storage class.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested storage of the claim.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessMode of the claim. Defaults to ReadWriteOnce, which every
+	// storage class supports but only lets agent pods on one node mount the
+	// claim at a time. Use ReadWriteMany with a storage class that supports
+	// it when concurrent Tasks are scheduled on different nodes.
+	// +optional
+	// +kubebuilder:validation:Enum=ReadWriteMany;ReadWriteOnce
+	// +kubebuilder:default=ReadWriteOnce
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+// WorkspaceCache configures a mirror of the repository kept on a
+// PersistentVolumeClaim named "<workspace>-git-cache". A controller-managed
+// Job clones the mirror once and then fetches into it periodically; Tasks
+// clone with the mirror as a reference, so only objects missing from it are
+// downloaded from the remote.
+type WorkspaceCache struct {
+	// Volume configures the claim holding the mirror. Size defaults to 20Gi.
+	// +optional
+	Volume CacheVolume `json:"volume,omitempty"`
+
+	// RefreshInterval is how often the cache Job fetches new commits into
+	// the mirror. Defaults to 1h.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// DependencyCache configures a PersistentVolumeClaim named
+// "<workspace>-dependency-cache" that is shared by the Tasks of a Workspace
+// to cache downloaded dependencies. The Go module and build caches, npm,
+// yarn and pip caches of the agent container are pointed at it.
+type DependencyCache struct {
+	// Volume configures the claim holding the caches. Size defaults to 10Gi.
+	// +optional
+	Volume CacheVolume `json:"volume,omitempty"`
+}
+
+// GitProvider is a git hosting service.
+// +kubebuilder:validation:Enum=github;gitlab;bitbucket;gitea
+type GitProvider string
+
+const (
+	// GitProviderGitHub is GitHub or GitHub Enterprise Server.
+	GitProviderGitHub GitProvider = "github"
+	// GitProviderGitLab is GitLab.com or a self-managed GitLab.
+	GitProviderGitLab GitProvider = "gitlab"
+	// GitProviderBitbucket is Bitbucket Cloud, authenticated with an app
+	// password.
+	GitProviderBitbucket GitProvider = "bitbucket"
+	// GitProviderGitea is Gitea or Forgejo.
+	GitProviderGitea GitProvider = "gitea"
+)
+
 // WorkspaceSpec defines the desired state of Workspace.
 type WorkspaceSpec struct {
 	// Repo is the git repository URL to clone.
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Pattern="^(https?://|git://|git@).*"
+	// +kubebuilder:validation:Pattern="^(https?://|ssh://|git://|git@).*"
 	Repo string `json:"repo"`
 
 	// Ref is the git reference to checkout (branch, tag, or commit SHA).
@@ -40,11 +202,51 @@ type WorkspaceSpec struct {
 	// +optional
 	Ref string `json:"ref,omitempty"`
 
-	// SecretRef references a Secret containing a GITHUB_TOKEN key for git
-	// authentication and GitHub CLI (gh) operations.
+	// Depth is the number of commits of history to clone. Zero clones the
+	// full history. Defaults to 1. When a Task sets a branch, it is fetched
+	// with the same depth.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Depth *int32 `json:"depth,omitempty"`
+
+	// SparseCheckout limits the working tree to these directories of the
+	// repository (cone mode). Files outside of them are neither checked
+	// out nor downloaded up front.
+	// +optional
+	SparseCheckout []string `json:"sparseCheckout,omitempty"`
+
+	// Submodules initializes and updates git submodules recursively after
+	// cloning and after checking out the Task branch.
+	// +optional
+	Submodules bool `json:"submodules,omitempty"`
+
+	// LFS downloads Git LFS objects after cloning. Agent images must
+	// include git-lfs for the agent to commit LFS-tracked files.
+	// +optional
+	LFS bool `json:"lfs,omitempty"`
+
+	// Provider is the git hosting service of the repository. It selects
+	// the keys read from SecretRef and the CLI configuration given to the
+	// agent. Defaults to github.
+	// +optional
+	// +kubebuilder:default=github
+	Provider GitProvider `json:"provider,omitempty"`
+
+	// SecretRef references a Secret with the credentials of the provider,
+	// used for git authentication over HTTPS and for the provider's CLI:
+	// GITHUB_TOKEN (or GitHub App credentials) for github, GITLAB_TOKEN
+	// for gitlab, BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD for
+	// bitbucket, and GITEA_TOKEN for gitea.
 	// +optional
 	SecretRef *SecretReference `json:"secretRef,omitempty"`
 
+	// SSHKeySecretRef references a Secret with an ssh-privatekey key (such
+	// as a kubernetes.io/ssh-auth Secret holding a deploy key) and a
+	// known_hosts key. Git uses them for repositories and remotes with SSH
+	// URLs, in the clone and in the agent container.
+	// +optional
+	SSHKeySecretRef *SecretReference `json:"sshKeySecretRef,omitempty"`
+
 	// Remotes are additional git remotes to configure after cloning.
 	// The credential from SecretRef applies to all remotes.
 	// +optional
@@ -52,12 +254,41 @@ type WorkspaceSpec struct {
 	// +kubebuilder:validation:XValidation:rule="self.map(r, r.name).size() == self.size()",message="remote names must be unique"
 	Remotes []GitRemote `json:"remotes,omitempty"`
 
+	// Repos are additional repositories cloned side by side with the main
+	// repository, each into /workspace/<name>, for changes that span
+	// several repositories. The Task branch is checked out in every
+	// repository, and the branch, commit and pull requests of each are
+	// reported in the Task results as branch.<name>, commit.<name> and
+	// pr.<name>.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:XValidation:rule="self.all(r, r.name != 'repo')",message="repo name 'repo' is reserved for the main repository"
+	Repos []WorkspaceRepo `json:"repos,omitempty"`
+
 	// Files are written into the cloned repository before the agent starts.
 	// This can be used to inject plugin-like assets such as skills
 	// (for example, ".claude/skills/<name>/SKILL.md") and instruction files
 	// like "CLAUDE.md" or "AGENTS.md".
 	// +optional
 	Files []WorkspaceFile `json:"files,omitempty"`
+
+	// Setup runs commands in the repository after it is cloned, the Task
+	// branch is checked out and Files are written, before the agent
+	// starts. The outcome is reported in the WorkspaceSetup condition of
+	// the Task.
+	// +optional
+	Setup *WorkspaceSetup `json:"setup,omitempty"`
+
+	// Cache keeps a mirror of the repository on a PersistentVolumeClaim to
+	// speed up clones of large repositories.
+	// +optional
+	Cache *WorkspaceCache `json:"cache,omitempty"`
+
+	// DependencyCache shares package manager caches between the Tasks of
+	// this Workspace.
+	// +optional
+	DependencyCache *DependencyCache `json:"dependencyCache,omitempty"`
 }
 
 // +genclient
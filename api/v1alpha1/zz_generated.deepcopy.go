@@ -121,6 +121,13 @@ func (in *AgentConfigSpec) DeepCopyInto(out *AgentConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentConfigSpec.
@@ -148,6 +155,205 @@ func (in *AgentDefinition) DeepCopy() *AgentDefinition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentType) DeepCopyInto(out *AgentType) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentType.
+func (in *AgentType) DeepCopy() *AgentType {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentType)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentType) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentTypeCredentials) DeepCopyInto(out *AgentTypeCredentials) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentTypeCredentials.
+func (in *AgentTypeCredentials) DeepCopy() *AgentTypeCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentTypeCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentTypeList) DeepCopyInto(out *AgentTypeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AgentType, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentTypeList.
+func (in *AgentTypeList) DeepCopy() *AgentTypeList {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentTypeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentTypeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentTypeMCP) DeepCopyInto(out *AgentTypeMCP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentTypeMCP.
+func (in *AgentTypeMCP) DeepCopy() *AgentTypeMCP {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentTypeMCP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentTypePlugins) DeepCopyInto(out *AgentTypePlugins) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentTypePlugins.
+func (in *AgentTypePlugins) DeepCopy() *AgentTypePlugins {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentTypePlugins)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentTypeSpec) DeepCopyInto(out *AgentTypeSpec) {
+	*out = *in
+	out.Credentials = in.Credentials
+	if in.MCP != nil {
+		in, out := &in.MCP, &out.MCP
+		*out = new(AgentTypeMCP)
+		**out = **in
+	}
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = new(AgentTypePlugins)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentTypeSpec.
+func (in *AgentTypeSpec) DeepCopy() *AgentTypeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentTypeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CacheVolume) DeepCopyInto(out *CacheVolume) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheVolume.
+func (in *CacheVolume) DeepCopy() *CacheVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(CacheVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapTranscriptStore) DeepCopyInto(out *ConfigMapTranscriptStore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapTranscriptStore.
+func (in *ConfigMapTranscriptStore) DeepCopy() *ConfigMapTranscriptStore {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapTranscriptStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Credentials) DeepCopyInto(out *Credentials) {
 	*out = *in
@@ -183,6 +389,22 @@ func (in *Cron) DeepCopy() *Cron {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DependencyCache) DeepCopyInto(out *DependencyCache) {
+	*out = *in
+	in.Volume.DeepCopyInto(&out.Volume)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DependencyCache.
+func (in *DependencyCache) DeepCopy() *DependencyCache {
+	if in == nil {
+		return nil
+	}
+	out := new(DependencyCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubCommentPolicy) DeepCopyInto(out *GitHubCommentPolicy) {
 	*out = *in
@@ -403,6 +625,21 @@ func (in *MCPServerSpec) DeepCopy() *MCPServerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCTranscriptStore) DeepCopyInto(out *PVCTranscriptStore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCTranscriptStore.
+func (in *PVCTranscriptStore) DeepCopy() *PVCTranscriptStore {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCTranscriptStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PluginSpec) DeepCopyInto(out *PluginSpec) {
 	*out = *in
@@ -455,6 +692,61 @@ func (in *PodOverrides) DeepCopyInto(out *PodOverrides) {
 			(*out)[key] = val
 		}
 	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodOverrides.
@@ -467,6 +759,62 @@ func (in *PodOverrides) DeepCopy() *PodOverrides {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromptSource) DeepCopyInto(out *PromptSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+	if in.RepoFile != nil {
+		in, out := &in.RepoFile, &out.RepoFile
+		*out = new(RepoFileReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromptSource.
+func (in *PromptSource) DeepCopy() *PromptSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PromptSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepoFileReference) DeepCopyInto(out *RepoFileReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoFileReference.
+func (in *RepoFileReference) DeepCopy() *RepoFileReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RepoFileReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3TranscriptStore) DeepCopyInto(out *S3TranscriptStore) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3TranscriptStore.
+func (in *S3TranscriptStore) DeepCopy() *S3TranscriptStore {
+	if in == nil {
+		return nil
+	}
+	out := new(S3TranscriptStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -498,6 +846,21 @@ func (in *SecretValuesSource) DeepCopy() *SecretValuesSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SessionSpec) DeepCopyInto(out *SessionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionSpec.
+func (in *SessionSpec) DeepCopy() *SessionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SessionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SkillDefinition) DeepCopyInto(out *SkillDefinition) {
 	*out = *in
@@ -646,6 +1009,35 @@ func (in *TaskSpawnerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskSpawnerPreviewItem) DeepCopyInto(out *TaskSpawnerPreviewItem) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSpawnerPreviewItem.
+func (in *TaskSpawnerPreviewItem) DeepCopy() *TaskSpawnerPreviewItem {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskSpawnerPreviewItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskSpawnerSpec) DeepCopyInto(out *TaskSpawnerSpec) {
 	*out = *in
@@ -666,6 +1058,16 @@ func (in *TaskSpawnerSpec) DeepCopyInto(out *TaskSpawnerSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.RetriggerOn != nil {
+		in, out := &in.RetriggerOn, &out.RetriggerOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSpawnerSpec.
@@ -692,6 +1094,13 @@ func (in *TaskSpawnerStatus) DeepCopyInto(out *TaskSpawnerStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Preview != nil {
+		in, out := &in.Preview, &out.Preview
+		*out = make([]TaskSpawnerPreviewItem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSpawnerStatus.
@@ -707,6 +1116,11 @@ func (in *TaskSpawnerStatus) DeepCopy() *TaskSpawnerStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 	*out = *in
+	if in.PromptFrom != nil {
+		in, out := &in.PromptFrom, &out.PromptFrom
+		*out = new(PromptSource)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	if in.WorkspaceRef != nil {
 		in, out := &in.WorkspaceRef, &out.WorkspaceRef
@@ -718,6 +1132,11 @@ func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 		*out = new(AgentConfigReference)
 		**out = **in
 	}
+	if in.AgentConfigRefs != nil {
+		in, out := &in.AgentConfigRefs, &out.AgentConfigRefs
+		*out = make([]AgentConfigReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.DependsOn != nil {
 		in, out := &in.DependsOn, &out.DependsOn
 		*out = make([]string, len(*in))
@@ -733,6 +1152,23 @@ func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 		*out = new(PodOverrides)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Session != nil {
+		in, out := &in.Session, &out.Session
+		*out = new(SessionSpec)
+		**out = **in
+	}
+	if in.TranscriptArchive != nil {
+		in, out := &in.TranscriptArchive, &out.TranscriptArchive
+		*out = new(TranscriptArchive)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSpec.
@@ -768,6 +1204,13 @@ func (in *TaskStatus) DeepCopyInto(out *TaskStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskStatus.
@@ -794,11 +1237,26 @@ func (in *TaskTemplate) DeepCopyInto(out *TaskTemplate) {
 		*out = new(AgentConfigReference)
 		**out = **in
 	}
+	if in.AgentConfigRefs != nil {
+		in, out := &in.AgentConfigRefs, &out.AgentConfigRefs
+		*out = make([]AgentConfigReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.DependsOn != nil {
 		in, out := &in.DependsOn, &out.DependsOn
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PromptTemplateFrom != nil {
+		in, out := &in.PromptTemplateFrom, &out.PromptTemplateFrom
+		*out = new(PromptSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PromptSnippetsRef != nil {
+		in, out := &in.PromptSnippetsRef, &out.PromptSnippetsRef
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
 	if in.TTLSecondsAfterFinished != nil {
 		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
 		*out = new(int32)
@@ -809,6 +1267,18 @@ func (in *TaskTemplate) DeepCopyInto(out *TaskTemplate) {
 		*out = new(PodOverrides)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TranscriptArchive != nil {
+		in, out := &in.TranscriptArchive, &out.TranscriptArchive
+		*out = new(TranscriptArchive)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Metadata != nil {
 		in, out := &in.Metadata, &out.Metadata
 		*out = new(TaskTemplateMetadata)
@@ -855,6 +1325,41 @@ func (in *TaskTemplateMetadata) DeepCopy() *TaskTemplateMetadata {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TranscriptArchive) DeepCopyInto(out *TranscriptArchive) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(PVCTranscriptStore)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3TranscriptStore)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ConfigMapTranscriptStore)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TranscriptArchive.
+func (in *TranscriptArchive) DeepCopy() *TranscriptArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(TranscriptArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *When) DeepCopyInto(out *When) {
 	*out = *in
@@ -916,9 +1421,70 @@ func (in *Workspace) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceCache) DeepCopyInto(out *WorkspaceCache) {
+	*out = *in
+	in.Volume.DeepCopyInto(&out.Volume)
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceCache.
+func (in *WorkspaceCache) DeepCopy() *WorkspaceCache {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceDirectorySource) DeepCopyInto(out *WorkspaceDirectorySource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Optional != nil {
+		in, out := &in.Optional, &out.Optional
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceDirectorySource.
+func (in *WorkspaceDirectorySource) DeepCopy() *WorkspaceDirectorySource {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceDirectorySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkspaceFile) DeepCopyInto(out *WorkspaceFile) {
 	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(WorkspaceFileSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DirectoryFrom != nil {
+		in, out := &in.DirectoryFrom, &out.DirectoryFrom
+		*out = new(WorkspaceDirectorySource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceFile.
@@ -931,6 +1497,31 @@ func (in *WorkspaceFile) DeepCopy() *WorkspaceFile {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceFileSource) DeepCopyInto(out *WorkspaceFileSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceFileSource.
+func (in *WorkspaceFileSource) DeepCopy() *WorkspaceFileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceFileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkspaceList) DeepCopyInto(out *WorkspaceList) {
 	*out = *in
@@ -978,23 +1569,114 @@ func (in *WorkspaceReference) DeepCopy() *WorkspaceReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceRepo) DeepCopyInto(out *WorkspaceRepo) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceRepo.
+func (in *WorkspaceRepo) DeepCopy() *WorkspaceRepo {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceRepo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceSetup) DeepCopyInto(out *WorkspaceSetup) {
+	*out = *in
+	if in.Commands != nil {
+		in, out := &in.Commands, &out.Commands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceSetup.
+func (in *WorkspaceSetup) DeepCopy() *WorkspaceSetup {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceSetup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkspaceSpec) DeepCopyInto(out *WorkspaceSpec) {
 	*out = *in
+	if in.Depth != nil {
+		in, out := &in.Depth, &out.Depth
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SparseCheckout != nil {
+		in, out := &in.SparseCheckout, &out.SparseCheckout
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.SecretRef != nil {
 		in, out := &in.SecretRef, &out.SecretRef
 		*out = new(SecretReference)
 		**out = **in
 	}
+	if in.SSHKeySecretRef != nil {
+		in, out := &in.SSHKeySecretRef, &out.SSHKeySecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
 	if in.Remotes != nil {
 		in, out := &in.Remotes, &out.Remotes
 		*out = make([]GitRemote, len(*in))
 		copy(*out, *in)
 	}
+	if in.Repos != nil {
+		in, out := &in.Repos, &out.Repos
+		*out = make([]WorkspaceRepo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Files != nil {
 		in, out := &in.Files, &out.Files
 		*out = make([]WorkspaceFile, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Setup != nil {
+		in, out := &in.Setup, &out.Setup
+		*out = new(WorkspaceSetup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(WorkspaceCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DependencyCache != nil {
+		in, out := &in.DependencyCache, &out.DependencyCache
+		*out = new(DependencyCache)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -0,0 +1,73 @@
+package claudecode
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEntrypointCapturesFinalOutputLine runs kelos_entrypoint.sh with a stub
+// claude whose last line is still being written when the claude process
+// itself exits, and checks that kelos-capture sees that line.
+func TestEntrypointCapturesFinalOutputLine(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	if err := os.Mkdir(binDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(dir, "agent-output.jsonl")
+	capturedFile := filepath.Join(dir, "captured.jsonl")
+	captureBin := filepath.Join(binDir, "kelos-capture")
+
+	resultLine := `{"type":"result","total_cost_usd":0.5}`
+	writeExecutable(t, filepath.Join(binDir, "claude"), `#!/bin/bash
+echo '{"type":"system"}'
+(sleep 0.5; echo '`+resultLine+`') &
+exit 0
+`)
+	writeExecutable(t, captureBin, `#!/bin/bash
+cp `+outputFile+` `+capturedFile+`
+`)
+
+	script, err := os.ReadFile("kelos_entrypoint.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaced := strings.NewReplacer(
+		"/tmp/agent-output.jsonl", outputFile,
+		"/kelos/kelos-capture", captureBin,
+	).Replace(string(script))
+	entrypoint := filepath.Join(dir, "kelos_entrypoint.sh")
+	writeExecutable(t, entrypoint, replaced)
+
+	cmd := exec.Command("bash", entrypoint, "do something")
+	cmd.Env = append(os.Environ(),
+		"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"HOME="+dir,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Entrypoint failed: %v\n%s", err, out)
+	}
+
+	captured, err := os.ReadFile(capturedFile)
+	if err != nil {
+		t.Fatalf("Reading captured output: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(captured), "\n"), "\n")
+	if last := lines[len(lines)-1]; last != resultLine {
+		t.Errorf("Expected the last captured line to be %q, got %q", resultLine, last)
+	}
+}
+
+func writeExecutable(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
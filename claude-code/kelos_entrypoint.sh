@@ -23,6 +23,19 @@ if [ -n "${KELOS_MODEL:-}" ]; then
   ARGS+=("--model" "$KELOS_MODEL")
 fi
 
+# Restore the session of the Task being resumed. Claude Code stores
+# conversations per working directory under ~/.claude/projects, so
+# --continue picks up the most recent conversation of that Task.
+if [ -n "${KELOS_RESUME_SESSION_DIR:-}" ]; then
+  if [ -d "${KELOS_RESUME_SESSION_DIR}/projects" ]; then
+    mkdir -p ~/.claude
+    cp -R "${KELOS_RESUME_SESSION_DIR}/projects" ~/.claude/
+    ARGS+=("--continue")
+  else
+    echo "kelos: no session found in ${KELOS_RESUME_SESSION_DIR}, starting a new session" >&2
+  fi
+fi
+
 # Write user-level instructions (additive, no conflict with repo)
 if [ -n "${KELOS_AGENTS_MD:-}" ]; then
   mkdir -p ~/.claude
@@ -51,8 +64,41 @@ fs.writeFileSync(cfgPath, JSON.stringify(existing, null, 2));
 '
 fi
 
-claude "${ARGS[@]}" | tee /tmp/agent-output.jsonl
-AGENT_EXIT_CODE=${PIPESTATUS[0]}
+# Save the session so that a later Task can resume it.
+save_session() {
+  if [ -n "${KELOS_SESSION_DIR:-}" ] && [ -d ~/.claude/projects ]; then
+    mkdir -p "${KELOS_SESSION_DIR}"
+    cp -R ~/.claude/projects "${KELOS_SESSION_DIR}/"
+  fi
+}
+
+# The agent runs in the background so that a SIGTERM sent to the container
+# (Job deadline, Task deletion, node drain) interrupts the wait below and the
+# session is still saved before the pod is killed. Its output goes through a
+# FIFO to tee so that the script can also wait for tee to flush the last
+# lines of /tmp/agent-output.jsonl before kelos-capture reads it.
+on_term() {
+  kill -TERM "$AGENT_PID" 2>/dev/null
+  wait "$AGENT_PID" 2>/dev/null
+  wait "$TEE_PID" 2>/dev/null
+  save_session
+  exit 143
+}
+
+OUTPUT_FIFO="$(mktemp -u)"
+mkfifo "$OUTPUT_FIFO"
+tee /tmp/agent-output.jsonl <"$OUTPUT_FIFO" &
+TEE_PID=$!
+claude "${ARGS[@]}" >"$OUTPUT_FIFO" &
+AGENT_PID=$!
+trap on_term TERM INT
+wait "$AGENT_PID"
+AGENT_EXIT_CODE=$?
+trap - TERM INT
+wait "$TEE_PID"
+rm -f "$OUTPUT_FIFO"
+
+save_session
 
 /kelos/kelos-capture
 
@@ -23,6 +23,7 @@ import (
 	"github.com/kelos-dev/kelos/internal/githubapp"
 	"github.com/kelos-dev/kelos/internal/logging"
 	"github.com/kelos-dev/kelos/internal/telemetry"
+	"github.com/kelos-dev/kelos/internal/usage"
 )
 
 var (
@@ -60,6 +61,7 @@ func main() {
 	var telemetryReport bool
 	var telemetryEndpoint string
 	var telemetryEnvironment string
+	var usageRetention time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -87,6 +89,7 @@ func main() {
 	flag.BoolVar(&telemetryReport, "telemetry-report", false, "Run a one-shot telemetry report and exit.")
 	flag.StringVar(&telemetryEndpoint, "telemetry-endpoint", telemetry.DefaultPostHogEndpoint, "The PostHog endpoint for sending telemetry reports.")
 	flag.StringVar(&telemetryEnvironment, "telemetry-environment", "production", "The environment label for telemetry reports (e.g., production, development).")
+	flag.DurationVar(&usageRetention, "usage-retention", usage.DefaultRetention, "How long per-day usage ledger ConfigMaps are kept (0 keeps them forever).")
 
 	opts, applyVerbosity := logging.SetupZapOptions(flag.CommandLine)
 	flag.Parse()
@@ -195,18 +198,29 @@ func main() {
 	jobBuilder.CursorImage = cursorImage
 	jobBuilder.CursorImagePullPolicy = corev1.PullPolicy(cursorImagePullPolicy)
 	if err = (&controller.TaskReconciler{
-		Client:       mgr.GetClient(),
-		Scheme:       mgr.GetScheme(),
-		JobBuilder:   jobBuilder,
-		Clientset:    clientset,
-		TokenClient:  githubapp.NewTokenClient(),
-		Recorder:     mgr.GetEventRecorderFor("kelos-controller"),
-		BranchLocker: controller.NewBranchLocker(),
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		JobBuilder:     jobBuilder,
+		Clientset:      clientset,
+		TokenClient:    githubapp.NewTokenClient(),
+		Recorder:       mgr.GetEventRecorderFor("kelos-controller"),
+		BranchLocker:   controller.NewBranchLocker(),
+		UsageRetention: usageRetention,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Task")
 		os.Exit(1)
 	}
 
+	if err = (&controller.WorkspaceReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		TokenClient: githubapp.NewTokenClient(),
+		Recorder:    mgr.GetEventRecorderFor("kelos-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Workspace")
+		os.Exit(1)
+	}
+
 	deploymentBuilder := controller.NewDeploymentBuilder()
 	deploymentBuilder.SpawnerImage = spawnerImage
 	deploymentBuilder.SpawnerImagePullPolicy = corev1.PullPolicy(spawnerImagePullPolicy)
@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,6 +36,16 @@ import (
 
 const ghProxyURL = "http://ghproxy.kelos-system:8888"
 
+const (
+	// annotationContentHash records the hash of the work item fields
+	// selected by spec.retriggerOn.
+	annotationContentHash = "kelos.dev/content-hash"
+
+	// annotationContentHashSettled marks that the content hash was recorded
+	// after the Task finished, so later changes retrigger it.
+	annotationContentHashSettled = "kelos.dev/content-hash-settled"
+)
+
 var scheme = runtime.NewScheme()
 
 func init() {
@@ -198,12 +213,30 @@ func runCycleCore(ctx context.Context, cl client.Client, key types.NamespacedNam
 		return fmt.Errorf("building source: %w", err)
 	}
 
-	return runCycleWithSourceCore(ctx, cl, key, src)
+	var files source.FileFetcher
+	if from := ts.Spec.TaskTemplate.PromptTemplateFrom; from != nil && from.RepoFile != nil && githubOwner != "" && githubRepo != "" {
+		token, err := readGitHubToken(githubTokenFile)
+		if err != nil {
+			return err
+		}
+		files = &source.GitHubFileFetcher{
+			Owner:   githubOwner,
+			Repo:    githubRepo,
+			Token:   token,
+			BaseURL: githubAPIBaseURL,
+			Client:  httpClient,
+		}
+	}
+
+	return runCycleWithSourceCore(ctx, cl, key, src, files)
 }
 
-func runCycleWithSource(ctx context.Context, cl client.Client, key types.NamespacedName, src source.Source) error {
+// runCycleWithSource runs a discovery cycle against src. files is used to
+// load spec.taskTemplate.promptTemplateFrom.repoFile and may be nil when the
+// TaskSpawner does not reference a repository file.
+func runCycleWithSource(ctx context.Context, cl client.Client, key types.NamespacedName, src source.Source, files source.FileFetcher) error {
 	start := time.Now()
-	err := runCycleWithSourceCore(ctx, cl, key, src)
+	err := runCycleWithSourceCore(ctx, cl, key, src, files)
 	discoveryDurationSeconds.Observe(time.Since(start).Seconds())
 	if err != nil {
 		discoveryErrorsTotal.Inc()
@@ -211,7 +244,7 @@ func runCycleWithSource(ctx context.Context, cl client.Client, key types.Namespa
 	return err
 }
 
-func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.NamespacedName, src source.Source) error {
+func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.NamespacedName, src source.Source, files source.FileFetcher) error {
 	log := ctrl.Log.WithName("spawner")
 
 	var ts kelosv1alpha1.TaskSpawner
@@ -250,6 +283,27 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 		return nil
 	}
 
+	var snippets map[string]string
+	if ref := ts.Spec.TaskTemplate.PromptSnippetsRef; ref != nil {
+		var cm corev1.ConfigMap
+		if err := cl.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ts.Namespace}, &cm); err != nil {
+			return fmt.Errorf("fetching prompt snippets ConfigMap %q: %w", ref.Name, err)
+		}
+		snippets = cm.Data
+		if snippets == nil {
+			snippets = map[string]string{}
+		}
+	}
+
+	promptTemplate := ts.Spec.TaskTemplate.PromptTemplate
+	if from := ts.Spec.TaskTemplate.PromptTemplateFrom; from != nil {
+		loaded, err := loadPromptTemplate(ctx, cl, ts.Namespace, from, files)
+		if err != nil {
+			return fmt.Errorf("loading promptTemplateFrom: %w", err)
+		}
+		promptTemplate = loaded
+	}
+
 	items, err := src.Discover(ctx)
 	if err != nil {
 		return fmt.Errorf("discovering items: %w", err)
@@ -278,6 +332,16 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 		}
 	}
 
+	// In dry-run mode nothing is created, deleted or patched; the outcome
+	// for each item is recorded in status.preview instead.
+	dryRun := ts.Spec.DryRun != nil && *ts.Spec.DryRun
+	var preview []kelosv1alpha1.TaskSpawnerPreviewItem
+	retriggerReasons := make(map[string]string)
+
+	if !dryRun {
+		activeTasks -= handleClosedSourceItems(ctx, cl, &ts, src, items, existingTaskList.Items)
+	}
+
 	var newItems []source.WorkItem
 	for _, item := range items {
 		taskName := fmt.Sprintf("%s-%s", ts.Name, item.ID)
@@ -287,24 +351,63 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 			continue
 		}
 
+		finished := existing.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || existing.Status.Phase == kelosv1alpha1.TaskPhaseFailed
+
 		// Retrigger: when the source provides a trigger time and the existing
 		// task is completed, check whether a new trigger arrived after the task
 		// finished. If so, delete the completed task so a new one can be created.
 		// Note: if creation is later blocked by maxConcurrency or maxTotalTasks,
 		// the item will be picked up as new on the next cycle since the old task
 		// no longer exists.
-		if !item.TriggerTime.IsZero() &&
-			(existing.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || existing.Status.Phase == kelosv1alpha1.TaskPhaseFailed) &&
+		triggered := !item.TriggerTime.IsZero() &&
+			finished &&
 			existing.Status.CompletionTime != nil &&
-			item.TriggerTime.After(existing.Status.CompletionTime.Time) {
+			item.TriggerTime.After(existing.Status.CompletionTime.Time)
+
+		contentChanged := false
+		if len(ts.Spec.RetriggerOn) > 0 {
+			hash := contentHash(item, ts.Spec.RetriggerOn)
+			if dryRun {
+				contentChanged = finished &&
+					existing.Annotations[annotationContentHashSettled] == "true" &&
+					existing.Annotations[annotationContentHash] != hash
+			} else {
+				contentChanged = trackContentHash(ctx, cl, existing, hash, finished)
+			}
+		}
 
-			if err := cl.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
-				log.Error(err, "Deleting completed task for retrigger", "task", taskName)
-				continue
+		if !triggered && !contentChanged {
+			if dryRun {
+				reason := "Task already exists"
+				if !finished {
+					reason = "Task is still active"
+				}
+				preview = append(preview, kelosv1alpha1.TaskSpawnerPreviewItem{
+					ItemID:   item.ID,
+					TaskName: taskName,
+					Action:   kelosv1alpha1.PreviewActionSkip,
+					Reason:   reason,
+				})
+			}
+			continue
+		}
+
+		if dryRun {
+			if triggered {
+				retriggerReasons[taskName] = "New trigger after Task finished"
+			} else {
+				retriggerReasons[taskName] = "Content changed after Task finished"
 			}
-			log.Info("Deleted completed task for retrigger", "task", taskName)
 			newItems = append(newItems, item)
+			continue
+		}
+
+		if err := cl.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Deleting completed task for retrigger", "task", taskName)
+			continue
 		}
+		log.Info("Deleted completed task for retrigger", "task", taskName, "contentChanged", contentChanged)
+		newItems = append(newItems, item)
 	}
 
 	// Sort new items by priority labels when configured
@@ -323,30 +426,42 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 	}
 
 	newTasksCreated := 0
-	for _, item := range newItems {
+	for i, item := range newItems {
 		// Enforce max concurrency limit
 		if maxConcurrency > 0 && int32(activeTasks) >= maxConcurrency {
 			log.Info("Max concurrency reached, skipping remaining items", "activeTasks", activeTasks, "maxConcurrency", maxConcurrency)
+			if dryRun {
+				preview = append(preview, skippedPreviewItems(&ts, newItems[i:], fmt.Sprintf("maxConcurrency (%d) reached", maxConcurrency))...)
+			}
 			break
 		}
 
 		// Enforce max total tasks limit
 		if maxTotalTasks > 0 && ts.Status.TotalTasksCreated+newTasksCreated >= maxTotalTasks {
 			log.Info("Task budget exhausted, skipping remaining items", "totalCreated", ts.Status.TotalTasksCreated+newTasksCreated, "maxTotalTasks", maxTotalTasks)
+			if dryRun {
+				preview = append(preview, skippedPreviewItems(&ts, newItems[i:], fmt.Sprintf("maxTotalTasks (%d) reached", maxTotalTasks))...)
+			}
 			break
 		}
 
 		taskName := fmt.Sprintf("%s-%s", ts.Name, item.ID)
 
-		prompt, err := source.RenderPrompt(ts.Spec.TaskTemplate.PromptTemplate, item)
+		prompt, err := source.RenderPromptWithSnippets(promptTemplate, item, snippets)
 		if err != nil {
 			log.Error(err, "rendering prompt", "item", item.ID)
+			if dryRun {
+				preview = append(preview, skippedPreviewItems(&ts, newItems[i:i+1], fmt.Sprintf("Rendering prompt: %v", err))...)
+			}
 			continue
 		}
 
 		renderedLabels, renderedAnnotations, err := renderTaskTemplateMetadata(&ts, item)
 		if err != nil {
 			log.Error(err, "Rendering task template metadata", "item", item.ID)
+			if dryRun {
+				preview = append(preview, skippedPreviewItems(&ts, newItems[i:i+1], fmt.Sprintf("Rendering metadata: %v", err))...)
+			}
 			continue
 		}
 
@@ -357,6 +472,11 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 		labels["kelos.dev/taskspawner"] = ts.Name
 
 		annotations := mergeStringMaps(renderedAnnotations, sourceAnnotations(&ts, item))
+		if len(ts.Spec.RetriggerOn) > 0 {
+			annotations = mergeStringMaps(annotations, map[string]string{
+				annotationContentHash: contentHash(item, ts.Spec.RetriggerOn),
+			})
+		}
 
 		task := &kelosv1alpha1.Task{
 			ObjectMeta: metav1.ObjectMeta{
@@ -373,6 +493,8 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 				Image:                   ts.Spec.TaskTemplate.Image,
 				TTLSecondsAfterFinished: ts.Spec.TaskTemplate.TTLSecondsAfterFinished,
 				PodOverrides:            ts.Spec.TaskTemplate.PodOverrides,
+				Sidecars:                ts.Spec.TaskTemplate.Sidecars,
+				TranscriptArchive:       ts.Spec.TaskTemplate.TranscriptArchive,
 			},
 		}
 
@@ -382,6 +504,9 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 		if ts.Spec.TaskTemplate.AgentConfigRef != nil {
 			task.Spec.AgentConfigRef = ts.Spec.TaskTemplate.AgentConfigRef
 		}
+		if len(ts.Spec.TaskTemplate.AgentConfigRefs) > 0 {
+			task.Spec.AgentConfigRefs = ts.Spec.TaskTemplate.AgentConfigRefs
+		}
 
 		if len(ts.Spec.TaskTemplate.DependsOn) > 0 {
 			task.Spec.DependsOn = ts.Spec.TaskTemplate.DependsOn
@@ -390,6 +515,9 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 			branch, err := source.RenderTemplate(ts.Spec.TaskTemplate.Branch, item)
 			if err != nil {
 				log.Error(err, "rendering branch template", "item", item.ID)
+				if dryRun {
+					preview = append(preview, skippedPreviewItems(&ts, newItems[i:i+1], fmt.Sprintf("Rendering branch: %v", err))...)
+				}
 				continue
 			}
 			task.Spec.Branch = branch
@@ -404,6 +532,27 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 			task.Spec.UpstreamRepo = upstreamRepo
 		}
 
+		if dryRun {
+			action := kelosv1alpha1.PreviewActionCreate
+			reason, retriggered := retriggerReasons[taskName]
+			if retriggered {
+				action = kelosv1alpha1.PreviewActionRetrigger
+			}
+			preview = append(preview, kelosv1alpha1.TaskSpawnerPreviewItem{
+				ItemID:      item.ID,
+				TaskName:    taskName,
+				Action:      action,
+				Reason:      reason,
+				Branch:      task.Spec.Branch,
+				Prompt:      truncatePreviewPrompt(task.Spec.Prompt),
+				Labels:      task.Labels,
+				Annotations: task.Annotations,
+			})
+			newTasksCreated++
+			activeTasks++
+			continue
+		}
+
 		if err := cl.Create(ctx, task); err != nil {
 			if apierrors.IsAlreadyExists(err) {
 				log.Info("Task already exists, skipping", "task", taskName)
@@ -418,8 +567,28 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 		activeTasks++
 	}
 
+	if dryRun {
+		// Tasks were only previewed; keep the counters and the active
+		// Task count reflecting what actually exists.
+		activeTasks -= newTasksCreated
+		newTasksCreated = 0
+	}
+
 	tasksCreatedTotal.Add(float64(newTasksCreated))
 
+	if dryRun {
+		if reporter, ok := src.(source.FilterReporter); ok {
+			for _, f := range reporter.FilteredItems() {
+				preview = append(preview, kelosv1alpha1.TaskSpawnerPreviewItem{
+					ItemID:   f.ID,
+					TaskName: fmt.Sprintf("%s-%s", ts.Name, f.ID),
+					Action:   kelosv1alpha1.PreviewActionSkip,
+					Reason:   f.Reason,
+				})
+			}
+		}
+	}
+
 	// Update status in a single batch
 	if err := cl.Get(ctx, key, &ts); err != nil {
 		return fmt.Errorf("re-fetching TaskSpawner for status update: %w", err)
@@ -432,6 +601,10 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 	ts.Status.TotalTasksCreated += newTasksCreated
 	ts.Status.ActiveTasks = activeTasks
 	ts.Status.Message = fmt.Sprintf("Discovered %d items, created %d tasks total", ts.Status.TotalDiscovered, ts.Status.TotalTasksCreated)
+	ts.Status.Preview, ts.Status.PreviewOmitted = capPreview(preview)
+	if dryRun {
+		ts.Status.Message = fmt.Sprintf("Dry run: discovered %d items, %s", ts.Status.TotalDiscovered, summarizePreview(preview))
+	}
 
 	// Clear Suspended condition since we are running
 	meta.SetStatusCondition(&ts.Status.Conditions, metav1.Condition{
@@ -471,6 +644,235 @@ func runCycleWithSourceCore(ctx context.Context, cl client.Client, key types.Nam
 	return nil
 }
 
+// loadPromptTemplate reads a prompt template from a ConfigMap key or a
+// repository file.
+func loadPromptTemplate(ctx context.Context, cl client.Client, namespace string, from *kelosv1alpha1.PromptSource, files source.FileFetcher) (string, error) {
+	switch {
+	case from.ConfigMapKeyRef != nil:
+		ref := from.ConfigMapKeyRef
+		var cm corev1.ConfigMap
+		if err := cl.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, &cm); err != nil {
+			return "", fmt.Errorf("fetching ConfigMap %q: %w", ref.Name, err)
+		}
+		value, ok := cm.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("key %q not found in ConfigMap %q", ref.Key, ref.Name)
+		}
+		return value, nil
+	case from.RepoFile != nil:
+		if files == nil {
+			return "", fmt.Errorf("repoFile %q requires a GitHub workspace", from.RepoFile.Path)
+		}
+		return files.FetchFile(ctx, from.RepoFile.Path, from.RepoFile.Ref)
+	}
+	return "", fmt.Errorf("no prompt source configured")
+}
+
+// maxPreviewPromptLength bounds the size of each rendered prompt recorded in
+// status.preview so that large prompts do not bloat the TaskSpawner object.
+const maxPreviewPromptLength = 2048
+
+// maxPreviewItems bounds the number of entries in status.preview so that
+// the TaskSpawner stays well below the object size limit when a source
+// returns many items.
+const maxPreviewItems = 100
+
+// truncatePreviewPrompt shortens a rendered prompt for status.preview,
+// cutting at a rune boundary so the result stays valid UTF-8.
+func truncatePreviewPrompt(prompt string) string {
+	if len(prompt) <= maxPreviewPromptLength {
+		return prompt
+	}
+	cut := maxPreviewPromptLength
+	for cut > 0 && !utf8.RuneStart(prompt[cut]) {
+		cut--
+	}
+	return prompt[:cut] + "\n... (truncated)"
+}
+
+// capPreview limits preview to maxPreviewItems entries, keeping items that
+// would create or retrigger a Task ahead of skipped ones, and returns the
+// number of entries left out.
+func capPreview(preview []kelosv1alpha1.TaskSpawnerPreviewItem) ([]kelosv1alpha1.TaskSpawnerPreviewItem, int) {
+	if len(preview) <= maxPreviewItems {
+		return preview, 0
+	}
+	sorted := append([]kelosv1alpha1.TaskSpawnerPreviewItem(nil), preview...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Action != kelosv1alpha1.PreviewActionSkip && sorted[j].Action == kelosv1alpha1.PreviewActionSkip
+	})
+	return sorted[:maxPreviewItems], len(preview) - maxPreviewItems
+}
+
+// skippedPreviewItems returns Skip preview entries for the given items.
+func skippedPreviewItems(ts *kelosv1alpha1.TaskSpawner, items []source.WorkItem, reason string) []kelosv1alpha1.TaskSpawnerPreviewItem {
+	previews := make([]kelosv1alpha1.TaskSpawnerPreviewItem, 0, len(items))
+	for _, item := range items {
+		previews = append(previews, kelosv1alpha1.TaskSpawnerPreviewItem{
+			ItemID:   item.ID,
+			TaskName: fmt.Sprintf("%s-%s", ts.Name, item.ID),
+			Action:   kelosv1alpha1.PreviewActionSkip,
+			Reason:   reason,
+		})
+	}
+	return previews
+}
+
+// summarizePreview returns a short human-readable summary of a dry-run
+// cycle, e.g. "would create 2, retrigger 1, skip 3 tasks".
+func summarizePreview(preview []kelosv1alpha1.TaskSpawnerPreviewItem) string {
+	counts := make(map[kelosv1alpha1.PreviewAction]int)
+	for _, p := range preview {
+		counts[p.Action]++
+	}
+	return fmt.Sprintf("would create %d, retrigger %d, skip %d tasks",
+		counts[kelosv1alpha1.PreviewActionCreate],
+		counts[kelosv1alpha1.PreviewActionRetrigger],
+		counts[kelosv1alpha1.PreviewActionSkip])
+}
+
+// contentHash returns a stable hash of the work item fields selected by
+// spec.retriggerOn. Kelos status comments are left out so that reporting
+// updates never count as new discussion.
+func contentHash(item source.WorkItem, fields []string) string {
+	h := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(h, "%s\x00", field)
+		switch field {
+		case "body":
+			fmt.Fprintf(h, "%s\x00", item.Body)
+		case "labels":
+			labels := append([]string(nil), item.Labels...)
+			sort.Strings(labels)
+			for _, l := range labels {
+				fmt.Fprintf(h, "%s\x00", l)
+			}
+		case "commits":
+			fmt.Fprintf(h, "%s\x00", item.HeadSHA)
+		case "comments":
+			for _, c := range item.DiscussionComments {
+				if strings.HasPrefix(strings.TrimSpace(c), reporting.StatusCommentHeader) {
+					continue
+				}
+				fmt.Fprintf(h, "%s\x00", c)
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// trackContentHash compares the current content hash of a work item with the
+// hash stored on its Task and reports whether the content changed since the
+// Task finished. While the Task is still running, and on the first cycle
+// that observes it finished, the stored hash is refreshed instead, so edits
+// made by the agent during its own run do not cause a retrigger.
+func trackContentHash(ctx context.Context, cl client.Client, task *kelosv1alpha1.Task, hash string, finished bool) bool {
+	log := ctrl.Log.WithName("spawner")
+
+	if finished && task.Annotations[annotationContentHashSettled] == "true" {
+		return task.Annotations[annotationContentHash] != hash
+	}
+	if task.Annotations[annotationContentHash] == hash && !finished {
+		return false
+	}
+
+	patch := client.MergeFrom(task.DeepCopy())
+	if task.Annotations == nil {
+		task.Annotations = make(map[string]string)
+	}
+	task.Annotations[annotationContentHash] = hash
+	if finished {
+		task.Annotations[annotationContentHashSettled] = "true"
+	}
+	if err := cl.Patch(ctx, task, patch); err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Updating content hash on task", "task", task.Name)
+	}
+	return false
+}
+
+// handleClosedSourceItems applies spec.onSourceClosed to active Tasks whose
+// source item no longer appears in the discovery results and that the
+// source confirms is closed. Items also go missing while still open, when
+// they are past the pagination limit or filtered out by labels or comment
+// policy, so Tasks are left alone when the source cannot look up item state.
+// It returns the number of Tasks that were cancelled or deleted so the
+// caller can stop counting them toward maxConcurrency.
+func handleClosedSourceItems(ctx context.Context, cl client.Client, ts *kelosv1alpha1.TaskSpawner, src source.Source, items []source.WorkItem, tasks []kelosv1alpha1.Task) int {
+	log := ctrl.Log.WithName("spawner")
+
+	policy := ts.Spec.OnSourceClosed
+	if policy != kelosv1alpha1.SourceClosedPolicyCancel && policy != kelosv1alpha1.SourceClosedPolicyDelete {
+		return 0
+	}
+	// Cron items are synthesized per tick, so a missing item never means the
+	// source was closed.
+	if ts.Spec.When.GitHubIssues == nil && ts.Spec.When.GitHubPullRequests == nil && ts.Spec.When.Jira == nil {
+		return 0
+	}
+	checker, ok := src.(source.ItemStateChecker)
+	if !ok {
+		return 0
+	}
+
+	discovered := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		discovered[fmt.Sprintf("%s-%s", ts.Name, item.ID)] = struct{}{}
+	}
+
+	handled := 0
+	for i := range tasks {
+		t := &tasks[i]
+		if t.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || t.Status.Phase == kelosv1alpha1.TaskPhaseFailed {
+			continue
+		}
+		if t.DeletionTimestamp != nil {
+			continue
+		}
+		if _, found := discovered[t.Name]; found {
+			continue
+		}
+		id := strings.TrimPrefix(t.Name, ts.Name+"-")
+		closed, err := checker.IsClosed(ctx, id)
+		if err != nil {
+			log.Error(err, "Checking state of source item", "task", t.Name, "item", id)
+			continue
+		}
+		if !closed {
+			continue
+		}
+
+		switch policy {
+		case kelosv1alpha1.SourceClosedPolicyCancel:
+			if _, requested := t.Annotations[kelosv1alpha1.AnnotationCancelRequested]; requested {
+				handled++
+				continue
+			}
+			patch := client.MergeFrom(t.DeepCopy())
+			if t.Annotations == nil {
+				t.Annotations = make(map[string]string)
+			}
+			t.Annotations[kelosv1alpha1.AnnotationCancelRequested] = "source item closed"
+			if err := cl.Patch(ctx, t, patch); err != nil {
+				if !apierrors.IsNotFound(err) {
+					log.Error(err, "Requesting cancellation of task for closed source item", "task", t.Name)
+				}
+				continue
+			}
+			log.Info("Requested cancellation of task for closed source item", "task", t.Name)
+		case kelosv1alpha1.SourceClosedPolicyDelete:
+			if err := cl.Delete(ctx, t); err != nil {
+				if !apierrors.IsNotFound(err) {
+					log.Error(err, "Deleting task for closed source item", "task", t.Name)
+				}
+				continue
+			}
+			log.Info("Deleted task for closed source item", "task", t.Name)
+		}
+		handled++
+	}
+	return handled
+}
+
 // mergeStringMaps returns a new map with keys from base, then keys from overlay
 // overwriting on duplicate keys.
 func mergeStringMaps(base, overlay map[string]string) map[string]string {
@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -33,12 +37,38 @@ import (
 
 type fakeSource struct {
 	items []source.WorkItem
+	// closed lists the IDs IsClosed reports as closed.
+	closed map[string]bool
+	// stateErr is returned by IsClosed when set.
+	stateErr error
+	// filtered is returned by FilteredItems.
+	filtered []source.FilteredItem
 }
 
 func (f *fakeSource) Discover(_ context.Context) ([]source.WorkItem, error) {
 	return f.items, nil
 }
 
+func (f *fakeSource) IsClosed(_ context.Context, id string) (bool, error) {
+	if f.stateErr != nil {
+		return false, f.stateErr
+	}
+	return f.closed[id], nil
+}
+
+func (f *fakeSource) FilteredItems() []source.FilteredItem {
+	return f.filtered
+}
+
+// discoverOnlySource is a source that cannot look up item state.
+type discoverOnlySource struct {
+	items []source.WorkItem
+}
+
+func (f *discoverOnlySource) Discover(_ context.Context) ([]source.WorkItem, error) {
+	return f.items, nil
+}
+
 func newTestScheme() *runtime.Scheme {
 	s := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(s))
@@ -285,7 +315,7 @@ func TestRunCycleWithSource_NoMaxConcurrency(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -312,7 +342,7 @@ func TestRunCycleWithSource_MaxConcurrencyLimitsCreation(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -344,7 +374,7 @@ func TestRunCycleWithSource_MaxConcurrencyWithExistingActiveTasks(t *testing.T)
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -376,7 +406,7 @@ func TestRunCycleWithSource_CompletedTasksDontCountTowardsLimit(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -402,7 +432,7 @@ func TestRunCycleWithSource_MaxConcurrencyZeroMeansNoLimit(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -431,7 +461,7 @@ func TestRunCycleWithSource_MaxConcurrencyAlreadyAtLimit(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -461,7 +491,7 @@ func TestRunCycleWithSource_ActiveTasksStatusUpdated(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -506,7 +536,7 @@ func TestRunCycleWithSource_StatusUpdateFailureCountsDiscoveryError(t *testing.T
 	beforeErrors := testutil.ToFloat64(discoveryErrorsTotal)
 	beforeTasksCreated := testutil.ToFloat64(tasksCreatedTotal)
 
-	err := runCycleWithSource(context.Background(), cl, key, src)
+	err := runCycleWithSource(context.Background(), cl, key, src, nil)
 	if !errors.Is(err, updateErr) {
 		t.Fatalf("Expected status update error %q, got %v", updateErr, err)
 	}
@@ -566,7 +596,7 @@ func TestRunCycleWithSource_AgentConfigRefForwarded(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -587,6 +617,41 @@ func TestRunCycleWithSource_AgentConfigRefForwarded(t *testing.T) {
 	}
 }
 
+func TestRunCycleWithSource_AgentConfigRefsForwarded(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.TaskTemplate.AgentConfigRefs = []kelosv1alpha1.AgentConfigReference{
+		{Name: "platform-baseline"},
+		{Name: "team-config"},
+	}
+	cl, key := setupTest(t, ts)
+
+	src := &fakeSource{
+		items: []source.WorkItem{
+			{ID: "1", Title: "Item 1"},
+		},
+	}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var taskList kelosv1alpha1.TaskList
+	if err := cl.List(context.Background(), &taskList, client.InNamespace("default")); err != nil {
+		t.Fatalf("Listing tasks: %v", err)
+	}
+	if len(taskList.Items) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(taskList.Items))
+	}
+
+	task := taskList.Items[0]
+	if task.Spec.AgentConfigRef != nil {
+		t.Errorf("Expected AgentConfigRef to be unset, got %v", task.Spec.AgentConfigRef)
+	}
+	if len(task.Spec.AgentConfigRefs) != 2 || task.Spec.AgentConfigRefs[0].Name != "platform-baseline" || task.Spec.AgentConfigRefs[1].Name != "team-config" {
+		t.Errorf("Expected AgentConfigRefs [platform-baseline team-config], got %v", task.Spec.AgentConfigRefs)
+	}
+}
+
 func TestRunCycleWithSource_PodOverridesForwarded(t *testing.T) {
 	ts := newTaskSpawner("spawner", "default", nil)
 	ts.Spec.TaskTemplate.PodOverrides = &kelosv1alpha1.PodOverrides{
@@ -606,7 +671,7 @@ func TestRunCycleWithSource_PodOverridesForwarded(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -646,7 +711,7 @@ func TestRunCycleWithSource_Suspended(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -684,7 +749,7 @@ func TestRunCycleWithSource_SuspendFalseRunsNormally(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -713,10 +778,10 @@ func TestRunCycleWithSource_SuspendedIdempotent(t *testing.T) {
 	}
 
 	// Run twice - should not error on the second run
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("First cycle error: %v", err)
 	}
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Second cycle error: %v", err)
 	}
 
@@ -744,7 +809,7 @@ func TestRunCycleWithSource_MaxTotalTasksLimitsCreation(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -794,7 +859,7 @@ func TestRunCycleWithSource_MaxTotalTasksWithExistingTasks(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -821,7 +886,7 @@ func TestRunCycleWithSource_MaxTotalTasksBudgetAlreadyExhausted(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -848,7 +913,7 @@ func TestRunCycleWithSource_MaxTotalTasksZeroMeansNoLimit(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -875,7 +940,7 @@ func TestRunCycleWithSource_MaxTotalTasksAndMaxConcurrencyCombined(t *testing.T)
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -900,7 +965,7 @@ func TestRunCycleWithSource_SuspendedConditionSet(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -933,7 +998,7 @@ func TestRunCycleWithSource_BranchTemplateRendered(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -957,6 +1022,143 @@ func TestRunCycleWithSource_BranchTemplateRendered(t *testing.T) {
 	}
 }
 
+func TestRunCycleWithSource_PromptSnippetsIncluded(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.TaskTemplate.PromptSnippetsRef = &kelosv1alpha1.ConfigMapReference{Name: "snippets"}
+	ts.Spec.TaskTemplate.PromptTemplate = `{{include "guidelines" .}}`
+	cl, key := setupTest(t, ts)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "snippets", Namespace: "default"},
+		Data:       map[string]string{"guidelines": "Fix issue #{{.Number}} with small commits."},
+	}
+	if err := cl.Create(context.Background(), cm); err != nil {
+		t.Fatalf("Creating ConfigMap: %v", err)
+	}
+
+	src := &fakeSource{items: []source.WorkItem{{ID: "5", Number: 5, Title: "Bug"}}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-5", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Getting task: %v", err)
+	}
+	if want := "Fix issue #5 with small commits."; task.Spec.Prompt != want {
+		t.Errorf("Prompt = %q, want %q", task.Spec.Prompt, want)
+	}
+}
+
+func TestRunCycleWithSource_PromptSnippetsMissingConfigMap(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.TaskTemplate.PromptSnippetsRef = &kelosv1alpha1.ConfigMapReference{Name: "missing"}
+	cl, key := setupTest(t, ts)
+
+	src := &fakeSource{items: []source.WorkItem{{ID: "1", Title: "Item"}}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err == nil {
+		t.Fatal("Expected error when the snippets ConfigMap is missing")
+	}
+}
+
+type fakeFileFetcher struct {
+	files map[string]string
+}
+
+func (f *fakeFileFetcher) FetchFile(_ context.Context, path, _ string) (string, error) {
+	content, ok := f.files[path]
+	if !ok {
+		return "", fmt.Errorf("file %s not found", path)
+	}
+	return content, nil
+}
+
+func TestRunCycleWithSource_PromptTemplateFromConfigMap(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.TaskTemplate.PromptTemplateFrom = &kelosv1alpha1.PromptSource{
+		ConfigMapKeyRef: &kelosv1alpha1.ConfigMapKeyReference{Name: "prompts", Key: "issue"},
+	}
+	cl, key := setupTest(t, ts)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "prompts", Namespace: "default"},
+		Data:       map[string]string{"issue": "Resolve {{.Title}}"},
+	}
+	if err := cl.Create(context.Background(), cm); err != nil {
+		t.Fatalf("Creating ConfigMap: %v", err)
+	}
+
+	src := &fakeSource{items: []source.WorkItem{{ID: "1", Title: "Flaky test"}}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Getting task: %v", err)
+	}
+	if task.Spec.Prompt != "Resolve Flaky test" {
+		t.Errorf("Prompt = %q, want %q", task.Spec.Prompt, "Resolve Flaky test")
+	}
+}
+
+func TestRunCycleWithSource_PromptTemplateFromRepoFile(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.TaskTemplate.PromptTemplateFrom = &kelosv1alpha1.PromptSource{
+		RepoFile: &kelosv1alpha1.RepoFileReference{Path: "self-development/prompts/update.md"},
+	}
+	cl, key := setupTest(t, ts)
+
+	files := &fakeFileFetcher{files: map[string]string{
+		"self-development/prompts/update.md": "Update for {{.ID}}",
+	}}
+	src := &fakeSource{items: []source.WorkItem{{ID: "7", Title: "Item"}}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, files); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-7", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Getting task: %v", err)
+	}
+	if task.Spec.Prompt != "Update for 7" {
+		t.Errorf("Prompt = %q, want %q", task.Spec.Prompt, "Update for 7")
+	}
+}
+
+func TestRunCycleWithSource_PromptTemplateFromRepoFileWithoutFetcher(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.TaskTemplate.PromptTemplateFrom = &kelosv1alpha1.PromptSource{
+		RepoFile: &kelosv1alpha1.RepoFileReference{Path: "prompt.md"},
+	}
+	cl, key := setupTest(t, ts)
+
+	src := &fakeSource{items: []source.WorkItem{{ID: "1", Title: "Item"}}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err == nil {
+		t.Fatal("Expected error when no file fetcher is available")
+	}
+}
+
+func TestReferencesConfigMap(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	if referencesConfigMap(ts, "prompts") {
+		t.Error("Expected no ConfigMap references by default")
+	}
+
+	ts.Spec.TaskTemplate.PromptSnippetsRef = &kelosv1alpha1.ConfigMapReference{Name: "snippets"}
+	ts.Spec.TaskTemplate.PromptTemplateFrom = &kelosv1alpha1.PromptSource{
+		ConfigMapKeyRef: &kelosv1alpha1.ConfigMapKeyReference{Name: "prompts", Key: "issue"},
+	}
+	for _, name := range []string{"snippets", "prompts"} {
+		if !referencesConfigMap(ts, name) {
+			t.Errorf("Expected ConfigMap %q to be referenced", name)
+		}
+	}
+	if referencesConfigMap(ts, "unrelated") {
+		t.Error("Expected unrelated ConfigMap not to be referenced")
+	}
+}
+
 func TestRunCycleWithSource_BranchStaticPassedThrough(t *testing.T) {
 	ts := newTaskSpawner("spawner", "default", nil)
 	ts.Spec.TaskTemplate.Branch = "feature/my-branch"
@@ -968,7 +1170,7 @@ func TestRunCycleWithSource_BranchStaticPassedThrough(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -995,7 +1197,7 @@ func TestRunCycleWithSource_NotSuspendedConditionCleared(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1029,7 +1231,7 @@ func TestRunCycleWithSource_PriorityLabelsOrderCreation(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1071,7 +1273,7 @@ func TestRunCycleWithSource_PriorityLabelsNotConfigured(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1315,7 +1517,7 @@ func TestRunCycleWithSource_RetriggerCompletedTask(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1351,7 +1553,7 @@ func TestRunCycleWithSource_RetriggerSkippedWhenTriggerBeforeCompletion(t *testi
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1386,7 +1588,7 @@ func TestRunCycleWithSource_RetriggerFailedTask(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1420,7 +1622,7 @@ func TestRunCycleWithSource_RetriggerFromSourceTriggerTime(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1453,7 +1655,7 @@ func TestRunCycleWithSource_RetriggerSkippedForRunningTask(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1488,7 +1690,7 @@ func TestRunCycleWithSource_RetriggerRespectsMaxConcurrency(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1511,6 +1713,618 @@ func TestRunCycleWithSource_RetriggerRespectsMaxConcurrency(t *testing.T) {
 	}
 }
 
+func TestRunCycleWithSource_RetriggerOnStampsContentHash(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.RetriggerOn = []string{"body"}
+	cl, key := setupTest(t, ts)
+
+	item := source.WorkItem{ID: "1", Title: "Item", Body: "original"}
+	src := &fakeSource{items: []source.WorkItem{item}}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Getting task: %v", err)
+	}
+	if got, want := task.Annotations[annotationContentHash], contentHash(item, ts.Spec.RetriggerOn); got != want {
+		t.Errorf("Content hash = %q, want %q", got, want)
+	}
+}
+
+func TestRunCycleWithSource_RetriggerOnContentChange(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.RetriggerOn = []string{"body", "labels"}
+
+	original := source.WorkItem{ID: "1", Title: "Item", Body: "original", Labels: []string{"bug"}}
+	task := newCompletedTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseSucceeded, time.Now().Add(-time.Hour))
+	task.Annotations = map[string]string{
+		annotationContentHash:        contentHash(original, ts.Spec.RetriggerOn),
+		annotationContentHashSettled: "true",
+	}
+	cl, key := setupTest(t, ts, task)
+
+	edited := original
+	edited.Body = "edited"
+	src := &fakeSource{items: []source.WorkItem{edited}}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var recreated kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &recreated); err != nil {
+		t.Fatalf("Expected task to be recreated: %v", err)
+	}
+	if recreated.Status.Phase != "" {
+		t.Errorf("Expected fresh task, got phase %q", recreated.Status.Phase)
+	}
+	if got, want := recreated.Annotations[annotationContentHash], contentHash(edited, ts.Spec.RetriggerOn); got != want {
+		t.Errorf("Content hash = %q, want %q", got, want)
+	}
+}
+
+func TestRunCycleWithSource_RetriggerOnIgnoresChangesDuringRun(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.RetriggerOn = []string{"body"}
+
+	original := source.WorkItem{ID: "1", Title: "Item", Body: "original"}
+	task := newCompletedTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseSucceeded, time.Now())
+	task.Annotations = map[string]string{
+		annotationContentHash: contentHash(original, ts.Spec.RetriggerOn),
+	}
+	cl, key := setupTest(t, ts, task)
+
+	// The body was edited while the task was running; the first cycle that
+	// sees the task finished records the new hash instead of retriggering.
+	edited := original
+	edited.Body = "edited by agent"
+	src := &fakeSource{items: []source.WorkItem{edited}}
+
+	for i := 0; i < 2; i++ {
+		if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	var got kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &got); err != nil {
+		t.Fatalf("Getting task: %v", err)
+	}
+	if got.Status.Phase != kelosv1alpha1.TaskPhaseSucceeded {
+		t.Errorf("Expected task not to be retriggered, got phase %q", got.Status.Phase)
+	}
+	if got.Annotations[annotationContentHashSettled] != "true" {
+		t.Error("Expected content hash to be settled")
+	}
+	if got.Annotations[annotationContentHash] != contentHash(edited, ts.Spec.RetriggerOn) {
+		t.Error("Expected content hash to be refreshed")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	base := source.WorkItem{
+		Body:               "body",
+		Labels:             []string{"a", "b"},
+		HeadSHA:            "abc",
+		DiscussionComments: []string{"first"},
+	}
+
+	tests := []struct {
+		name    string
+		fields  []string
+		modify  func(*source.WorkItem)
+		changed bool
+	}{
+		{
+			name:    "body change",
+			fields:  []string{"body"},
+			modify:  func(i *source.WorkItem) { i.Body = "new" },
+			changed: true,
+		},
+		{
+			name:    "body change not selected",
+			fields:  []string{"labels"},
+			modify:  func(i *source.WorkItem) { i.Body = "new" },
+			changed: false,
+		},
+		{
+			name:    "label order ignored",
+			fields:  []string{"labels"},
+			modify:  func(i *source.WorkItem) { i.Labels = []string{"b", "a"} },
+			changed: false,
+		},
+		{
+			name:    "new commit",
+			fields:  []string{"commits"},
+			modify:  func(i *source.WorkItem) { i.HeadSHA = "def" },
+			changed: true,
+		},
+		{
+			name:   "new comment",
+			fields: []string{"comments"},
+			modify: func(i *source.WorkItem) {
+				i.DiscussionComments = append(i.DiscussionComments, "second")
+			},
+			changed: true,
+		},
+		{
+			name:   "status comment ignored",
+			fields: []string{"comments"},
+			modify: func(i *source.WorkItem) {
+				i.DiscussionComments = append(i.DiscussionComments, reporting.FormatSucceededComment("spawner-1"))
+			},
+			changed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := base
+			modified.Labels = append([]string(nil), base.Labels...)
+			modified.DiscussionComments = append([]string(nil), base.DiscussionComments...)
+			tt.modify(&modified)
+
+			changed := contentHash(base, tt.fields) != contentHash(modified, tt.fields)
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestRunCycleWithSource_DryRunRecordsPreview(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", int32Ptr(2))
+	ts.Spec.DryRun = boolPtr(true)
+	ts.Spec.TaskTemplate.Branch = "kelos-{{.Number}}"
+	ts.Spec.TaskTemplate.PromptTemplate = "Fix {{.Title}}"
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+		newCompletedTask("spawner-2", "default", "spawner", kelosv1alpha1.TaskPhaseSucceeded, time.Now().Add(-time.Hour)),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	src := &fakeSource{
+		items: []source.WorkItem{
+			{ID: "1", Number: 1, Title: "Running"},
+			{ID: "2", Number: 2, Title: "Retriggered", TriggerTime: time.Now()},
+			{ID: "3", Number: 3, Title: "New"},
+		},
+	}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var taskList kelosv1alpha1.TaskList
+	if err := cl.List(context.Background(), &taskList, client.InNamespace("default")); err != nil {
+		t.Fatalf("Listing tasks: %v", err)
+	}
+	if len(taskList.Items) != 2 {
+		t.Fatalf("Expected dry run not to create or delete tasks, got %d tasks", len(taskList.Items))
+	}
+	for _, task := range taskList.Items {
+		if task.Name == "spawner-2" && task.Status.Phase != kelosv1alpha1.TaskPhaseSucceeded {
+			t.Errorf("Expected retriggered task to be left untouched, got phase %q", task.Status.Phase)
+		}
+	}
+
+	var updated kelosv1alpha1.TaskSpawner
+	if err := cl.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("Getting TaskSpawner: %v", err)
+	}
+	if updated.Status.TotalTasksCreated != 0 {
+		t.Errorf("TotalTasksCreated = %d, want 0", updated.Status.TotalTasksCreated)
+	}
+	if updated.Status.ActiveTasks != 1 {
+		t.Errorf("ActiveTasks = %d, want 1", updated.Status.ActiveTasks)
+	}
+
+	want := map[string]struct {
+		action kelosv1alpha1.PreviewAction
+		reason string
+	}{
+		"spawner-1": {kelosv1alpha1.PreviewActionSkip, "Task is still active"},
+		"spawner-2": {kelosv1alpha1.PreviewActionRetrigger, "New trigger after Task finished"},
+		"spawner-3": {kelosv1alpha1.PreviewActionSkip, "maxConcurrency (2) reached"},
+	}
+	if len(updated.Status.Preview) != len(want) {
+		t.Fatalf("Expected %d preview items, got %d: %+v", len(want), len(updated.Status.Preview), updated.Status.Preview)
+	}
+	for _, p := range updated.Status.Preview {
+		w, ok := want[p.TaskName]
+		if !ok {
+			t.Errorf("Unexpected preview item %q", p.TaskName)
+			continue
+		}
+		if p.Action != w.action || p.Reason != w.reason {
+			t.Errorf("Preview %q = (%s, %q), want (%s, %q)", p.TaskName, p.Action, p.Reason, w.action, w.reason)
+		}
+		if p.TaskName == "spawner-2" {
+			if p.Branch != "kelos-2" {
+				t.Errorf("Preview branch = %q, want %q", p.Branch, "kelos-2")
+			}
+			if p.Prompt != "Fix Retriggered" {
+				t.Errorf("Preview prompt = %q, want %q", p.Prompt, "Fix Retriggered")
+			}
+		}
+	}
+}
+
+func TestRunCycleWithSource_DryRunListsFilteredItems(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.DryRun = boolPtr(true)
+	cl, key := setupTest(t, ts)
+
+	src := &fakeSource{
+		items: []source.WorkItem{{ID: "1", Number: 1, Title: "New"}},
+		filtered: []source.FilteredItem{
+			{ID: "2", Reason: `Filtered out by exclude label "wontfix"`},
+			{ID: "3", Reason: "Filtered out by comment policy"},
+		},
+	}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var updated kelosv1alpha1.TaskSpawner
+	if err := cl.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("Getting TaskSpawner: %v", err)
+	}
+
+	want := map[string]string{
+		"spawner-2": `Filtered out by exclude label "wontfix"`,
+		"spawner-3": "Filtered out by comment policy",
+	}
+	for _, p := range updated.Status.Preview {
+		reason, ok := want[p.TaskName]
+		if !ok {
+			continue
+		}
+		if p.Action != kelosv1alpha1.PreviewActionSkip || p.Reason != reason {
+			t.Errorf("Preview %q = (%s, %q), want (Skip, %q)", p.TaskName, p.Action, p.Reason, reason)
+		}
+		delete(want, p.TaskName)
+	}
+	if len(want) > 0 {
+		t.Errorf("Expected filtered items in preview, missing %v: %+v", want, updated.Status.Preview)
+	}
+}
+
+func TestRunCycleWithSource_DryRunCapsPreview(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", int32Ptr(5))
+	ts.Spec.DryRun = boolPtr(true)
+	cl, key := setupTest(t, ts)
+
+	src := &fakeSource{}
+	for i := 1; i <= maxPreviewItems+50; i++ {
+		src.items = append(src.items, source.WorkItem{ID: strconv.Itoa(i), Number: i, Title: "Item"})
+	}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var updated kelosv1alpha1.TaskSpawner
+	if err := cl.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("Getting TaskSpawner: %v", err)
+	}
+	if len(updated.Status.Preview) != maxPreviewItems {
+		t.Fatalf("Expected %d preview items, got %d", maxPreviewItems, len(updated.Status.Preview))
+	}
+	if updated.Status.PreviewOmitted != 50 {
+		t.Errorf("PreviewOmitted = %d, want 50", updated.Status.PreviewOmitted)
+	}
+	creates := 0
+	for _, p := range updated.Status.Preview[:5] {
+		if p.Action == kelosv1alpha1.PreviewActionCreate {
+			creates++
+		}
+	}
+	if creates != 5 {
+		t.Errorf("Expected the 5 Create items to be kept first, got %+v", updated.Status.Preview[:5])
+	}
+	if !strings.Contains(updated.Status.Message, "would create 5, retrigger 0, skip 145 tasks") {
+		t.Errorf("Expected message to count every item, got %q", updated.Status.Message)
+	}
+}
+
+func TestTruncatePreviewPrompt(t *testing.T) {
+	short := "Fix the bug"
+	if got := truncatePreviewPrompt(short); got != short {
+		t.Errorf("truncatePreviewPrompt(short) = %q, want unchanged", got)
+	}
+
+	// A three-byte rune straddles the length limit.
+	long := strings.Repeat("a", maxPreviewPromptLength-1) + "€" + strings.Repeat("b", 10)
+	got := truncatePreviewPrompt(long)
+	if !utf8.ValidString(got) {
+		t.Fatalf("Expected valid UTF-8 after truncation, got %q", got[len(got)-20:])
+	}
+	want := strings.Repeat("a", maxPreviewPromptLength-1) + "\n... (truncated)"
+	if got != want {
+		t.Errorf("Expected truncation before the split rune, got suffix %q", got[len(got)-20:])
+	}
+}
+
+func TestRunCycleWithSource_DryRunDisabledClearsPreview(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Status.Preview = []kelosv1alpha1.TaskSpawnerPreviewItem{
+		{ItemID: "1", TaskName: "spawner-1", Action: kelosv1alpha1.PreviewActionCreate},
+	}
+	cl, key := setupTest(t, ts)
+
+	src := &fakeSource{items: []source.WorkItem{{ID: "1", Title: "Item"}}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var updated kelosv1alpha1.TaskSpawner
+	if err := cl.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("Getting TaskSpawner: %v", err)
+	}
+	if len(updated.Status.Preview) != 0 {
+		t.Errorf("Expected preview to be cleared, got %+v", updated.Status.Preview)
+	}
+	if updated.Status.TotalTasksCreated != 1 {
+		t.Errorf("TotalTasksCreated = %d, want 1", updated.Status.TotalTasksCreated)
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedCancel(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", int32Ptr(1))
+	ts.Spec.OnSourceClosed = kelosv1alpha1.SourceClosedPolicyCancel
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+		newCompletedTask("spawner-2", "default", "spawner", kelosv1alpha1.TaskPhaseSucceeded, time.Now()),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	// Item 1 was closed; item 3 is new.
+	src := &fakeSource{
+		items: []source.WorkItem{
+			{ID: "3", Title: "New item"},
+		},
+		closed: map[string]bool{"1": true},
+	}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var closed kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &closed); err != nil {
+		t.Fatalf("Getting closed task: %v", err)
+	}
+	if got := closed.Annotations[kelosv1alpha1.AnnotationCancelRequested]; got != "source item closed" {
+		t.Errorf("Cancel annotation = %q, want %q", got, "source item closed")
+	}
+
+	var finished kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-2", Namespace: "default"}, &finished); err != nil {
+		t.Fatalf("Getting finished task: %v", err)
+	}
+	if _, ok := finished.Annotations[kelosv1alpha1.AnnotationCancelRequested]; ok {
+		t.Error("Expected finished task not to be cancelled")
+	}
+
+	// The cancelled task no longer counts toward maxConcurrency.
+	var created kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-3", Namespace: "default"}, &created); err != nil {
+		t.Fatalf("Expected new task to be created: %v", err)
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedDelete(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.OnSourceClosed = kelosv1alpha1.SourceClosedPolicyDelete
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseWaiting),
+		newTask("spawner-2", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	src := &fakeSource{
+		items: []source.WorkItem{
+			{ID: "2", Title: "Still open"},
+		},
+		closed: map[string]bool{"1": true},
+	}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var taskList kelosv1alpha1.TaskList
+	if err := cl.List(context.Background(), &taskList, client.InNamespace("default")); err != nil {
+		t.Fatalf("Listing tasks: %v", err)
+	}
+	if len(taskList.Items) != 1 {
+		t.Fatalf("Expected 1 task after deleting the closed item's task, got %d", len(taskList.Items))
+	}
+	if taskList.Items[0].Name != "spawner-2" {
+		t.Errorf("Expected spawner-2 to remain, got %q", taskList.Items[0].Name)
+	}
+
+	var updatedTS kelosv1alpha1.TaskSpawner
+	if err := cl.Get(context.Background(), key, &updatedTS); err != nil {
+		t.Fatalf("Getting TaskSpawner: %v", err)
+	}
+	if updatedTS.Status.ActiveTasks != 1 {
+		t.Errorf("ActiveTasks = %d, want 1", updatedTS.Status.ActiveTasks)
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedIgnoreByDefault(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	src := &fakeSource{}
+
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Expected task to remain: %v", err)
+	}
+	if _, ok := task.Annotations[kelosv1alpha1.AnnotationCancelRequested]; ok {
+		t.Error("Expected task not to be cancelled when onSourceClosed is unset")
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedSkipsUnconfirmedItems(t *testing.T) {
+	tests := []struct {
+		name string
+		src  source.Source
+	}{
+		{
+			// The item is past the pagination limit or was filtered out by
+			// labels or comment policy, but is still open.
+			name: "item still open",
+			src:  &fakeSource{closed: map[string]bool{}},
+		},
+		{
+			name: "state lookup fails",
+			src:  &fakeSource{stateErr: errors.New("rate limited")},
+		},
+		{
+			name: "source cannot look up state",
+			src:  &discoverOnlySource{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTaskSpawner("spawner", "default", nil)
+			ts.Spec.OnSourceClosed = kelosv1alpha1.SourceClosedPolicyDelete
+
+			existingTasks := []kelosv1alpha1.Task{
+				newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+			}
+			cl, key := setupTest(t, ts, existingTasks...)
+
+			if err := runCycleWithSource(context.Background(), cl, key, tt.src, nil); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var task kelosv1alpha1.Task
+			if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &task); err != nil {
+				t.Fatalf("Expected task to remain: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedKeepsTruncatedGitHubItems(t *testing.T) {
+	// Every page links to a next page, so discovery stops at the page
+	// limit and never returns issue 1; the issue itself is still open.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos/owner/repo/issues?page=%d>; rel="next"`, r.Host, page+1))
+			fmt.Fprintf(w, `[{"number": %d, "title": "Issue", "state": "open"}]`, 1000+page)
+		case "/repos/owner/repo/issues/1":
+			fmt.Fprint(w, `{"number": 1, "state": "open"}`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.OnSourceClosed = kelosv1alpha1.SourceClosedPolicyDelete
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	src := &source.GitHubSource{Owner: "owner", Repo: "repo", BaseURL: server.URL}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Expected task for an open item past the page limit to remain: %v", err)
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedKeepsFilteredGitHubItems(t *testing.T) {
+	// Issue 1 gained an exclude label, so discovery filters it out, but it
+	// is still open.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues":
+			fmt.Fprint(w, `[{"number": 1, "title": "Issue", "labels": [{"name": "wontfix"}]}]`)
+		case "/repos/owner/repo/issues/1":
+			fmt.Fprint(w, `{"number": 1, "state": "open"}`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.OnSourceClosed = kelosv1alpha1.SourceClosedPolicyCancel
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-1", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	src := &source.GitHubSource{Owner: "owner", Repo: "repo", BaseURL: server.URL, ExcludeLabels: []string{"wontfix"}}
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-1", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Getting task: %v", err)
+	}
+	if _, ok := task.Annotations[kelosv1alpha1.AnnotationCancelRequested]; ok {
+		t.Error("Expected task for a filtered but open item not to be cancelled")
+	}
+}
+
+func TestRunCycleWithSource_OnSourceClosedSkipsCron(t *testing.T) {
+	ts := newTaskSpawner("spawner", "default", nil)
+	ts.Spec.When = kelosv1alpha1.When{
+		Cron: &kelosv1alpha1.Cron{Schedule: "0 * * * *"},
+	}
+	ts.Spec.OnSourceClosed = kelosv1alpha1.SourceClosedPolicyDelete
+
+	existingTasks := []kelosv1alpha1.Task{
+		newTask("spawner-20260101-0900", "default", "spawner", kelosv1alpha1.TaskPhaseRunning),
+	}
+	cl, key := setupTest(t, ts, existingTasks...)
+
+	if err := runCycleWithSource(context.Background(), cl, key, &fakeSource{}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var task kelosv1alpha1.Task
+	if err := cl.Get(context.Background(), types.NamespacedName{Name: "spawner-20260101-0900", Namespace: "default"}, &task); err != nil {
+		t.Fatalf("Expected cron task to remain: %v", err)
+	}
+}
+
 func TestDeriveUpstreamRepo(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1634,7 +2448,7 @@ func TestRunCycleWithSource_PropagatesUpstreamRepo(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1678,7 +2492,7 @@ func TestRunCycleWithSource_ExplicitUpstreamRepoTakesPrecedence(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1830,7 +2644,7 @@ func TestRunCycleWithSource_AnnotationsStamped(t *testing.T) {
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1868,7 +2682,7 @@ func TestRunCycleWithSource_TaskTemplateMetadataLabelsAndAnnotations(t *testing.
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1910,7 +2724,7 @@ func TestRunCycleWithSource_TaskTemplateMetadataReservedAnnotationsPrecedence(t
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
@@ -1948,7 +2762,7 @@ func TestRunCycleWithSource_TaskTemplateMetadataTaskSpawnerLabelWins(t *testing.
 		},
 	}
 
-	if err := runCycleWithSource(context.Background(), cl, key, src); err != nil {
+	if err := runCycleWithSource(context.Background(), cl, key, src, nil); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
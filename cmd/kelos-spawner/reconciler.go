@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -65,6 +66,10 @@ func (r *spawnerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.requestsForTask),
 			builder.WithPredicates(r.taskPredicate()),
 		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForConfigMap),
+		).
 		Complete(r)
 }
 
@@ -130,6 +135,33 @@ func (r *spawnerReconciler) requestsForTask(_ context.Context, obj client.Object
 	return []reconcile.Request{{NamespacedName: r.Key}}
 }
 
+// requestsForConfigMap triggers a cycle when a ConfigMap that the
+// TaskSpawner loads its prompt template or snippets from changes.
+func (r *spawnerReconciler) requestsForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != r.Key.Namespace {
+		return nil
+	}
+	var ts kelosv1alpha1.TaskSpawner
+	if err := r.Get(ctx, r.Key, &ts); err != nil {
+		return nil
+	}
+	if !referencesConfigMap(&ts, obj.GetName()) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: r.Key}}
+}
+
+// referencesConfigMap reports whether the TaskSpawner's task template reads
+// the named ConfigMap.
+func referencesConfigMap(ts *kelosv1alpha1.TaskSpawner, name string) bool {
+	tmpl := ts.Spec.TaskTemplate
+	if tmpl.PromptSnippetsRef != nil && tmpl.PromptSnippetsRef.Name == name {
+		return true
+	}
+	from := tmpl.PromptTemplateFrom
+	return from != nil && from.ConfigMapKeyRef != nil && from.ConfigMapKeyRef.Name == name
+}
+
 func (r *spawnerReconciler) taskSpawnerPredicate() predicate.Predicate {
 	return predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
@@ -20,9 +20,15 @@ const (
 )
 
 // Run captures deterministic outputs (branch, commit, PRs, token usage) from
-// the workspace and emits them between markers to stdout. Returns 0 on success.
+// the workspace, archives the agent transcript when configured, and emits
+// the outputs between markers to stdout. Returns 0 on success.
 func Run() int {
 	outputs := captureOutputs(realRunner{}, agentOutputFile)
+	if uri, err := archiveTranscript(agentOutputFile, os.Getenv, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "kelos-capture: archiving transcript: %v\n", err)
+	} else if uri != "" {
+		outputs = append(outputs, "transcript: "+uri)
+	}
 	if len(outputs) == 0 {
 		return 0
 	}
@@ -37,14 +43,23 @@ func Run() int {
 // runner abstracts command execution for testing.
 type runner interface {
 	run(name string, args ...string) (string, error)
+	// runWithEnv is run with env added to the command's environment.
+	runWithEnv(env []string, name string, args ...string) (string, error)
 }
 
 type realRunner struct{}
 
-func (realRunner) run(name string, args ...string) (string, error) {
+func (r realRunner) run(name string, args ...string) (string, error) {
+	return r.runWithEnv(nil, name, args...)
+}
+
+func (realRunner) runWithEnv(env []string, name string, args ...string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
 	defer cancel()
 	cmd := exec.CommandContext(ctx, name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = io.Discard
@@ -82,8 +97,15 @@ func captureOutputs(r runner, usageFile string) []string {
 		}
 	}
 
-	agentType := os.Getenv("KELOS_AGENT_TYPE")
-	usage := ParseUsage(agentType, usageFile)
+	outputs = append(outputs, captureAdditionalRepos(r, os.Getenv("KELOS_ADDITIONAL_REPOS"))...)
+
+	// Agents registered through an AgentType name the built-in agent
+	// whose output format they share.
+	usageFormat := os.Getenv("KELOS_USAGE_FORMAT")
+	if usageFormat == "" {
+		usageFormat = os.Getenv("KELOS_AGENT_TYPE")
+	}
+	usage := ParseUsage(usageFormat, usageFile)
 	for _, key := range []string{"cost-usd", "input-tokens", "output-tokens"} {
 		if v, ok := usage[key]; ok {
 			outputs = append(outputs, key+": "+v)
@@ -93,6 +115,51 @@ func captureOutputs(r runner, usageFile string) []string {
 	return outputs
 }
 
+// additionalRepo is an entry of KELOS_ADDITIONAL_REPOS, which describes the
+// repositories cloned next to the main workspace repository. TokenEnv names
+// the variable holding the repository's own token, if it has one.
+type additionalRepo struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Repo     string `json:"repo"`
+	TokenEnv string `json:"tokenEnv"`
+}
+
+// captureAdditionalRepos reports the branch, pull requests and commit of
+// every additional repository, with keys suffixed by the repository name.
+func captureAdditionalRepos(r runner, reposJSON string) []string {
+	if reposJSON == "" {
+		return nil
+	}
+	var repos []additionalRepo
+	if err := json.Unmarshal([]byte(reposJSON), &repos); err != nil {
+		fmt.Fprintf(os.Stderr, "kelos-capture: parsing KELOS_ADDITIONAL_REPOS: %v\n", err)
+		return nil
+	}
+
+	var outputs []string
+	for _, repo := range repos {
+		if repo.Name == "" || repo.Path == "" {
+			continue
+		}
+		branch, err := r.run("git", "-C", repo.Path, "branch", "--show-current")
+		if err == nil && branch != "" {
+			outputs = append(outputs, "branch."+repo.Name+": "+branch)
+			if repo.Repo != "" {
+				for _, line := range queryPRs(r, branch, repo.Repo, repoTokenEnv(repo.TokenEnv)) {
+					outputs = append(outputs, "pr."+repo.Name+strings.TrimPrefix(line, "pr"))
+				}
+			}
+		}
+
+		commit, err := r.run("git", "-C", repo.Path, "rev-parse", "HEAD")
+		if err == nil && commit != "" {
+			outputs = append(outputs, "commit."+repo.Name+": "+commit)
+		}
+	}
+	return outputs
+}
+
 func isGitRepo(r runner) bool {
 	_, err := r.run("git", "rev-parse", "--is-inside-work-tree")
 	return err == nil
@@ -100,22 +167,37 @@ func isGitRepo(r runner) bool {
 
 func capturePRs(r runner, branch string) []string {
 	// Check origin repo (current behavior)
-	lines := queryPRs(r, branch, "")
+	lines := queryPRs(r, branch, "", nil)
 
 	// Also check upstream repo if set (fork workflow)
 	if upstreamRepo := os.Getenv("KELOS_UPSTREAM_REPO"); upstreamRepo != "" {
-		lines = append(lines, queryPRs(r, branch, upstreamRepo)...)
+		lines = append(lines, queryPRs(r, branch, upstreamRepo, nil)...)
 	}
 
 	return lines
 }
 
-func queryPRs(r runner, branch, repo string) []string {
+// repoTokenEnv returns the gh environment that authenticates with the token
+// in the variable tokenEnv, or nil to use the workspace credentials.
+func repoTokenEnv(tokenEnv string) []string {
+	if tokenEnv == "" {
+		return nil
+	}
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return nil
+	}
+	return []string{"GH_TOKEN=" + token, "GH_ENTERPRISE_TOKEN=" + token}
+}
+
+// queryPRs lists the pull requests for branch in repo, or in the origin
+// repository when repo is empty. env is added to the gh environment.
+func queryPRs(r runner, branch, repo string, env []string) []string {
 	args := []string{"pr", "list", "--head", branch, "--json", "url"}
 	if repo != "" {
 		args = append(args, "--repo", repo)
 	}
-	output, err := r.run("gh", args...)
+	output, err := r.runWithEnv(env, "gh", args...)
 	if err != nil || output == "" {
 		return nil
 	}
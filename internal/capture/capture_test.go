@@ -18,7 +18,15 @@ type mockResult struct {
 }
 
 func (m mockRunner) run(name string, args ...string) (string, error) {
+	return m.runWithEnv(nil, name, args...)
+}
+
+// runWithEnv prefixes the command key with the added environment, if any.
+func (m mockRunner) runWithEnv(env []string, name string, args ...string) (string, error) {
 	key := name + " " + strings.Join(args, " ")
+	if len(env) > 0 {
+		key = strings.Join(env, " ") + " " + key
+	}
 	if r, ok := m.commands[key]; ok {
 		return r.output, r.err
 	}
@@ -291,3 +299,85 @@ func TestMain(m *testing.M) {
 	os.Unsetenv("KELOS_UPSTREAM_REPO")
 	os.Exit(m.Run())
 }
+
+func TestCaptureOutputsWithAdditionalRepos(t *testing.T) {
+	r := mockRunner{commands: map[string]mockResult{
+		"git rev-parse --is-inside-work-tree": {output: "true"},
+		"git branch --show-current":           {output: "feature"},
+		"gh pr list --head feature --json url": {
+			output: `[{"url":"https://github.com/org/repo/pull/1"}]`,
+		},
+		"git rev-parse HEAD":                                  {output: "abc123"},
+		"git -C /workspace/api branch --show-current":         {output: "feature"},
+		"git -C /workspace/api rev-parse HEAD":                {output: "def456"},
+		"gh pr list --head feature --json url --repo org/api": {output: `[{"url":"https://github.com/org/api/pull/7"}]`},
+		"git -C /workspace/docs branch --show-current":        {output: "feature"},
+		"git -C /workspace/docs rev-parse HEAD":               {output: "789abc"},
+	}}
+
+	t.Setenv("KELOS_BASE_BRANCH", "main")
+	t.Setenv("KELOS_AGENT_TYPE", "")
+	t.Setenv("KELOS_ADDITIONAL_REPOS",
+		`[{"name":"api","path":"/workspace/api","repo":"org/api"},{"name":"docs","path":"/workspace/docs"}]`)
+
+	outputs := captureOutputs(r, "/nonexistent")
+
+	expected := []string{
+		"branch: feature",
+		"pr: https://github.com/org/repo/pull/1",
+		"commit: abc123",
+		"base-branch: main",
+		"branch.api: feature",
+		"pr.api: https://github.com/org/api/pull/7",
+		"commit.api: def456",
+		"branch.docs: feature",
+		"commit.docs: 789abc",
+	}
+	assertOutputLines(t, expected, outputs)
+}
+
+func TestCaptureAdditionalReposUsesRepoToken(t *testing.T) {
+	r := mockRunner{commands: map[string]mockResult{
+		"git -C /workspace/infra branch --show-current": {output: "feature"},
+		"git -C /workspace/infra rev-parse HEAD":        {output: "def456"},
+		"GH_TOKEN=infra-token GH_ENTERPRISE_TOKEN=infra-token gh pr list --head feature --json url --repo github.example.com/ops/infra": {
+			output: `[{"url":"https://github.example.com/ops/infra/pull/3"}]`,
+		},
+	}}
+	t.Setenv("KELOS_GIT_TOKEN_INFRA", "infra-token")
+
+	outputs := captureAdditionalRepos(r,
+		`[{"name":"infra","path":"/workspace/infra","repo":"github.example.com/ops/infra","tokenEnv":"KELOS_GIT_TOKEN_INFRA"}]`)
+
+	expected := []string{
+		"branch.infra: feature",
+		"pr.infra: https://github.example.com/ops/infra/pull/3",
+		"commit.infra: def456",
+	}
+	assertOutputLines(t, expected, outputs)
+}
+
+func TestCaptureOutputsUsageFormat(t *testing.T) {
+	r := mockRunner{commands: map[string]mockResult{
+		"git rev-parse --is-inside-work-tree": {err: fmt.Errorf("not a git repo")},
+	}}
+
+	usageFile := writeTempFile(t, `{"type":"result","total_cost_usd":0.05,"usage":{"input_tokens":1000,"output_tokens":500}}`)
+	t.Setenv("KELOS_BASE_BRANCH", "")
+	t.Setenv("KELOS_AGENT_TYPE", "internal-agent")
+	t.Setenv("KELOS_USAGE_FORMAT", "claude-code")
+
+	outputs := captureOutputs(r, usageFile)
+
+	expected := []string{
+		"cost-usd: 0.05",
+		"input-tokens: 1000",
+		"output-tokens: 500",
+	}
+	assertOutputLines(t, expected, outputs)
+
+	t.Setenv("KELOS_USAGE_FORMAT", "none")
+	if outputs := captureOutputs(r, usageFile); len(outputs) != 0 {
+		t.Errorf("Expected no usage with format none, got %v", outputs)
+	}
+}
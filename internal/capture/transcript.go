@@ -0,0 +1,77 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kelos-dev/kelos/internal/transcript"
+)
+
+// transcriptUploadTimeout bounds the transcript upload so that an
+// unreachable store does not hold the pod open.
+const transcriptUploadTimeout = 2 * time.Minute
+
+// archiveTranscript copies the agent transcript to the store configured by
+// the KELOS_TRANSCRIPT_* environment variables and returns its reference.
+// For the ConfigMap store, which only the controller can write, the
+// compressed transcript is printed to stdout as a block for the controller
+// to pick up and "" is returned. It also returns "" when archival is not
+// configured.
+func archiveTranscript(file string, getenv func(string) string, stdout io.Writer) (string, error) {
+	store := getenv("KELOS_TRANSCRIPT_STORE")
+	if store == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("reading transcript: %w", err)
+	}
+
+	objectPath := transcript.ObjectPath(getenv("KELOS_POD_NAMESPACE"), getenv("KELOS_TASK_NAME"), getenv("KELOS_POD_NAME"))
+	root := getenv("KELOS_TRANSCRIPT_ROOT")
+
+	switch store {
+	case transcript.SchemePVC:
+		dest := filepath.Join(getenv("KELOS_TRANSCRIPT_DIR"), objectPath)
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return "", fmt.Errorf("creating transcript directory: %w", err)
+		}
+		if err := os.WriteFile(dest, data, 0o644); err != nil {
+			return "", fmt.Errorf("writing transcript: %w", err)
+		}
+		return transcript.URI(transcript.SchemePVC, root, objectPath), nil
+
+	case transcript.SchemeConfigMap:
+		compressed, truncated, err := transcript.Compress(data, transcript.MaxConfigMapBytes)
+		if err != nil {
+			return "", fmt.Errorf("compressing transcript: %w", err)
+		}
+		return "", transcript.WriteBlock(stdout, compressed, truncated)
+
+	case transcript.SchemeS3:
+		key := objectPath
+		if prefix := getenv("KELOS_TRANSCRIPT_PREFIX"); prefix != "" {
+			key = prefix + "/" + objectPath
+		}
+		c := &transcript.S3Client{
+			Endpoint:        getenv("KELOS_TRANSCRIPT_S3_ENDPOINT"),
+			Region:          getenv("KELOS_TRANSCRIPT_S3_REGION"),
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), transcriptUploadTimeout)
+		defer cancel()
+		if err := c.Put(ctx, root, key, data); err != nil {
+			return "", err
+		}
+		return transcript.URI(transcript.SchemeS3, root, key), nil
+
+	default:
+		return "", fmt.Errorf("unknown transcript store %q", store)
+	}
+}
@@ -0,0 +1,123 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kelos-dev/kelos/internal/transcript"
+)
+
+func transcriptEnv(env map[string]string) func(string) string {
+	return func(k string) string { return env[k] }
+}
+
+func writeTranscript(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "agent-output.jsonl")
+	if err := os.WriteFile(file, []byte(`{"type":"result"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestArchiveTranscript_NotConfigured(t *testing.T) {
+	uri, err := archiveTranscript("/nonexistent", transcriptEnv(nil), io.Discard)
+	if err != nil || uri != "" {
+		t.Fatalf("archiveTranscript() = %q, %v; want no archive", uri, err)
+	}
+}
+
+func TestArchiveTranscript_PVC(t *testing.T) {
+	file := writeTranscript(t)
+	dir := t.TempDir()
+
+	uri, err := archiveTranscript(file, transcriptEnv(map[string]string{
+		"KELOS_TRANSCRIPT_STORE": "pvc",
+		"KELOS_TRANSCRIPT_ROOT":  "transcripts",
+		"KELOS_TRANSCRIPT_DIR":   dir,
+		"KELOS_POD_NAMESPACE":    "default",
+		"KELOS_TASK_NAME":        "task-1",
+		"KELOS_POD_NAME":         "task-1-abcde",
+	}), io.Discard)
+	if err != nil {
+		t.Fatalf("archiveTranscript() error: %v", err)
+	}
+	if uri != "pvc://transcripts/default/task-1/task-1-abcde.jsonl" {
+		t.Errorf("uri = %q", uri)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "default", "task-1", "task-1-abcde.jsonl"))
+	if err != nil {
+		t.Fatalf("reading archived transcript: %v", err)
+	}
+	if string(data) != `{"type":"result"}`+"\n" {
+		t.Errorf("archived transcript = %q", data)
+	}
+}
+
+func TestArchiveTranscript_S3(t *testing.T) {
+	file := writeTranscript(t)
+
+	var gotPath string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	uri, err := archiveTranscript(file, transcriptEnv(map[string]string{
+		"KELOS_TRANSCRIPT_STORE":       "s3",
+		"KELOS_TRANSCRIPT_ROOT":        "bucket",
+		"KELOS_TRANSCRIPT_PREFIX":      "kelos",
+		"KELOS_TRANSCRIPT_S3_ENDPOINT": server.URL,
+		"AWS_ACCESS_KEY_ID":            "key",
+		"AWS_SECRET_ACCESS_KEY":        "secret",
+		"KELOS_POD_NAMESPACE":          "default",
+		"KELOS_TASK_NAME":              "task-1",
+		"KELOS_POD_NAME":               "task-1-abcde",
+	}), io.Discard)
+	if err != nil {
+		t.Fatalf("archiveTranscript() error: %v", err)
+	}
+	if uri != "s3://bucket/kelos/default/task-1/task-1-abcde.jsonl" {
+		t.Errorf("uri = %q", uri)
+	}
+	if gotPath != "/bucket/kelos/default/task-1/task-1-abcde.jsonl" {
+		t.Errorf("uploaded to %q", gotPath)
+	}
+	if string(gotBody) != `{"type":"result"}`+"\n" {
+		t.Errorf("uploaded body = %q", gotBody)
+	}
+}
+
+func TestArchiveTranscript_ConfigMap(t *testing.T) {
+	file := writeTranscript(t)
+
+	var stdout bytes.Buffer
+	uri, err := archiveTranscript(file, transcriptEnv(map[string]string{
+		"KELOS_TRANSCRIPT_STORE": "configmap",
+	}), &stdout)
+	if err != nil {
+		t.Fatalf("archiveTranscript() error: %v", err)
+	}
+	if uri != "" {
+		t.Errorf("uri = %q; the controller records ConfigMap archives", uri)
+	}
+
+	compressed, truncated, found, err := transcript.ParseBlock(stdout.Bytes())
+	if err != nil || !found || truncated {
+		t.Fatalf("ParseBlock() = truncated %v, found %v, error %v", truncated, found, err)
+	}
+	data, err := transcript.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if string(data) != `{"type":"result"}`+"\n" {
+		t.Errorf("transcript block = %q, want the agent output file", data)
+	}
+}
@@ -0,0 +1,423 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+// applyChange describes how apply changes one resource.
+type applyChange struct {
+	// desired is the object to create or update.
+	desired client.Object
+	// exists is true when the resource is already present.
+	exists bool
+	// diff is a unified diff from the live to the desired resource; it is
+	// empty when nothing changes.
+	diff string
+}
+
+func newApplyCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		filenames []string
+		dryRun    bool
+		yes       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply -f <file|dir>",
+		Short: "Create or update Kelos resources from manifests",
+		Long: `Create or update Tasks, Workspaces, AgentConfigs and TaskSpawners from YAML
+manifests. A directory applies every .yaml, .yml and .json file in it, and
+"-" reads manifests from stdin.
+
+String fields that hold prompts and instructions may reference a file with
+"@path", relative to the manifest: Task spec.prompt, TaskSpawner
+spec.taskTemplate.promptTemplate, AgentConfig spec.agentsMD and plugin skill
+and agent content, and Workspace file content.
+
+A diff of the changes is shown and confirmed before anything is applied.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(filenames) == 0 {
+				return fmt.Errorf("at least one --filename is required\nUsage: %s", cmd.Use)
+			}
+
+			for _, f := range filenames {
+				if f == "-" && !yes && !dryRun {
+					return fmt.Errorf("--yes or --dry-run is required when reading manifests from stdin")
+				}
+			}
+
+			objs, err := loadManifests(filenames)
+			if err != nil {
+				return err
+			}
+			if len(objs) == 0 {
+				return fmt.Errorf("no Kelos resources found in %s", strings.Join(filenames, ", "))
+			}
+
+			cl, ns, err := cfg.NewClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			var changes []applyChange
+			for _, obj := range objs {
+				if obj.GetNamespace() == "" {
+					obj.SetNamespace(ns)
+				}
+				change, err := planApply(ctx, cl, obj)
+				if err != nil {
+					return err
+				}
+				if change.diff != "" {
+					fmt.Fprint(os.Stdout, change.diff)
+					changes = append(changes, change)
+				}
+			}
+
+			if len(changes) == 0 {
+				fmt.Fprintln(os.Stdout, "No changes to apply")
+				return nil
+			}
+			if dryRun {
+				return nil
+			}
+			if !yes && !confirmPrompt(fmt.Sprintf("Apply %d change(s)?", len(changes))) {
+				return fmt.Errorf("aborted")
+			}
+
+			for _, change := range changes {
+				if err := applyObject(ctx, cl, change); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&filenames, "filename", "f", nil, "manifest file or directory to apply, or - for stdin (repeatable)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the diff without applying it")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "apply without asking for confirmation")
+
+	return cmd
+}
+
+// loadManifests reads the Kelos resources from the given files and
+// directories, resolving @file references.
+func loadManifests(paths []string) ([]client.Object, error) {
+	var objs []client.Object
+	for _, p := range paths {
+		if p == "-" {
+			data, err := io.ReadAll(stdinReader)
+			if err != nil {
+				return nil, fmt.Errorf("reading stdin: %w", err)
+			}
+			stdinObjs, err := decodeManifests(data, ".")
+			if err != nil {
+				return nil, fmt.Errorf("stdin: %w", err)
+			}
+			objs = append(objs, stdinObjs...)
+			continue
+		}
+
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		files := []string{p}
+		if info.IsDir() {
+			entries, err := os.ReadDir(p)
+			if err != nil {
+				return nil, err
+			}
+			files = nil
+			for _, e := range entries {
+				switch filepath.Ext(e.Name()) {
+				case ".yaml", ".yml", ".json":
+					if !e.IsDir() {
+						files = append(files, filepath.Join(p, e.Name()))
+					}
+				}
+			}
+			sort.Strings(files)
+		}
+
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			fileObjs, err := decodeManifests(data, filepath.Dir(f))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+			objs = append(objs, fileObjs...)
+		}
+	}
+	return objs, nil
+}
+
+// decodeManifests decodes a multi-document YAML or JSON stream of Kelos
+// resources. @file references are resolved relative to baseDir.
+func decodeManifests(data []byte, baseDir string) ([]client.Object, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var objs []client.Object
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, err
+		}
+		if typeMeta.APIVersion == "" && typeMeta.Kind == "" {
+			// A document holding only comments.
+			continue
+		}
+		if typeMeta.APIVersion != kelosv1alpha1.GroupVersion.String() {
+			return nil, fmt.Errorf("unsupported apiVersion %q for kind %q: must be %s", typeMeta.APIVersion, typeMeta.Kind, kelosv1alpha1.GroupVersion.String())
+		}
+
+		var obj client.Object
+		switch typeMeta.Kind {
+		case "Task":
+			obj = &kelosv1alpha1.Task{}
+		case "Workspace":
+			obj = &kelosv1alpha1.Workspace{}
+		case "AgentConfig":
+			obj = &kelosv1alpha1.AgentConfig{}
+		case "TaskSpawner":
+			obj = &kelosv1alpha1.TaskSpawner{}
+		default:
+			return nil, fmt.Errorf("unsupported kind %q: must be one of Task, Workspace, AgentConfig, TaskSpawner", typeMeta.Kind)
+		}
+		if err := yaml.UnmarshalStrict(doc, obj); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", typeMeta.Kind, err)
+		}
+		if obj.GetName() == "" {
+			return nil, fmt.Errorf("%s is missing metadata.name", typeMeta.Kind)
+		}
+		if err := resolveManifestFiles(obj, baseDir); err != nil {
+			return nil, fmt.Errorf("%s %q: %w", typeMeta.Kind, obj.GetName(), err)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// resolveManifestFiles replaces @file references in the fields of obj that
+// hold prompts, instructions and file content.
+func resolveManifestFiles(obj client.Object, baseDir string) error {
+	var fields []*string
+	switch o := obj.(type) {
+	case *kelosv1alpha1.Task:
+		fields = append(fields, &o.Spec.Prompt)
+	case *kelosv1alpha1.TaskSpawner:
+		fields = append(fields, &o.Spec.TaskTemplate.PromptTemplate)
+	case *kelosv1alpha1.AgentConfig:
+		fields = append(fields, &o.Spec.AgentsMD)
+		for i := range o.Spec.Plugins {
+			for j := range o.Spec.Plugins[i].Skills {
+				fields = append(fields, &o.Spec.Plugins[i].Skills[j].Content)
+			}
+			for j := range o.Spec.Plugins[i].Agents {
+				fields = append(fields, &o.Spec.Plugins[i].Agents[j].Content)
+			}
+		}
+	case *kelosv1alpha1.Workspace:
+		for i := range o.Spec.Files {
+			fields = append(fields, &o.Spec.Files[i].Content)
+		}
+	}
+
+	for _, f := range fields {
+		if !strings.HasPrefix(*f, "@") {
+			continue
+		}
+		ref := (*f)[1:]
+		if !filepath.IsAbs(ref) {
+			ref = filepath.Join(baseDir, ref)
+		}
+		content, err := resolveContent("@" + ref)
+		if err != nil {
+			return err
+		}
+		*f = content
+	}
+	return nil
+}
+
+// planApply compares desired with the live resource and returns the change
+// apply would make. Labels and annotations in desired are merged into those
+// of the live resource, so that labels added by controllers are kept. The
+// update is first sent to the API server as a dry run, so that fields the
+// manifest leaves out are compared with their defaults rather than shown
+// as removed.
+func planApply(ctx context.Context, cl client.Client, desired client.Object) (applyChange, error) {
+	kind := desired.GetObjectKind().GroupVersionKind().Kind
+	ref := strings.ToLower(kind) + "/" + desired.GetName()
+
+	live, ok := desired.DeepCopyObject().(client.Object)
+	if !ok {
+		return applyChange{}, fmt.Errorf("copying %s", ref)
+	}
+	err := cl.Get(ctx, client.ObjectKeyFromObject(desired), live)
+	if apierrors.IsNotFound(err) {
+		after, err := exportYAML(desired)
+		if err != nil {
+			return applyChange{}, err
+		}
+		diff, err := unifiedDiff(ref, nil, after)
+		return applyChange{desired: desired, diff: diff}, err
+	}
+	if err != nil {
+		return applyChange{}, fmt.Errorf("getting %s: %w", ref, err)
+	}
+	setKelosGVK(live)
+
+	merged, ok := live.DeepCopyObject().(client.Object)
+	if !ok {
+		return applyChange{}, fmt.Errorf("copying %s", ref)
+	}
+	merged.SetLabels(mergeStringMaps(live.GetLabels(), desired.GetLabels()))
+	merged.SetAnnotations(mergeStringMaps(live.GetAnnotations(), desired.GetAnnotations()))
+	copySpec(merged, desired)
+
+	defaulted, ok := merged.DeepCopyObject().(client.Object)
+	if !ok {
+		return applyChange{}, fmt.Errorf("copying %s", ref)
+	}
+	_, isTask := live.(*kelosv1alpha1.Task)
+	errImmutable := fmt.Errorf("%s already exists with a different spec: Task specs are immutable, delete it first or use a new name", ref)
+	if err := cl.Update(ctx, defaulted, client.DryRunAll); err != nil {
+		if isTask && apierrors.IsInvalid(err) {
+			return applyChange{}, errImmutable
+		}
+		return applyChange{}, fmt.Errorf("validating update of %s: %w", ref, err)
+	}
+	setKelosGVK(defaulted)
+
+	if isTask && copySpec(live.DeepCopyObject().(client.Object), defaulted) {
+		return applyChange{}, errImmutable
+	}
+
+	before, err := exportYAML(live)
+	if err != nil {
+		return applyChange{}, err
+	}
+	after, err := exportYAML(defaulted)
+	if err != nil {
+		return applyChange{}, err
+	}
+	diff, err := unifiedDiff(ref, before, after)
+	return applyChange{desired: merged, exists: true, diff: diff}, err
+}
+
+// copySpec copies the spec of src into dst, which must be of the same kind,
+// and reports whether it changed.
+func copySpec(dst, src client.Object) bool {
+	switch d := dst.(type) {
+	case *kelosv1alpha1.Task:
+		s := src.(*kelosv1alpha1.Task)
+		changed := !reflect.DeepEqual(d.Spec, s.Spec)
+		d.Spec = *s.Spec.DeepCopy()
+		return changed
+	case *kelosv1alpha1.Workspace:
+		s := src.(*kelosv1alpha1.Workspace)
+		changed := !reflect.DeepEqual(d.Spec, s.Spec)
+		d.Spec = *s.Spec.DeepCopy()
+		return changed
+	case *kelosv1alpha1.AgentConfig:
+		s := src.(*kelosv1alpha1.AgentConfig)
+		changed := !reflect.DeepEqual(d.Spec, s.Spec)
+		d.Spec = *s.Spec.DeepCopy()
+		return changed
+	case *kelosv1alpha1.TaskSpawner:
+		s := src.(*kelosv1alpha1.TaskSpawner)
+		changed := !reflect.DeepEqual(d.Spec, s.Spec)
+		d.Spec = *s.Spec.DeepCopy()
+		return changed
+	}
+	return false
+}
+
+// applyObject creates or updates the resource of a planned change.
+func applyObject(ctx context.Context, cl client.Client, change applyChange) error {
+	ref := strings.ToLower(change.desired.GetObjectKind().GroupVersionKind().Kind) + "/" + change.desired.GetName()
+	if !change.exists {
+		if err := cl.Create(ctx, change.desired); err != nil {
+			return fmt.Errorf("creating %s: %w", ref, err)
+		}
+		fmt.Fprintf(os.Stdout, "%s created\n", ref)
+		return nil
+	}
+	if err := cl.Update(ctx, change.desired); err != nil {
+		return fmt.Errorf("updating %s: %w", ref, err)
+	}
+	fmt.Fprintf(os.Stdout, "%s configured\n", ref)
+	return nil
+}
+
+// unifiedDiff returns a unified diff between two renderings of a resource,
+// or an empty string when they are equal.
+func unifiedDiff(ref string, before, after []byte) (string, error) {
+	if bytes.Equal(before, after) {
+		return "", nil
+	}
+	from := ref
+	if before == nil {
+		from = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: from,
+		ToFile:   ref,
+		Context:  3,
+	})
+}
+
+// mergeStringMaps returns base overlaid with overrides. It returns nil when
+// both are empty.
+func mergeStringMaps(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overrides {
+		out[k] = v
+	}
+	return out
+}
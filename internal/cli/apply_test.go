@@ -0,0 +1,341 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "prompts", "triage.md"), "Triage the issue.\n")
+	writeTestFile(t, filepath.Join(dir, "skills", "review.md"), "Review carefully.\n")
+	writeTestFile(t, filepath.Join(dir, "a-agentconfig.yaml"), `apiVersion: kelos.dev/v1alpha1
+kind: AgentConfig
+metadata:
+  name: reviewer
+spec:
+  plugins:
+  - name: team
+    skills:
+    - name: review
+      content: "@skills/review.md"
+`)
+	writeTestFile(t, filepath.Join(dir, "b-spawner.yaml"), `# Spawns triage tasks.
+---
+apiVersion: kelos.dev/v1alpha1
+kind: TaskSpawner
+metadata:
+  name: triage
+spec:
+  when:
+    cron:
+      schedule: "0 * * * *"
+  taskTemplate:
+    type: claude-code
+    credentials:
+      type: none
+    promptTemplate: "@prompts/triage.md"
+---
+apiVersion: kelos.dev/v1alpha1
+kind: Task
+metadata:
+  name: once
+spec:
+  type: codex
+  prompt: inline prompt
+  credentials:
+    type: none
+`)
+	writeTestFile(t, filepath.Join(dir, "README.md"), "not a manifest")
+
+	objs, err := loadManifests([]string{dir})
+	if err != nil {
+		t.Fatalf("loadManifests() error: %v", err)
+	}
+	if len(objs) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objs))
+	}
+
+	ac := objs[0].(*kelosv1alpha1.AgentConfig)
+	if got := ac.Spec.Plugins[0].Skills[0].Content; got != "Review carefully." {
+		t.Errorf("skill content = %q", got)
+	}
+	ts := objs[1].(*kelosv1alpha1.TaskSpawner)
+	if ts.Spec.TaskTemplate.PromptTemplate != "Triage the issue." {
+		t.Errorf("promptTemplate = %q", ts.Spec.TaskTemplate.PromptTemplate)
+	}
+	task := objs[2].(*kelosv1alpha1.Task)
+	if task.Spec.Prompt != "inline prompt" {
+		t.Errorf("prompt = %q", task.Spec.Prompt)
+	}
+}
+
+func TestDecodeManifests_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "unsupported kind",
+			input:   "apiVersion: kelos.dev/v1alpha1\nkind: Job\nmetadata:\n  name: x\n",
+			wantErr: `unsupported kind "Job"`,
+		},
+		{
+			name:    "wrong apiVersion",
+			input:   "apiVersion: batch/v1\nkind: Task\nmetadata:\n  name: x\n",
+			wantErr: `unsupported apiVersion "batch/v1"`,
+		},
+		{
+			name:    "unknown field",
+			input:   "apiVersion: kelos.dev/v1alpha1\nkind: Task\nmetadata:\n  name: x\nspec:\n  promt: typo\n",
+			wantErr: "promt",
+		},
+		{
+			name:    "missing name",
+			input:   "apiVersion: kelos.dev/v1alpha1\nkind: Workspace\nspec:\n  repo: https://github.com/o/r.git\n",
+			wantErr: "missing metadata.name",
+		},
+		{
+			name:    "missing file",
+			input:   "apiVersion: kelos.dev/v1alpha1\nkind: Task\nmetadata:\n  name: x\nspec:\n  prompt: \"@missing.md\"\n",
+			wantErr: "missing.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeManifests([]byte(tt.input), t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	ctx := context.Background()
+	live := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "repo",
+			Namespace:       "default",
+			Labels:          map[string]string{"kelos.dev/managed-by": "controller"},
+			ResourceVersion: "7",
+		},
+		Spec: kelosv1alpha1.WorkspaceSpec{Repo: "https://github.com/o/r.git", Ref: "main"},
+	}
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "once", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex", Prompt: "old"},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(live, task).Build()
+
+	decode := func(manifest string) client.Object {
+		t.Helper()
+		objs, err := decodeManifests([]byte(manifest), t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		objs[0].SetNamespace("default")
+		return objs[0]
+	}
+
+	t.Run("update keeps live labels", func(t *testing.T) {
+		change, err := planApply(ctx, cl, decode(`apiVersion: kelos.dev/v1alpha1
+kind: Workspace
+metadata:
+  name: repo
+  labels:
+    team: platform
+spec:
+  repo: https://github.com/o/r.git
+  ref: develop
+`))
+		if err != nil {
+			t.Fatalf("planApply() error: %v", err)
+		}
+		if !change.exists {
+			t.Error("expected an update of the live resource")
+		}
+		for _, want := range []string{"--- workspace/repo", "-  ref: main", "+  ref: develop", "+    team: platform"} {
+			if !strings.Contains(change.diff, want) {
+				t.Errorf("diff missing %q:\n%s", want, change.diff)
+			}
+		}
+		if err := applyObject(ctx, cl, change); err != nil {
+			t.Fatalf("applyObject() error: %v", err)
+		}
+		var got kelosv1alpha1.Workspace
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "repo"}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Spec.Ref != "develop" || got.Labels["kelos.dev/managed-by"] != "controller" || got.Labels["team"] != "platform" {
+			t.Errorf("unexpected workspace after apply: %+v", got.ObjectMeta.Labels)
+		}
+
+		// Applying the same manifest again is a no-op.
+		change, err = planApply(ctx, cl, decode(`apiVersion: kelos.dev/v1alpha1
+kind: Workspace
+metadata:
+  name: repo
+  labels:
+    team: platform
+spec:
+  repo: https://github.com/o/r.git
+  ref: develop
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if change.diff != "" {
+			t.Errorf("expected no diff, got:\n%s", change.diff)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		change, err := planApply(ctx, cl, decode(`apiVersion: kelos.dev/v1alpha1
+kind: AgentConfig
+metadata:
+  name: new
+spec:
+  agentsMD: Be brief.
+`))
+		if err != nil {
+			t.Fatalf("planApply() error: %v", err)
+		}
+		if change.exists || !strings.Contains(change.diff, "--- /dev/null") || !strings.Contains(change.diff, "+  agentsMD: Be brief.") {
+			t.Errorf("unexpected create plan: exists=%v diff:\n%s", change.exists, change.diff)
+		}
+		if err := applyObject(ctx, cl, change); err != nil {
+			t.Fatalf("applyObject() error: %v", err)
+		}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "new"}, &kelosv1alpha1.AgentConfig{}); err != nil {
+			t.Errorf("agent config not created: %v", err)
+		}
+	})
+
+	t.Run("task spec is immutable", func(t *testing.T) {
+		_, err := planApply(ctx, cl, decode(`apiVersion: kelos.dev/v1alpha1
+kind: Task
+metadata:
+  name: once
+spec:
+  type: codex
+  prompt: new
+`))
+		if err == nil || !strings.Contains(err.Error(), "immutable") {
+			t.Errorf("expected immutable spec error, got %v", err)
+		}
+	})
+}
+
+func TestPlanApply_ServerDefaults(t *testing.T) {
+	ctx := context.Background()
+	// The live TaskSpawner carries the defaults the API server filled in
+	// when it was first applied.
+	live := &kelosv1alpha1.TaskSpawner{
+		ObjectMeta: metav1.ObjectMeta{Name: "triage", Namespace: "default", ResourceVersion: "3"},
+		Spec: kelosv1alpha1.TaskSpawnerSpec{
+			When: kelosv1alpha1.When{
+				GitHubIssues: &kelosv1alpha1.GitHubIssues{Types: []string{"issues"}, State: "open"},
+			},
+			TaskTemplate: kelosv1alpha1.TaskTemplate{
+				Type:        "claude-code",
+				Credentials: kelosv1alpha1.Credentials{Type: kelosv1alpha1.CredentialTypeNone},
+			},
+			PollInterval: "5m",
+		},
+	}
+	// Simulate the API server defaulting a dry-run update.
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(live).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			updateOpts := &client.UpdateOptions{}
+			updateOpts.ApplyOptions(opts)
+			if ts, ok := obj.(*kelosv1alpha1.TaskSpawner); ok && len(updateOpts.DryRun) > 0 {
+				if ts.Spec.PollInterval == "" {
+					ts.Spec.PollInterval = "5m"
+				}
+				if gh := ts.Spec.When.GitHubIssues; gh != nil {
+					if len(gh.Types) == 0 {
+						gh.Types = []string{"issues"}
+					}
+					if gh.State == "" {
+						gh.State = "open"
+					}
+				}
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+
+	manifest := func(templateType string) client.Object {
+		t.Helper()
+		objs, err := decodeManifests([]byte(`apiVersion: kelos.dev/v1alpha1
+kind: TaskSpawner
+metadata:
+  name: triage
+spec:
+  when:
+    githubIssues: {}
+  taskTemplate:
+    type: `+templateType+`
+    credentials:
+      type: none
+`), t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		objs[0].SetNamespace("default")
+		return objs[0]
+	}
+
+	change, err := planApply(ctx, cl, manifest("claude-code"))
+	if err != nil {
+		t.Fatalf("planApply() error: %v", err)
+	}
+	if change.diff != "" {
+		t.Errorf("expected re-applying the manifest to be a no-op, got:\n%s", change.diff)
+	}
+
+	change, err = planApply(ctx, cl, manifest("codex"))
+	if err != nil {
+		t.Fatalf("planApply() error: %v", err)
+	}
+	var removed []string
+	for _, line := range strings.Split(change.diff, "\n") {
+		if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+			removed = append(removed, line)
+		}
+	}
+	if len(removed) != 1 || removed[0] != "-    type: claude-code" || !strings.Contains(change.diff, "+    type: codex") {
+		t.Errorf("expected only the type change, without defaulted fields shown as removed, got:\n%s", change.diff)
+	}
+}
+
+func TestApplyCommand_StdinRequiresYes(t *testing.T) {
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"apply", "-f", "-"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--yes or --dry-run") {
+		t.Errorf("expected error requiring --yes, got %v", err)
+	}
+}
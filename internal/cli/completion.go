@@ -117,3 +117,55 @@ func completeWorkspaceNames(cfg *ClientConfig) cobra.CompletionFunc {
 		return names, cobra.ShellCompDirectiveNoFileComp
 	}
 }
+
+// completeTaskContainerNames completes the containers of the Task given as
+// the first argument, including its per-repository clone containers. It falls
+// back to the well-known init container names when the Task has no pod yet.
+func completeTaskContainerNames(cfg *ClientConfig) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return initContainerNames, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cl, ns, err := cfg.NewClient()
+		if err != nil {
+			return initContainerNames, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		pods, err := listTaskPods(ctx, cl, ns, args[0])
+		if err != nil || len(pods) == 0 {
+			return initContainerNames, cobra.ShellCompDirectiveNoFileComp
+		}
+		return podContainerNames(&pods[len(pods)-1]), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeAgentTypes completes the built-in agent types followed by the
+// AgentTypes registered in the cluster.
+func completeAgentTypes(cfg *ClientConfig) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names := append([]string(nil), builtinAgentTypes...)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cl, _, err := cfg.NewClient()
+		if err != nil {
+			return names, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		agentTypes := &kelosv1alpha1.AgentTypeList{}
+		if err := cl.List(ctx, agentTypes); err != nil {
+			return names, cobra.ShellCompDirectiveNoFileComp
+		}
+		for _, at := range agentTypes.Items {
+			if !isBuiltinAgentType(at.Name) {
+				names = append(names, at.Name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -161,3 +161,29 @@ func findSubcommand(t *testing.T, root *cobra.Command, path []string) *cobra.Com
 	}
 	return cmd
 }
+
+func TestCompleteTaskContainerNamesFallsBack(t *testing.T) {
+	cfg := &ClientConfig{Kubeconfig: "/nonexistent/kubeconfig"}
+	fn := completeTaskContainerNames(cfg)
+
+	for _, args := range [][]string{nil, {"my-task"}} {
+		results, directive := fn(nil, args, "")
+		if strings.Join(results, ",") != strings.Join(initContainerNames, ",") {
+			t.Errorf("args %v: expected init container names, got %v", args, results)
+		}
+		if directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("args %v: expected ShellCompDirectiveNoFileComp, got %d", args, directive)
+		}
+	}
+}
+
+func TestCompleteAgentTypesFallsBackToBuiltins(t *testing.T) {
+	cfg := &ClientConfig{Kubeconfig: "/nonexistent/kubeconfig"}
+	results, directive := completeAgentTypes(cfg)(nil, nil, "")
+	if strings.Join(results, ",") != strings.Join(builtinAgentTypes, ",") {
+		t.Errorf("expected built-in agent types, got %v", results)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("expected ShellCompDirectiveNoFileComp, got %d", directive)
+	}
+}
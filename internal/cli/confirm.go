@@ -16,14 +16,20 @@ var stdinReader io.Reader = os.Stdin
 // It returns true if the user answers "y" or "yes" (case-insensitive).
 // If the input is empty or cannot be read, it returns false.
 func confirmOverride(resource string) (bool, error) {
-	fmt.Fprintf(os.Stderr, "Resource %s already exists. Override? [y/N]: ", resource)
+	return confirmPrompt(fmt.Sprintf("Resource %s already exists. Override?", resource)), nil
+}
+
+// confirmPrompt asks a yes/no question and returns true if the user answers
+// "y" or "yes" (case-insensitive).
+func confirmPrompt(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
 
 	reader := bufio.NewReader(stdinReader)
 	answer, err := reader.ReadString('\n')
 	if err != nil && err != io.EOF {
-		return false, nil
+		return false
 	}
 
 	answer = strings.TrimSpace(strings.ToLower(answer))
-	return answer == "y" || answer == "yes", nil
+	return answer == "y" || answer == "yes"
 }
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func newContinueCommand(cfg *ClientConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "continue",
+		Short: "Continue resources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			return fmt.Errorf("must specify a resource type")
+		},
+	}
+
+	cmd.AddCommand(newContinueTaskCommand(cfg))
+
+	return cmd
+}
+
+func newContinueTaskCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		prompt string
+		model  string
+		name   string
+		watch  bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "task <name>",
+		Aliases: []string{"tasks"},
+		Short:   "Continue a finished task's agent session with a follow-up prompt",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("task name is required\nUsage: %s", cmd.Use)
+			}
+			if len(args) > 1 {
+				return fmt.Errorf("too many arguments: expected 1 task name, got %d\nUsage: %s", len(args), cmd.Use)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveContent(prompt)
+			if err != nil {
+				return fmt.Errorf("resolving --prompt: %w", err)
+			}
+			if resolved == "" {
+				return fmt.Errorf("--prompt must not be empty")
+			}
+
+			cl, ns, err := cfg.NewClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			orig := &kelosv1alpha1.Task{}
+			if err := cl.Get(ctx, client.ObjectKey{Name: args[0], Namespace: ns}, orig); err != nil {
+				return fmt.Errorf("getting task: %w", err)
+			}
+
+			task, err := buildContinueTask(orig, name, resolved, model)
+			if err != nil {
+				return err
+			}
+			if task.Spec.Type != "claude-code" {
+				fmt.Fprintf(os.Stderr, "Warning: %s agents cannot resume sessions; the new task starts a new session\n", task.Spec.Type)
+			}
+
+			if dryRun {
+				return printYAML(os.Stdout, task)
+			}
+
+			if err := cl.Create(ctx, task); err != nil {
+				return fmt.Errorf("creating task: %w", err)
+			}
+			fmt.Fprintf(os.Stdout, "task/%s created\n", task.Name)
+
+			if watch {
+				return watchTask(ctx, cl, task.Name, ns)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&prompt, "prompt", "p", "", "follow-up prompt (content or @file path, required)")
+	cmd.Flags().StringVar(&model, "model", "", "model override")
+	cmd.Flags().StringVar(&name, "name", "", "name of the new task (defaults to <name>-continue-<suffix>)")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "watch task status after creation")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the resource that would be created without submitting it")
+
+	cmd.MarkFlagRequired("prompt")
+
+	cmd.ValidArgsFunction = completeTaskNames(cfg)
+
+	return cmd
+}
+
+// buildContinueTask returns a new Task that resumes the agent session of orig
+// with a follow-up prompt. The new Task keeps the workspace, branch and
+// session claim of orig. Dependencies are dropped because they were already
+// satisfied by orig.
+func buildContinueTask(orig *kelosv1alpha1.Task, name, prompt, model string) (*kelosv1alpha1.Task, error) {
+	if err := requireFinished(orig); err != nil {
+		return nil, err
+	}
+	if orig.Spec.Session == nil {
+		return nil, fmt.Errorf("task %s did not persist its agent session (spec.session is unset); create tasks with --session-pvc to continue them, or use kelos rerun task", orig.Name)
+	}
+
+	spec := *orig.Spec.DeepCopy()
+	spec.Prompt = prompt
+	spec.PromptFrom = nil
+	spec.DependsOn = nil
+	spec.ResumeFrom = orig.Name
+	if model != "" {
+		spec.Model = model
+	}
+
+	if name == "" {
+		name = followUpName(orig.Name, "continue")
+	}
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: orig.Namespace,
+			Labels:    userLabels(orig.Labels),
+		},
+		Spec: spec,
+	}
+	task.SetGroupVersionKind(kelosv1alpha1.GroupVersion.WithKind("Task"))
+	return task, nil
+}
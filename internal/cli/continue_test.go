@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func TestBuildContinueTask(t *testing.T) {
+	orig := finishedTask()
+	orig.Spec.Session = &kelosv1alpha1.SessionSpec{PersistentVolumeClaimName: "sessions"}
+	orig.Spec.DependsOn = []string{"setup"}
+	orig.Spec.WorkspaceRef = &kelosv1alpha1.WorkspaceReference{Name: "ws"}
+
+	task, err := buildContinueTask(orig, "", "Now add tests", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(task.Name, "fixer-42-continue-") {
+		t.Errorf("unexpected name %q", task.Name)
+	}
+	if task.Spec.ResumeFrom != "fixer-42" {
+		t.Errorf("resumeFrom = %q, want fixer-42", task.Spec.ResumeFrom)
+	}
+	if task.Spec.Prompt != "Now add tests" {
+		t.Errorf("prompt = %q", task.Spec.Prompt)
+	}
+	if task.Spec.Session == nil || task.Spec.Session.PersistentVolumeClaimName != "sessions" {
+		t.Errorf("expected session to be copied, got %+v", task.Spec.Session)
+	}
+	if task.Spec.Branch != "kelos-42" || task.Spec.WorkspaceRef == nil || task.Spec.WorkspaceRef.Name != "ws" {
+		t.Errorf("expected branch and workspace to be kept, got %+v", task.Spec)
+	}
+	if task.Spec.Model != "sonnet" {
+		t.Errorf("model = %q, want sonnet", task.Spec.Model)
+	}
+	if len(task.Spec.DependsOn) != 0 {
+		t.Errorf("expected dependencies to be dropped, got %v", task.Spec.DependsOn)
+	}
+	if _, ok := task.Labels["kelos.dev/taskspawner"]; ok {
+		t.Error("expected taskspawner label not to be copied")
+	}
+
+	again, err := buildContinueTask(&kelosv1alpha1.Task{
+		ObjectMeta: task.ObjectMeta,
+		Spec:       task.Spec,
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseSucceeded},
+	}, "third", "And docs", "opus")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.Name != "third" || again.Spec.Model != "opus" || again.Spec.ResumeFrom != task.Name {
+		t.Errorf("unexpected follow-up %s: %+v", again.Name, again.Spec)
+	}
+}
+
+func TestBuildContinueTaskErrors(t *testing.T) {
+	if _, err := buildContinueTask(finishedTask(), "", "more", ""); err == nil || !strings.Contains(err.Error(), "did not persist its agent session") {
+		t.Errorf("expected session error, got %v", err)
+	}
+
+	running := finishedTask()
+	running.Spec.Session = &kelosv1alpha1.SessionSpec{PersistentVolumeClaimName: "sessions"}
+	running.Status.Phase = kelosv1alpha1.TaskPhaseRunning
+	if _, err := buildContinueTask(running, "", "more", ""); err == nil || !strings.Contains(err.Error(), "has not finished") {
+		t.Errorf("expected not finished error, got %v", err)
+	}
+}
+
+func TestContinueCommand_MissingPrompt(t *testing.T) {
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"continue", "task", "my-task"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatal("Expected error when prompt is missing")
+	}
+	if !strings.Contains(err.Error(), "prompt") {
+		t.Errorf("Expected prompt error, got: %v", err)
+	}
+}
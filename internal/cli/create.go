@@ -23,18 +23,21 @@ func newCreateCommand(cfg *ClientConfig) *cobra.Command {
 
 	cmd.AddCommand(newCreateWorkspaceCommand(cfg))
 	cmd.AddCommand(newCreateAgentConfigCommand(cfg))
+	cmd.AddCommand(newCreateTaskSpawnerCommand(cfg))
 
 	return cmd
 }
 
 func newCreateWorkspaceCommand(cfg *ClientConfig) *cobra.Command {
 	var (
-		repo   string
-		ref    string
-		secret string
-		token  string
-		dryRun bool
-		yes    bool
+		repo      string
+		ref       string
+		provider  string
+		secret    string
+		sshSecret string
+		token     string
+		dryRun    bool
+		yes       bool
 	)
 
 	cmd := &cobra.Command{
@@ -56,6 +59,20 @@ func newCreateWorkspaceCommand(cfg *ClientConfig) *cobra.Command {
 			if secret != "" && token != "" {
 				return fmt.Errorf("cannot specify both --secret and --token")
 			}
+			tokenKey := "GITHUB_TOKEN"
+			switch kelosv1alpha1.GitProvider(provider) {
+			case "", kelosv1alpha1.GitProviderGitHub:
+			case kelosv1alpha1.GitProviderGitLab:
+				tokenKey = "GITLAB_TOKEN"
+			case kelosv1alpha1.GitProviderGitea:
+				tokenKey = "GITEA_TOKEN"
+			case kelosv1alpha1.GitProviderBitbucket:
+				if token != "" {
+					return fmt.Errorf("--token is not supported for bitbucket, use --secret with BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD")
+				}
+			default:
+				return fmt.Errorf("invalid --provider %q: must be one of github, gitlab, bitbucket, gitea", provider)
+			}
 
 			cl, ns, err := newClientOrDryRun(cfg, dryRun)
 			if err != nil {
@@ -68,15 +85,16 @@ func newCreateWorkspaceCommand(cfg *ClientConfig) *cobra.Command {
 					Namespace: ns,
 				},
 				Spec: kelosv1alpha1.WorkspaceSpec{
-					Repo: repo,
-					Ref:  ref,
+					Repo:     repo,
+					Ref:      ref,
+					Provider: kelosv1alpha1.GitProvider(provider),
 				},
 			}
 
 			if token != "" {
 				secretName := name + "-credentials"
 				if !dryRun {
-					if err := ensureCredentialSecret(cfg, secretName, "GITHUB_TOKEN", token, yes); err != nil {
+					if err := ensureCredentialSecret(cfg, secretName, tokenKey, token, yes); err != nil {
 						return err
 					}
 				}
@@ -88,6 +106,11 @@ func newCreateWorkspaceCommand(cfg *ClientConfig) *cobra.Command {
 					Name: secret,
 				}
 			}
+			if sshSecret != "" {
+				ws.Spec.SSHKeySecretRef = &kelosv1alpha1.SecretReference{
+					Name: sshSecret,
+				}
+			}
 
 			ws.SetGroupVersionKind(kelosv1alpha1.GroupVersion.WithKind("Workspace"))
 
@@ -105,8 +128,10 @@ func newCreateWorkspaceCommand(cfg *ClientConfig) *cobra.Command {
 
 	cmd.Flags().StringVar(&repo, "repo", "", "git repository URL (required)")
 	cmd.Flags().StringVar(&ref, "ref", "", "git reference (branch, tag, or commit SHA)")
-	cmd.Flags().StringVar(&secret, "secret", "", "secret name containing GITHUB_TOKEN for git authentication")
-	cmd.Flags().StringVar(&token, "token", "", "GitHub token (auto-creates a secret)")
+	cmd.Flags().StringVar(&provider, "provider", "", "git hosting provider (github, gitlab, bitbucket, gitea; default github)")
+	cmd.Flags().StringVar(&secret, "secret", "", "secret name containing the provider credentials (e.g. GITHUB_TOKEN) for git authentication")
+	cmd.Flags().StringVar(&sshSecret, "ssh-key-secret", "", "secret name containing ssh-privatekey and known_hosts for SSH repository URLs")
+	cmd.Flags().StringVar(&token, "token", "", "GitHub, GitLab or Gitea token (auto-creates a secret)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the resource that would be created without submitting it")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip confirmation prompts")
 
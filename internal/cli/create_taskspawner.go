@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func newCreateTaskSpawnerCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		githubIssues              bool
+		githubIssuesLabels        []string
+		githubIssuesExcludeLabels []string
+		githubIssuesState         string
+		githubIssuesAssignee      string
+		githubPRs                 bool
+		githubPRsLabels           []string
+		githubPRsExcludeLabels    []string
+		githubPRsState            string
+		githubPRsReviewState      string
+		githubRepo                string
+		triggerComment            string
+		excludeComments           []string
+		reporting                 bool
+		cronSchedule              string
+		jiraProject               string
+		jiraBaseURL               string
+		jiraJQL                   string
+		jiraSecret                string
+		pollInterval              string
+
+		promptTemplate string
+		agentType      string
+		secret         string
+		credentialType string
+		model          string
+		image          string
+		workspace      string
+		agentConfigRef string
+		dependsOn      []string
+		branch         string
+		timeout        string
+		envFlags       []string
+		maxConcurrency int32
+		maxTotalTasks  int32
+		dryRun         bool
+		yes            bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "taskspawner <name>",
+		Aliases: []string{"ts"},
+		Short:   "Create a TaskSpawner resource",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("taskspawner name is required\nUsage: %s", cmd.Use)
+			}
+			if len(args) > 1 {
+				return fmt.Errorf("too many arguments: expected 1 taskspawner name, got %d\nUsage: %s", len(args), cmd.Use)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			flags := cmd.Flags()
+
+			githubIssues = githubIssues || flags.Changed("github-issues-labels") ||
+				flags.Changed("github-issues-exclude-labels") || flags.Changed("github-issues-state") ||
+				flags.Changed("github-issues-assignee")
+			githubPRs = githubPRs || flags.Changed("github-prs-labels") ||
+				flags.Changed("github-prs-exclude-labels") || flags.Changed("github-prs-state") ||
+				flags.Changed("github-prs-review-state")
+			jira := jiraProject != "" || jiraBaseURL != "" || jiraJQL != "" || jiraSecret != ""
+
+			triggers := 0
+			for _, set := range []bool{githubIssues, githubPRs, cronSchedule != "", jira} {
+				if set {
+					triggers++
+				}
+			}
+			if triggers != 1 {
+				return fmt.Errorf("exactly one trigger must be specified (--github-issues, --github-prs, --cron, or --jira-project)")
+			}
+			github := githubIssues || githubPRs
+			if !github && (githubRepo != "" || triggerComment != "" || len(excludeComments) > 0 || reporting) {
+				return fmt.Errorf("--repo, --trigger-comment, --exclude-comment and --reporting require a GitHub trigger")
+			}
+			if jira && (jiraProject == "" || jiraBaseURL == "" || jiraSecret == "") {
+				return fmt.Errorf("--jira-project, --jira-base-url and --jira-secret are required for a Jira trigger")
+			}
+			if pollInterval != "" {
+				if cronSchedule != "" {
+					return fmt.Errorf("--poll-interval cannot be used with --cron")
+				}
+				if _, err := time.ParseDuration(pollInterval); err != nil {
+					return fmt.Errorf("invalid --poll-interval value %q: %w", pollInterval, err)
+				}
+			}
+
+			if c := cfg.Config; c != nil {
+				if !flags.Changed("secret") && c.Secret != "" {
+					secret = c.Secret
+				}
+				if !flags.Changed("credential-type") && c.CredentialType != "" {
+					credentialType = c.CredentialType
+				}
+				if !flags.Changed("type") && c.Type != "" {
+					agentType = c.Type
+				}
+				if !flags.Changed("model") && c.Model != "" {
+					model = c.Model
+				}
+				if !flags.Changed("workspace") && c.Workspace.Name != "" {
+					workspace = c.Workspace.Name
+				}
+				if !flags.Changed("agent-config") && c.AgentConfig != "" {
+					agentConfigRef = c.AgentConfig
+				}
+			}
+
+			resolvedTemplate, err := resolveContent(promptTemplate)
+			if err != nil {
+				return fmt.Errorf("resolving --prompt-template: %w", err)
+			}
+			if resolvedTemplate == "" {
+				return fmt.Errorf("--prompt-template must not be empty")
+			}
+
+			po, err := buildPodOverrides(timeout, envFlags)
+			if err != nil {
+				return err
+			}
+
+			secret, credentialType, err = resolveConfigCredentials(cfg, agentType, secret, credentialType, dryRun, yes)
+			if err != nil {
+				return err
+			}
+
+			cl, ns, err := newClientOrDryRun(cfg, dryRun)
+			if err != nil {
+				return err
+			}
+
+			if workspace == "" {
+				workspace, err = ensureConfigWorkspace(cfg, cl, ns, dryRun, yes)
+				if err != nil {
+					return err
+				}
+			}
+			if github && workspace == "" {
+				return fmt.Errorf("a workspace is required for GitHub triggers (use --workspace or set workspace in config file)")
+			}
+
+			var when kelosv1alpha1.When
+			var commentPolicy *kelosv1alpha1.GitHubCommentPolicy
+			if triggerComment != "" || len(excludeComments) > 0 {
+				commentPolicy = &kelosv1alpha1.GitHubCommentPolicy{
+					TriggerComment:  triggerComment,
+					ExcludeComments: excludeComments,
+				}
+			}
+			var githubReporting *kelosv1alpha1.GitHubReporting
+			if reporting {
+				githubReporting = &kelosv1alpha1.GitHubReporting{Enabled: true}
+			}
+			switch {
+			case githubIssues:
+				when.GitHubIssues = &kelosv1alpha1.GitHubIssues{
+					Repo:          githubRepo,
+					Labels:        githubIssuesLabels,
+					ExcludeLabels: githubIssuesExcludeLabels,
+					State:         githubIssuesState,
+					Assignee:      githubIssuesAssignee,
+					CommentPolicy: commentPolicy,
+					Reporting:     githubReporting,
+					PollInterval:  pollInterval,
+				}
+			case githubPRs:
+				when.GitHubPullRequests = &kelosv1alpha1.GitHubPullRequests{
+					Repo:          githubRepo,
+					Labels:        githubPRsLabels,
+					ExcludeLabels: githubPRsExcludeLabels,
+					State:         githubPRsState,
+					ReviewState:   githubPRsReviewState,
+					CommentPolicy: commentPolicy,
+					Reporting:     githubReporting,
+					PollInterval:  pollInterval,
+				}
+			case cronSchedule != "":
+				when.Cron = &kelosv1alpha1.Cron{Schedule: cronSchedule}
+			case jira:
+				when.Jira = &kelosv1alpha1.Jira{
+					BaseURL:      jiraBaseURL,
+					Project:      jiraProject,
+					JQL:          jiraJQL,
+					SecretRef:    kelosv1alpha1.SecretReference{Name: jiraSecret},
+					PollInterval: pollInterval,
+				}
+			}
+
+			creds := kelosv1alpha1.Credentials{
+				Type: kelosv1alpha1.CredentialType(credentialType),
+			}
+			if secret != "" {
+				creds.SecretRef = &kelosv1alpha1.SecretReference{Name: secret}
+			}
+
+			tmpl := kelosv1alpha1.TaskTemplate{
+				Type:           agentType,
+				Credentials:    creds,
+				Model:          model,
+				Image:          image,
+				DependsOn:      dependsOn,
+				Branch:         branch,
+				PromptTemplate: resolvedTemplate,
+				PodOverrides:   po,
+			}
+			if workspace != "" {
+				tmpl.WorkspaceRef = &kelosv1alpha1.WorkspaceReference{Name: workspace}
+			}
+			if agentConfigRef != "" {
+				tmpl.AgentConfigRef = &kelosv1alpha1.AgentConfigReference{Name: agentConfigRef}
+			}
+
+			ts := &kelosv1alpha1.TaskSpawner{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: ns,
+				},
+				Spec: kelosv1alpha1.TaskSpawnerSpec{
+					When:         when,
+					TaskTemplate: tmpl,
+				},
+			}
+			if flags.Changed("max-concurrency") {
+				ts.Spec.MaxConcurrency = &maxConcurrency
+			}
+			if flags.Changed("max-total-tasks") {
+				ts.Spec.MaxTotalTasks = &maxTotalTasks
+			}
+
+			ts.SetGroupVersionKind(kelosv1alpha1.GroupVersion.WithKind("TaskSpawner"))
+
+			if dryRun {
+				return printYAML(os.Stdout, ts)
+			}
+
+			if err := cl.Create(context.Background(), ts); err != nil {
+				return fmt.Errorf("creating taskspawner: %w", err)
+			}
+			fmt.Fprintf(os.Stdout, "taskspawner/%s created\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&githubIssues, "github-issues", false, "spawn Tasks for GitHub issues")
+	cmd.Flags().StringSliceVar(&githubIssuesLabels, "github-issues-labels", nil, "only include issues with all of these labels (implies --github-issues)")
+	cmd.Flags().StringSliceVar(&githubIssuesExcludeLabels, "github-issues-exclude-labels", nil, "skip issues with any of these labels (implies --github-issues)")
+	cmd.Flags().StringVar(&githubIssuesState, "github-issues-state", "", "issue state to include (open, closed, all)")
+	cmd.Flags().StringVar(&githubIssuesAssignee, "github-issues-assignee", "", "only include issues assigned to this user (\"*\" for any, \"none\" for unassigned)")
+	cmd.Flags().BoolVar(&githubPRs, "github-prs", false, "spawn Tasks for GitHub pull requests")
+	cmd.Flags().StringSliceVar(&githubPRsLabels, "github-prs-labels", nil, "only include pull requests with all of these labels (implies --github-prs)")
+	cmd.Flags().StringSliceVar(&githubPRsExcludeLabels, "github-prs-exclude-labels", nil, "skip pull requests with any of these labels (implies --github-prs)")
+	cmd.Flags().StringVar(&githubPRsState, "github-prs-state", "", "pull request state to include (open, closed, all)")
+	cmd.Flags().StringVar(&githubPRsReviewState, "github-prs-review-state", "", "only include pull requests in this review state (approved, changes_requested, any)")
+	cmd.Flags().StringVar(&githubRepo, "repo", "", "GitHub repository to poll as owner/repo (defaults to the workspace repository)")
+	cmd.Flags().StringVar(&triggerComment, "trigger-comment", "", "only include GitHub items with this comment command")
+	cmd.Flags().StringArrayVar(&excludeComments, "exclude-comment", nil, "comment command that excludes a GitHub item (repeatable)")
+	cmd.Flags().BoolVar(&reporting, "reporting", false, "post status comments back to the GitHub issue or pull request")
+	cmd.Flags().StringVar(&cronSchedule, "cron", "", "spawn Tasks on a cron schedule (e.g. \"0 9 * * 1\")")
+	cmd.Flags().StringVar(&jiraProject, "jira-project", "", "spawn Tasks for issues in this Jira project")
+	cmd.Flags().StringVar(&jiraBaseURL, "jira-base-url", "", "Jira instance URL (e.g. https://mycompany.atlassian.net)")
+	cmd.Flags().StringVar(&jiraJQL, "jira-jql", "", "additional JQL filter for Jira issues")
+	cmd.Flags().StringVar(&jiraSecret, "jira-secret", "", "secret name containing JIRA_TOKEN and optional JIRA_USER")
+	cmd.Flags().StringVar(&pollInterval, "poll-interval", "", "how often to poll the source (e.g. 1m, 5m)")
+
+	cmd.Flags().StringVar(&promptTemplate, "prompt-template", "", "prompt template for spawned Tasks (content or @file path, required)")
+	cmd.Flags().StringVarP(&agentType, "type", "t", "claude-code", "agent type (claude-code, codex, gemini, opencode, cursor, or the name of an AgentType)")
+	cmd.Flags().StringVar(&secret, "secret", "", "secret name with credentials (overrides oauthToken/apiKey in config)")
+	cmd.Flags().StringVar(&credentialType, "credential-type", "api-key", "credential type (api-key, oauth, none)")
+	cmd.Flags().StringVar(&model, "model", "", "model override")
+	cmd.Flags().StringVar(&image, "image", "", "custom agent image (must implement agent image interface)")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "name of Workspace resource to use")
+	cmd.Flags().StringVar(&agentConfigRef, "agent-config", "", "name of AgentConfig resource to use")
+	cmd.Flags().StringArrayVar(&dependsOn, "depends-on", nil, "Task names spawned Tasks depend on (repeatable)")
+	cmd.Flags().StringVar(&branch, "branch", "", "branch template for spawned Tasks (e.g. kelos-{{.Number}})")
+	cmd.Flags().StringVar(&timeout, "timeout", "", "maximum execution time for each agent (e.g. 30m, 1h)")
+	cmd.Flags().StringArrayVar(&envFlags, "env", nil, "additional environment variables for the agent (NAME=VALUE)")
+	cmd.Flags().Int32Var(&maxConcurrency, "max-concurrency", 0, "maximum number of concurrently running Tasks")
+	cmd.Flags().Int32Var(&maxTotalTasks, "max-total-tasks", 0, "maximum number of Tasks to create over the spawner's lifetime")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the resource that would be created without submitting it")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip confirmation prompts")
+
+	cmd.MarkFlagRequired("prompt-template")
+
+	_ = cmd.RegisterFlagCompletionFunc("credential-type", cobra.FixedCompletions([]string{"api-key", "oauth", "none"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("type", completeAgentTypes(cfg))
+	_ = cmd.RegisterFlagCompletionFunc("github-issues-state", cobra.FixedCompletions([]string{"open", "closed", "all"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("github-prs-state", cobra.FixedCompletions([]string{"open", "closed", "all"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("workspace", completeWorkspaceNames(cfg))
+
+	return cmd
+}
@@ -0,0 +1,837 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	"github.com/kelos-dev/kelos/pkg/generated/clientset/versioned"
+	"github.com/kelos-dev/kelos/pkg/generated/informers/externalversions"
+	listers "github.com/kelos-dev/kelos/pkg/generated/listers/api/v1alpha1"
+)
+
+const (
+	ansiClearScreen = "\x1b[H\x1b[2J"
+	ansiReverse     = "\x1b[7m"
+	ansiBold        = "\x1b[1m"
+	ansiReset       = "\x1b[0m"
+	ansiEnterAlt    = "\x1b[?1049h\x1b[?25l"
+	ansiExitAlt     = "\x1b[?25h\x1b[?1049l"
+
+	// logRetryInterval is how long the log view waits before it retries
+	// reading the logs of a Task whose pod is not ready.
+	logRetryInterval = 2 * time.Second
+
+	// maxLogViewLines bounds the parsed log lines the log view keeps.
+	maxLogViewLines = 10000
+)
+
+// dashboardKey is a key press the dashboard reacts to.
+type dashboardKey int
+
+const (
+	keyUp dashboardKey = iota
+	keyDown
+	keyTop
+	keyBottom
+	keyEnter
+	keyBack
+	keyQuit
+)
+
+func newDashboardCommand(cfg *ClientConfig) *cobra.Command {
+	var refresh time.Duration
+
+	cmd := &cobra.Command{
+		Use:     "dashboard",
+		Aliases: []string{"top"},
+		Short:   "Show a live dashboard of Tasks and TaskSpawners",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if refresh <= 0 {
+				return fmt.Errorf("--refresh must be positive")
+			}
+			if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+				return fmt.Errorf("kelos dashboard requires an interactive terminal")
+			}
+
+			restConfig, ns, err := cfg.resolveConfig()
+			if err != nil {
+				return err
+			}
+			kelosClient, err := versioned.NewForConfig(restConfig)
+			if err != nil {
+				return fmt.Errorf("creating clientset: %w", err)
+			}
+			cs, err := kubernetes.NewForConfig(restConfig)
+			if err != nil {
+				return fmt.Errorf("creating clientset: %w", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			factory := externalversions.NewSharedInformerFactoryWithOptions(kelosClient, 0, externalversions.WithNamespace(ns))
+			taskInformer := factory.Api().V1alpha1().Tasks()
+			spawnerInformer := factory.Api().V1alpha1().TaskSpawners()
+
+			changed := make(chan struct{}, 1)
+			notify := cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(interface{}) { notifyChanged(changed) },
+				UpdateFunc: func(interface{}, interface{}) { notifyChanged(changed) },
+				DeleteFunc: func(interface{}) { notifyChanged(changed) },
+			}
+			if _, err := taskInformer.Informer().AddEventHandler(notify); err != nil {
+				return fmt.Errorf("watching tasks: %w", err)
+			}
+			if _, err := spawnerInformer.Informer().AddEventHandler(notify); err != nil {
+				return fmt.Errorf("watching task spawners: %w", err)
+			}
+			factory.Start(ctx.Done())
+			defer factory.Shutdown()
+			for typ, ok := range factory.WaitForCacheSync(ctx.Done()) {
+				if !ok {
+					return fmt.Errorf("syncing %v informer", typ)
+				}
+			}
+
+			oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+			if err != nil {
+				return fmt.Errorf("configuring terminal: %w", err)
+			}
+			defer term.Restore(int(os.Stdin.Fd()), oldState)
+			fmt.Fprint(os.Stdout, ansiEnterAlt)
+			defer fmt.Fprint(os.Stdout, ansiExitAlt)
+
+			d := &dashboard{
+				namespace: ns,
+				tasks:     taskInformer.Lister(),
+				spawners:  spawnerInformer.Lister(),
+				clientset: cs,
+				kelos:     kelosClient,
+			}
+			return d.run(ctx, os.Stdin, os.Stdout, changed, refresh)
+		},
+	}
+
+	cmd.Flags().DurationVar(&refresh, "refresh", 2*time.Second, "how often to refresh ages")
+
+	return cmd
+}
+
+// notifyChanged performs a non-blocking send so that bursts of informer events
+// result in a single redraw.
+func notifyChanged(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// dashboard holds the interactive state of kelos dashboard.
+type dashboard struct {
+	namespace string
+	tasks     listers.TaskLister
+	spawners  listers.TaskSpawnerLister
+	clientset kubernetes.Interface
+	// kelos reads the AgentTypes of custom agents. It may be nil.
+	kelos versioned.Interface
+
+	// selected is the name of the selected Task, so the selection follows
+	// the Task when the list is re-sorted.
+	selected string
+
+	// logTask is the name of the Task whose logs are shown, or empty in
+	// the list view.
+	logTask   string
+	logFormat string
+	logLines  []string
+	logUsage  string
+	logScroll int
+	// logTail follows the logs of logTask in the background and signals
+	// logUpdated when it has new lines.
+	logTail    *logTail
+	logUpdated chan struct{}
+}
+
+func (d *dashboard) run(ctx context.Context, in io.Reader, out io.Writer, changed <-chan struct{}, refresh time.Duration) error {
+	d.logUpdated = make(chan struct{}, 1)
+	defer d.stopLogTail()
+
+	keys := make(chan []dashboardKey)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- parseDashboardKeys(buf[:n])
+		}
+	}()
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		d.draw(out)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case ks, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range ks {
+				if d.handleKey(ctx, k) {
+					return nil
+				}
+			}
+		case <-changed:
+		case <-d.logUpdated:
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleKey applies a key press and reports whether the dashboard should
+// exit.
+func (d *dashboard) handleKey(ctx context.Context, k dashboardKey) bool {
+	if k == keyQuit {
+		return true
+	}
+
+	if d.logTask != "" {
+		switch k {
+		case keyBack:
+			d.logTask = ""
+			d.stopLogTail()
+		case keyUp:
+			d.logScroll++
+		case keyDown:
+			if d.logScroll > 0 {
+				d.logScroll--
+			}
+		case keyTop:
+			d.logScroll = len(d.logLines)
+		case keyBottom:
+			d.logScroll = 0
+		}
+		return false
+	}
+
+	tasks := d.sortedTasks()
+	if len(tasks) == 0 {
+		return false
+	}
+	idx := selectedIndex(tasks, d.selected)
+	switch k {
+	case keyUp:
+		if idx > 0 {
+			idx--
+		}
+	case keyDown:
+		if idx < len(tasks)-1 {
+			idx++
+		}
+	case keyTop:
+		idx = 0
+	case keyBottom:
+		idx = len(tasks) - 1
+	case keyEnter:
+		d.logTask = tasks[idx].Name
+		d.logFormat = d.agentLogFormat(ctx, tasks[idx].Spec.Type)
+		d.logLines = nil
+		d.logUsage = ""
+		d.logScroll = 0
+		d.startLogTail(ctx)
+	}
+	d.selected = tasks[idx].Name
+	return false
+}
+
+func (d *dashboard) sortedTasks() []kelosv1alpha1.Task {
+	list, err := d.tasks.Tasks(d.namespace).List(labels.Everything())
+	if err != nil {
+		return nil
+	}
+	tasks := make([]kelosv1alpha1.Task, 0, len(list))
+	for _, t := range list {
+		tasks = append(tasks, *t)
+	}
+	sortDashboardTasks(tasks)
+	return tasks
+}
+
+func (d *dashboard) sortedSpawners() []kelosv1alpha1.TaskSpawner {
+	list, err := d.spawners.TaskSpawners(d.namespace).List(labels.Everything())
+	if err != nil {
+		return nil
+	}
+	spawners := make([]kelosv1alpha1.TaskSpawner, 0, len(list))
+	for _, s := range list {
+		spawners = append(spawners, *s)
+	}
+	sort.Slice(spawners, func(i, j int) bool { return spawners[i].Name < spawners[j].Name })
+	return spawners
+}
+
+// startLogTail starts following the logs of the Task shown in the log view,
+// replacing the previous follower.
+func (d *dashboard) startLogTail(ctx context.Context) {
+	d.stopLogTail()
+	ctx, cancel := context.WithCancel(ctx)
+	tail := &logTail{cancel: cancel, notify: d.notifyLogs}
+	d.logTail = tail
+	go d.followLogs(ctx, tail, d.logTask, d.logFormat)
+}
+
+func (d *dashboard) stopLogTail() {
+	if d.logTail != nil {
+		d.logTail.cancel()
+		d.logTail = nil
+	}
+}
+
+func (d *dashboard) notifyLogs() {
+	notifyChanged(d.logUpdated)
+}
+
+// followLogs streams the agent logs of the named Task into tail until ctx
+// is cancelled or the log stream of the agent container ends. Each line is
+// parsed once as it arrives instead of re-reading the whole log.
+func (d *dashboard) followLogs(ctx context.Context, tail *logTail, taskName, format string) {
+	for {
+		task, err := d.tasks.Tasks(d.namespace).Get(taskName)
+		switch {
+		case err != nil:
+			tail.setStatus(fmt.Sprintf("Error getting task: %v", err))
+		case task.Status.PodName == "":
+			tail.setStatus(fmt.Sprintf("Task %s has no pod yet (phase: %s)", task.Name, task.Status.Phase))
+		default:
+			err := d.streamLogs(ctx, tail, task, format)
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+			if !isContainerNotReady(err) {
+				tail.setStatus(fmt.Sprintf("Error streaming logs: %v", err))
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(logRetryInterval):
+		}
+	}
+}
+
+// streamLogs follows the agent container log of task, feeding every line to
+// the running usage and, through the parser for format, to the log view.
+func (d *dashboard) streamLogs(ctx context.Context, tail *logTail, task *kelosv1alpha1.Task, format string) error {
+	stream, err := d.clientset.CoreV1().Pods(d.namespace).GetLogs(task.Status.PodName, &corev1.PodLogOptions{
+		Container: task.Spec.Type,
+		Follow:    true,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	tail.reset()
+	pr, pw := io.Pipe()
+	parsed := make(chan struct{})
+	go func() {
+		defer close(parsed)
+		w := &logLineWriter{tail: tail}
+		if err := formatAgentLogs(pr, format, w, w); err != nil {
+			fmt.Fprintf(w, "\nError reading logs: %v\n", err)
+		}
+		w.flush()
+		// Keep draining so that the reader below never blocks.
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		tail.addUsage(format, line)
+		buf := make([]byte, 0, len(line)+1)
+		if _, err := pw.Write(append(append(buf, line...), '\n')); err != nil {
+			break
+		}
+	}
+	pw.CloseWithError(scanner.Err())
+	<-parsed
+	return scanner.Err()
+}
+
+// logTail holds the parsed log lines and the running usage of the Task in
+// the log view. It is written by the follower goroutine and read on redraw.
+type logTail struct {
+	cancel context.CancelFunc
+	notify func()
+
+	mu    sync.Mutex
+	lines []string
+	usage runningUsage
+}
+
+// snapshot returns the parsed lines and a summary of the running usage.
+func (t *logTail) snapshot() ([]string, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...), t.usage.String()
+}
+
+func (t *logTail) reset() {
+	t.mu.Lock()
+	t.lines = nil
+	t.usage = runningUsage{}
+	t.mu.Unlock()
+}
+
+// setStatus replaces the lines with a single status message.
+func (t *logTail) setStatus(msg string) {
+	t.mu.Lock()
+	t.lines = []string{msg}
+	t.mu.Unlock()
+	t.notify()
+}
+
+// appendLines adds parsed lines, keeping at most maxLogViewLines.
+func (t *logTail) appendLines(lines ...string) {
+	t.mu.Lock()
+	t.lines = append(t.lines, lines...)
+	if n := len(t.lines) - maxLogViewLines; n > 0 {
+		t.lines = append([]string(nil), t.lines[n:]...)
+	}
+	t.mu.Unlock()
+	t.notify()
+}
+
+func (t *logTail) addUsage(format string, line []byte) {
+	t.mu.Lock()
+	t.usage.add(format, line)
+	t.mu.Unlock()
+}
+
+// logLineWriter splits parser output into lines for a logTail.
+type logLineWriter struct {
+	tail    *logTail
+	partial []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	var lines []string
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	if len(lines) > 0 {
+		w.tail.appendLines(lines...)
+	}
+	return len(p), nil
+}
+
+func (w *logLineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.tail.appendLines(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// runningUsage adds up the token usage and cost an agent reports while it
+// runs, so the log view shows them before the Task finishes and reports
+// its results.
+type runningUsage struct {
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
+	// messages holds the IDs of the claude-code messages already counted;
+	// a message is streamed as several events that repeat its usage.
+	messages map[string]bool
+}
+
+// usageEvent holds the usage fields of the output formats of the built-in
+// agents.
+type usageEvent struct {
+	Type         string  `json:"type"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Message      *struct {
+		ID    string      `json:"id"`
+		Usage *tokenCount `json:"usage"`
+	} `json:"message"`
+	Usage *tokenCount `json:"usage"`
+	Stats *struct {
+		InputTokens  int64 `json:"inputTokens"`
+		OutputTokens int64 `json:"outputTokens"`
+	} `json:"stats"`
+	Part *struct {
+		Tokens *struct {
+			Input  int64 `json:"input"`
+			Output int64 `json:"output"`
+		} `json:"tokens"`
+	} `json:"part"`
+}
+
+// tokenCount is a usage object in snake_case (claude-code, codex) or
+// camelCase (cursor).
+type tokenCount struct {
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	InputTokensCamel  int64 `json:"inputTokens"`
+	OutputTokensCamel int64 `json:"outputTokens"`
+}
+
+// add accounts for one line of agent output in the given log format.
+func (u *runningUsage) add(format string, line []byte) {
+	var ev usageEvent
+	if json.Unmarshal(line, &ev) != nil {
+		return
+	}
+	switch {
+	case format == "claude-code" && ev.Type == "assistant" && ev.Message != nil && ev.Message.Usage != nil:
+		if ev.Message.ID != "" {
+			if u.messages[ev.Message.ID] {
+				return
+			}
+			if u.messages == nil {
+				u.messages = make(map[string]bool)
+			}
+			u.messages[ev.Message.ID] = true
+		}
+		u.InputTokens += ev.Message.Usage.InputTokens
+		u.OutputTokens += ev.Message.Usage.OutputTokens
+	case format == "claude-code" && ev.Type == "result":
+		// The result reports the totals of the whole run.
+		u.CostUSD = ev.TotalCostUSD
+		if ev.Usage != nil {
+			u.InputTokens, u.OutputTokens = ev.Usage.InputTokens, ev.Usage.OutputTokens
+		}
+	case format == "codex" && ev.Type == "turn.completed" && ev.Usage != nil:
+		u.InputTokens += ev.Usage.InputTokens
+		u.OutputTokens += ev.Usage.OutputTokens
+	case format == "cursor" && ev.Type == "result" && ev.Usage != nil:
+		u.InputTokens, u.OutputTokens = ev.Usage.InputTokensCamel, ev.Usage.OutputTokensCamel
+	case format == "gemini" && ev.Type == "result" && ev.Stats != nil:
+		u.InputTokens, u.OutputTokens = ev.Stats.InputTokens, ev.Stats.OutputTokens
+	case format == "opencode" && ev.Type == "step_finish" && ev.Part != nil && ev.Part.Tokens != nil:
+		u.InputTokens += ev.Part.Tokens.Input
+		u.OutputTokens += ev.Part.Tokens.Output
+	}
+}
+
+// String summarises the usage for the log view title, or returns an empty
+// string when nothing was reported yet.
+func (u *runningUsage) String() string {
+	var parts []string
+	if u.InputTokens > 0 || u.OutputTokens > 0 {
+		parts = append(parts, fmt.Sprintf("tokens: %d in / %d out", u.InputTokens, u.OutputTokens))
+	}
+	if u.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("cost: $%.4f", u.CostUSD))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// agentLogFormat returns the log format of an agent type, reading the
+// AgentType of custom agents.
+func (d *dashboard) agentLogFormat(ctx context.Context, agentType string) string {
+	if isBuiltinAgentType(agentType) || d.kelos == nil {
+		return agentLogFormat(agentType, nil)
+	}
+	at, err := d.kelos.ApiV1alpha1().AgentTypes().Get(ctx, agentType, metav1.GetOptions{})
+	if err != nil {
+		return agentLogFormat(agentType, nil)
+	}
+	return agentLogFormat(agentType, at)
+}
+
+func (d *dashboard) draw(out io.Writer) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 120, 40
+	}
+
+	var screen bytes.Buffer
+	if d.logTask != "" {
+		if d.logTail != nil {
+			d.logLines, d.logUsage = d.logTail.snapshot()
+		}
+		renderLogView(&screen, d.logTask, d.logUsage, d.logLines, d.logScroll, width, height)
+	} else {
+		tasks := d.sortedTasks()
+		renderDashboard(&screen, dashboardState{
+			Namespace: d.namespace,
+			Tasks:     tasks,
+			Spawners:  d.sortedSpawners(),
+			Selected:  selectedIndex(tasks, d.selected),
+			Width:     width,
+			Height:    height,
+			Now:       time.Now(),
+		})
+	}
+
+	// The terminal is in raw mode, so line feeds do not return the cursor.
+	fmt.Fprint(out, ansiClearScreen+strings.ReplaceAll(screen.String(), "\n", "\r\n"))
+}
+
+// parseDashboardKeys decodes the keys in a chunk read from the terminal.
+func parseDashboardKeys(b []byte) []dashboardKey {
+	var keys []dashboardKey
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; c {
+		case 'q', 0x03:
+			keys = append(keys, keyQuit)
+		case 'k':
+			keys = append(keys, keyUp)
+		case 'j':
+			keys = append(keys, keyDown)
+		case 'g':
+			keys = append(keys, keyTop)
+		case 'G':
+			keys = append(keys, keyBottom)
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case 'b', 'h', 0x7f:
+			keys = append(keys, keyBack)
+		case 0x1b:
+			if i+2 < len(b) && b[i+1] == '[' {
+				switch b[i+2] {
+				case 'A':
+					keys = append(keys, keyUp)
+				case 'B':
+					keys = append(keys, keyDown)
+				case 'D':
+					keys = append(keys, keyBack)
+				case 'C':
+					keys = append(keys, keyEnter)
+				}
+				i += 2
+				continue
+			}
+			keys = append(keys, keyBack)
+		}
+	}
+	return keys
+}
+
+// dashboardState is a snapshot of everything shown in the list view.
+type dashboardState struct {
+	Namespace string
+	Tasks     []kelosv1alpha1.Task
+	Spawners  []kelosv1alpha1.TaskSpawner
+	Selected  int
+	Width     int
+	Height    int
+	Now       time.Time
+}
+
+// sortDashboardTasks orders Tasks that have not finished first, then the
+// most recently created.
+func sortDashboardTasks(tasks []kelosv1alpha1.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		ai, aj := !isTerminalTaskPhase(tasks[i].Status.Phase), !isTerminalTaskPhase(tasks[j].Status.Phase)
+		if ai != aj {
+			return ai
+		}
+		ti, tj := tasks[i].CreationTimestamp.Time, tasks[j].CreationTimestamp.Time
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return tasks[i].Name < tasks[j].Name
+	})
+}
+
+func selectedIndex(tasks []kelosv1alpha1.Task, name string) int {
+	for i := range tasks {
+		if tasks[i].Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// renderDashboard writes the list view: TaskSpawners on top and Tasks below,
+// with the selected Task highlighted.
+func renderDashboard(w io.Writer, st dashboardState) {
+	fmt.Fprintf(w, "%skelos dashboard%s  namespace: %s  %s\n", ansiBold, ansiReset, st.Namespace, st.Now.Format("15:04:05"))
+	used := 1
+
+	if len(st.Spawners) > 0 {
+		fmt.Fprintf(w, "\n%sTASKSPAWNERS (%d)%s\n", ansiBold, len(st.Spawners), ansiReset)
+		var rows []string
+		rows = append(rows, "NAME\tSOURCE\tPHASE\tDISCOVERED\tCREATED\tACTIVE\tLAST DISCOVERY")
+		for _, s := range st.Spawners {
+			last := "-"
+			if s.Status.LastDiscoveryTime != nil {
+				last = duration.HumanDuration(st.Now.Sub(s.Status.LastDiscoveryTime.Time)) + " ago"
+			}
+			rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%s",
+				s.Name, taskSpawnerSource(&s), s.Status.Phase,
+				s.Status.TotalDiscovered, s.Status.TotalTasksCreated, s.Status.ActiveTasks, last))
+		}
+		lines := alignColumns(rows)
+		// Leave at least half of the screen to Tasks.
+		maxLines := st.Height/2 - 3
+		if maxLines < 2 {
+			maxLines = 2
+		}
+		if len(lines) > maxLines {
+			lines = lines[:maxLines]
+		}
+		for _, l := range lines {
+			fmt.Fprintln(w, truncateLine(l, st.Width))
+		}
+		used += 2 + len(lines)
+	}
+
+	fmt.Fprintf(w, "\n%sTASKS (%d)%s\n", ansiBold, len(st.Tasks), ansiReset)
+	used += 2
+	if len(st.Tasks) == 0 {
+		fmt.Fprintln(w, "No tasks found.")
+	} else {
+		rows := []string{"NAME\tTYPE\tPHASE\tBRANCH\tAGE\tDURATION\tCOST\tMESSAGE"}
+		for _, t := range st.Tasks {
+			branch := t.Spec.Branch
+			if branch == "" {
+				branch = "-"
+			}
+			rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+				t.Name, t.Spec.Type, taskPhaseOrPending(t.Status.Phase), branch,
+				duration.HumanDuration(st.Now.Sub(t.CreationTimestamp.Time)),
+				taskDuration(&t.Status), taskCost(&t.Status), firstLine(t.Status.Message)))
+		}
+		lines := alignColumns(rows)
+		header, body := lines[0], lines[1:]
+		fmt.Fprintln(w, "  "+truncateLine(header, st.Width-2))
+
+		// Scroll so that the selected Task stays visible above the footer.
+		visible := st.Height - used - 3
+		if visible < 1 {
+			visible = 1
+		}
+		start := 0
+		if st.Selected >= visible {
+			start = st.Selected - visible + 1
+		}
+		end := start + visible
+		if end > len(body) {
+			end = len(body)
+		}
+		for i := start; i < end; i++ {
+			line := truncateLine(body[i], st.Width-2)
+			if i == st.Selected {
+				fmt.Fprintf(w, "%s> %s%s\n", ansiReverse, line, ansiReset)
+			} else {
+				fmt.Fprintln(w, "  "+line)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n↑/↓ select  enter logs  q quit")
+}
+
+// renderLogView writes the parsed logs of a Task, scrolled up from the end
+// by scroll lines, with its running usage in the title.
+func renderLogView(w io.Writer, taskName, usage string, lines []string, scroll, width, height int) {
+	if usage != "" {
+		fmt.Fprintf(w, "%slogs: %s%s  %s\n", ansiBold, taskName, ansiReset, usage)
+	} else {
+		fmt.Fprintf(w, "%slogs: %s%s\n", ansiBold, taskName, ansiReset)
+	}
+
+	visible := height - 3
+	if visible < 1 {
+		visible = 1
+	}
+	end := len(lines) - scroll
+	if end < 0 {
+		end = 0
+	}
+	start := end - visible
+	if start < 0 {
+		start = 0
+	}
+	for _, l := range lines[start:end] {
+		fmt.Fprintln(w, truncateLine(l, width))
+	}
+
+	fmt.Fprintf(w, "\n↑/↓ scroll  g/G top/bottom  esc back  q quit")
+}
+
+// alignColumns formats tab-separated rows into aligned columns.
+func alignColumns(rows []string) []string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	for _, r := range rows {
+		fmt.Fprintln(tw, r)
+	}
+	tw.Flush()
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+// truncateLine shortens s to at most width runes so that lines do not wrap.
+func truncateLine(s string, width int) string {
+	s = strings.TrimRight(s, " ")
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width == 1 {
+		return string(runes[:1])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func taskPhaseOrPending(phase kelosv1alpha1.TaskPhase) string {
+	if phase == "" {
+		return string(kelosv1alpha1.TaskPhasePending)
+	}
+	return string(phase)
+}
+
+// taskCost returns the cost reported by the agent, or "-" when unknown.
+func taskCost(status *kelosv1alpha1.TaskStatus) string {
+	if v := status.Results["cost-usd"]; v != "" {
+		return "$" + v
+	}
+	return "-"
+}
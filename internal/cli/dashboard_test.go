@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	listers "github.com/kelos-dev/kelos/pkg/generated/listers/api/v1alpha1"
+)
+
+func TestParseDashboardKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []dashboardKey
+	}{
+		{name: "vim keys", input: "jkgG", want: []dashboardKey{keyDown, keyUp, keyTop, keyBottom}},
+		{name: "arrow keys", input: "\x1b[A\x1b[B\x1b[C\x1b[D", want: []dashboardKey{keyUp, keyDown, keyEnter, keyBack}},
+		{name: "enter", input: "\r", want: []dashboardKey{keyEnter}},
+		{name: "lone escape", input: "\x1b", want: []dashboardKey{keyBack}},
+		{name: "quit", input: "q", want: []dashboardKey{keyQuit}},
+		{name: "ctrl-c", input: "\x03", want: []dashboardKey{keyQuit}},
+		{name: "unknown keys ignored", input: "xyz", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDashboardKeys([]byte(tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDashboardKeys(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortDashboardTasks(t *testing.T) {
+	now := time.Now()
+	task := func(name string, phase kelosv1alpha1.TaskPhase, age time.Duration) kelosv1alpha1.Task {
+		return kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))},
+			Status:     kelosv1alpha1.TaskStatus{Phase: phase},
+		}
+	}
+	tasks := []kelosv1alpha1.Task{
+		task("old-done", kelosv1alpha1.TaskPhaseSucceeded, time.Hour),
+		task("new-done", kelosv1alpha1.TaskPhaseFailed, time.Minute),
+		task("old-running", kelosv1alpha1.TaskPhaseRunning, 2*time.Hour),
+		task("new-waiting", kelosv1alpha1.TaskPhaseWaiting, time.Second),
+	}
+	sortDashboardTasks(tasks)
+
+	var got []string
+	for _, tk := range tasks {
+		got = append(got, tk.Name)
+	}
+	want := []string{"new-waiting", "old-running", "new-done", "old-done"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestRenderDashboard(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	lastDiscovery := metav1.NewTime(now.Add(-30 * time.Second))
+	st := dashboardState{
+		Namespace: "default",
+		Now:       now,
+		Width:     200,
+		Height:    40,
+		Selected:  1,
+		Spawners: []kelosv1alpha1.TaskSpawner{{
+			ObjectMeta: metav1.ObjectMeta{Name: "fixer"},
+			Spec: kelosv1alpha1.TaskSpawnerSpec{
+				When: kelosv1alpha1.When{Cron: &kelosv1alpha1.Cron{Schedule: "0 * * * *"}},
+			},
+			Status: kelosv1alpha1.TaskSpawnerStatus{
+				Phase:             kelosv1alpha1.TaskSpawnerPhaseRunning,
+				TotalDiscovered:   7,
+				TotalTasksCreated: 5,
+				ActiveTasks:       2,
+				LastDiscoveryTime: &lastDiscovery,
+			},
+		}},
+		Tasks: []kelosv1alpha1.Task{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "fixer-1", CreationTimestamp: metav1.NewTime(now.Add(-5 * time.Minute))},
+				Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code", Branch: "fix-1"},
+				Status: kelosv1alpha1.TaskStatus{
+					Phase:   kelosv1alpha1.TaskPhaseWaiting,
+					Message: "Waiting for dependency \"setup\" to succeed",
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "fixer-2", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+				Spec:       kelosv1alpha1.TaskSpec{Type: "codex"},
+				Status: kelosv1alpha1.TaskStatus{
+					Phase:   kelosv1alpha1.TaskPhaseSucceeded,
+					Results: map[string]string{"cost-usd": "1.25"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	renderDashboard(&buf, st)
+	out := buf.String()
+
+	for _, want := range []string{
+		"namespace: default",
+		"TASKSPAWNERS (1)",
+		"cron: 0 * * * *",
+		"30s ago",
+		"TASKS (2)",
+		"fix-1",
+		"Waiting for dependency \"setup\" to succeed",
+		"$1.25",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	var selected string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, ansiReverse+"> ") {
+			selected = line
+		}
+	}
+	if !strings.Contains(selected, "fixer-2") {
+		t.Errorf("expected fixer-2 to be selected, got %q", selected)
+	}
+}
+
+func TestRenderDashboard_ScrollsToSelection(t *testing.T) {
+	now := time.Now()
+	var tasks []kelosv1alpha1.Task
+	for i := 0; i < 50; i++ {
+		tasks = append(tasks, kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "task-" + string(rune('a'+i%26)) + string(rune('a'+i/26)), CreationTimestamp: metav1.NewTime(now)},
+		})
+	}
+
+	var buf bytes.Buffer
+	renderDashboard(&buf, dashboardState{Tasks: tasks, Selected: 49, Width: 80, Height: 20, Now: now})
+	out := buf.String()
+
+	if !strings.Contains(out, "> "+tasks[49].Name) {
+		t.Errorf("selected task not visible:\n%s", out)
+	}
+	if lines := strings.Count(out, "\n") + 1; lines > 20 {
+		t.Errorf("rendered %d lines, want at most 20", lines)
+	}
+}
+
+func TestRenderLogView(t *testing.T) {
+	lines := []string{"one", "two", "three", "four", "five"}
+
+	var buf bytes.Buffer
+	renderLogView(&buf, "fixer-1", "", lines, 0, 80, 5)
+	out := buf.String()
+	if !strings.Contains(out, "logs: fixer-1") {
+		t.Errorf("missing title:\n%s", out)
+	}
+	if strings.Contains(out, "two") || !strings.Contains(out, "four\nfive") {
+		t.Errorf("expected the last two lines only:\n%s", out)
+	}
+
+	buf.Reset()
+	renderLogView(&buf, "fixer-1", "", lines, 2, 80, 5)
+	out = buf.String()
+	if !strings.Contains(out, "two\nthree") || strings.Contains(out, "four") {
+		t.Errorf("expected scrolled lines:\n%s", out)
+	}
+}
+
+func TestRenderLogViewUsage(t *testing.T) {
+	var buf bytes.Buffer
+	renderLogView(&buf, "fixer-1", "tokens: 10 in / 5 out", []string{"one"}, 0, 80, 5)
+	if !strings.Contains(buf.String(), "tokens: 10 in / 5 out") {
+		t.Errorf("missing running usage in title:\n%s", buf.String())
+	}
+}
+
+func TestRunningUsage(t *testing.T) {
+	var u runningUsage
+	for _, line := range []string{
+		`{"type":"assistant","message":{"id":"m1","usage":{"input_tokens":100,"output_tokens":10}}}`,
+		`{"type":"assistant","message":{"id":"m1","usage":{"input_tokens":100,"output_tokens":10}}}`,
+		`{"type":"assistant","message":{"id":"m2","usage":{"input_tokens":50,"output_tokens":5}}}`,
+		`not json`,
+	} {
+		u.add("claude-code", []byte(line))
+	}
+	if u.InputTokens != 150 || u.OutputTokens != 15 || u.CostUSD != 0 {
+		t.Errorf("running claude-code usage = %+v, want 150 in / 15 out", u)
+	}
+	if got := u.String(); got != "tokens: 150 in / 15 out" {
+		t.Errorf("String() = %q", got)
+	}
+
+	u.add("claude-code", []byte(`{"type":"result","total_cost_usd":0.25,"usage":{"input_tokens":200,"output_tokens":20}}`))
+	if u.InputTokens != 200 || u.OutputTokens != 20 || u.CostUSD != 0.25 {
+		t.Errorf("final claude-code usage = %+v, want the result totals", u)
+	}
+
+	var codex runningUsage
+	for i := 0; i < 2; i++ {
+		codex.add("codex", []byte(`{"type":"turn.completed","usage":{"input_tokens":30,"output_tokens":3}}`))
+	}
+	if codex.InputTokens != 60 || codex.OutputTokens != 6 {
+		t.Errorf("codex usage = %+v, want 60 in / 6 out", codex)
+	}
+
+	var none runningUsage
+	none.add("", []byte(`{"type":"result","total_cost_usd":1}`))
+	if none.String() != "" {
+		t.Errorf("expected no usage for an unknown format, got %q", none.String())
+	}
+}
+
+func TestDashboardFollowLogs(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(&kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "fixer-1", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code"},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseRunning, PodName: "fixer-1-pod"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	d := &dashboard{
+		namespace:  "default",
+		tasks:      listers.NewTaskLister(indexer),
+		clientset:  k8sfake.NewSimpleClientset(),
+		logUpdated: make(chan struct{}, 1),
+	}
+	tail := &logTail{cancel: func() {}, notify: d.notifyLogs}
+
+	// The fake clientset serves "fake logs" and ends the stream.
+	d.followLogs(context.Background(), tail, "fixer-1", "claude-code")
+
+	lines, _ := tail.snapshot()
+	if !reflect.DeepEqual(lines, []string{"fake logs"}) {
+		t.Errorf("lines = %q, want the streamed log", lines)
+	}
+	select {
+	case <-d.logUpdated:
+	default:
+		t.Error("expected the log view to be notified")
+	}
+}
+
+func TestLogTailCapsLines(t *testing.T) {
+	tail := &logTail{notify: func() {}}
+	for i := 0; i < maxLogViewLines+5; i++ {
+		tail.appendLines("line")
+	}
+	if lines, _ := tail.snapshot(); len(lines) != maxLogViewLines {
+		t.Errorf("kept %d lines, want %d", len(lines), maxLogViewLines)
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	if got := truncateLine("hello world", 5); got != "hell…" {
+		t.Errorf("truncateLine = %q, want %q", got, "hell…")
+	}
+	if got := truncateLine("short   ", 20); got != "short" {
+		t.Errorf("truncateLine = %q, want %q", got, "short")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/kelos-dev/kelos/internal/manifests/manifeststest"
 )
 
 // captureStdout redirects os.Stdout to a pipe, executes fn, and returns
@@ -238,6 +240,34 @@ workspace:
 	}
 }
 
+func TestRunCommand_DryRun_SessionPVC(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("secret: my-secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{
+		"run",
+		"--config", cfgPath,
+		"--dry-run",
+		"--prompt", "hello",
+		"--session-pvc", "agent-sessions",
+	})
+
+	var execErr error
+	output := captureStdout(t, func() {
+		execErr = cmd.Execute()
+	})
+	if execErr != nil {
+		t.Fatalf("unexpected error: %v", execErr)
+	}
+	if !strings.Contains(output, "persistentVolumeClaimName: agent-sessions") {
+		t.Errorf("expected session claim in dry-run output, got:\n%s", output)
+	}
+}
+
 func TestCreateWorkspaceCommand_DryRun(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, "config.yaml")
@@ -331,16 +361,191 @@ func TestCreateWorkspaceCommand_DryRun_WithToken(t *testing.T) {
 	}
 }
 
-func TestCreateCommand_NoTaskSpawnerSubcommand(t *testing.T) {
+func TestCreateTaskSpawnerCommand_MissingName(t *testing.T) {
 	root := NewRootCommand()
-	root.SetArgs([]string{"create", "taskspawner", "--name", "test"})
+	root.SetArgs([]string{"create", "taskspawner", "--cron", "0 9 * * 1", "--prompt-template", "hi"})
 
 	// Silence usage output from cobra.
 	root.SilenceUsage = true
 
 	err := root.Execute()
 	if err == nil {
-		t.Fatal("expected error when running 'create taskspawner', but got none")
+		t.Fatal("expected error when taskspawner name is missing")
+	}
+	if !strings.Contains(err.Error(), "taskspawner name is required") {
+		t.Errorf("expected 'taskspawner name is required' error, got: %v", err)
+	}
+}
+
+func TestCreateTaskSpawnerCommand_DryRun_GitHubIssues(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := `apiKey: sk-test
+type: codex
+workspace:
+  repo: https://github.com/org/repo.git
+`
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	promptPath := filepath.Join(dir, "prompt.md")
+	if err := os.WriteFile(promptPath, []byte("Fix issue #{{.Number}}: {{.Title}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{
+		"create", "taskspawner", "issue-fixer",
+		"--config", cfgPath,
+		"--dry-run",
+		"--namespace", "test-ns",
+		"--github-issues-labels", "bug,help wanted",
+		"--trigger-comment", "/kelos pick-up",
+		"--prompt-template", "@" + promptPath,
+		"--branch", "kelos-{{.Number}}",
+		"--max-concurrency", "2",
+		"--poll-interval", "2m",
+	})
+
+	var execErr error
+	output := captureStdout(t, func() {
+		execErr = cmd.Execute()
+	})
+	if execErr != nil {
+		t.Fatalf("unexpected error: %v", execErr)
+	}
+
+	for _, want := range []string{
+		"kind: TaskSpawner",
+		"name: issue-fixer",
+		"namespace: test-ns",
+		"githubIssues:",
+		"- bug",
+		"- help wanted",
+		"triggerComment: /kelos pick-up",
+		"pollInterval: 2m",
+		"type: codex",
+		"name: kelos-credentials",
+		"name: kelos-workspace",
+		"promptTemplate: 'Fix issue #{{.Number}}: {{.Title}}'",
+		"branch: kelos-{{.Number}}",
+		"maxConcurrency: 2",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "maxTotalTasks") {
+		t.Errorf("expected maxTotalTasks to be omitted, got:\n%s", output)
+	}
+}
+
+func TestCreateTaskSpawnerCommand_DryRun_Cron(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("secret: my-secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{
+		"create", "taskspawner", "weekly",
+		"--config", cfgPath,
+		"--dry-run",
+		"--cron", "0 9 * * 1",
+		"--prompt-template", "Update dependencies",
+		"--timeout", "30m",
+	})
+
+	var execErr error
+	output := captureStdout(t, func() {
+		execErr = cmd.Execute()
+	})
+	if execErr != nil {
+		t.Fatalf("unexpected error: %v", execErr)
+	}
+
+	for _, want := range []string{
+		"cron:",
+		"schedule: 0 9 * * 1",
+		"name: my-secret",
+		"activeDeadlineSeconds: 1800",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "workspaceRef") {
+		t.Errorf("expected no workspaceRef for cron spawner, got:\n%s", output)
+	}
+}
+
+func TestCreateTaskSpawnerCommand_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "no trigger",
+			args:    []string{"--prompt-template", "hi"},
+			wantErr: "exactly one trigger must be specified",
+		},
+		{
+			name:    "two triggers",
+			args:    []string{"--prompt-template", "hi", "--cron", "0 * * * *", "--github-issues", "--workspace", "ws"},
+			wantErr: "exactly one trigger must be specified",
+		},
+		{
+			name:    "github without workspace",
+			args:    []string{"--prompt-template", "hi", "--github-prs-labels", "ok-to-test"},
+			wantErr: "a workspace is required for GitHub triggers",
+		},
+		{
+			name:    "incomplete jira",
+			args:    []string{"--prompt-template", "hi", "--jira-project", "PROJ"},
+			wantErr: "--jira-base-url and --jira-secret are required",
+		},
+		{
+			name:    "comment flags with cron",
+			args:    []string{"--prompt-template", "hi", "--cron", "0 * * * *", "--trigger-comment", "/go"},
+			wantErr: "require a GitHub trigger",
+		},
+		{
+			name:    "poll interval with cron",
+			args:    []string{"--prompt-template", "hi", "--cron", "0 * * * *", "--poll-interval", "1m"},
+			wantErr: "--poll-interval cannot be used with --cron",
+		},
+		{
+			name:    "empty prompt template",
+			args:    []string{"--prompt-template", "", "--cron", "0 * * * *"},
+			wantErr: "--prompt-template must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(cfgPath, []byte("secret: my-secret\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := NewRootCommand()
+			cmd.SilenceUsage = true
+			cmd.SetArgs(append([]string{"create", "taskspawner", "ts", "--config", cfgPath, "--dry-run"}, tt.args...))
+
+			var execErr error
+			captureStdout(t, func() {
+				execErr = cmd.Execute()
+			})
+			if execErr == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(execErr.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, execErr)
+			}
+		})
 	}
 }
 
@@ -709,7 +914,7 @@ func TestInstallCommand_DryRun_Version(t *testing.T) {
 		}
 	})
 
-	if strings.Contains(output, ":latest") {
+	if manifeststest.LatestImageTag.MatchString(output) {
 		t.Errorf("expected all :latest tags to be replaced, got:\n%s", output[:min(len(output), 500)])
 	}
 	if !strings.Contains(output, ":v0.5.0") {
@@ -1021,3 +1226,68 @@ func TestResolveContent(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateWorkspaceCommand_DryRun_Provider(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{
+		"create", "workspace", "my-ws",
+		"--config", cfgPath,
+		"--dry-run",
+		"--repo", "git@gitlab.example.com:org/repo.git",
+		"--provider", "gitlab",
+		"--secret", "gitlab-token",
+		"--ssh-key-secret", "deploy-key",
+		"--namespace", "test-ns",
+	})
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	if err := cmd.Execute(); err != nil {
+		w.Close()
+		os.Stdout = old
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w.Close()
+	os.Stdout = old
+	var out bytes.Buffer
+	out.ReadFrom(r)
+	output := out.String()
+
+	for _, want := range []string{"provider: gitlab", "sshKeySecretRef:", "name: deploy-key", "name: gitlab-token"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestCreateWorkspaceCommand_BitbucketToken(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{
+		"create", "workspace", "my-ws",
+		"--config", cfgPath,
+		"--dry-run",
+		"--repo", "https://bitbucket.org/team/repo.git",
+		"--provider", "bitbucket",
+		"--token", "secret",
+	})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--token is not supported for bitbucket") {
+		t.Errorf("expected bitbucket --token error, got %v", err)
+	}
+}
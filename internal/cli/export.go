@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+// exportedAnnotations lists annotations written by tooling that are
+// dropped from exported resources.
+var exportedAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+func newExportCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		outputDir           string
+		includeSpawnedTasks bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a namespace's Kelos resources as YAML",
+		Long: `Export the Workspaces, AgentConfigs, TaskSpawners and Tasks of a namespace
+as clean YAML, without status and server-populated metadata, for use with
+GitOps or "kelos apply -f". Tasks created by TaskSpawners are skipped unless
+--include-spawned-tasks is set.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, ns, err := cfg.NewClient()
+			if err != nil {
+				return err
+			}
+
+			objs, err := listExportObjects(context.Background(), cl, ns, includeSpawnedTasks)
+			if err != nil {
+				return err
+			}
+
+			if outputDir == "" {
+				return writeExportBundle(os.Stdout, objs)
+			}
+
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return fmt.Errorf("creating output directory: %w", err)
+			}
+			for _, obj := range objs {
+				data, err := exportYAML(obj)
+				if err != nil {
+					return err
+				}
+				kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+				path := filepath.Join(outputDir, kind+"-"+obj.GetName()+".yaml")
+				if err := os.WriteFile(path, data, 0o644); err != nil {
+					return fmt.Errorf("writing %s: %w", path, err)
+				}
+				fmt.Fprintf(os.Stdout, "%s/%s exported to %s\n", kind, obj.GetName(), path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "write one file per resource to this directory instead of printing a bundle")
+	cmd.Flags().BoolVar(&includeSpawnedTasks, "include-spawned-tasks", false, "also export Tasks created by TaskSpawners")
+
+	return cmd
+}
+
+// listExportObjects returns the Kelos resources in a namespace in the order
+// they should be applied: Workspaces and AgentConfigs before the
+// TaskSpawners and Tasks that reference them.
+func listExportObjects(ctx context.Context, cl client.Client, namespace string, includeSpawnedTasks bool) ([]client.Object, error) {
+	var objs []client.Object
+
+	workspaces := &kelosv1alpha1.WorkspaceList{}
+	if err := cl.List(ctx, workspaces, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("listing workspaces: %w", err)
+	}
+	for i := range workspaces.Items {
+		objs = append(objs, &workspaces.Items[i])
+	}
+
+	agentConfigs := &kelosv1alpha1.AgentConfigList{}
+	if err := cl.List(ctx, agentConfigs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("listing agent configs: %w", err)
+	}
+	for i := range agentConfigs.Items {
+		objs = append(objs, &agentConfigs.Items[i])
+	}
+
+	spawners := &kelosv1alpha1.TaskSpawnerList{}
+	if err := cl.List(ctx, spawners, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("listing task spawners: %w", err)
+	}
+	for i := range spawners.Items {
+		objs = append(objs, &spawners.Items[i])
+	}
+
+	tasks := &kelosv1alpha1.TaskList{}
+	if err := cl.List(ctx, tasks, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("listing tasks: %w", err)
+	}
+	for i := range tasks.Items {
+		if !includeSpawnedTasks && tasks.Items[i].Labels["kelos.dev/taskspawner"] != "" {
+			continue
+		}
+		objs = append(objs, &tasks.Items[i])
+	}
+
+	for _, obj := range objs {
+		setKelosGVK(obj)
+	}
+	return objs, nil
+}
+
+// setKelosGVK sets the apiVersion and kind of a Kelos resource, which are
+// empty on objects decoded from a typed list.
+func setKelosGVK(obj client.Object) {
+	var kind string
+	switch obj.(type) {
+	case *kelosv1alpha1.Task:
+		kind = "Task"
+	case *kelosv1alpha1.Workspace:
+		kind = "Workspace"
+	case *kelosv1alpha1.AgentConfig:
+		kind = "AgentConfig"
+	case *kelosv1alpha1.TaskSpawner:
+		kind = "TaskSpawner"
+	default:
+		return
+	}
+	obj.GetObjectKind().SetGroupVersionKind(kelosv1alpha1.GroupVersion.WithKind(kind))
+}
+
+// writeExportBundle writes objs as a multi-document YAML stream.
+func writeExportBundle(w io.Writer, objs []client.Object) error {
+	for i, obj := range objs {
+		data, err := exportYAML(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportYAML renders obj without its status, namespace and server-populated
+// metadata, so that it can be applied to another namespace or cluster.
+func exportYAML(obj client.Object) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	delete(m, "status")
+
+	meta := map[string]interface{}{"name": obj.GetName()}
+	if labels := obj.GetLabels(); len(labels) > 0 {
+		meta["labels"] = labels
+	}
+	annotations := make(map[string]string, len(obj.GetAnnotations()))
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+	for _, k := range exportedAnnotations {
+		delete(annotations, k)
+	}
+	if len(annotations) > 0 {
+		meta["annotations"] = annotations
+	}
+	m["metadata"] = meta
+
+	out, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	// Keep apiVersion and kind first for readability.
+	var head, rest bytes.Buffer
+	for _, line := range strings.SplitAfter(string(out), "\n") {
+		if strings.HasPrefix(line, "apiVersion: ") || strings.HasPrefix(line, "kind: ") {
+			head.WriteString(line)
+		} else {
+			rest.WriteString(line)
+		}
+	}
+	head.Write(rest.Bytes())
+	return head.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func TestExportBundle(t *testing.T) {
+	now := metav1.Now()
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "repo",
+			Namespace:         "default",
+			UID:               "abc",
+			CreationTimestamp: now,
+			Annotations:       map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"},
+		},
+		Spec: kelosv1alpha1.WorkspaceSpec{Repo: "https://github.com/o/r.git"},
+	}
+	ts := &kelosv1alpha1.TaskSpawner{
+		ObjectMeta: metav1.ObjectMeta{Name: "triage", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpawnerSpec{
+			When:         kelosv1alpha1.When{Cron: &kelosv1alpha1.Cron{Schedule: "0 * * * *"}},
+			TaskTemplate: kelosv1alpha1.TaskTemplate{Type: "codex", PromptTemplate: "triage"},
+		},
+		Status: kelosv1alpha1.TaskSpawnerStatus{Phase: kelosv1alpha1.TaskSpawnerPhaseRunning},
+	}
+	manual := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex", Prompt: "hello"},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseSucceeded},
+	}
+	spawned := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "triage-1", Namespace: "default", Labels: map[string]string{"kelos.dev/taskspawner": "triage"}},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex", Prompt: "triage"},
+	}
+	other := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "elsewhere", Namespace: "other"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex", Prompt: "hi"},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ws, ts, manual, spawned, other).Build()
+
+	objs, err := listExportObjects(context.Background(), cl, "default", false)
+	if err != nil {
+		t.Fatalf("listExportObjects() error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeExportBundle(&buf, objs); err != nil {
+		t.Fatalf("writeExportBundle() error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{"kind: Workspace", "kind: TaskSpawner", "name: manual", "apiVersion: kelos.dev/v1alpha1\nkind: Workspace\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("export missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"status:", "managedFields", "uid:", "resourceVersion", "creationTimestamp", "namespace:", "last-applied-configuration", "triage-1", "elsewhere"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("export should not contain %q:\n%s", unwanted, out)
+		}
+	}
+	if strings.Index(out, "kind: Workspace") > strings.Index(out, "kind: Task\n") {
+		t.Error("Workspaces should be exported before Tasks")
+	}
+
+	// The bundle can be applied as-is.
+	decoded, err := decodeManifests(buf.Bytes(), t.TempDir())
+	if err != nil {
+		t.Fatalf("decoding exported bundle: %v", err)
+	}
+	if len(decoded) != 3 {
+		t.Errorf("expected 3 exported resources, got %d", len(decoded))
+	}
+
+	objs, err = listExportObjects(context.Background(), cl, "default", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 4 {
+		t.Errorf("expected spawned Task to be included, got %d objects", len(objs))
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
@@ -36,6 +39,7 @@ func newGetCommand(cfg *ClientConfig) *cobra.Command {
 func newGetTaskSpawnerCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Command {
 	var output string
 	var detail bool
+	var preview bool
 
 	cmd := &cobra.Command{
 		Use:     "taskspawner [name]",
@@ -51,6 +55,10 @@ func newGetTaskSpawnerCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Com
 				return fmt.Errorf("a resource cannot be retrieved by name across all namespaces")
 			}
 
+			if preview && len(args) != 1 {
+				return fmt.Errorf("--preview requires a task spawner name")
+			}
+
 			cl, ns, err := cfg.NewClient()
 			if err != nil {
 				return err
@@ -64,6 +72,21 @@ func newGetTaskSpawnerCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Com
 					return fmt.Errorf("getting task spawner: %w", err)
 				}
 
+				if preview {
+					if ts.Spec.DryRun == nil || !*ts.Spec.DryRun {
+						return fmt.Errorf("task spawner %q is not in dry-run mode: set spec.dryRun to true to record a preview", ts.Name)
+					}
+					switch output {
+					case "yaml":
+						return printYAML(os.Stdout, ts.Status.Preview)
+					case "json":
+						return printJSON(os.Stdout, ts.Status.Preview)
+					default:
+						printTaskSpawnerPreview(os.Stdout, ts, detail)
+						return nil
+					}
+				}
+
 				ts.SetGroupVersionKind(kelosv1alpha1.GroupVersion.WithKind("TaskSpawner"))
 				switch output {
 				case "yaml":
@@ -104,6 +127,7 @@ func newGetTaskSpawnerCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Com
 
 	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format (yaml or json)")
 	cmd.Flags().BoolVarP(&detail, "detail", "d", false, "Show detailed information for a specific task spawner")
+	cmd.Flags().BoolVar(&preview, "preview", false, "Show the Tasks a dry-run task spawner would create, retrigger or skip (with --detail, include rendered prompts)")
 
 	cmd.ValidArgsFunction = completeTaskSpawnerNames(cfg)
 	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"yaml", "json"}, cobra.ShellCompDirectiveNoFileComp))
@@ -115,6 +139,8 @@ func newGetTaskCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Command {
 	var output string
 	var detail bool
 	var phases []string
+	var selector string
+	var graph string
 
 	cmd := &cobra.Command{
 		Use:     "task [name]",
@@ -134,6 +160,27 @@ func newGetTaskCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Command {
 				return err
 			}
 
+			if graph != "" {
+				if !slices.Contains(graphFormats, graph) {
+					return fmt.Errorf("unknown graph format %q: must be one of %s", graph, strings.Join(graphFormats, ", "))
+				}
+				if output != "" {
+					return fmt.Errorf("--graph and --output cannot be used together")
+				}
+				if *allNamespaces {
+					return fmt.Errorf("--graph cannot be used with --all-namespaces: dependencies are resolved within a namespace")
+				}
+			}
+
+			var labelSelector labels.Selector
+			if selector != "" {
+				parsed, err := labels.Parse(selector)
+				if err != nil {
+					return fmt.Errorf("invalid selector %q: %w", selector, err)
+				}
+				labelSelector = parsed
+			}
+
 			cl, ns, err := cfg.NewClient()
 			if err != nil {
 				return err
@@ -141,6 +188,10 @@ func newGetTaskCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Command {
 
 			ctx := context.Background()
 
+			if graph != "" {
+				return printTaskGraph(ctx, cl, ns, args, labelSelector, phases, graph)
+			}
+
 			if len(args) == 1 {
 				task := &kelosv1alpha1.Task{}
 				if err := cl.Get(ctx, client.ObjectKey{Name: args[0], Namespace: ns}, task); err != nil {
@@ -168,6 +219,9 @@ func newGetTaskCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Command {
 			if !*allNamespaces {
 				listOpts = append(listOpts, client.InNamespace(ns))
 			}
+			if labelSelector != nil {
+				listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: labelSelector})
+			}
 			if err := cl.List(ctx, taskList, listOpts...); err != nil {
 				return fmt.Errorf("listing tasks: %w", err)
 			}
@@ -193,8 +247,12 @@ func newGetTaskCommand(cfg *ClientConfig, allNamespaces *bool) *cobra.Command {
 	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format (yaml or json)")
 	cmd.Flags().BoolVarP(&detail, "detail", "d", false, "Show detailed information for a specific task")
 	cmd.Flags().StringSliceVar(&phases, "phase", nil, "Filter tasks by phase (Pending, Running, Waiting, Succeeded, Failed)")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter tasks (e.g. kelos.dev/taskspawner=my-spawner)")
+	cmd.Flags().StringVar(&graph, "graph", "", "Render the dependsOn graph and branch locks (ascii, mermaid or dot)")
+	cmd.Flags().Lookup("graph").NoOptDefVal = "ascii"
 
 	cmd.ValidArgsFunction = completeTaskNames(cfg)
+	_ = cmd.RegisterFlagCompletionFunc("graph", cobra.FixedCompletions(graphFormats, cobra.ShellCompDirectiveNoFileComp))
 	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"yaml", "json"}, cobra.ShellCompDirectiveNoFileComp))
 	_ = cmd.RegisterFlagCompletionFunc("phase", cobra.FixedCompletions(
 		[]string{"Pending", "Running", "Waiting", "Succeeded", "Failed"},
@@ -0,0 +1,456 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+// graphFormats lists the output formats of kelos get task --graph.
+var graphFormats = []string{"ascii", "mermaid", "dot"}
+
+// printTaskGraph renders the graph of the named Task, or of the Tasks
+// matching selector and phases, in the given format.
+func printTaskGraph(ctx context.Context, cl client.Client, namespace string, names []string, selector labels.Selector, phases []string, format string) error {
+	taskList := &kelosv1alpha1.TaskList{}
+	if err := cl.List(ctx, taskList, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("listing tasks: %w", err)
+	}
+
+	var selected []kelosv1alpha1.Task
+	for _, t := range taskList.Items {
+		if len(names) > 0 && !slices.Contains(names, t.Name) {
+			continue
+		}
+		if selector != nil && !selector.Matches(labels.Set(t.Labels)) {
+			continue
+		}
+		selected = append(selected, t)
+	}
+	if len(names) > 0 && len(selected) == 0 {
+		return fmt.Errorf("task %q not found", names[0])
+	}
+	if len(phases) > 0 {
+		selected = filterTasksByPhase(selected, phases)
+	}
+
+	g := buildTaskGraph(taskList.Items, selected)
+	switch format {
+	case "mermaid":
+		renderTaskGraphMermaid(os.Stdout, g)
+	case "dot":
+		renderTaskGraphDOT(os.Stdout, g)
+	default:
+		renderTaskGraphASCII(os.Stdout, g, term.IsTerminal(int(os.Stdout.Fd())))
+	}
+	return nil
+}
+
+// taskGraph is the dependsOn DAG of a set of Tasks, together with the
+// branch locks that serialize Tasks working on the same branch.
+type taskGraph struct {
+	Nodes []taskGraphNode
+	Locks []branchLock
+}
+
+// taskGraphNode is a Task in a taskGraph.
+type taskGraphNode struct {
+	Name      string
+	Phase     kelosv1alpha1.TaskPhase
+	DependsOn []string
+	// Stage is the length of the longest dependency chain leading to the
+	// Task, starting at 1.
+	Stage int
+	// Missing is set for dependencies that do not exist.
+	Missing bool
+	// BlockedBy explains why a Waiting Task has not started.
+	BlockedBy []string
+}
+
+// branchLock is a workspace branch that is held by one Task while others
+// wait for it, mirroring the controller's branch lock.
+type branchLock struct {
+	Workspace string
+	Branch    string
+	Holder    string
+	Waiters   []string
+}
+
+// buildTaskGraph returns the graph of the selected Tasks and, transitively,
+// the Tasks they depend on. all holds every Task in the namespace and is
+// used to resolve dependencies and branch lock holders.
+func buildTaskGraph(all, selected []kelosv1alpha1.Task) taskGraph {
+	byName := make(map[string]*kelosv1alpha1.Task, len(all))
+	for i := range all {
+		byName[all[i].Name] = &all[i]
+	}
+
+	included := make(map[string]bool)
+	var queue []string
+	for _, t := range selected {
+		queue = append(queue, t.Name)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if included[name] {
+			continue
+		}
+		included[name] = true
+		if t, ok := byName[name]; ok {
+			queue = append(queue, t.Spec.DependsOn...)
+		}
+	}
+
+	// Branch locks, computed over all Tasks because the holder of a lock
+	// may not be selected.
+	locks := make(map[string]*branchLock)
+	lockKey := func(t *kelosv1alpha1.Task) string {
+		ws := ""
+		if t.Spec.WorkspaceRef != nil {
+			ws = t.Spec.WorkspaceRef.Name
+		}
+		return ws + ":" + t.Spec.Branch
+	}
+	var waiting []*kelosv1alpha1.Task
+	for i := range all {
+		t := &all[i]
+		if t.Spec.Branch == "" {
+			continue
+		}
+		key := lockKey(t)
+		lock, ok := locks[key]
+		if !ok {
+			lock = &branchLock{Branch: t.Spec.Branch}
+			if t.Spec.WorkspaceRef != nil {
+				lock.Workspace = t.Spec.WorkspaceRef.Name
+			}
+			locks[key] = lock
+		}
+		switch t.Status.Phase {
+		case kelosv1alpha1.TaskPhaseRunning, kelosv1alpha1.TaskPhasePending:
+			if lock.Holder == "" {
+				lock.Holder = t.Name
+			}
+		case kelosv1alpha1.TaskPhaseWaiting:
+			waiting = append(waiting, t)
+		}
+	}
+	sort.SliceStable(waiting, func(i, j int) bool {
+		return waiting[i].CreationTimestamp.Before(&waiting[j].CreationTimestamp)
+	})
+	for _, t := range waiting {
+		lock := locks[lockKey(t)]
+		lock.Waiters = append(lock.Waiters, t.Name)
+	}
+
+	var lockList []branchLock
+	for _, lock := range locks {
+		if lock.Holder == "" && len(lock.Waiters) == 0 {
+			continue
+		}
+		if !included[lock.Holder] && !anyIncluded(lock.Waiters, included) {
+			continue
+		}
+		lockList = append(lockList, *lock)
+	}
+	sort.Slice(lockList, func(i, j int) bool {
+		if lockList[i].Workspace != lockList[j].Workspace {
+			return lockList[i].Workspace < lockList[j].Workspace
+		}
+		return lockList[i].Branch < lockList[j].Branch
+	})
+
+	// The Tasks holding or waiting for a lock are shown even when not
+	// selected, so that the graph explains what a Task is waiting for.
+	for _, lock := range lockList {
+		if lock.Holder != "" {
+			included[lock.Holder] = true
+		}
+		for _, w := range lock.Waiters {
+			included[w] = true
+		}
+	}
+
+	var nodes []taskGraphNode
+	stages := make(map[string]int)
+	var stageOf func(name string, visiting map[string]bool) int
+	stageOf = func(name string, visiting map[string]bool) int {
+		if s, ok := stages[name]; ok {
+			return s
+		}
+		t, ok := byName[name]
+		if !ok || visiting[name] {
+			// Missing Tasks and dependency cycles start a chain.
+			return 1
+		}
+		visiting[name] = true
+		stage := 1
+		for _, dep := range t.Spec.DependsOn {
+			if s := stageOf(dep, visiting) + 1; s > stage {
+				stage = s
+			}
+		}
+		delete(visiting, name)
+		stages[name] = stage
+		return stage
+	}
+
+	for name := range included {
+		t, ok := byName[name]
+		if !ok {
+			nodes = append(nodes, taskGraphNode{Name: name, Stage: 1, Missing: true})
+			continue
+		}
+		node := taskGraphNode{
+			Name:      name,
+			Phase:     t.Status.Phase,
+			DependsOn: t.Spec.DependsOn,
+			Stage:     stageOf(name, map[string]bool{}),
+		}
+		if t.Status.Phase == kelosv1alpha1.TaskPhaseWaiting {
+			node.BlockedBy = taskBlockers(t, byName, locks[lockKey(t)])
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Stage != nodes[j].Stage {
+			return nodes[i].Stage < nodes[j].Stage
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	return taskGraph{Nodes: nodes, Locks: lockList}
+}
+
+// taskBlockers returns why a Waiting Task has not started: unfinished
+// dependencies, or a branch lock held by or queued behind another Task.
+func taskBlockers(t *kelosv1alpha1.Task, byName map[string]*kelosv1alpha1.Task, lock *branchLock) []string {
+	var blockers []string
+	for _, dep := range t.Spec.DependsOn {
+		d, ok := byName[dep]
+		switch {
+		case !ok:
+			blockers = append(blockers, fmt.Sprintf("dependency %s (not found)", dep))
+		case d.Status.Phase != kelosv1alpha1.TaskPhaseSucceeded:
+			blockers = append(blockers, fmt.Sprintf("dependency %s (%s)", dep, taskPhaseOrPending(d.Status.Phase)))
+		}
+	}
+	if lock == nil {
+		return blockers
+	}
+	if lock.Holder != "" && lock.Holder != t.Name {
+		blockers = append(blockers, fmt.Sprintf("branch %s locked by %s", lock.Branch, lock.Holder))
+	} else if len(lock.Waiters) > 0 && lock.Waiters[0] != t.Name {
+		blockers = append(blockers, fmt.Sprintf("branch %s queued behind %s", lock.Branch, lock.Waiters[0]))
+	}
+	return blockers
+}
+
+func anyIncluded(names []string, included map[string]bool) bool {
+	for _, n := range names {
+		if included[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// graphPhase returns the phase shown for a node.
+func graphPhase(n taskGraphNode) string {
+	if n.Missing {
+		return "Missing"
+	}
+	return taskPhaseOrPending(n.Phase)
+}
+
+// phaseANSIColor returns the terminal color of a phase.
+func phaseANSIColor(phase string) string {
+	switch phase {
+	case string(kelosv1alpha1.TaskPhaseSucceeded):
+		return "\x1b[32m"
+	case string(kelosv1alpha1.TaskPhaseFailed), "Missing":
+		return "\x1b[31m"
+	case string(kelosv1alpha1.TaskPhaseRunning):
+		return "\x1b[36m"
+	case string(kelosv1alpha1.TaskPhaseWaiting):
+		return "\x1b[33m"
+	default:
+		return "\x1b[90m"
+	}
+}
+
+// phaseFillColor returns the fill color of a phase in Mermaid and DOT
+// output.
+func phaseFillColor(phase string) string {
+	switch phase {
+	case string(kelosv1alpha1.TaskPhaseSucceeded):
+		return "#c8e6c9"
+	case string(kelosv1alpha1.TaskPhaseFailed), "Missing":
+		return "#ffcdd2"
+	case string(kelosv1alpha1.TaskPhaseRunning):
+		return "#bbdefb"
+	case string(kelosv1alpha1.TaskPhaseWaiting):
+		return "#fff9c4"
+	default:
+		return "#eeeeee"
+	}
+}
+
+// renderTaskGraphASCII writes the graph as a table of stages followed by the
+// branch locks. Phases are colored when color is true.
+func renderTaskGraphASCII(w io.Writer, g taskGraph, color bool) {
+	if len(g.Nodes) == 0 {
+		fmt.Fprintln(w, "No tasks found")
+		return
+	}
+
+	header := []string{"STAGE", "TASK", "PHASE", "DEPENDS ON", "BLOCKED BY"}
+	rows := [][]string{header}
+	for _, n := range g.Nodes {
+		deps, blocked := "-", "-"
+		if len(n.DependsOn) > 0 {
+			deps = strings.Join(n.DependsOn, ", ")
+		}
+		if len(n.BlockedBy) > 0 {
+			blocked = strings.Join(n.BlockedBy, "; ")
+		}
+		rows = append(rows, []string{fmt.Sprint(n.Stage), n.Name, graphPhase(n), deps, blocked})
+	}
+	writePaddedRows(w, rows, func(row, col int, cell string) string {
+		if color && row > 0 && col == 2 {
+			return phaseANSIColor(rows[row][2]) + cell + ansiReset
+		}
+		return cell
+	})
+
+	if len(g.Locks) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	rows = [][]string{{"BRANCH LOCK", "HELD BY", "WAITING"}}
+	for _, l := range g.Locks {
+		name := l.Branch
+		if l.Workspace != "" {
+			name = l.Workspace + "/" + l.Branch
+		}
+		holder, waiters := "-", "-"
+		if l.Holder != "" {
+			holder = l.Holder
+		}
+		if len(l.Waiters) > 0 {
+			waiters = strings.Join(l.Waiters, ", ")
+		}
+		rows = append(rows, []string{name, holder, waiters})
+	}
+	writePaddedRows(w, rows, nil)
+}
+
+// writePaddedRows writes rows with aligned columns. decorate, if set, wraps
+// each padded cell, e.g. with color codes that must not count toward the
+// column width.
+func writePaddedRows(w io.Writer, rows [][]string, decorate func(row, col int, cell string) string) {
+	widths := make([]int, len(rows[0]))
+	for _, r := range rows {
+		for i, c := range r {
+			if len(c) > widths[i] {
+				widths[i] = len(c)
+			}
+		}
+	}
+	for ri, r := range rows {
+		var line strings.Builder
+		for ci, c := range r {
+			cell := c
+			if ci < len(r)-1 {
+				cell += strings.Repeat(" ", widths[ci]-len(c)+3)
+			}
+			if decorate != nil {
+				cell = decorate(ri, ci, cell)
+			}
+			line.WriteString(cell)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
+// renderTaskGraphMermaid writes the graph as a Mermaid flowchart.
+func renderTaskGraphMermaid(w io.Writer, g taskGraph) {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.Name] = fmt.Sprintf("t%d", i)
+	}
+
+	fmt.Fprintln(w, "flowchart LR")
+	for _, n := range g.Nodes {
+		phase := graphPhase(n)
+		fmt.Fprintf(w, "  %s[\"%s<br/>%s\"]:::%s\n", ids[n.Name], n.Name, phase, strings.ToLower(phase))
+	}
+	for _, n := range g.Nodes {
+		for _, dep := range n.DependsOn {
+			if id, ok := ids[dep]; ok {
+				fmt.Fprintf(w, "  %s --> %s\n", id, ids[n.Name])
+			}
+		}
+	}
+	for i, l := range g.Locks {
+		lockID := fmt.Sprintf("lock%d", i)
+		fmt.Fprintf(w, "  %s{{\"branch %s\"}}:::lock\n", lockID, l.Branch)
+		if id, ok := ids[l.Holder]; ok {
+			fmt.Fprintf(w, "  %s -. holds .-> %s\n", id, lockID)
+		}
+		for _, waiter := range l.Waiters {
+			if id, ok := ids[waiter]; ok {
+				fmt.Fprintf(w, "  %s -. waiting .-> %s\n", lockID, id)
+			}
+		}
+	}
+	for _, phase := range []string{"Pending", "Waiting", "Running", "Succeeded", "Failed", "Missing"} {
+		fmt.Fprintf(w, "  classDef %s fill:%s\n", strings.ToLower(phase), phaseFillColor(phase))
+	}
+	fmt.Fprintln(w, "  classDef lock fill:#ffffff,stroke-dasharray:4")
+}
+
+// renderTaskGraphDOT writes the graph in Graphviz DOT format.
+func renderTaskGraphDOT(w io.Writer, g taskGraph) {
+	shown := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		shown[n.Name] = true
+	}
+
+	fmt.Fprintln(w, "digraph tasks {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box, style=\"rounded,filled\"];")
+	for _, n := range g.Nodes {
+		phase := graphPhase(n)
+		fmt.Fprintf(w, "  %q [label=%q, fillcolor=%q];\n", n.Name, n.Name+"\n"+phase, phaseFillColor(phase))
+	}
+	for _, n := range g.Nodes {
+		for _, dep := range n.DependsOn {
+			if shown[dep] {
+				fmt.Fprintf(w, "  %q -> %q;\n", dep, n.Name)
+			}
+		}
+	}
+	for i, l := range g.Locks {
+		lockID := fmt.Sprintf("lock%d", i)
+		fmt.Fprintf(w, "  %q [label=%q, shape=hexagon, style=dashed];\n", lockID, "branch "+l.Branch)
+		if l.Holder != "" {
+			fmt.Fprintf(w, "  %q -> %q [style=dashed, label=\"holds\"];\n", l.Holder, lockID)
+		}
+		for _, waiter := range l.Waiters {
+			fmt.Fprintf(w, "  %q -> %q [style=dashed, label=\"waiting\"];\n", lockID, waiter)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func graphTestTasks() []kelosv1alpha1.Task {
+	base := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	task := func(name string, minutes int, phase kelosv1alpha1.TaskPhase, branch string, deps ...string) kelosv1alpha1.Task {
+		t := kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(base.Add(time.Duration(minutes) * time.Minute)),
+				Labels:            map[string]string{"pipeline": "release"},
+			},
+			Spec:   kelosv1alpha1.TaskSpec{DependsOn: deps, Branch: branch},
+			Status: kelosv1alpha1.TaskStatus{Phase: phase},
+		}
+		if branch != "" {
+			t.Spec.WorkspaceRef = &kelosv1alpha1.WorkspaceReference{Name: "repo"}
+		}
+		return t
+	}
+
+	hotfix := task("hotfix", 0, kelosv1alpha1.TaskPhaseRunning, "release")
+	hotfix.Labels = nil
+	return []kelosv1alpha1.Task{
+		task("plan", 1, kelosv1alpha1.TaskPhaseSucceeded, ""),
+		task("implement", 2, kelosv1alpha1.TaskPhaseRunning, "", "plan"),
+		task("docs", 3, kelosv1alpha1.TaskPhaseSucceeded, "", "plan"),
+		task("test", 4, kelosv1alpha1.TaskPhaseWaiting, "", "implement", "docs"),
+		task("release", 5, kelosv1alpha1.TaskPhaseWaiting, "release", "test"),
+		task("changelog", 6, kelosv1alpha1.TaskPhaseWaiting, "release"),
+		task("orphan", 7, kelosv1alpha1.TaskPhaseWaiting, "", "deleted"),
+		hotfix,
+	}
+}
+
+func TestBuildTaskGraph(t *testing.T) {
+	tasks := graphTestTasks()
+	g := buildTaskGraph(tasks, tasks[:5])
+
+	stages := make(map[string]int)
+	nodes := make(map[string]taskGraphNode)
+	for _, n := range g.Nodes {
+		stages[n.Name] = n.Stage
+		nodes[n.Name] = n
+	}
+	want := map[string]int{"plan": 1, "implement": 2, "docs": 2, "test": 3, "release": 4, "hotfix": 1, "changelog": 1}
+	for name, stage := range want {
+		if stages[name] != stage {
+			t.Errorf("stage of %s = %d, want %d", name, stages[name], stage)
+		}
+	}
+	if _, ok := nodes["orphan"]; ok {
+		t.Error("unselected task without a branch lock should not be shown")
+	}
+
+	if got := strings.Join(nodes["test"].BlockedBy, "; "); got != "dependency implement (Running)" {
+		t.Errorf("test blocked by %q", got)
+	}
+	if got := strings.Join(nodes["release"].BlockedBy, "; "); got != "dependency test (Waiting); branch release locked by hotfix" {
+		t.Errorf("release blocked by %q", got)
+	}
+
+	if len(g.Locks) != 1 {
+		t.Fatalf("expected 1 branch lock, got %+v", g.Locks)
+	}
+	lock := g.Locks[0]
+	if lock.Workspace != "repo" || lock.Branch != "release" || lock.Holder != "hotfix" || strings.Join(lock.Waiters, ",") != "release,changelog" {
+		t.Errorf("unexpected lock: %+v", lock)
+	}
+}
+
+func TestBuildTaskGraph_MissingDependency(t *testing.T) {
+	tasks := graphTestTasks()
+	g := buildTaskGraph(tasks, []kelosv1alpha1.Task{tasks[6]})
+
+	var names []string
+	for _, n := range g.Nodes {
+		names = append(names, n.Name)
+		if n.Name == "deleted" && !n.Missing {
+			t.Error("expected deleted dependency to be marked missing")
+		}
+		if n.Name == "orphan" && (n.Stage != 2 || strings.Join(n.BlockedBy, "") != "dependency deleted (not found)") {
+			t.Errorf("unexpected orphan node: %+v", n)
+		}
+	}
+	if strings.Join(names, ",") != "deleted,orphan" {
+		t.Errorf("nodes = %v", names)
+	}
+}
+
+func TestRenderTaskGraph(t *testing.T) {
+	tasks := graphTestTasks()
+	g := buildTaskGraph(tasks, tasks[:5])
+
+	var ascii bytes.Buffer
+	renderTaskGraphASCII(&ascii, g, false)
+	for _, want := range []string{
+		"STAGE   TASK",
+		"3       test        Waiting     implement, docs   dependency implement (Running)",
+		"BRANCH LOCK",
+		"repo/release   hotfix    release, changelog",
+	} {
+		if !strings.Contains(ascii.String(), want) {
+			t.Errorf("ascii output missing %q:\n%s", want, ascii.String())
+		}
+	}
+	if strings.Contains(ascii.String(), "\x1b[") {
+		t.Error("ascii output without color should not contain escape codes")
+	}
+
+	var colored bytes.Buffer
+	renderTaskGraphASCII(&colored, g, true)
+	if !strings.Contains(colored.String(), "\x1b[32mSucceeded") {
+		t.Errorf("expected colored phases:\n%q", colored.String())
+	}
+
+	var mermaid bytes.Buffer
+	renderTaskGraphMermaid(&mermaid, g)
+	for _, want := range []string{"flowchart LR", `["test<br/>Waiting"]:::waiting`, "-. holds .->", "-. waiting .->", "classDef succeeded fill:#c8e6c9"} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("mermaid output missing %q:\n%s", want, mermaid.String())
+		}
+	}
+
+	var dot bytes.Buffer
+	renderTaskGraphDOT(&dot, g)
+	for _, want := range []string{"digraph tasks {", `"plan" -> "implement";`, `"hotfix" -> "lock0" [style=dashed, label="holds"];`, `fillcolor="#fff9c4"`} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("dot output missing %q:\n%s", want, dot.String())
+		}
+	}
+}
+
+func TestGetTaskCommand_GraphValidation(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"get", "task", "--graph=svg"}, wantErr: "unknown graph format"},
+		{args: []string{"get", "task", "--graph", "-o", "yaml"}, wantErr: "cannot be used together"},
+		{args: []string{"get", "task", "--graph", "-A"}, wantErr: "--all-namespaces"},
+		{args: []string{"get", "task", "-l", "=x"}, wantErr: "invalid selector"},
+	}
+	for _, tt := range tests {
+		cmd := NewRootCommand()
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/kelos-dev/kelos/internal/helmchart"
 	"github.com/kelos-dev/kelos/internal/manifests"
+	"github.com/kelos-dev/kelos/internal/manifests/manifeststest"
 )
 
 func TestParseManifests_SingleDocument(t *testing.T) {
@@ -226,7 +227,7 @@ func TestRenderChart_VersionSubstitution(t *testing.T) {
 	if err != nil {
 		t.Fatalf("rendering chart: %v", err)
 	}
-	if bytes.Contains(data, []byte(":latest")) {
+	if manifeststest.LatestImageTag.Match(data) {
 		t.Error("expected all :latest tags to be replaced")
 	}
 	if !bytes.Contains(data, []byte(":v0.5.0")) {
@@ -284,11 +285,25 @@ func TestRenderChart_NoPullPolicyByDefault(t *testing.T) {
 	if err != nil {
 		t.Fatalf("rendering chart: %v", err)
 	}
-	if bytes.Contains(data, []byte("imagePullPolicy:")) {
-		t.Error("expected no imagePullPolicy when not set")
+	objs, err := parseManifests(data)
+	if err != nil {
+		t.Fatalf("parsing rendered chart: %v", err)
 	}
-	if bytes.Contains(data, []byte("-pull-policy=")) {
-		t.Error("expected no -pull-policy args when not set")
+	for _, obj := range objs {
+		// CRD schemas describe the imagePullPolicy of embedded containers.
+		if obj.GetKind() == "CustomResourceDefinition" {
+			continue
+		}
+		out, err := obj.MarshalJSON()
+		if err != nil {
+			t.Fatalf("marshaling %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		if bytes.Contains(out, []byte(`"imagePullPolicy":`)) {
+			t.Errorf("expected no imagePullPolicy in %s %s when not set", obj.GetKind(), obj.GetName())
+		}
+		if bytes.Contains(out, []byte("-pull-policy=")) {
+			t.Errorf("expected no -pull-policy args in %s %s when not set", obj.GetKind(), obj.GetName())
+		}
 	}
 }
 
@@ -476,11 +491,12 @@ func TestInstallCommand_DryRunIncludesEachCRDOnce(t *testing.T) {
 		crdNames[obj.GetName()]++
 	}
 
-	if crdCount != 4 {
-		t.Fatalf("expected 4 CRDs in dry-run output, got %d", crdCount)
+	if crdCount != 5 {
+		t.Fatalf("expected 5 CRDs in dry-run output, got %d", crdCount)
 	}
 	for _, name := range []string{
 		"agentconfigs.kelos.dev",
+		"agenttypes.kelos.dev",
 		"tasks.kelos.dev",
 		"taskspawners.kelos.dev",
 		"workspaces.kelos.dev",
@@ -501,7 +517,7 @@ func TestInstallCommand_VersionFlag(t *testing.T) {
 		}
 	})
 
-	if strings.Contains(output, ":latest") {
+	if manifeststest.LatestImageTag.MatchString(output) {
 		t.Errorf("expected all :latest tags to be replaced, got:\n%s", output[:min(len(output), 500)])
 	}
 	if !strings.Contains(output, ":v0.5.0") {
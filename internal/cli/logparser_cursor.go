@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CursorEvent represents a single NDJSON event from cursor agent --output-format stream-json.
+type CursorEvent struct {
+	Type         string                     `json:"type"`
+	Subtype      string                     `json:"subtype,omitempty"`
+	Model        string                     `json:"model,omitempty"`
+	Message      *MessagePayload            `json:"message,omitempty"`
+	ToolCall     map[string]json.RawMessage `json:"tool_call,omitempty"`
+	Result       string                     `json:"result,omitempty"`
+	IsError      bool                       `json:"is_error,omitempty"`
+	DurationMS   int64                      `json:"duration_ms,omitempty"`
+	TotalCostUSD float64                    `json:"total_cost_usd,omitempty"`
+	Usage        *CursorUsage               `json:"usage,omitempty"`
+}
+
+// CursorUsage holds token usage from the result event.
+type CursorUsage struct {
+	InputTokens  int `json:"inputTokens,omitempty"`
+	OutputTokens int `json:"outputTokens,omitempty"`
+}
+
+// cursorToolCall is the payload of a tool call, keyed by tool kind
+// (e.g. "readToolCall") in the tool_call field.
+type cursorToolCall struct {
+	Args map[string]interface{} `json:"args,omitempty"`
+	// Name is set for MCP and other function tool calls.
+	Name string `json:"name,omitempty"`
+}
+
+// ParseAndFormatCursorLogs reads NDJSON lines from cursor agent --output-format
+// stream-json and writes formatted output: assistant text goes to stdout,
+// status/tool info goes to stderr. Non-JSON lines are passed through to stdout as-is.
+func ParseAndFormatCursorLogs(r io.Reader, stdout, stderr io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	turnCount := 0
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var event CursorEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			fmt.Fprintf(stdout, "%s\n", line)
+			continue
+		}
+
+		switch event.Type {
+		case "system":
+			if event.Subtype == "init" && event.Model != "" {
+				fmt.Fprintf(stderr, "[init] model=%s\n", event.Model)
+			}
+		case "assistant":
+			turnCount++
+			fmt.Fprintf(stderr, "\n--- Turn %d ---\n", turnCount)
+			if event.Message != nil {
+				for _, block := range event.Message.Content {
+					if block.Type == "text" && block.Text != "" {
+						fmt.Fprintf(stdout, "%s\n", block.Text)
+					}
+				}
+			}
+		case "tool_call":
+			// Each tool call is reported when it starts and again when it
+			// completes; only the start is displayed.
+			if event.Subtype != "started" {
+				continue
+			}
+			name, summary := cursorToolSummary(event.ToolCall)
+			if name == "" {
+				continue
+			}
+			if summary != "" {
+				fmt.Fprintf(stderr, "[tool] %s: %s\n", name, summary)
+			} else {
+				fmt.Fprintf(stderr, "[tool] %s\n", name)
+			}
+		case "result":
+			fmt.Fprintf(stderr, "\n[result] ")
+			if event.IsError {
+				fmt.Fprintf(stderr, "error")
+			} else {
+				fmt.Fprintf(stderr, "completed")
+			}
+			fmt.Fprintf(stderr, " (%d turns", turnCount)
+			if event.TotalCostUSD > 0 {
+				fmt.Fprintf(stderr, ", $%.4f", event.TotalCostUSD)
+			}
+			if event.Usage != nil {
+				fmt.Fprintf(stderr, ", input=%d, output=%d", event.Usage.InputTokens, event.Usage.OutputTokens)
+			}
+			fmt.Fprintf(stderr, ")\n")
+			if event.IsError && event.Result != "" {
+				fmt.Fprintf(stdout, "%s\n", event.Result)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// cursorToolSummary returns a display name and a concise summary for a
+// cursor tool_call payload such as {"readToolCall":{"args":{"path":"x"}}}.
+func cursorToolSummary(toolCall map[string]json.RawMessage) (string, string) {
+	if len(toolCall) == 0 {
+		return "", ""
+	}
+
+	// The payload has a single key naming the tool kind; sort for a
+	// deterministic choice if there are more.
+	kinds := make([]string, 0, len(toolCall))
+	for k := range toolCall {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	kind := kinds[0]
+
+	var call cursorToolCall
+	if err := json.Unmarshal(toolCall[kind], &call); err != nil {
+		return strings.TrimSuffix(kind, "ToolCall"), ""
+	}
+
+	name := strings.TrimSuffix(kind, "ToolCall")
+	if call.Name != "" {
+		name = call.Name
+	}
+
+	var summary string
+	switch kind {
+	case "readToolCall", "writeToolCall", "editToolCall", "deleteToolCall", "lsToolCall":
+		summary = stringField(call.Args, "path")
+	case "shellToolCall":
+		summary = stringField(call.Args, "command")
+	case "grepToolCall":
+		summary = stringField(call.Args, "pattern")
+	case "globToolCall":
+		summary = stringField(call.Args, "globPattern")
+	case "webSearchToolCall":
+		summary = stringField(call.Args, "searchTerm")
+	}
+
+	if summary == "" {
+		return name, ""
+	}
+
+	summary = strings.ReplaceAll(summary, "\n", "\\n")
+
+	if utf8.RuneCountInString(summary) > maxSummaryLen {
+		runes := []rune(summary)
+		summary = string(runes[:maxSummaryLen]) + "..."
+	}
+
+	return name, summary
+}
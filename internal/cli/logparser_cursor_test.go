@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAndFormatCursorLogs_Fixtures(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			fixture: "cursor_stream.jsonl",
+			wantStdout: "I'll start by running the parser tests to see what fails.\n" +
+				"Parse returns an error for empty input instead of an empty result. Fixing it.\n" +
+				"The test passes now. Parse returns an empty result for empty input.\n",
+			wantStderr: "[init] model=Claude 4 Sonnet\n" +
+				"\n--- Turn 1 ---\n" +
+				"[tool] shell: go test ./pkg/parser/...\n" +
+				"[tool] read: pkg/parser/parser.go\n" +
+				"\n--- Turn 2 ---\n" +
+				"[tool] edit: pkg/parser/parser.go\n" +
+				"[tool] grep: Parse\\(\n" +
+				"\n--- Turn 3 ---\n" +
+				"\n[result] completed (3 turns, input=18342, output=1207)\n",
+		},
+		{
+			fixture:    "cursor_stream_error.jsonl",
+			wantStdout: "Deployment failed: the deploy_site tool returned unauthorized.\n",
+			wantStderr: "[init] model=GPT-5\n" +
+				"[tool] deploy_site\n" +
+				"\n[result] error (0 turns)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("Opening fixture: %v", err)
+			}
+			defer f.Close()
+
+			var stdout, stderr bytes.Buffer
+			if err := ParseAndFormatCursorLogs(f, &stdout, &stderr); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout:\n got: %q\nwant: %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr:\n got: %q\nwant: %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestParseAndFormatCursorLogs(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "non-JSON line passes through",
+			input:      "this is plain text",
+			wantStdout: "this is plain text\n",
+		},
+		{
+			name:       "user message ignored",
+			input:      `{"type":"user","message":{"role":"user","content":[{"type":"text","text":"hello"}]}}`,
+			wantStdout: "",
+		},
+		{
+			name:       "completed tool call not displayed",
+			input:      `{"type":"tool_call","subtype":"completed","tool_call":{"readToolCall":{"args":{"path":"a.go"},"result":{"success":{}}}}}`,
+			wantStderr: "",
+		},
+		{
+			name:       "glob tool call",
+			input:      `{"type":"tool_call","subtype":"started","tool_call":{"globToolCall":{"args":{"globPattern":"**/*.go"}}}}`,
+			wantStderr: "[tool] glob: **/*.go\n",
+		},
+		{
+			name:       "unknown tool without summary",
+			input:      `{"type":"tool_call","subtype":"started","tool_call":{"todoToolCall":{"args":{"todos":[]}}}}`,
+			wantStderr: "[tool] todo\n",
+		},
+		{
+			name:       "long shell command is truncated",
+			input:      `{"type":"tool_call","subtype":"started","tool_call":{"shellToolCall":{"args":{"command":"` + strings.Repeat("a", 130) + `"}}}}`,
+			wantStderr: "[tool] shell: " + strings.Repeat("a", maxSummaryLen) + "...\n",
+		},
+		{
+			name:       "result with cost",
+			input:      `{"type":"result","subtype":"success","is_error":false,"total_cost_usd":0.1234}`,
+			wantStderr: "\n[result] completed (0 turns, $0.1234)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := ParseAndFormatCursorLogs(strings.NewReader(tt.input), &stdout, &stderr); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("stdout:\n got: %q\nwant: %q", got, tt.wantStdout)
+			}
+			if got := stderr.String(); got != tt.wantStderr {
+				t.Errorf("stderr:\n got: %q\nwant: %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -11,14 +12,65 @@ import (
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	"github.com/kelos-dev/kelos/internal/transcript"
 )
 
+// initContainerNames lists the init containers a Task pod may have, in the
+// order they run. Each additional repository of a Workspace also gets a
+// "git-clone-<name>" container right after "git-clone".
+var initContainerNames = []string{"git-clone", "remote-setup", "branch-setup", "workspace-files", "workspace-setup", "plugin-setup", "skills-install", "mcp-config"}
+
+// isCloneContainer reports whether name is the init container that clones
+// the workspace repository or one of its additional repositories.
+func isCloneContainer(name string) bool {
+	return name == "git-clone" || strings.HasPrefix(name, "git-clone-")
+}
+
+// logOptions holds the flags shared by the logs commands.
+type logOptions struct {
+	follow bool
+	since  time.Duration
+	tail   int64
+}
+
+func (o *logOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&o.follow, "follow", "f", false, "follow log output")
+	cmd.Flags().DurationVar(&o.since, "since", 0, "only show logs newer than a relative duration like 5s, 2m or 3h")
+	cmd.Flags().Int64Var(&o.tail, "tail", -1, "number of recent log lines to show (-1 shows all)")
+}
+
+// podLogOptions returns the options for reading the logs of container.
+func (o *logOptions) podLogOptions(container string) *corev1.PodLogOptions {
+	opts := &corev1.PodLogOptions{
+		Follow:    o.follow,
+		Container: container,
+	}
+	if o.since > 0 {
+		seconds := int64(o.since.Seconds())
+		if seconds < 1 {
+			seconds = 1
+		}
+		opts.SinceSeconds = &seconds
+	}
+	if o.tail >= 0 {
+		tail := o.tail
+		opts.TailLines = &tail
+	}
+	return opts
+}
+
 func newLogsCommand(cfg *ClientConfig) *cobra.Command {
-	var follow bool
+	var (
+		opts          = &logOptions{}
+		container     string
+		allContainers bool
+		attempt       int
+	)
 
 	cmd := &cobra.Command{
 		Use:   "logs <name>",
@@ -33,6 +85,13 @@ func newLogsCommand(cfg *ClientConfig) *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if container != "" && allContainers {
+				return fmt.Errorf("--container and --all-containers cannot be used together")
+			}
+			if attempt < 0 {
+				return fmt.Errorf("--attempt must be positive")
+			}
+
 			cl, ns, err := cfg.NewClient()
 			if err != nil {
 				return err
@@ -46,19 +105,41 @@ func newLogsCommand(cfg *ClientConfig) *cobra.Command {
 			ctx := context.Background()
 			task := &kelosv1alpha1.Task{}
 			if err := cl.Get(ctx, client.ObjectKey{Name: args[0], Namespace: ns}, task); err != nil {
+				if apierrors.IsNotFound(err) && container == "" && !allContainers {
+					// A deleted Task may have left its transcript behind.
+					if found, archiveErr := printTranscriptRecord(ctx, cl, ns, args[0], "", os.Stdout, os.Stderr); found || archiveErr != nil {
+						return archiveErr
+					}
+				}
 				return fmt.Errorf("getting task: %w", err)
 			}
 
-			podName, err := resolveTaskPodName(ctx, cl, ns, task)
-			if err != nil {
-				return err
+			var podName string
+			if attempt > 0 {
+				pods, err := listTaskPods(ctx, cl, ns, task.Name)
+				if err != nil {
+					return err
+				}
+				if attempt > len(pods) {
+					return fmt.Errorf("task %q has %d pod attempt(s) still present, cannot show attempt %d", args[0], len(pods), attempt)
+				}
+				podName = pods[attempt-1].Name
+			} else {
+				podName, err = resolveTaskPodName(ctx, cl, ns, task)
+				if err != nil {
+					return err
+				}
 			}
 
 			if podName == "" {
 				if isTerminalTaskPhase(task.Status.Phase) {
+					if task.Status.Transcript != "" && (container == "" || container == task.Spec.Type) && !allContainers {
+						fmt.Fprintf(os.Stderr, "Task %q has no live pod, showing archived transcript %s\n", args[0], task.Status.Transcript)
+						return printArchivedTranscript(ctx, cl, task, os.Stdout, os.Stderr)
+					}
 					return fmt.Errorf("task %q has no live pod (task phase: %s)", args[0], task.Status.Phase)
 				}
-				if !follow {
+				if !opts.follow {
 					return fmt.Errorf("task %q has no pod yet", args[0])
 				}
 
@@ -76,25 +157,122 @@ func newLogsCommand(cfg *ClientConfig) *cobra.Command {
 				}
 			}
 
-			containerName := task.Spec.Type
+			agentContainer := task.Spec.Type
 
-			if follow && task.Spec.WorkspaceRef != nil {
-				fmt.Fprintf(os.Stderr, "Streaming init container (git-clone) logs...\n")
-				if err := streamLogs(ctx, cs, ns, podName, "git-clone", follow); err != nil {
-					return err
+			if container != "" && container != agentContainer {
+				pod := &corev1.Pod{}
+				if err := cl.Get(ctx, client.ObjectKey{Name: podName, Namespace: ns}, pod); err != nil {
+					return fmt.Errorf("getting pod: %w", err)
+				}
+				if !podHasContainer(pod, container) {
+					return fmt.Errorf("pod %s has no container %q (available: %s)", podName, container, strings.Join(podContainerNames(pod), ", "))
 				}
+				return streamLogs(ctx, cs, ns, podName, opts.podLogOptions(container), os.Stdout)
 			}
 
-			if follow {
-				fmt.Fprintf(os.Stderr, "Streaming container (%s) logs...\n", containerName)
+			if allContainers {
+				pod := &corev1.Pod{}
+				if err := cl.Get(ctx, client.ObjectKey{Name: podName, Namespace: ns}, pod); err != nil {
+					return fmt.Errorf("getting pod: %w", err)
+				}
+				for _, c := range pod.Spec.InitContainers {
+					fmt.Fprintf(os.Stderr, "==> init container (%s) <==\n", c.Name)
+					if err := streamLogs(ctx, cs, ns, podName, opts.podLogOptions(c.Name), os.Stdout); err != nil {
+						return err
+					}
+				}
+				fmt.Fprintf(os.Stderr, "==> container (%s) <==\n", agentContainer)
+			} else if opts.follow && task.Spec.WorkspaceRef != nil {
+				pod := &corev1.Pod{}
+				if err := cl.Get(ctx, client.ObjectKey{Name: podName, Namespace: ns}, pod); err != nil {
+					return fmt.Errorf("getting pod: %w", err)
+				}
+				for _, c := range pod.Spec.InitContainers {
+					if !isCloneContainer(c.Name) {
+						continue
+					}
+					fmt.Fprintf(os.Stderr, "Streaming init container (%s) logs...\n", c.Name)
+					if err := streamLogs(ctx, cs, ns, podName, opts.podLogOptions(c.Name), os.Stdout); err != nil {
+						return err
+					}
+				}
+			}
+
+			if opts.follow && !allContainers {
+				fmt.Fprintf(os.Stderr, "Streaming container (%s) logs...\n", agentContainer)
 			}
-			return streamAgentLogs(ctx, cs, ns, podName, containerName, task.Spec.Type, follow)
+			return streamAgentLogs(ctx, cs, ns, podName, opts.podLogOptions(agentContainer), resolveLogFormat(ctx, cl, task.Spec.Type))
 		},
 	}
 
-	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "follow log output")
+	opts.addFlags(cmd)
+	cmd.Flags().StringVarP(&container, "container", "c", "", "container to show logs for, e.g. git-clone or branch-setup (defaults to the agent container)")
+	cmd.Flags().BoolVar(&allContainers, "all-containers", false, "show the logs of all init containers before the agent's logs")
+	cmd.Flags().IntVar(&attempt, "attempt", 0, "show logs of the Nth pod of the task's Job, starting at 1 (defaults to the latest)")
+
+	cmd.AddCommand(newLogsTaskSpawnerCommand(cfg))
 
 	cmd.ValidArgsFunction = completeTaskNames(cfg)
+	_ = cmd.RegisterFlagCompletionFunc("container", completeTaskContainerNames(cfg))
+
+	return cmd
+}
+
+func newLogsTaskSpawnerCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		opts      = &logOptions{}
+		container string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "taskspawner <name>",
+		Aliases: []string{"taskspawners"},
+		Short:   "View logs from a task spawner's Deployment or CronJob pod",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("task spawner name is required\nUsage: %s", cmd.Use)
+			}
+			if len(args) > 1 {
+				return fmt.Errorf("too many arguments: expected 1 task spawner name, got %d\nUsage: %s", len(args), cmd.Use)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, ns, err := cfg.NewClient()
+			if err != nil {
+				return err
+			}
+
+			cs, _, err := cfg.NewClientset()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			ts := &kelosv1alpha1.TaskSpawner{}
+			if err := cl.Get(ctx, client.ObjectKey{Name: args[0], Namespace: ns}, ts); err != nil {
+				return fmt.Errorf("getting task spawner: %w", err)
+			}
+
+			podName, err := resolveTaskSpawnerPodName(ctx, cl, ns, ts.Name)
+			if err != nil {
+				return err
+			}
+			if podName == "" {
+				if ts.Status.CronJobName != "" {
+					return fmt.Errorf("task spawner %q has no pod: CronJob %s has not run yet or its Jobs were cleaned up", args[0], ts.Status.CronJobName)
+				}
+				return fmt.Errorf("task spawner %q has no pod", args[0])
+			}
+
+			return streamLogs(ctx, cs, ns, podName, opts.podLogOptions(container), os.Stdout)
+		},
+	}
+
+	opts.addFlags(cmd)
+	cmd.Flags().StringVarP(&container, "container", "c", "spawner", "container to show logs for")
+
+	cmd.ValidArgsFunction = completeTaskSpawnerNames(cfg)
 
 	return cmd
 }
@@ -129,46 +307,90 @@ func waitForPod(ctx context.Context, cl client.Client, name, namespace string) (
 }
 
 func resolveTaskPodName(ctx context.Context, cl client.Client, namespace string, task *kelosv1alpha1.Task) (string, error) {
-	var pods corev1.PodList
-	if err := cl.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabels{
-		"kelos.dev/task": task.Name,
-	}); err != nil {
+	pods, err := listTaskPods(ctx, cl, namespace, task.Name)
+	if err != nil {
 		if task.Status.PodName != "" {
 			return task.Status.PodName, nil
 		}
-		return "", fmt.Errorf("listing task pods: %w", err)
+		return "", err
+	}
+
+	if len(pods) == 0 {
+		return "", nil
 	}
+	return pods[len(pods)-1].Name, nil
+}
 
+// listTaskPods returns the pods of a Task's Job, oldest first. A Job that
+// retried has one pod per attempt.
+func listTaskPods(ctx context.Context, cl client.Client, namespace, taskName string) ([]corev1.Pod, error) {
+	var pods corev1.PodList
+	if err := cl.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabels{
+		"kelos.dev/task": taskName,
+	}); err != nil {
+		return nil, fmt.Errorf("listing task pods: %w", err)
+	}
+	sortPodsByCreation(pods.Items)
+	return pods.Items, nil
+}
+
+// resolveTaskSpawnerPodName returns the newest pod of a TaskSpawner's
+// Deployment or CronJob, or "" if there is none.
+func resolveTaskSpawnerPodName(ctx context.Context, cl client.Client, namespace, spawnerName string) (string, error) {
+	var pods corev1.PodList
+	if err := cl.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabels{
+		"kelos.dev/component":   "spawner",
+		"kelos.dev/taskspawner": spawnerName,
+	}); err != nil {
+		return "", fmt.Errorf("listing task spawner pods: %w", err)
+	}
 	if len(pods.Items) == 0 {
 		return "", nil
 	}
+	sortPodsByCreation(pods.Items)
+	return pods.Items[len(pods.Items)-1].Name, nil
+}
 
-	sort.Slice(pods.Items, func(i, j int) bool {
-		left := pods.Items[i]
-		right := pods.Items[j]
+func sortPodsByCreation(pods []corev1.Pod) {
+	sort.Slice(pods, func(i, j int) bool {
+		left := pods[i]
+		right := pods[j]
 		if left.CreationTimestamp.Time.Equal(right.CreationTimestamp.Time) {
 			return left.Name < right.Name
 		}
 		return left.CreationTimestamp.Time.Before(right.CreationTimestamp.Time)
 	})
+}
 
-	return pods.Items[len(pods.Items)-1].Name, nil
+func podContainerNames(pod *corev1.Pod) []string {
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func podHasContainer(pod *corev1.Pod, name string) bool {
+	for _, n := range podContainerNames(pod) {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
 func isTerminalTaskPhase(phase kelosv1alpha1.TaskPhase) bool {
 	return phase == kelosv1alpha1.TaskPhaseSucceeded || phase == kelosv1alpha1.TaskPhaseFailed
 }
 
-func streamLogs(ctx context.Context, cs *kubernetes.Clientset, namespace, podName, container string, follow bool) error {
-	opts := &corev1.PodLogOptions{
-		Follow:    follow,
-		Container: container,
-	}
-
+func streamLogs(ctx context.Context, cs kubernetes.Interface, namespace, podName string, opts *corev1.PodLogOptions, w io.Writer) error {
 	for {
 		stream, err := cs.CoreV1().Pods(namespace).GetLogs(podName, opts).Stream(ctx)
 		if err != nil {
-			if follow && isContainerNotReady(err) {
+			if opts.Follow && isContainerNotReady(err) {
 				time.Sleep(2 * time.Second)
 				continue
 			}
@@ -176,23 +398,18 @@ func streamLogs(ctx context.Context, cs *kubernetes.Clientset, namespace, podNam
 		}
 		defer stream.Close()
 
-		if _, err := io.Copy(os.Stdout, stream); err != nil {
+		if _, err := io.Copy(w, stream); err != nil {
 			return fmt.Errorf("reading logs: %w", err)
 		}
 		return nil
 	}
 }
 
-func streamAgentLogs(ctx context.Context, cs *kubernetes.Clientset, namespace, podName, container, agentType string, follow bool) error {
-	opts := &corev1.PodLogOptions{
-		Follow:    follow,
-		Container: container,
-	}
-
+func streamAgentLogs(ctx context.Context, cs kubernetes.Interface, namespace, podName string, opts *corev1.PodLogOptions, format string) error {
 	for {
 		stream, err := cs.CoreV1().Pods(namespace).GetLogs(podName, opts).Stream(ctx)
 		if err != nil {
-			if follow && isContainerNotReady(err) {
+			if opts.Follow && isContainerNotReady(err) {
 				time.Sleep(2 * time.Second)
 				continue
 			}
@@ -200,17 +417,158 @@ func streamAgentLogs(ctx context.Context, cs *kubernetes.Clientset, namespace, p
 		}
 		defer stream.Close()
 
-		switch agentType {
-		case "codex":
-			return ParseAndFormatCodexLogs(stream, os.Stdout, os.Stderr)
-		case "gemini":
-			return ParseAndFormatGeminiLogs(stream, os.Stdout, os.Stderr)
-		case "opencode":
-			return ParseAndFormatOpenCodeLogs(stream, os.Stdout, os.Stderr)
+		return formatAgentLogs(stream, format, os.Stdout, os.Stderr)
+	}
+}
+
+// formatAgentLogs parses the agent's stream output with the parser for the
+// given log format, the name of a built-in agent type. Output in an unknown
+// format is copied to stdout as is. The transcript block kelos-capture
+// prints for ConfigMap archives is left out.
+func formatAgentLogs(r io.Reader, format string, stdout, stderr io.Writer) error {
+	r = transcript.StripBlock(r)
+	switch format {
+	case "claude-code":
+		return ParseAndFormatLogs(r, stdout, stderr)
+	case "codex":
+		return ParseAndFormatCodexLogs(r, stdout, stderr)
+	case "gemini":
+		return ParseAndFormatGeminiLogs(r, stdout, stderr)
+	case "opencode":
+		return ParseAndFormatOpenCodeLogs(r, stdout, stderr)
+	case "cursor":
+		return ParseAndFormatCursorLogs(r, stdout, stderr)
+	default:
+		_, err := io.Copy(stdout, r)
+		return err
+	}
+}
+
+// agentLogFormat returns the log format of an agent type. Built-in types
+// use their own format; custom types use the usageFormat of their
+// AgentType, and are printed unparsed without one.
+func agentLogFormat(agentType string, at *kelosv1alpha1.AgentType) string {
+	if isBuiltinAgentType(agentType) {
+		return agentType
+	}
+	if at != nil && at.Spec.UsageFormat != "none" {
+		return at.Spec.UsageFormat
+	}
+	return ""
+}
+
+// resolveLogFormat looks up the AgentType of a custom agent type and
+// returns its log format.
+func resolveLogFormat(ctx context.Context, cl client.Client, agentType string) string {
+	if isBuiltinAgentType(agentType) {
+		return agentType
+	}
+	return agentLogFormat(agentType, lookupAgentType(ctx, cl, agentType))
+}
+
+// printArchivedTranscript formats the transcript recorded in the status of
+// a finished Task whose pod is gone.
+func printArchivedTranscript(ctx context.Context, cl client.Client, task *kelosv1alpha1.Task, stdout, stderr io.Writer) error {
+	scheme, root, p, err := transcript.ParseURI(task.Status.Transcript)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch scheme {
+	case transcript.SchemeConfigMap:
+		found, err := printTranscriptRecord(ctx, cl, task.Namespace, task.Name, task.Spec.Type, stdout, stderr)
+		if err == nil && !found {
+			err = fmt.Errorf("transcript ConfigMap %s not found", root)
+		}
+		return err
+	case transcript.SchemeS3:
+		if task.Spec.TranscriptArchive == nil || task.Spec.TranscriptArchive.S3 == nil {
+			return fmt.Errorf("task %q has no S3 transcript archive configured to read %s", task.Name, task.Status.Transcript)
+		}
+		store := task.Spec.TranscriptArchive.S3
+		data, err = downloadS3Transcript(ctx, cl, task.Namespace, store.Endpoint, store.Region, store.SecretRef.Name, root, p)
+		if err != nil {
+			return err
+		}
+	default:
+		return pvcTranscriptError(task.Status.Transcript, root, p)
+	}
+	return formatAgentLogs(bytes.NewReader(data), resolveLogFormat(ctx, cl, task.Spec.Type), stdout, stderr)
+}
+
+// printTranscriptRecord formats the transcript of the named Task using its
+// <task>-transcript ConfigMap, which holds ConfigMap transcripts and
+// records where other stores put theirs, so that it works after the Task
+// is deleted. It reports false when no such ConfigMap exists. When
+// agentType is empty, the type recorded on the ConfigMap is used.
+func printTranscriptRecord(ctx context.Context, cl client.Client, namespace, taskName, agentType string, stdout, stderr io.Writer) (bool, error) {
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: transcript.ConfigMapName(taskName), Namespace: namespace}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("getting transcript ConfigMap: %w", err)
+	}
+	if cm.Labels["kelos.dev/task"] != taskName {
+		return false, nil
+	}
+	if agentType == "" {
+		agentType = cm.Annotations[transcript.AgentTypeAnnotation]
+	}
+
+	var data []byte
+	if compressed, ok := cm.BinaryData[transcript.ConfigMapKey]; ok {
+		var err error
+		data, err = transcript.Decompress(compressed)
+		if err != nil {
+			return true, err
+		}
+		if cm.Annotations[transcript.TruncatedAnnotation] == "true" {
+			fmt.Fprintln(stderr, "Transcript was truncated when archived; showing its end")
+		}
+	} else {
+		uri := cm.Annotations[transcript.URIAnnotation]
+		scheme, root, p, err := transcript.ParseURI(uri)
+		if err != nil {
+			return true, fmt.Errorf("transcript ConfigMap %s: %w", cm.Name, err)
+		}
+		switch scheme {
+		case transcript.SchemeS3:
+			data, err = downloadS3Transcript(ctx, cl, namespace,
+				cm.Annotations[transcript.S3EndpointAnnotation],
+				cm.Annotations[transcript.S3RegionAnnotation],
+				cm.Annotations[transcript.S3SecretAnnotation], root, p)
+			if err != nil {
+				return true, err
+			}
+		case transcript.SchemePVC:
+			return true, pvcTranscriptError(uri, root, p)
 		default:
-			return ParseAndFormatLogs(stream, os.Stdout, os.Stderr)
+			return true, fmt.Errorf("transcript ConfigMap %s holds no transcript", cm.Name)
 		}
 	}
+	return true, formatAgentLogs(bytes.NewReader(data), resolveLogFormat(ctx, cl, agentType), stdout, stderr)
+}
+
+// downloadS3Transcript reads a transcript from S3 with the credentials in
+// the named Secret.
+func downloadS3Transcript(ctx context.Context, cl client.Client, namespace, endpoint, region, secretName, bucket, key string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: secretName, Namespace: namespace}, secret); err != nil {
+		return nil, fmt.Errorf("getting S3 credentials: %w", err)
+	}
+	s3 := &transcript.S3Client{
+		Endpoint:        endpoint,
+		Region:          region,
+		AccessKeyID:     string(secret.Data["AWS_ACCESS_KEY_ID"]),
+		SecretAccessKey: string(secret.Data["AWS_SECRET_ACCESS_KEY"]),
+	}
+	return s3.Get(ctx, bucket, key)
+}
+
+func pvcTranscriptError(uri, claim, p string) error {
+	return fmt.Errorf("transcript %s is stored on PersistentVolumeClaim %q at %s; mount the claim to read it", uri, claim, p)
 }
 
 func isContainerNotReady(err error) bool {
@@ -1,17 +1,23 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	"github.com/kelos-dev/kelos/internal/transcript"
 )
 
 func TestResolveTaskPodName(t *testing.T) {
@@ -134,3 +140,358 @@ func TestResolveTaskPodNameAfterWait(t *testing.T) {
 		t.Fatalf("post-wait pod validation error = %v", err)
 	}
 }
+
+func TestListTaskPods(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, age time.Duration, task string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+				Labels:            map[string]string{"kelos.dev/task": task},
+			},
+		}
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("attempt-2", 2*time.Minute, "task-1"),
+		pod("attempt-3", time.Minute, "task-1"),
+		pod("attempt-1", 3*time.Minute, "task-1"),
+		pod("other", time.Minute, "task-2"),
+	).Build()
+
+	pods, err := listTaskPods(context.Background(), cl, "default", "task-1")
+	if err != nil {
+		t.Fatalf("listTaskPods() error = %v", err)
+	}
+	var names []string
+	for _, p := range pods {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, ","); got != "attempt-1,attempt-2,attempt-3" {
+		t.Fatalf("listTaskPods() = %s, want attempt-1,attempt-2,attempt-3", got)
+	}
+}
+
+func TestResolveTaskSpawnerPodName(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, age time.Duration, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+				Labels:            labels,
+			},
+		}
+	}
+	spawnerLabels := map[string]string{"kelos.dev/component": "spawner", "kelos.dev/taskspawner": "fixer"}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("fixer-old", time.Hour, spawnerLabels),
+		pod("fixer-new", time.Minute, spawnerLabels),
+		// Task pods carry the spawner label too but are not spawner pods.
+		pod("task-pod", time.Second, map[string]string{"kelos.dev/component": "task", "kelos.dev/taskspawner": "fixer"}),
+	).Build()
+
+	got, err := resolveTaskSpawnerPodName(context.Background(), cl, "default", "fixer")
+	if err != nil {
+		t.Fatalf("resolveTaskSpawnerPodName() error = %v", err)
+	}
+	if got != "fixer-new" {
+		t.Fatalf("resolveTaskSpawnerPodName() = %q, want %q", got, "fixer-new")
+	}
+
+	got, err = resolveTaskSpawnerPodName(context.Background(), cl, "default", "other")
+	if err != nil {
+		t.Fatalf("resolveTaskSpawnerPodName() error = %v", err)
+	}
+	if got != "" {
+		t.Fatalf("resolveTaskSpawnerPodName() = %q, want empty", got)
+	}
+}
+
+func TestPodLogOptions(t *testing.T) {
+	opts := (&logOptions{tail: -1}).podLogOptions("git-clone")
+	if opts.Container != "git-clone" || opts.Follow || opts.SinceSeconds != nil || opts.TailLines != nil {
+		t.Fatalf("unexpected default options: %+v", opts)
+	}
+
+	opts = (&logOptions{follow: true, since: 90 * time.Second, tail: 20}).podLogOptions("codex")
+	if !opts.Follow || opts.SinceSeconds == nil || *opts.SinceSeconds != 90 || opts.TailLines == nil || *opts.TailLines != 20 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
+
+func TestPodHasContainer(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "git-clone"}, {Name: "branch-setup"}},
+		Containers:     []corev1.Container{{Name: "claude-code"}},
+	}}
+	for _, name := range []string{"git-clone", "branch-setup", "claude-code"} {
+		if !podHasContainer(pod, name) {
+			t.Errorf("podHasContainer(%q) = false, want true", name)
+		}
+	}
+	if podHasContainer(pod, "plugin-setup") {
+		t.Error("podHasContainer(plugin-setup) = true, want false")
+	}
+}
+
+func TestIsCloneContainer(t *testing.T) {
+	for name, want := range map[string]bool{
+		"git-clone":       true,
+		"git-clone-docs":  true,
+		"git-clone-infra": true,
+		"git-cloner":      false,
+		"workspace-setup": false,
+		"mcp-config":      false,
+	} {
+		if got := isCloneContainer(name); got != want {
+			t.Errorf("isCloneContainer(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	cs := k8sfake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "task-pod", Namespace: "default"},
+	})
+
+	var out bytes.Buffer
+	if err := streamLogs(context.Background(), cs, "default", "task-pod", (&logOptions{tail: 10}).podLogOptions("git-clone"), &out); err != nil {
+		t.Fatalf("streamLogs() error = %v", err)
+	}
+	if out.String() != "fake logs" {
+		t.Fatalf("streamLogs() wrote %q, want %q", out.String(), "fake logs")
+	}
+}
+
+func TestLogsCommand_FlagValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "container and all-containers",
+			args:    []string{"logs", "my-task", "-c", "git-clone", "--all-containers"},
+			wantErr: "--container and --all-containers cannot be used together",
+		},
+		{
+			name:    "negative attempt",
+			args:    []string{"logs", "my-task", "--attempt", "-1"},
+			wantErr: "--attempt must be positive",
+		},
+		{
+			name:    "taskspawner name required",
+			args:    []string{"logs", "taskspawner"},
+			wantErr: "task spawner name is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAgentLogFormat(t *testing.T) {
+	withFormat := func(format string) *kelosv1alpha1.AgentType {
+		return &kelosv1alpha1.AgentType{Spec: kelosv1alpha1.AgentTypeSpec{UsageFormat: format}}
+	}
+	tests := []struct {
+		agentType string
+		at        *kelosv1alpha1.AgentType
+		want      string
+	}{
+		{"claude-code", nil, "claude-code"},
+		{"codex", withFormat("claude-code"), "codex"},
+		{"aider", nil, ""},
+		{"aider", withFormat(""), ""},
+		{"aider", withFormat("none"), ""},
+		{"aider", withFormat("codex"), "codex"},
+	}
+	for _, tt := range tests {
+		if got := agentLogFormat(tt.agentType, tt.at); got != tt.want {
+			t.Errorf("agentLogFormat(%q, %v) = %q, want %q", tt.agentType, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAgentLogsRaw(t *testing.T) {
+	in := "plain output\n{\"type\":\"result\"}\n"
+	var stdout, stderr bytes.Buffer
+	if err := formatAgentLogs(strings.NewReader(in), "", &stdout, &stderr); err != nil {
+		t.Fatalf("formatAgentLogs() error = %v", err)
+	}
+	if stdout.String() != in || stderr.Len() != 0 {
+		t.Errorf("expected unparsed output, got stdout %q stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestFormatAgentLogsSkipsTranscriptBlock(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("plain output\n")
+	if err := transcript.WriteBlock(&in, []byte("compressed transcript"), false); err != nil {
+		t.Fatal(err)
+	}
+	in.WriteString("---KELOS_OUTPUTS_START---\n")
+
+	var stdout, stderr bytes.Buffer
+	if err := formatAgentLogs(&in, "", &stdout, &stderr); err != nil {
+		t.Fatalf("formatAgentLogs() error = %v", err)
+	}
+	if want := "plain output\n---KELOS_OUTPUTS_START---\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestPrintArchivedTranscript(t *testing.T) {
+	ctx := context.Background()
+	body := []byte(`{"type":"result","subtype":"success","result":"done","num_turns":1}` + "\n")
+	compressed, _, err := transcript.Compress(body, transcript.MaxConfigMapBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("configmap", func(t *testing.T) {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "task-1-transcript",
+				Namespace:   "default",
+				Labels:      map[string]string{"kelos.dev/task": "task-1"},
+				Annotations: map[string]string{transcript.AgentTypeAnnotation: "claude-code"},
+			},
+			BinaryData: map[string][]byte{transcript.ConfigMapKey: compressed},
+		}
+		task := &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+			Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code"},
+			Status:     kelosv1alpha1.TaskStatus{Transcript: "configmap://task-1-transcript"},
+		}
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+
+		var stdout, stderr bytes.Buffer
+		if err := printArchivedTranscript(ctx, cl, task, &stdout, &stderr); err != nil {
+			t.Fatalf("printArchivedTranscript() error = %v", err)
+		}
+		if !strings.Contains(stderr.String(), "[result] completed") {
+			t.Errorf("expected formatted result, got stderr %q", stderr.String())
+		}
+
+		// The transcript remains readable after the Task is deleted.
+		stderr.Reset()
+		found, err := printTranscriptRecord(ctx, cl, "default", "task-1", "", &stdout, &stderr)
+		if err != nil || !found {
+			t.Fatalf("printTranscriptRecord() = %v, %v", found, err)
+		}
+		if !strings.Contains(stderr.String(), "[result] completed") {
+			t.Errorf("expected formatted result, got stderr %q", stderr.String())
+		}
+
+		found, err = printTranscriptRecord(ctx, cl, "default", "task-2", "", &stdout, &stderr)
+		if err != nil || found {
+			t.Errorf("printTranscriptRecord() for a missing ConfigMap = %v, %v", found, err)
+		}
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/agents/kelos/default/task-1/task-1-abc.jsonl" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(body)
+		}))
+		defer server.Close()
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-creds", Namespace: "default"},
+			Data: map[string][]byte{
+				"AWS_ACCESS_KEY_ID":     []byte("minio"),
+				"AWS_SECRET_ACCESS_KEY": []byte("minio123"),
+			},
+		}
+		task := &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+			Spec: kelosv1alpha1.TaskSpec{
+				Type: "claude-code",
+				TranscriptArchive: &kelosv1alpha1.TranscriptArchive{
+					S3: &kelosv1alpha1.S3TranscriptStore{
+						Endpoint:  server.URL,
+						Bucket:    "agents",
+						SecretRef: kelosv1alpha1.SecretReference{Name: "s3-creds"},
+					},
+				},
+			},
+			Status: kelosv1alpha1.TaskStatus{Transcript: "s3://agents/kelos/default/task-1/task-1-abc.jsonl"},
+		}
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+		var stdout, stderr bytes.Buffer
+		if err := printArchivedTranscript(ctx, cl, task, &stdout, &stderr); err != nil {
+			t.Fatalf("printArchivedTranscript() error = %v", err)
+		}
+		if !strings.Contains(stderr.String(), "[result] completed") {
+			t.Errorf("expected formatted result, got stderr %q", stderr.String())
+		}
+
+		// Once the Task is deleted, its transcript ConfigMap records where
+		// the transcript is and how to read it.
+		record := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "task-1-transcript",
+				Namespace: "default",
+				Labels:    map[string]string{"kelos.dev/task": "task-1"},
+				Annotations: map[string]string{
+					transcript.AgentTypeAnnotation:  "claude-code",
+					transcript.URIAnnotation:        task.Status.Transcript,
+					transcript.S3EndpointAnnotation: server.URL,
+					transcript.S3SecretAnnotation:   "s3-creds",
+				},
+			},
+		}
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, record).Build()
+		stderr.Reset()
+		found, err := printTranscriptRecord(ctx, cl, "default", "task-1", "", &stdout, &stderr)
+		if err != nil || !found {
+			t.Fatalf("printTranscriptRecord() = %v, %v", found, err)
+		}
+		if !strings.Contains(stderr.String(), "[result] completed") {
+			t.Errorf("expected formatted result, got stderr %q", stderr.String())
+		}
+	})
+
+	t.Run("pvc", func(t *testing.T) {
+		task := &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+			Status:     kelosv1alpha1.TaskStatus{Transcript: "pvc://transcripts/default/task-1/task-1-abc.jsonl"},
+		}
+		cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+		err := printArchivedTranscript(ctx, cl, task, &bytes.Buffer{}, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "mount the claim") {
+			t.Errorf("expected an error explaining how to read the claim, got %v", err)
+		}
+
+		record := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "task-1-transcript",
+				Namespace:   "default",
+				Labels:      map[string]string{"kelos.dev/task": "task-1"},
+				Annotations: map[string]string{transcript.URIAnnotation: task.Status.Transcript},
+			},
+		}
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(record).Build()
+		found, err := printTranscriptRecord(ctx, cl, "default", "task-1", "", &bytes.Buffer{}, &bytes.Buffer{})
+		if !found || err == nil || !strings.Contains(err.Error(), "mount the claim") {
+			t.Errorf("expected the claim location of a deleted Task, got %v, %v", found, err)
+		}
+	})
+}
@@ -43,8 +43,8 @@ func printTaskTable(w io.Writer, tasks []kelosv1alpha1.Task, allNamespaces bool)
 			workspace = t.Spec.WorkspaceRef.Name
 		}
 		agentConfig := "-"
-		if t.Spec.AgentConfigRef != nil {
-			agentConfig = t.Spec.AgentConfigRef.Name
+		if names := agentConfigNames(&t.Spec); names != "" {
+			agentConfig = names
 		}
 		dur := taskDuration(&t.Status)
 		if allNamespaces {
@@ -63,7 +63,23 @@ func printTaskDetail(w io.Writer, t *kelosv1alpha1.Task) {
 	printField(w, "Namespace", t.Namespace)
 	printField(w, "Type", t.Spec.Type)
 	printField(w, "Phase", string(t.Status.Phase))
-	printField(w, "Prompt", t.Spec.Prompt)
+	if rerunOf := t.Annotations[kelosv1alpha1.AnnotationRerunOf]; rerunOf != "" {
+		printField(w, "Rerun Of", rerunOf)
+	}
+	if from := t.Spec.PromptFrom; from != nil {
+		switch {
+		case from.ConfigMapKeyRef != nil:
+			printField(w, "Prompt From", fmt.Sprintf("configmap/%s[%s]", from.ConfigMapKeyRef.Name, from.ConfigMapKeyRef.Key))
+		case from.RepoFile != nil:
+			value := from.RepoFile.Path
+			if from.RepoFile.Ref != "" {
+				value += "@" + from.RepoFile.Ref
+			}
+			printField(w, "Prompt From", value)
+		}
+	} else {
+		printField(w, "Prompt", t.Spec.Prompt)
+	}
 	if t.Spec.Credentials.SecretRef != nil {
 		printField(w, "Secret", t.Spec.Credentials.SecretRef.Name)
 	}
@@ -83,8 +99,14 @@ func printTaskDetail(w io.Writer, t *kelosv1alpha1.Task) {
 	if t.Spec.WorkspaceRef != nil {
 		printField(w, "Workspace", t.Spec.WorkspaceRef.Name)
 	}
-	if t.Spec.AgentConfigRef != nil {
-		printField(w, "Agent Config", t.Spec.AgentConfigRef.Name)
+	if names := agentConfigNames(&t.Spec); names != "" {
+		printField(w, "Agent Config", names)
+	}
+	if t.Spec.Session != nil {
+		printField(w, "Session", t.Spec.Session.PersistentVolumeClaimName)
+	}
+	if t.Spec.ResumeFrom != "" {
+		printField(w, "Resume From", t.Spec.ResumeFrom)
 	}
 	if t.Spec.TTLSecondsAfterFinished != nil {
 		printField(w, "TTL", fmt.Sprintf("%ds", *t.Spec.TTLSecondsAfterFinished))
@@ -111,6 +133,16 @@ func printTaskDetail(w io.Writer, t *kelosv1alpha1.Task) {
 	if t.Status.Message != "" {
 		printField(w, "Message", t.Status.Message)
 	}
+	if t.Status.Transcript != "" {
+		printField(w, "Transcript", t.Status.Transcript)
+	}
+	for _, c := range t.Status.Conditions {
+		lines := strings.Split(c.Message, "\n")
+		printField(w, c.Type, fmt.Sprintf("%s (%s)", lines[0], c.Reason))
+		for _, line := range lines[1:] {
+			fmt.Fprintf(w, "%-20s%s\n", "", line)
+		}
+	}
 	if len(t.Status.Outputs) > 0 {
 		printField(w, "Outputs", t.Status.Outputs[0])
 		for _, o := range t.Status.Outputs[1:] {
@@ -143,24 +175,7 @@ func printTaskSpawnerTable(w io.Writer, spawners []kelosv1alpha1.TaskSpawner, al
 	}
 	for _, s := range spawners {
 		age := duration.HumanDuration(time.Since(s.CreationTimestamp.Time))
-		source := ""
-		if s.Spec.When.GitHubIssues != nil {
-			if s.Spec.TaskTemplate.WorkspaceRef != nil {
-				source = s.Spec.TaskTemplate.WorkspaceRef.Name
-			} else {
-				source = "GitHub Issues"
-			}
-		} else if s.Spec.When.GitHubPullRequests != nil {
-			if s.Spec.TaskTemplate.WorkspaceRef != nil {
-				source = s.Spec.TaskTemplate.WorkspaceRef.Name
-			} else {
-				source = "GitHub Pull Requests"
-			}
-		} else if s.Spec.When.Jira != nil {
-			source = s.Spec.When.Jira.Project
-		} else if s.Spec.When.Cron != nil {
-			source = "cron: " + s.Spec.When.Cron.Schedule
-		}
+		source := taskSpawnerSource(&s)
 		if allNamespaces {
 			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
 				s.Namespace, s.Name, source, s.Status.Phase,
@@ -174,6 +189,28 @@ func printTaskSpawnerTable(w io.Writer, spawners []kelosv1alpha1.TaskSpawner, al
 	tw.Flush()
 }
 
+// taskSpawnerSource returns a short description of the source a TaskSpawner
+// discovers work items from.
+func taskSpawnerSource(s *kelosv1alpha1.TaskSpawner) string {
+	switch {
+	case s.Spec.When.GitHubIssues != nil:
+		if s.Spec.TaskTemplate.WorkspaceRef != nil {
+			return s.Spec.TaskTemplate.WorkspaceRef.Name
+		}
+		return "GitHub Issues"
+	case s.Spec.When.GitHubPullRequests != nil:
+		if s.Spec.TaskTemplate.WorkspaceRef != nil {
+			return s.Spec.TaskTemplate.WorkspaceRef.Name
+		}
+		return "GitHub Pull Requests"
+	case s.Spec.When.Jira != nil:
+		return s.Spec.When.Jira.Project
+	case s.Spec.When.Cron != nil:
+		return "cron: " + s.Spec.When.Cron.Schedule
+	}
+	return ""
+}
+
 func printTaskSpawnerDetail(w io.Writer, ts *kelosv1alpha1.TaskSpawner) {
 	printField(w, "Name", ts.Name)
 	printField(w, "Namespace", ts.Namespace)
@@ -221,6 +258,9 @@ func printTaskSpawnerDetail(w io.Writer, ts *kelosv1alpha1.TaskSpawner) {
 		printField(w, "Model", ts.Spec.TaskTemplate.Model)
 	}
 	printField(w, "Poll Interval", ts.Spec.PollInterval)
+	if ts.Spec.DryRun != nil && *ts.Spec.DryRun {
+		printField(w, "Dry Run", "true")
+	}
 	if ts.Status.DeploymentName != "" {
 		printField(w, "Deployment", ts.Status.DeploymentName)
 	}
@@ -234,6 +274,61 @@ func printTaskSpawnerDetail(w io.Writer, ts *kelosv1alpha1.TaskSpawner) {
 	}
 }
 
+// printTaskSpawnerPreview prints the outcome of the last dry-run cycle of a
+// TaskSpawner. When detail is true, the rendered prompt of each Task that
+// would be created or retriggered is printed after the table.
+func printTaskSpawnerPreview(w io.Writer, ts *kelosv1alpha1.TaskSpawner, detail bool) {
+	if ts.Status.LastDiscoveryTime == nil {
+		fmt.Fprintf(w, "No preview recorded yet for task spawner %q\n", ts.Name)
+		return
+	}
+	if len(ts.Status.Preview) == 0 {
+		fmt.Fprintf(w, "No items discovered at %s\n", ts.Status.LastDiscoveryTime.Time.Format(time.RFC3339))
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tTASK\tACTION\tBRANCH\tREASON")
+	for _, p := range ts.Status.Preview {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.ItemID, p.TaskName, p.Action, p.Branch, p.Reason)
+	}
+	tw.Flush()
+	if ts.Status.PreviewOmitted > 0 {
+		fmt.Fprintf(w, "... %d more items not shown\n", ts.Status.PreviewOmitted)
+	}
+
+	if !detail {
+		return
+	}
+	for _, p := range ts.Status.Preview {
+		if p.Action == kelosv1alpha1.PreviewActionSkip {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- %s ---\n", p.TaskName)
+		if len(p.Labels) > 0 {
+			printField(w, "Labels", formatStringMap(p.Labels))
+		}
+		if len(p.Annotations) > 0 {
+			printField(w, "Annotations", formatStringMap(p.Annotations))
+		}
+		fmt.Fprintln(w, p.Prompt)
+	}
+}
+
+// formatStringMap formats a map as sorted comma-separated key=value pairs.
+func formatStringMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+m[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
 func printWorkspaceTable(w io.Writer, workspaces []kelosv1alpha1.Workspace, allNamespaces bool) {
 	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
 	if allNamespaces {
@@ -259,9 +354,15 @@ func printWorkspaceDetail(w io.Writer, ws *kelosv1alpha1.Workspace) {
 	if ws.Spec.Ref != "" {
 		printField(w, "Ref", ws.Spec.Ref)
 	}
+	if ws.Spec.Provider != "" {
+		printField(w, "Provider", string(ws.Spec.Provider))
+	}
 	if ws.Spec.SecretRef != nil {
 		printField(w, "Secret", ws.Spec.SecretRef.Name)
 	}
+	if ws.Spec.SSHKeySecretRef != nil {
+		printField(w, "SSH Key Secret", ws.Spec.SSHKeySecretRef.Name)
+	}
 }
 
 func printAgentConfigTable(w io.Writer, configs []kelosv1alpha1.AgentConfig, allNamespaces bool) {
@@ -370,3 +471,16 @@ func printJSON(w io.Writer, obj interface{}) error {
 	_, err = fmt.Fprintln(w, string(data))
 	return err
 }
+
+// agentConfigNames returns the comma-separated names of the AgentConfigs a
+// Task references.
+func agentConfigNames(spec *kelosv1alpha1.TaskSpec) string {
+	if spec.AgentConfigRef != nil {
+		return spec.AgentConfigRef.Name
+	}
+	names := make([]string, 0, len(spec.AgentConfigRefs))
+	for _, ref := range spec.AgentConfigRefs {
+		names = append(names, ref.Name)
+	}
+	return strings.Join(names, ",")
+}
@@ -424,6 +424,51 @@ func TestPrintTaskSpawnerDetailGitHubPullRequests(t *testing.T) {
 	}
 }
 
+func TestPrintTaskSpawnerPreview(t *testing.T) {
+	now := metav1.Now()
+	spawner := &kelosv1alpha1.TaskSpawner{
+		ObjectMeta: metav1.ObjectMeta{Name: "spawner", Namespace: "default"},
+		Status: kelosv1alpha1.TaskSpawnerStatus{
+			LastDiscoveryTime: &now,
+			Preview: []kelosv1alpha1.TaskSpawnerPreviewItem{
+				{ItemID: "1", TaskName: "spawner-1", Action: kelosv1alpha1.PreviewActionCreate, Branch: "kelos-1", Prompt: "Fix issue 1"},
+				{ItemID: "2", TaskName: "spawner-2", Action: kelosv1alpha1.PreviewActionSkip, Reason: "Task is still active"},
+			},
+			PreviewOmitted: 3,
+		},
+	}
+
+	var buf bytes.Buffer
+	printTaskSpawnerPreview(&buf, spawner, false)
+	output := buf.String()
+	for _, expected := range []string{"ITEM", "spawner-1", "Create", "kelos-1", "spawner-2", "Skip", "Task is still active", "3 more items not shown"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in preview output, got %q", expected, output)
+		}
+	}
+	if strings.Contains(output, "Fix issue 1") {
+		t.Errorf("expected prompt to be omitted without detail, got %q", output)
+	}
+
+	buf.Reset()
+	printTaskSpawnerPreview(&buf, spawner, true)
+	if !strings.Contains(buf.String(), "Fix issue 1") {
+		t.Errorf("expected prompt in detailed preview output, got %q", buf.String())
+	}
+}
+
+func TestPrintTaskSpawnerPreviewNotYetRecorded(t *testing.T) {
+	spawner := &kelosv1alpha1.TaskSpawner{
+		ObjectMeta: metav1.ObjectMeta{Name: "spawner", Namespace: "default"},
+	}
+
+	var buf bytes.Buffer
+	printTaskSpawnerPreview(&buf, spawner, false)
+	if !strings.Contains(buf.String(), "No preview recorded yet") {
+		t.Errorf("expected no-preview message, got %q", buf.String())
+	}
+}
+
 func TestPrintTaskSpawnerDetailJira(t *testing.T) {
 	spawner := &kelosv1alpha1.TaskSpawner{
 		ObjectMeta: metav1.ObjectMeta{
@@ -982,6 +1027,62 @@ func TestPrintAgentConfigDetailMinimal(t *testing.T) {
 	}
 }
 
+func TestPrintTaskDetailConditions(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "setup-task",
+			Namespace: "default",
+		},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase: kelosv1alpha1.TaskPhaseFailed,
+			Conditions: []metav1.Condition{{
+				Type:    kelosv1alpha1.TaskConditionWorkspaceSetup,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Failed",
+				Message: "Workspace setup commands failed with exit code 1\nnpm ERR! missing script: ci",
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	printTaskDetail(&buf, task)
+	output := buf.String()
+
+	for _, want := range []string{
+		"WorkspaceSetup:     Workspace setup commands failed with exit code 1 (Failed)\n",
+		"                    npm ERR! missing script: ci\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintTaskDetailAgentConfigRefs(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "composed-task",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "claude-code",
+			Prompt: "Do something",
+			AgentConfigRefs: []kelosv1alpha1.AgentConfigReference{
+				{Name: "platform-baseline"},
+				{Name: "team-config"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	printTaskDetail(&buf, task)
+	output := buf.String()
+
+	if !strings.Contains(output, "platform-baseline,team-config") {
+		t.Errorf("expected agent config names in output, got:\n%s", output)
+	}
+}
+
 func TestPrintTaskDetailMinimal(t *testing.T) {
 	task := &kelosv1alpha1.Task{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	"github.com/kelos-dev/kelos/internal/usage"
+)
+
+// noGroupValue is shown for records that have no value for the grouping,
+// e.g. Tasks that were not created by a TaskSpawner.
+const noGroupValue = "<none>"
+
+// costGroup is the aggregated usage of one group in a cost report.
+type costGroup struct {
+	Key          string  `json:"key"`
+	Tasks        int     `json:"tasks"`
+	CostUSD      float64 `json:"costUSD"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+}
+
+func (g *costGroup) add(r usage.Record) {
+	g.Tasks++
+	g.CostUSD += r.CostUSD
+	g.InputTokens += r.InputTokens
+	g.OutputTokens += r.OutputTokens
+}
+
+// costReport is the result of kelos report cost.
+type costReport struct {
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	GroupBy string      `json:"groupBy"`
+	Groups  []costGroup `json:"groups"`
+	Total   costGroup   `json:"total"`
+}
+
+func newReportCommand(cfg *ClientConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report on Task usage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			return fmt.Errorf("must specify a report")
+		},
+	}
+
+	cmd.AddCommand(newReportCostCommand(cfg))
+
+	return cmd
+}
+
+func newReportCostCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		groupBy       string
+		since         string
+		until         string
+		output        string
+		allNamespaces bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cost",
+		Short: "Report cost and token usage of finished Tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "csv" && output != "json" {
+				return fmt.Errorf("unknown output format %q: must be one of table, csv, json", output)
+			}
+			keyFn, err := costGroupKey(groupBy)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			from, err := parseReportTime(since, now, true)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			to := now
+			if until != "" {
+				to, err = parseReportTime(until, now, false)
+				if err != nil {
+					return fmt.Errorf("invalid --until: %w", err)
+				}
+			}
+			if !from.Before(to) {
+				return fmt.Errorf("--since must be before --until")
+			}
+
+			cl, ns, err := cfg.NewClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			var listOpts []client.ListOption
+			if !allNamespaces {
+				listOpts = append(listOpts, client.InNamespace(ns))
+			}
+			records, err := collectUsageRecords(ctx, cl, listOpts...)
+			if err != nil {
+				return err
+			}
+
+			report := buildCostReport(records, groupBy, keyFn, from, to)
+			switch output {
+			case "json":
+				return printJSON(os.Stdout, report)
+			case "csv":
+				return printCostReportCSV(os.Stdout, report)
+			default:
+				printCostReportTable(os.Stdout, report)
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&groupBy, "group-by", "spawner", "group by spawner, model, type, day or label:<key>")
+	cmd.Flags().StringVar(&since, "since", "7d", "start of the range, as a duration ago (e.g. 24h, 7d) or a date (2006-01-02 or RFC 3339)")
+	cmd.Flags().StringVar(&until, "until", "", "end of the range, as a duration ago or a date (default now)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, csv or json)")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Report across all namespaces")
+
+	_ = cmd.RegisterFlagCompletionFunc("group-by", cobra.FixedCompletions([]string{"spawner", "model", "type", "day", "label:"}, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace))
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "csv", "json"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+// collectUsageRecords returns the usage of finished Tasks from the usage
+// ledger, merged with Tasks that still exist. Live Tasks take precedence so
+// that results captured after the ledger entry was written are included.
+func collectUsageRecords(ctx context.Context, cl client.Client, opts ...client.ListOption) ([]usage.Record, error) {
+	ledger, err := usage.List(ctx, cl, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]usage.Record, len(ledger))
+	for _, r := range ledger {
+		byKey[r.Namespace+"/"+r.Key()] = r
+	}
+
+	var tasks kelosv1alpha1.TaskList
+	if err := cl.List(ctx, &tasks, opts...); err != nil {
+		return nil, fmt.Errorf("listing tasks: %w", err)
+	}
+	for i := range tasks.Items {
+		if r, ok := usage.RecordFromTask(&tasks.Items[i]); ok {
+			byKey[r.Namespace+"/"+r.Key()] = r
+		}
+	}
+
+	records := make([]usage.Record, 0, len(byKey))
+	for _, r := range byKey {
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// costGroupKey returns the function that maps a record to its group for the
+// given --group-by value.
+func costGroupKey(groupBy string) (func(usage.Record) string, error) {
+	orNone := func(s string) string {
+		if s == "" {
+			return noGroupValue
+		}
+		return s
+	}
+
+	switch groupBy {
+	case "spawner":
+		return func(r usage.Record) string { return orNone(r.Spawner) }, nil
+	case "model":
+		return func(r usage.Record) string { return orNone(r.Model) }, nil
+	case "type":
+		return func(r usage.Record) string { return orNone(r.Type) }, nil
+	case "day":
+		return func(r usage.Record) string { return r.CompletionTime.UTC().Format("2006-01-02") }, nil
+	}
+
+	if key, ok := strings.CutPrefix(groupBy, "label:"); ok && key != "" {
+		return func(r usage.Record) string { return orNone(r.Labels[key]) }, nil
+	}
+	return nil, fmt.Errorf("unknown --group-by %q: must be one of spawner, model, type, day or label:<key>", groupBy)
+}
+
+// parseReportTime parses a point in time given either as a duration before
+// now (with d for days in addition to Go duration units) or as a date. A
+// bare date is the start of that day in UTC when start is true and the end
+// of it otherwise.
+func parseReportTime(s string, now time.Time, start bool) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if !start {
+			t = t.Add(24 * time.Hour)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration nor a date", s)
+}
+
+// buildCostReport aggregates the records completed within [from, to).
+// Groups are ordered by cost, or chronologically when grouping by day.
+func buildCostReport(records []usage.Record, groupBy string, keyFn func(usage.Record) string, from, to time.Time) costReport {
+	report := costReport{From: from.UTC(), To: to.UTC(), GroupBy: groupBy, Total: costGroup{Key: "TOTAL"}}
+
+	groups := make(map[string]*costGroup)
+	for _, r := range records {
+		if r.CompletionTime.Before(from) || !r.CompletionTime.Before(to) {
+			continue
+		}
+		key := keyFn(r)
+		g, ok := groups[key]
+		if !ok {
+			g = &costGroup{Key: key}
+			groups[key] = g
+		}
+		g.add(r)
+		report.Total.add(r)
+	}
+
+	report.Groups = make([]costGroup, 0, len(groups))
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if groupBy != "day" && a.CostUSD != b.CostUSD {
+			return a.CostUSD > b.CostUSD
+		}
+		return a.Key < b.Key
+	})
+	return report
+}
+
+func printCostReportTable(w io.Writer, report costReport) {
+	fmt.Fprintf(w, "Cost from %s to %s\n\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+	if len(report.Groups) == 0 {
+		fmt.Fprintln(w, "No finished tasks in range.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "%s\tTASKS\tCOST (USD)\tINPUT TOKENS\tOUTPUT TOKENS\n", costGroupHeader(report.GroupBy))
+	for _, g := range append(report.Groups, report.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%d\t%d\n", g.Key, g.Tasks, g.CostUSD, g.InputTokens, g.OutputTokens)
+	}
+	tw.Flush()
+}
+
+func printCostReportCSV(w io.Writer, report costReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{report.GroupBy, "tasks", "cost_usd", "input_tokens", "output_tokens"}); err != nil {
+		return err
+	}
+	for _, g := range report.Groups {
+		if err := cw.Write([]string{
+			g.Key,
+			strconv.Itoa(g.Tasks),
+			strconv.FormatFloat(g.CostUSD, 'f', -1, 64),
+			strconv.FormatInt(g.InputTokens, 10),
+			strconv.FormatInt(g.OutputTokens, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func costGroupHeader(groupBy string) string {
+	if key, ok := strings.CutPrefix(groupBy, "label:"); ok {
+		return strings.ToUpper(key)
+	}
+	return strings.ToUpper(groupBy)
+}
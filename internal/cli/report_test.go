@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	"github.com/kelos-dev/kelos/internal/usage"
+)
+
+func testUsageRecords(day time.Time) []usage.Record {
+	return []usage.Record{
+		{Task: "a", UID: "1", Spawner: "fixer", Type: "claude-code", Model: "opus", Labels: map[string]string{"team": "platform"}, CompletionTime: day, CostUSD: 1.5, InputTokens: 100, OutputTokens: 10},
+		{Task: "b", UID: "2", Spawner: "fixer", Type: "claude-code", Model: "sonnet", CompletionTime: day.Add(time.Hour), CostUSD: 0.5, InputTokens: 50, OutputTokens: 5},
+		{Task: "c", UID: "3", Type: "codex", Labels: map[string]string{"team": "web"}, CompletionTime: day.Add(25 * time.Hour), CostUSD: 3, InputTokens: 300, OutputTokens: 30},
+		{Task: "old", UID: "4", Spawner: "fixer", Type: "codex", CompletionTime: day.Add(-30 * 24 * time.Hour), CostUSD: 100},
+	}
+}
+
+func TestBuildCostReport(t *testing.T) {
+	day := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	from := day.Add(-24 * time.Hour)
+	to := day.Add(48 * time.Hour)
+
+	tests := []struct {
+		groupBy string
+		want    []string
+	}{
+		{groupBy: "spawner", want: []string{noGroupValue, "fixer"}},
+		{groupBy: "model", want: []string{noGroupValue, "opus", "sonnet"}},
+		{groupBy: "type", want: []string{"codex", "claude-code"}},
+		{groupBy: "day", want: []string{"2026-03-04", "2026-03-05"}},
+		{groupBy: "label:team", want: []string{"web", "platform", noGroupValue}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			keyFn, err := costGroupKey(tt.groupBy)
+			if err != nil {
+				t.Fatalf("costGroupKey error: %v", err)
+			}
+			report := buildCostReport(testUsageRecords(day), tt.groupBy, keyFn, from, to)
+
+			var got []string
+			for _, g := range report.Groups {
+				got = append(got, g.Key)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+			if report.Total.Tasks != 3 || report.Total.CostUSD != 5 || report.Total.InputTokens != 450 || report.Total.OutputTokens != 45 {
+				t.Errorf("unexpected total: %+v", report.Total)
+			}
+		})
+	}
+}
+
+func TestCostGroupKey_Invalid(t *testing.T) {
+	for _, groupBy := range []string{"namespace", "label:", ""} {
+		if _, err := costGroupKey(groupBy); err == nil {
+			t.Errorf("expected error for --group-by %q", groupBy)
+		}
+	}
+}
+
+func TestParseReportTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		start bool
+		want  time.Time
+	}{
+		{input: "7d", start: true, want: now.Add(-7 * 24 * time.Hour)},
+		{input: "36h", start: true, want: now.Add(-36 * time.Hour)},
+		{input: "2026-03-01", start: true, want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2026-03-01", start: false, want: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{input: "2026-03-01T08:00:00Z", start: true, want: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseReportTime(tt.input, now, tt.start)
+		if err != nil {
+			t.Errorf("parseReportTime(%q) error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseReportTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := parseReportTime("last week", now, true); err == nil {
+		t.Error("expected error for an invalid time")
+	}
+}
+
+func TestPrintCostReport(t *testing.T) {
+	day := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	keyFn, _ := costGroupKey("spawner")
+	report := buildCostReport(testUsageRecords(day), "spawner", keyFn, day.Add(-time.Hour), day.Add(48*time.Hour))
+
+	var table bytes.Buffer
+	printCostReportTable(&table, report)
+	for _, want := range []string{"SPAWNER", "COST (USD)", "fixer", "2.00", "TOTAL", "5.00"} {
+		if !strings.Contains(table.String(), want) {
+			t.Errorf("table output missing %q:\n%s", want, table.String())
+		}
+	}
+
+	var csvOut bytes.Buffer
+	if err := printCostReportCSV(&csvOut, report); err != nil {
+		t.Fatalf("printCostReportCSV error: %v", err)
+	}
+	want := "spawner,tasks,cost_usd,input_tokens,output_tokens\n<none>,1,3,300,30\nfixer,2,2,150,15\n"
+	if csvOut.String() != want {
+		t.Errorf("csv output = %q, want %q", csvOut.String(), want)
+	}
+}
+
+func TestCollectUsageRecords(t *testing.T) {
+	ctx := context.Background()
+	completed := metav1.NewTime(time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC))
+
+	live := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "live", Namespace: "default", UID: "uid-live"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex"},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase:          kelosv1alpha1.TaskPhaseSucceeded,
+			CompletionTime: &completed,
+			Results:        map[string]string{"cost-usd": "2"},
+		},
+	}
+	running := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default", UID: "uid-running"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex"},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseRunning},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(live, running).Build()
+
+	// The live Task was recorded before its results were captured, and a
+	// deleted Task only exists in the ledger.
+	stale, _ := usage.RecordFromTask(live)
+	stale.CostUSD = 0
+	deleted := usage.Record{Task: "deleted", Namespace: "default", UID: "uid-deleted", CompletionTime: completed.Time, CostUSD: 1}
+	for _, r := range []usage.Record{stale, deleted} {
+		if err := usage.Append(ctx, cl, r, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := collectUsageRecords(ctx, cl, client.InNamespace("default"))
+	if err != nil {
+		t.Fatalf("collectUsageRecords error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d: %+v", len(records), records)
+	}
+	var total float64
+	for _, r := range records {
+		total += r.CostUSD
+	}
+	if total != 3 {
+		t.Errorf("total cost = %v, want 3", total)
+	}
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+// followUpSuffixRe matches the suffix added to the names of rerun and
+// continued Tasks so that following up on a follow-up does not keep growing
+// the name.
+var followUpSuffixRe = regexp.MustCompile(`-(rerun|continue)-[a-z0-9]{5}$`)
+
+// rerunOptions holds the overrides applied when rerunning a Task.
+type rerunOptions struct {
+	Name            string
+	Prompt          string
+	AppendPrompt    string
+	Model           string
+	Type            string
+	IncludePrevious bool
+}
+
+func newRerunCommand(cfg *ClientConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rerun",
+		Short: "Rerun resources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			return fmt.Errorf("must specify a resource type")
+		},
+	}
+
+	cmd.AddCommand(newRerunTaskCommand(cfg))
+
+	return cmd
+}
+
+func newRerunTaskCommand(cfg *ClientConfig) *cobra.Command {
+	var (
+		opts   rerunOptions
+		watch  bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "task <name>",
+		Aliases: []string{"tasks"},
+		Short:   "Rerun a finished task as a new task",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("task name is required\nUsage: %s", cmd.Use)
+			}
+			if len(args) > 1 {
+				return fmt.Errorf("too many arguments: expected 1 task name, got %d\nUsage: %s", len(args), cmd.Use)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prompt, err := resolveContent(opts.Prompt)
+			if err != nil {
+				return fmt.Errorf("resolving --prompt: %w", err)
+			}
+			opts.Prompt = prompt
+			appendPrompt, err := resolveContent(opts.AppendPrompt)
+			if err != nil {
+				return fmt.Errorf("resolving --append-prompt: %w", err)
+			}
+			opts.AppendPrompt = appendPrompt
+
+			cl, ns, err := cfg.NewClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			orig := &kelosv1alpha1.Task{}
+			if err := cl.Get(ctx, client.ObjectKey{Name: args[0], Namespace: ns}, orig); err != nil {
+				return fmt.Errorf("getting task: %w", err)
+			}
+
+			task, err := buildRerunTask(orig, opts)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printYAML(os.Stdout, task)
+			}
+
+			if err := cl.Create(ctx, task); err != nil {
+				return fmt.Errorf("creating task: %w", err)
+			}
+			fmt.Fprintf(os.Stdout, "task/%s created\n", task.Name)
+
+			if watch {
+				return watchTask(ctx, cl, task.Name, ns)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Prompt, "prompt", "p", "", "replace the prompt (content or @file path)")
+	cmd.Flags().StringVar(&opts.AppendPrompt, "append-prompt", "", "text appended to the prompt (content or @file path)")
+	cmd.Flags().StringVar(&opts.Model, "model", "", "model override")
+	cmd.Flags().StringVarP(&opts.Type, "type", "t", "", "agent type override; clears the model unless --model is set")
+	cmd.Flags().StringVar(&opts.Name, "name", "", "name of the new task (defaults to <name>-rerun-<suffix>)")
+	cmd.Flags().BoolVar(&opts.IncludePrevious, "include-previous", false, "include the previous attempt's outcome, message and outputs in the prompt")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "watch task status after creation")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the resource that would be created without submitting it")
+
+	_ = cmd.RegisterFlagCompletionFunc("type", completeAgentTypes(cfg))
+	cmd.ValidArgsFunction = completeTaskNames(cfg)
+
+	return cmd
+}
+
+// buildRerunTask returns a new Task with the spec of orig and the given
+// overrides applied. Only finished Tasks can be rerun. Labels managed by
+// Kelos are not copied so that the new Task is not mistaken for one created
+// by a TaskSpawner.
+func buildRerunTask(orig *kelosv1alpha1.Task, opts rerunOptions) (*kelosv1alpha1.Task, error) {
+	if err := requireFinished(orig); err != nil {
+		return nil, err
+	}
+
+	spec := *orig.Spec.DeepCopy()
+
+	if opts.Type != "" && opts.Type != spec.Type {
+		spec.Type = opts.Type
+		spec.Model = ""
+	}
+	if opts.Model != "" {
+		spec.Model = opts.Model
+	}
+
+	if opts.Prompt != "" {
+		spec.Prompt = opts.Prompt
+		spec.PromptFrom = nil
+	}
+	if spec.PromptFrom != nil && (opts.AppendPrompt != "" || opts.IncludePrevious) {
+		return nil, fmt.Errorf("task %s loads its prompt with promptFrom; use --prompt to set an inline prompt before appending to it", orig.Name)
+	}
+	if opts.IncludePrevious {
+		spec.Prompt += "\n\n" + previousAttemptSummary(orig)
+	}
+	if opts.AppendPrompt != "" {
+		spec.Prompt += "\n\n" + opts.AppendPrompt
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = followUpName(orig.Name, "rerun")
+	}
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: orig.Namespace,
+			Labels:    userLabels(orig.Labels),
+			Annotations: map[string]string{
+				kelosv1alpha1.AnnotationRerunOf: orig.Name,
+			},
+		},
+		Spec: spec,
+	}
+	task.SetGroupVersionKind(kelosv1alpha1.GroupVersion.WithKind("Task"))
+	return task, nil
+}
+
+// requireFinished returns an error unless the Task has Succeeded or Failed.
+func requireFinished(task *kelosv1alpha1.Task) error {
+	if task.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || task.Status.Phase == kelosv1alpha1.TaskPhaseFailed {
+		return nil
+	}
+	phase := string(task.Status.Phase)
+	if phase == "" {
+		phase = "not started"
+	}
+	return fmt.Errorf("task %s has not finished (phase: %s)", task.Name, phase)
+}
+
+// followUpName returns a name for a Task that follows up on the named Task,
+// e.g. "fix-42-rerun-x7k2p".
+func followUpName(name, kind string) string {
+	return followUpSuffixRe.ReplaceAllString(name, "") + "-" + kind + "-" + rand.String(5)
+}
+
+// userLabels returns labels without those managed by Kelos, or nil when none
+// remain.
+func userLabels(labels map[string]string) map[string]string {
+	var result map[string]string
+	for k, v := range labels {
+		if strings.HasPrefix(k, "kelos.dev/") {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// previousAttemptSummary describes the outcome of a finished Task so that a
+// rerun can build on it.
+func previousAttemptSummary(task *kelosv1alpha1.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Previous attempt\n\nA previous attempt at this task (%s) %s.", task.Name, strings.ToLower(string(task.Status.Phase)))
+	if task.Status.Message != "" {
+		fmt.Fprintf(&b, "\n\nStatus message: %s", task.Status.Message)
+	}
+	if len(task.Status.Outputs) > 0 {
+		b.WriteString("\n\nOutputs:")
+		for _, o := range task.Status.Outputs {
+			fmt.Fprintf(&b, "\n- %s", o)
+		}
+	}
+	if len(task.Status.Results) > 0 {
+		keys := make([]string, 0, len(task.Status.Results))
+		for k := range task.Status.Results {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("\n\nResults:")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\n- %s: %s", k, task.Status.Results[k])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func finishedTask() *kelosv1alpha1.Task {
+	return &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "fixer-42",
+			Namespace: "default",
+			Labels: map[string]string{
+				"kelos.dev/taskspawner": "fixer",
+				"team":                  "platform",
+			},
+			Annotations: map[string]string{
+				"kelos.dev/source-number": "42",
+			},
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "claude-code",
+			Prompt: "Fix issue #42",
+			Model:  "sonnet",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+			},
+			Branch: "kelos-42",
+		},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase:   kelosv1alpha1.TaskPhaseFailed,
+			Message: "Job failed: BackoffLimitExceeded",
+			Outputs: []string{"branch: kelos-42"},
+			Results: map[string]string{"pr": "12", "branch": "kelos-42"},
+		},
+	}
+}
+
+func TestBuildRerunTask(t *testing.T) {
+	orig := finishedTask()
+
+	task, err := buildRerunTask(orig, rerunOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(task.Name, "fixer-42-rerun-") || len(task.Name) != len("fixer-42-rerun-")+5 {
+		t.Errorf("unexpected name %q", task.Name)
+	}
+	if task.Namespace != "default" {
+		t.Errorf("namespace = %q, want default", task.Namespace)
+	}
+	if got := task.Annotations[kelosv1alpha1.AnnotationRerunOf]; got != "fixer-42" {
+		t.Errorf("rerun-of annotation = %q, want fixer-42", got)
+	}
+	if _, ok := task.Annotations["kelos.dev/source-number"]; ok {
+		t.Error("expected source annotations not to be copied")
+	}
+	if _, ok := task.Labels["kelos.dev/taskspawner"]; ok {
+		t.Error("expected taskspawner label not to be copied")
+	}
+	if task.Labels["team"] != "platform" {
+		t.Errorf("expected user labels to be copied, got %v", task.Labels)
+	}
+	if task.Spec.Prompt != "Fix issue #42" || task.Spec.Model != "sonnet" || task.Spec.Branch != "kelos-42" {
+		t.Errorf("expected spec to be copied, got %+v", task.Spec)
+	}
+	if task.Kind != "Task" {
+		t.Errorf("kind = %q, want Task", task.Kind)
+	}
+
+	// Mutating the rerun must not affect the original.
+	task.Spec.Credentials.SecretRef.Name = "other"
+	if orig.Spec.Credentials.SecretRef.Name != "creds" {
+		t.Error("expected spec to be deep-copied")
+	}
+
+	again, err := buildRerunTask(&kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: task.Name},
+		Spec:       task.Spec,
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseSucceeded},
+	}, rerunOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(again.Name, "-rerun-") != 1 {
+		t.Errorf("expected rerun suffix to be replaced, got %q", again.Name)
+	}
+}
+
+func TestBuildRerunTaskOverrides(t *testing.T) {
+	task, err := buildRerunTask(finishedTask(), rerunOptions{
+		Name:            "retry",
+		AppendPrompt:    "Run the tests before pushing.",
+		Type:            "codex",
+		IncludePrevious: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if task.Name != "retry" {
+		t.Errorf("name = %q, want retry", task.Name)
+	}
+	if task.Spec.Type != "codex" {
+		t.Errorf("type = %q, want codex", task.Spec.Type)
+	}
+	if task.Spec.Model != "" {
+		t.Errorf("expected model to be cleared when the type changes, got %q", task.Spec.Model)
+	}
+
+	want := "Fix issue #42\n\n" +
+		"## Previous attempt\n\n" +
+		"A previous attempt at this task (fixer-42) failed.\n\n" +
+		"Status message: Job failed: BackoffLimitExceeded\n\n" +
+		"Outputs:\n- branch: kelos-42\n\n" +
+		"Results:\n- branch: kelos-42\n- pr: 12\n\n" +
+		"Run the tests before pushing."
+	if task.Spec.Prompt != want {
+		t.Errorf("prompt = %q\nwant %q", task.Spec.Prompt, want)
+	}
+
+	task, err = buildRerunTask(finishedTask(), rerunOptions{Prompt: "Try again", Model: "opus", Type: "claude-code"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.Spec.Prompt != "Try again" || task.Spec.Model != "opus" {
+		t.Errorf("unexpected spec %+v", task.Spec)
+	}
+}
+
+func TestBuildRerunTaskErrors(t *testing.T) {
+	running := finishedTask()
+	running.Status.Phase = kelosv1alpha1.TaskPhaseRunning
+	if _, err := buildRerunTask(running, rerunOptions{}); err == nil || !strings.Contains(err.Error(), "has not finished") {
+		t.Errorf("expected not finished error, got %v", err)
+	}
+
+	fromConfigMap := finishedTask()
+	fromConfigMap.Spec.Prompt = ""
+	fromConfigMap.Spec.PromptFrom = &kelosv1alpha1.PromptSource{
+		ConfigMapKeyRef: &kelosv1alpha1.ConfigMapKeyReference{Name: "prompts", Key: "fix"},
+	}
+	if _, err := buildRerunTask(fromConfigMap, rerunOptions{AppendPrompt: "more"}); err == nil || !strings.Contains(err.Error(), "promptFrom") {
+		t.Errorf("expected promptFrom error, got %v", err)
+	}
+
+	task, err := buildRerunTask(fromConfigMap, rerunOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.Spec.PromptFrom == nil || task.Spec.Prompt != "" {
+		t.Errorf("expected promptFrom to be kept, got %+v", task.Spec)
+	}
+
+	task, err = buildRerunTask(fromConfigMap, rerunOptions{Prompt: "inline", AppendPrompt: "more"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.Spec.PromptFrom != nil || task.Spec.Prompt != "inline\n\nmore" {
+		t.Errorf("expected inline prompt to replace promptFrom, got %+v", task.Spec)
+	}
+}
+
+func TestRerunCommand_MissingName(t *testing.T) {
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"rerun", "task"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatal("Expected error when name is missing")
+	}
+	if !strings.Contains(err.Error(), "task name is required") {
+		t.Errorf("Expected 'task name is required' error, got: %v", err)
+	}
+}
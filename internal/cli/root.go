@@ -40,9 +40,15 @@ func NewRootCommand() *cobra.Command {
 
 	cmd.AddCommand(
 		newRunCommand(cfg),
+		newRerunCommand(cfg),
+		newContinueCommand(cfg),
 		newCreateCommand(cfg),
 		newGetCommand(cfg),
+		newApplyCommand(cfg),
+		newExportCommand(cfg),
 		newLogsCommand(cfg),
+		newDashboardCommand(cfg),
+		newReportCommand(cfg),
 		newDeleteCommand(cfg),
 		newSuspendCommand(cfg),
 		newResumeCommand(cfg),
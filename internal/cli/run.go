@@ -50,6 +50,7 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 		agentConfigRef string
 		dependsOn      []string
 		branch         string
+		sessionPVC     string
 	)
 
 	cmd := &cobra.Command{
@@ -77,49 +78,10 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 				}
 			}
 
-			// Auto-create secret from token if no explicit secret is set.
-			if secret == "" && cfg.Config != nil {
-				sources := 0
-				if cfg.Config.OAuthToken != "" {
-					sources++
-				}
-				if cfg.Config.APIKey != "" {
-					sources++
-				}
-				if sources > 1 {
-					return fmt.Errorf("config file must specify only one of oauthToken or apiKey")
-				}
-				if token := cfg.Config.OAuthToken; token != "" {
-					resolved, err := resolveContent(token)
-					if err != nil {
-						return fmt.Errorf("resolving oauthToken: %w", err)
-					}
-					if !dryRun {
-						oauthKey := oauthSecretKey(agentType)
-						if err := ensureCredentialSecret(cfg, "kelos-credentials", oauthKey, resolveCredentialValue(resolved), yes); err != nil {
-							return err
-						}
-					}
-					secret = "kelos-credentials"
-					credentialType = "oauth"
-				} else if key := cfg.Config.APIKey; key != "" {
-					resolved, err := resolveContent(key)
-					if err != nil {
-						return fmt.Errorf("resolving apiKey: %w", err)
-					}
-					if !dryRun {
-						apiKey := apiKeySecretKey(agentType)
-						if err := ensureCredentialSecret(cfg, "kelos-credentials", apiKey, resolveCredentialValue(resolved), yes); err != nil {
-							return err
-						}
-					}
-					secret = "kelos-credentials"
-					credentialType = "api-key"
-				}
-			}
-
-			if secret == "" && credentialType != "none" {
-				return fmt.Errorf("no credentials configured (set oauthToken/apiKey in config file, or use --secret flag)")
+			var err error
+			secret, credentialType, err = resolveConfigCredentials(cfg, agentType, secret, credentialType, dryRun, yes)
+			if err != nil {
+				return err
 			}
 
 			cl, ns, err := newClientOrDryRun(cfg, dryRun)
@@ -127,72 +89,10 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 				return err
 			}
 
-			if dryRun {
-				// Resolve workspace from inline config for dry-run.
-				if workspace == "" && cfg.Config != nil && cfg.Config.Workspace.Repo != "" {
-					workspace = "kelos-workspace"
-				}
-			} else {
-				// Auto-create Workspace CR from inline config if no --workspace flag.
-				if workspace == "" && cfg.Config != nil && cfg.Config.Workspace.Repo != "" {
-					wsCfg := cfg.Config.Workspace
-
-					if wsCfg.Token != "" && wsCfg.GitHubApp != nil {
-						return fmt.Errorf("workspace config must specify either token or githubApp, not both")
-					}
-
-					wsName := "kelos-workspace"
-					ws := &kelosv1alpha1.Workspace{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      wsName,
-							Namespace: ns,
-						},
-						Spec: kelosv1alpha1.WorkspaceSpec{
-							Repo: wsCfg.Repo,
-							Ref:  wsCfg.Ref,
-						},
-					}
-					if wsCfg.Token != "" {
-						if err := ensureCredentialSecret(cfg, "kelos-workspace-credentials", "GITHUB_TOKEN", wsCfg.Token, yes); err != nil {
-							return err
-						}
-						ws.Spec.SecretRef = &kelosv1alpha1.SecretReference{
-							Name: "kelos-workspace-credentials",
-						}
-					} else if wsCfg.GitHubApp != nil {
-						if err := ensureGitHubAppSecret(cfg, "kelos-workspace-credentials", wsCfg.GitHubApp, yes); err != nil {
-							return err
-						}
-						ws.Spec.SecretRef = &kelosv1alpha1.SecretReference{
-							Name: "kelos-workspace-credentials",
-						}
-					}
-					ctx := context.Background()
-					if err := cl.Create(ctx, ws); err != nil {
-						if !apierrors.IsAlreadyExists(err) {
-							return fmt.Errorf("creating workspace: %w", err)
-						}
-						existing := &kelosv1alpha1.Workspace{}
-						if err := cl.Get(ctx, client.ObjectKey{Name: wsName, Namespace: ns}, existing); err != nil {
-							return fmt.Errorf("fetching existing workspace: %w", err)
-						}
-						if !reflect.DeepEqual(existing.Spec, ws.Spec) {
-							if !yes {
-								ok, confirmErr := confirmOverride(fmt.Sprintf("workspace/%s", wsName))
-								if confirmErr != nil {
-									return confirmErr
-								}
-								if !ok {
-									return fmt.Errorf("aborted")
-								}
-							}
-							existing.Spec = ws.Spec
-							if err := cl.Update(ctx, existing); err != nil {
-								return fmt.Errorf("updating workspace: %w", err)
-							}
-						}
-					}
-					workspace = wsName
+			if workspace == "" {
+				workspace, err = ensureConfigWorkspace(cfg, cl, ns, dryRun, yes)
+				if err != nil {
+					return err
 				}
 			}
 
@@ -227,6 +127,9 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 			if branch != "" {
 				task.Spec.Branch = branch
 			}
+			if sessionPVC != "" {
+				task.Spec.Session = &kelosv1alpha1.SessionSpec{PersistentVolumeClaimName: sessionPVC}
+			}
 
 			if workspace != "" {
 				task.Spec.WorkspaceRef = &kelosv1alpha1.WorkspaceReference{
@@ -240,36 +143,9 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 				}
 			}
 
-			// Build PodOverrides from --timeout and --env flags.
-			var po *kelosv1alpha1.PodOverrides
-			if timeout != "" {
-				d, err := time.ParseDuration(timeout)
-				if err != nil {
-					return fmt.Errorf("invalid --timeout value %q: %w", timeout, err)
-				}
-				secs := int64(d.Seconds())
-				if secs < 1 {
-					return fmt.Errorf("--timeout must be at least 1s")
-				}
-				if po == nil {
-					po = &kelosv1alpha1.PodOverrides{}
-				}
-				po.ActiveDeadlineSeconds = &secs
-			}
-			if len(envFlags) > 0 {
-				if po == nil {
-					po = &kelosv1alpha1.PodOverrides{}
-				}
-				for _, e := range envFlags {
-					parts := strings.SplitN(e, "=", 2)
-					if len(parts) != 2 || parts[0] == "" {
-						return fmt.Errorf("invalid --env value %q: must be NAME=VALUE", e)
-					}
-					po.Env = append(po.Env, corev1.EnvVar{
-						Name:  parts[0],
-						Value: parts[1],
-					})
-				}
+			po, err := buildPodOverrides(timeout, envFlags)
+			if err != nil {
+				return err
 			}
 			if po != nil {
 				task.Spec.PodOverrides = po
@@ -295,7 +171,7 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&prompt, "prompt", "p", "", "task prompt (required)")
-	cmd.Flags().StringVarP(&agentType, "type", "t", "claude-code", "agent type (claude-code, codex, gemini, opencode, cursor)")
+	cmd.Flags().StringVarP(&agentType, "type", "t", "claude-code", "agent type (claude-code, codex, gemini, opencode, cursor, or the name of an AgentType)")
 	cmd.Flags().StringVar(&secret, "secret", "", "secret name with credentials (overrides oauthToken/apiKey in config)")
 	cmd.Flags().StringVar(&credentialType, "credential-type", "api-key", "credential type (api-key, oauth, none)")
 	cmd.Flags().StringVar(&model, "model", "", "model override")
@@ -310,15 +186,172 @@ func newRunCommand(cfg *ClientConfig) *cobra.Command {
 	cmd.Flags().StringVar(&agentConfigRef, "agent-config", "", "name of AgentConfig resource to use")
 	cmd.Flags().StringArrayVar(&dependsOn, "depends-on", nil, "Task names this task depends on (repeatable)")
 	cmd.Flags().StringVar(&branch, "branch", "", "Git branch to work on")
+	cmd.Flags().StringVar(&sessionPVC, "session-pvc", "", "PersistentVolumeClaim that stores the agent session so the task can be continued")
 
 	cmd.MarkFlagRequired("prompt")
 
 	_ = cmd.RegisterFlagCompletionFunc("credential-type", cobra.FixedCompletions([]string{"api-key", "oauth", "none"}, cobra.ShellCompDirectiveNoFileComp))
-	_ = cmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions([]string{"claude-code", "codex", "gemini", "opencode", "cursor"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("type", completeAgentTypes(cfg))
 
 	return cmd
 }
 
+// buildPodOverrides returns PodOverrides for the --timeout and --env flags,
+// or nil when neither is set.
+func buildPodOverrides(timeout string, envFlags []string) (*kelosv1alpha1.PodOverrides, error) {
+	var po *kelosv1alpha1.PodOverrides
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --timeout value %q: %w", timeout, err)
+		}
+		secs := int64(d.Seconds())
+		if secs < 1 {
+			return nil, fmt.Errorf("--timeout must be at least 1s")
+		}
+		po = &kelosv1alpha1.PodOverrides{}
+		po.ActiveDeadlineSeconds = &secs
+	}
+	if len(envFlags) > 0 {
+		if po == nil {
+			po = &kelosv1alpha1.PodOverrides{}
+		}
+		for _, e := range envFlags {
+			parts := strings.SplitN(e, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return nil, fmt.Errorf("invalid --env value %q: must be NAME=VALUE", e)
+			}
+			po.Env = append(po.Env, corev1.EnvVar{
+				Name:  parts[0],
+				Value: parts[1],
+			})
+		}
+	}
+	return po, nil
+}
+
+// resolveConfigCredentials returns the secret name and credential type to use
+// for an agent. An explicit secret takes precedence; otherwise the oauthToken
+// or apiKey from the config file is stored in the kelos-credentials secret,
+// which is skipped in dry-run mode.
+func resolveConfigCredentials(cfg *ClientConfig, agentType, secret, credentialType string, dryRun, yes bool) (string, string, error) {
+	if secret == "" && cfg.Config != nil {
+		sources := 0
+		if cfg.Config.OAuthToken != "" {
+			sources++
+		}
+		if cfg.Config.APIKey != "" {
+			sources++
+		}
+		if sources > 1 {
+			return "", "", fmt.Errorf("config file must specify only one of oauthToken or apiKey")
+		}
+		if token := cfg.Config.OAuthToken; token != "" {
+			resolved, err := resolveContent(token)
+			if err != nil {
+				return "", "", fmt.Errorf("resolving oauthToken: %w", err)
+			}
+			if !dryRun {
+				oauthKey := oauthSecretKey(agentType, configAgentType(cfg, agentType))
+				if err := ensureCredentialSecret(cfg, "kelos-credentials", oauthKey, resolveCredentialValue(resolved), yes); err != nil {
+					return "", "", err
+				}
+			}
+			secret = "kelos-credentials"
+			credentialType = "oauth"
+		} else if key := cfg.Config.APIKey; key != "" {
+			resolved, err := resolveContent(key)
+			if err != nil {
+				return "", "", fmt.Errorf("resolving apiKey: %w", err)
+			}
+			if !dryRun {
+				apiKey := apiKeySecretKey(agentType, configAgentType(cfg, agentType))
+				if err := ensureCredentialSecret(cfg, "kelos-credentials", apiKey, resolveCredentialValue(resolved), yes); err != nil {
+					return "", "", err
+				}
+			}
+			secret = "kelos-credentials"
+			credentialType = "api-key"
+		}
+	}
+
+	if secret == "" && credentialType != "none" {
+		return "", "", fmt.Errorf("no credentials configured (set oauthToken/apiKey in config file, or use --secret flag)")
+	}
+	return secret, credentialType, nil
+}
+
+// ensureConfigWorkspace creates or updates the kelos-workspace Workspace from
+// the inline workspace in the config file and returns its name. It returns an
+// empty name when the config file has no inline workspace. In dry-run mode
+// the name is returned without touching the cluster.
+func ensureConfigWorkspace(cfg *ClientConfig, cl client.Client, ns string, dryRun, yes bool) (string, error) {
+	if cfg.Config == nil || cfg.Config.Workspace.Repo == "" {
+		return "", nil
+	}
+	wsName := "kelos-workspace"
+	if dryRun {
+		return wsName, nil
+	}
+
+	wsCfg := cfg.Config.Workspace
+	if wsCfg.Token != "" && wsCfg.GitHubApp != nil {
+		return "", fmt.Errorf("workspace config must specify either token or githubApp, not both")
+	}
+
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      wsName,
+			Namespace: ns,
+		},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo: wsCfg.Repo,
+			Ref:  wsCfg.Ref,
+		},
+	}
+	if wsCfg.Token != "" {
+		if err := ensureCredentialSecret(cfg, "kelos-workspace-credentials", "GITHUB_TOKEN", wsCfg.Token, yes); err != nil {
+			return "", err
+		}
+		ws.Spec.SecretRef = &kelosv1alpha1.SecretReference{
+			Name: "kelos-workspace-credentials",
+		}
+	} else if wsCfg.GitHubApp != nil {
+		if err := ensureGitHubAppSecret(cfg, "kelos-workspace-credentials", wsCfg.GitHubApp, yes); err != nil {
+			return "", err
+		}
+		ws.Spec.SecretRef = &kelosv1alpha1.SecretReference{
+			Name: "kelos-workspace-credentials",
+		}
+	}
+	ctx := context.Background()
+	if err := cl.Create(ctx, ws); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("creating workspace: %w", err)
+		}
+		existing := &kelosv1alpha1.Workspace{}
+		if err := cl.Get(ctx, client.ObjectKey{Name: wsName, Namespace: ns}, existing); err != nil {
+			return "", fmt.Errorf("fetching existing workspace: %w", err)
+		}
+		if !reflect.DeepEqual(existing.Spec, ws.Spec) {
+			if !yes {
+				ok, confirmErr := confirmOverride(fmt.Sprintf("workspace/%s", wsName))
+				if confirmErr != nil {
+					return "", confirmErr
+				}
+				if !ok {
+					return "", fmt.Errorf("aborted")
+				}
+			}
+			existing.Spec = ws.Spec
+			if err := cl.Update(ctx, existing); err != nil {
+				return "", fmt.Errorf("updating workspace: %w", err)
+			}
+		}
+	}
+	return wsName, nil
+}
+
 func watchTask(ctx context.Context, cl client.Client, name, namespace string) error {
 	var lastPhase kelosv1alpha1.TaskPhase
 	for {
@@ -340,10 +373,53 @@ func watchTask(ctx context.Context, cl client.Client, name, namespace string) er
 	}
 }
 
+// builtinAgentTypes lists the agent types Kelos ships images for.
+var builtinAgentTypes = []string{"claude-code", "codex", "gemini", "opencode", "cursor"}
+
+func isBuiltinAgentType(agentType string) bool {
+	for _, t := range builtinAgentTypes {
+		if t == agentType {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupAgentType returns the AgentType registered under the name of an
+// agent type, or nil when there is none or it cannot be read.
+func lookupAgentType(ctx context.Context, cl client.Client, agentType string) *kelosv1alpha1.AgentType {
+	if agentType == "" {
+		return nil
+	}
+	at := &kelosv1alpha1.AgentType{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: agentType}, at); err != nil {
+		return nil
+	}
+	return at
+}
+
+// configAgentType looks up the AgentType of agentType with a client built
+// from cfg.
+func configAgentType(cfg *ClientConfig, agentType string) *kelosv1alpha1.AgentType {
+	cl, _, err := cfg.NewClient()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return lookupAgentType(ctx, cl, agentType)
+}
+
 // apiKeySecretKey returns the secret key name for API key credentials
-// based on the agent type.
-func apiKeySecretKey(agentType string) string {
+// based on the agent type. Custom agent types use the variable mapped by
+// their AgentType, or KELOS_API_KEY.
+func apiKeySecretKey(agentType string, at *kelosv1alpha1.AgentType) string {
+	if at != nil && at.Spec.Credentials.APIKeyEnv != "" {
+		return at.Spec.Credentials.APIKeyEnv
+	}
 	switch agentType {
+	case "claude-code", "":
+		return "ANTHROPIC_API_KEY"
 	case "codex":
 		return "CODEX_API_KEY"
 	case "gemini":
@@ -353,14 +429,20 @@ func apiKeySecretKey(agentType string) string {
 	case "cursor":
 		return "CURSOR_API_KEY"
 	default:
-		return "ANTHROPIC_API_KEY"
+		return "KELOS_API_KEY"
 	}
 }
 
 // oauthSecretKey returns the secret key name for OAuth credentials
-// based on the agent type.
-func oauthSecretKey(agentType string) string {
+// based on the agent type. Custom agent types use the variable mapped by
+// their AgentType, or KELOS_OAUTH_TOKEN.
+func oauthSecretKey(agentType string, at *kelosv1alpha1.AgentType) string {
+	if at != nil && at.Spec.Credentials.OAuthEnv != "" {
+		return at.Spec.Credentials.OAuthEnv
+	}
 	switch agentType {
+	case "claude-code", "":
+		return "CLAUDE_CODE_OAUTH_TOKEN"
 	case "codex":
 		return "CODEX_AUTH_JSON"
 	case "gemini":
@@ -370,7 +452,7 @@ func oauthSecretKey(agentType string) string {
 	case "cursor":
 		return "CURSOR_API_KEY"
 	default:
-		return "CLAUDE_CODE_OAUTH_TOKEN"
+		return "KELOS_OAUTH_TOKEN"
 	}
 }
 
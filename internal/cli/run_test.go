@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func TestCredentialSecretKeys(t *testing.T) {
+	mapped := &kelosv1alpha1.AgentType{
+		ObjectMeta: metav1.ObjectMeta{Name: "aider"},
+		Spec: kelosv1alpha1.AgentTypeSpec{
+			Image: "example.com/aider:latest",
+			Credentials: kelosv1alpha1.AgentTypeCredentials{
+				APIKeyEnv: "AIDER_API_KEY",
+				OAuthEnv:  "AIDER_TOKEN",
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		agentType  string
+		at         *kelosv1alpha1.AgentType
+		wantAPIKey string
+		wantOAuth  string
+	}{
+		{"claude-code", "claude-code", nil, "ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN"},
+		{"codex", "codex", nil, "CODEX_API_KEY", "CODEX_AUTH_JSON"},
+		{"custom type without AgentType", "aider", nil, "KELOS_API_KEY", "KELOS_OAUTH_TOKEN"},
+		{"custom type with mapping", "aider", mapped, "AIDER_API_KEY", "AIDER_TOKEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apiKeySecretKey(tt.agentType, tt.at); got != tt.wantAPIKey {
+				t.Errorf("apiKeySecretKey() = %q, want %q", got, tt.wantAPIKey)
+			}
+			if got := oauthSecretKey(tt.agentType, tt.at); got != tt.wantOAuth {
+				t.Errorf("oauthSecretKey() = %q, want %q", got, tt.wantOAuth)
+			}
+		})
+	}
+}
+
+func TestLookupAgentType(t *testing.T) {
+	at := &kelosv1alpha1.AgentType{
+		ObjectMeta: metav1.ObjectMeta{Name: "aider"},
+		Spec:       kelosv1alpha1.AgentTypeSpec{Image: "example.com/aider:latest"},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(at).Build()
+
+	if got := lookupAgentType(context.Background(), cl, "aider"); got == nil || got.Spec.Image != at.Spec.Image {
+		t.Errorf("lookupAgentType(aider) = %v, want the registered AgentType", got)
+	}
+	if got := lookupAgentType(context.Background(), cl, "missing"); got != nil {
+		t.Errorf("lookupAgentType(missing) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"strings"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+// agentConfigRefs returns the AgentConfigs a Task references, in merge
+// order.
+func agentConfigRefs(spec *kelosv1alpha1.TaskSpec) []kelosv1alpha1.AgentConfigReference {
+	if spec.AgentConfigRef != nil {
+		return []kelosv1alpha1.AgentConfigReference{*spec.AgentConfigRef}
+	}
+	return spec.AgentConfigRefs
+}
+
+// mergeAgentConfigs merges AgentConfigs in order into a single spec.
+// AgentsMD is concatenated with blank lines in between. Plugins, MCP
+// servers and sidecars are merged by name: a later entry replaces an
+// earlier one with the same name in place. Skills are deduplicated.
+func mergeAgentConfigs(specs []kelosv1alpha1.AgentConfigSpec) *kelosv1alpha1.AgentConfigSpec {
+	if len(specs) == 1 {
+		return &specs[0]
+	}
+
+	merged := &kelosv1alpha1.AgentConfigSpec{}
+	var agentsMD []string
+	plugins := map[string]int{}
+	mcpServers := map[string]int{}
+	sidecars := map[string]int{}
+	skills := map[kelosv1alpha1.SkillsShSpec]bool{}
+
+	for _, spec := range specs {
+		if md := strings.TrimRight(spec.AgentsMD, "\n"); md != "" {
+			agentsMD = append(agentsMD, md)
+		}
+		for _, p := range spec.Plugins {
+			if i, ok := plugins[p.Name]; ok {
+				merged.Plugins[i] = p
+				continue
+			}
+			plugins[p.Name] = len(merged.Plugins)
+			merged.Plugins = append(merged.Plugins, p)
+		}
+		for _, s := range spec.Skills {
+			if skills[s] {
+				continue
+			}
+			skills[s] = true
+			merged.Skills = append(merged.Skills, s)
+		}
+		for _, m := range spec.MCPServers {
+			if i, ok := mcpServers[m.Name]; ok {
+				merged.MCPServers[i] = m
+				continue
+			}
+			mcpServers[m.Name] = len(merged.MCPServers)
+			merged.MCPServers = append(merged.MCPServers, m)
+		}
+		for _, c := range spec.Sidecars {
+			if i, ok := sidecars[c.Name]; ok {
+				merged.Sidecars[i] = c
+				continue
+			}
+			sidecars[c.Name] = len(merged.Sidecars)
+			merged.Sidecars = append(merged.Sidecars, c)
+		}
+	}
+	if len(agentsMD) > 0 {
+		merged.AgentsMD = strings.Join(agentsMD, "\n\n")
+	}
+	return merged
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func TestAgentConfigRefs(t *testing.T) {
+	single := &kelosv1alpha1.TaskSpec{
+		AgentConfigRef: &kelosv1alpha1.AgentConfigReference{Name: "only"},
+	}
+	if got := agentConfigRefs(single); len(got) != 1 || got[0].Name != "only" {
+		t.Errorf("Expected [only], got %v", got)
+	}
+
+	list := &kelosv1alpha1.TaskSpec{
+		AgentConfigRefs: []kelosv1alpha1.AgentConfigReference{{Name: "base"}, {Name: "team"}},
+	}
+	if got := agentConfigRefs(list); len(got) != 2 || got[0].Name != "base" || got[1].Name != "team" {
+		t.Errorf("Expected [base team], got %v", got)
+	}
+
+	if got := agentConfigRefs(&kelosv1alpha1.TaskSpec{}); len(got) != 0 {
+		t.Errorf("Expected no refs, got %v", got)
+	}
+}
+
+func TestMergeAgentConfigs(t *testing.T) {
+	base := kelosv1alpha1.AgentConfigSpec{
+		AgentsMD: "Never commit secrets.\n",
+		Plugins: []kelosv1alpha1.PluginSpec{
+			{Name: "security", Skills: []kelosv1alpha1.SkillDefinition{{Name: "audit", Content: "base"}}},
+			{Name: "review"},
+		},
+		Skills: []kelosv1alpha1.SkillsShSpec{
+			{Source: "acme/skills", Skill: "lint"},
+		},
+		MCPServers: []kelosv1alpha1.MCPServerSpec{
+			{Name: "internal-docs", Type: "http", URL: "https://docs.internal/mcp"},
+			{Name: "github", Type: "http", URL: "https://api.githubcopilot.com/mcp/"},
+		},
+		Sidecars: []corev1.Container{{Name: "postgres", Image: "postgres:15"}},
+	}
+	team := kelosv1alpha1.AgentConfigSpec{
+		AgentsMD: "Use pnpm.",
+		Plugins: []kelosv1alpha1.PluginSpec{
+			{Name: "security", Skills: []kelosv1alpha1.SkillDefinition{{Name: "audit", Content: "team"}}},
+			{Name: "frontend"},
+		},
+		Skills: []kelosv1alpha1.SkillsShSpec{
+			{Source: "acme/skills", Skill: "lint"},
+			{Source: "acme/skills", Skill: "test"},
+		},
+		MCPServers: []kelosv1alpha1.MCPServerSpec{
+			{Name: "github", Type: "stdio", Command: "github-mcp-server"},
+		},
+		Sidecars: []corev1.Container{{Name: "postgres", Image: "postgres:16"}, {Name: "redis", Image: "redis:7"}},
+	}
+
+	got := mergeAgentConfigs([]kelosv1alpha1.AgentConfigSpec{base, {}, team})
+
+	if got.AgentsMD != "Never commit secrets.\n\nUse pnpm." {
+		t.Errorf("Unexpected AgentsMD %q", got.AgentsMD)
+	}
+
+	wantPlugins := []kelosv1alpha1.PluginSpec{
+		{Name: "security", Skills: []kelosv1alpha1.SkillDefinition{{Name: "audit", Content: "team"}}},
+		{Name: "review"},
+		{Name: "frontend"},
+	}
+	if !reflect.DeepEqual(got.Plugins, wantPlugins) {
+		t.Errorf("Expected plugins %+v, got %+v", wantPlugins, got.Plugins)
+	}
+
+	wantSkills := []kelosv1alpha1.SkillsShSpec{
+		{Source: "acme/skills", Skill: "lint"},
+		{Source: "acme/skills", Skill: "test"},
+	}
+	if !reflect.DeepEqual(got.Skills, wantSkills) {
+		t.Errorf("Expected skills %+v, got %+v", wantSkills, got.Skills)
+	}
+
+	wantMCP := []kelosv1alpha1.MCPServerSpec{
+		{Name: "internal-docs", Type: "http", URL: "https://docs.internal/mcp"},
+		{Name: "github", Type: "stdio", Command: "github-mcp-server"},
+	}
+	if !reflect.DeepEqual(got.MCPServers, wantMCP) {
+		t.Errorf("Expected MCP servers %+v, got %+v", wantMCP, got.MCPServers)
+	}
+
+	wantSidecars := []corev1.Container{{Name: "postgres", Image: "postgres:16"}, {Name: "redis", Image: "redis:7"}}
+	if !reflect.DeepEqual(got.Sidecars, wantSidecars) {
+		t.Errorf("Expected sidecars %+v, got %+v", wantSidecars, got.Sidecars)
+	}
+
+	if base.Plugins[0].Skills[0].Content != "base" {
+		t.Error("Expected merge not to modify its inputs")
+	}
+}
+
+func TestMergeAgentConfigsSingle(t *testing.T) {
+	spec := kelosv1alpha1.AgentConfigSpec{AgentsMD: "Be concise.\n"}
+	got := mergeAgentConfigs([]kelosv1alpha1.AgentConfigSpec{spec})
+	if !reflect.DeepEqual(*got, spec) {
+		t.Errorf("Expected a single AgentConfig to be used unchanged, got %+v", got)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -60,6 +63,62 @@ const (
 	// PluginMountPath is the mount path for the plugin volume.
 	PluginMountPath = "/kelos/plugin"
 
+	// SessionVolumeName is the name of the agent session volume.
+	SessionVolumeName = "kelos-session"
+
+	// SessionMountPath is the mount path for the agent session volume.
+	// Each Task's session is stored in a subdirectory named after the Task.
+	SessionMountPath = "/kelos/session"
+
+	// TranscriptVolumeName is the name of the transcript archive volume.
+	TranscriptVolumeName = "kelos-transcripts"
+
+	// TranscriptMountPath is the mount path for the transcript archive volume.
+	TranscriptMountPath = "/kelos/transcripts"
+
+	// GitCacheVolumeName is the name of the Workspace git cache volume.
+	GitCacheVolumeName = "kelos-git-cache"
+
+	// GitCacheMountPath is the mount path for the Workspace git cache volume.
+	GitCacheMountPath = "/kelos/git-cache"
+
+	// GitCacheMirrorPath is the path of the bare mirror on the git cache
+	// volume that Tasks clone with as a reference.
+	GitCacheMirrorPath = GitCacheMountPath + "/mirror.git"
+
+	// DependencyCacheVolumeName is the name of the Workspace dependency
+	// cache volume.
+	DependencyCacheVolumeName = "kelos-dependency-cache"
+
+	// DependencyCacheMountPath is the mount path for the Workspace
+	// dependency cache volume.
+	DependencyCacheMountPath = "/kelos/dependency-cache"
+
+	// MCPConfigVolumeName is the name of the volume holding the MCP
+	// configuration file written for an AgentType.
+	MCPConfigVolumeName = "kelos-mcp-config"
+
+	// MCPConfigMountPath is the mount path for the MCP configuration
+	// volume in the init container that writes it.
+	MCPConfigMountPath = "/kelos/mcp-config"
+
+	// mcpConfigFileName is the name of the MCP configuration file on its
+	// volume.
+	mcpConfigFileName = "config"
+
+	// workspaceFileSourcePath is where the ConfigMaps and Secrets that
+	// Workspace files are read from are mounted in the workspace-files
+	// init container.
+	workspaceFileSourcePath = "/kelos/files"
+
+	// WorkspaceSetupContainerName is the name of the init container that
+	// runs the setup commands of a Workspace.
+	WorkspaceSetupContainerName = "workspace-setup"
+
+	// DefaultWorkspaceSetupTimeoutSeconds limits the Workspace setup
+	// commands when spec.setup.timeoutSeconds is not set.
+	DefaultWorkspaceSetupTimeoutSeconds = int64(600)
+
 	// NodeImage is the image used for running Node.js-based init containers
 	// (e.g., installing skills.sh packages).
 	NodeImage = "node:22.14.0-alpine"
@@ -70,6 +129,23 @@ const (
 	// image's home directory.
 	GHConfigDir = WorkspaceMountPath + "/.gh-config"
 
+	// GLabConfigDir is the directory used for glab CLI configuration when
+	// a GitLab workspace has a secretRef, for the same reason as
+	// GHConfigDir.
+	GLabConfigDir = WorkspaceMountPath + "/.glab-config"
+
+	// SSHKeyVolumeName is the name of the volume holding the Workspace SSH
+	// key Secret.
+	SSHKeyVolumeName = "kelos-ssh-key"
+
+	// SSHKeyMountPath is where the Workspace SSH key Secret is mounted in
+	// the containers that copy it.
+	SSHKeyMountPath = "/kelos/ssh-key"
+
+	// SSHDir holds the copy of the Workspace SSH key with the permissions
+	// ssh requires, on the shared workspace volume.
+	SSHDir = WorkspaceMountPath + "/.ssh"
+
 	// AgentUID is the UID shared between the git-clone init
 	// container and the agent container. Custom agent images must run
 	// as this UID so that both containers can read and write the
@@ -108,22 +184,43 @@ func NewJobBuilder() *JobBuilder {
 // Build creates a Job for the given Task. The prompt parameter is the
 // resolved prompt text (which may have been expanded from a template).
 func (b *JobBuilder) Build(task *kelosv1alpha1.Task, workspace *kelosv1alpha1.WorkspaceSpec, agentConfig *kelosv1alpha1.AgentConfigSpec, prompt string) (*batchv1.Job, error) {
+	return b.BuildWithAgentType(task, nil, workspace, agentConfig, prompt)
+}
+
+// BuildWithAgentType creates a Job for a Task whose type is described by
+// the AgentType with the Task's type name. A nil agentType builds one of the
+// built-in agent types.
+func (b *JobBuilder) BuildWithAgentType(task *kelosv1alpha1.Task, agentType *kelosv1alpha1.AgentTypeSpec, workspace *kelosv1alpha1.WorkspaceSpec, agentConfig *kelosv1alpha1.AgentConfigSpec, prompt string) (*batchv1.Job, error) {
+	if agentType != nil {
+		return b.buildAgentJob(task, workspace, agentConfig, agentType, agentType.Image, agentType.ImagePullPolicy, prompt)
+	}
 	switch task.Spec.Type {
 	case AgentTypeClaudeCode:
-		return b.buildAgentJob(task, workspace, agentConfig, b.ClaudeCodeImage, b.ClaudeCodeImagePullPolicy, prompt)
+		return b.buildAgentJob(task, workspace, agentConfig, nil, b.ClaudeCodeImage, b.ClaudeCodeImagePullPolicy, prompt)
 	case AgentTypeCodex:
-		return b.buildAgentJob(task, workspace, agentConfig, b.CodexImage, b.CodexImagePullPolicy, prompt)
+		return b.buildAgentJob(task, workspace, agentConfig, nil, b.CodexImage, b.CodexImagePullPolicy, prompt)
 	case AgentTypeGemini:
-		return b.buildAgentJob(task, workspace, agentConfig, b.GeminiImage, b.GeminiImagePullPolicy, prompt)
+		return b.buildAgentJob(task, workspace, agentConfig, nil, b.GeminiImage, b.GeminiImagePullPolicy, prompt)
 	case AgentTypeOpenCode:
-		return b.buildAgentJob(task, workspace, agentConfig, b.OpenCodeImage, b.OpenCodeImagePullPolicy, prompt)
+		return b.buildAgentJob(task, workspace, agentConfig, nil, b.OpenCodeImage, b.OpenCodeImagePullPolicy, prompt)
 	case AgentTypeCursor:
-		return b.buildAgentJob(task, workspace, agentConfig, b.CursorImage, b.CursorImagePullPolicy, prompt)
+		return b.buildAgentJob(task, workspace, agentConfig, nil, b.CursorImage, b.CursorImagePullPolicy, prompt)
 	default:
 		return nil, fmt.Errorf("unsupported agent type: %s", task.Spec.Type)
 	}
 }
 
+// isBuiltinAgentType reports whether the agent type is built into the
+// controller and does not need an AgentType.
+func isBuiltinAgentType(agentType string) bool {
+	switch agentType {
+	case AgentTypeClaudeCode, AgentTypeCodex, AgentTypeGemini, AgentTypeOpenCode, AgentTypeCursor:
+		return true
+	default:
+		return false
+	}
+}
+
 // apiKeyEnvVar returns the environment variable name used for API key
 // credentials for the given agent type.
 func apiKeyEnvVar(agentType string) string {
@@ -168,10 +265,35 @@ func oauthEnvVar(agentType string) string {
 	}
 }
 
+// agentTypeAPIKeyEnvVar returns the API key environment variable of an
+// agent type, preferring the AgentType's mapping over the built-in one.
+// Agents without either read KELOS_API_KEY.
+func agentTypeAPIKeyEnvVar(agentType string, spec *kelosv1alpha1.AgentTypeSpec) string {
+	if spec != nil && spec.Credentials.APIKeyEnv != "" {
+		return spec.Credentials.APIKeyEnv
+	}
+	if !isBuiltinAgentType(agentType) {
+		return "KELOS_API_KEY"
+	}
+	return apiKeyEnvVar(agentType)
+}
+
+// agentTypeOAuthEnvVar is agentTypeAPIKeyEnvVar for OAuth credentials.
+// Agents without a mapping read KELOS_OAUTH_TOKEN.
+func agentTypeOAuthEnvVar(agentType string, spec *kelosv1alpha1.AgentTypeSpec) string {
+	if spec != nil && spec.Credentials.OAuthEnv != "" {
+		return spec.Credentials.OAuthEnv
+	}
+	if !isBuiltinAgentType(agentType) {
+		return "KELOS_OAUTH_TOKEN"
+	}
+	return oauthEnvVar(agentType)
+}
+
 // credentialEnvVars returns the environment variables to inject for the given
 // credentials and agent type. This centralises all credential-type-specific
 // logic so that new providers (e.g. Vertex) only need to add a case here.
-func credentialEnvVars(creds kelosv1alpha1.Credentials, agentType string) []corev1.EnvVar {
+func credentialEnvVars(creds kelosv1alpha1.Credentials, agentType string, spec *kelosv1alpha1.AgentTypeSpec) []corev1.EnvVar {
 	secretName := ""
 	if creds.SecretRef != nil {
 		secretName = creds.SecretRef.Name
@@ -193,11 +315,11 @@ func credentialEnvVars(creds kelosv1alpha1.Credentials, agentType string) []core
 
 	switch creds.Type {
 	case kelosv1alpha1.CredentialTypeAPIKey:
-		keyName := apiKeyEnvVar(agentType)
+		keyName := agentTypeAPIKeyEnvVar(agentType, spec)
 		return []corev1.EnvVar{secretEnvRef(keyName, false)}
 
 	case kelosv1alpha1.CredentialTypeOAuth:
-		tokenName := oauthEnvVar(agentType)
+		tokenName := agentTypeOAuthEnvVar(agentType, spec)
 		return []corev1.EnvVar{secretEnvRef(tokenName, false)}
 
 	case kelosv1alpha1.CredentialTypeNone:
@@ -235,8 +357,9 @@ func upstreamRepoEnvValue(remotes []kelosv1alpha1.GitRemote) string {
 	return ""
 }
 
-// buildAgentJob creates a Job for the given agent type.
-func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1alpha1.WorkspaceSpec, agentConfig *kelosv1alpha1.AgentConfigSpec, defaultImage string, pullPolicy corev1.PullPolicy, prompt string) (*batchv1.Job, error) {
+// buildAgentJob creates a Job for the given agent type. agentType is the
+// AgentType of the Task's type, if any.
+func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1alpha1.WorkspaceSpec, agentConfig *kelosv1alpha1.AgentConfigSpec, agentType *kelosv1alpha1.AgentTypeSpec, defaultImage string, pullPolicy corev1.PullPolicy, prompt string) (*batchv1.Job, error) {
 	image := defaultImage
 	if task.Spec.Image != "" {
 		image = task.Spec.Image
@@ -264,6 +387,13 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		Value: task.Spec.Type,
 	})
 
+	if agentType != nil && agentType.UsageFormat != "" {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "KELOS_USAGE_FORMAT",
+			Value: agentType.UsageFormat,
+		})
+	}
+
 	if spawner := task.Labels["kelos.dev/taskspawner"]; spawner != "" {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "KELOS_TASKSPAWNER",
@@ -271,15 +401,16 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		})
 	}
 
-	credEnvVars := credentialEnvVars(task.Spec.Credentials, task.Spec.Type)
+	credEnvVars := credentialEnvVars(task.Spec.Credentials, task.Spec.Type, agentType)
 	envVars = append(envVars, credEnvVars...)
 
 	var workspaceEnvVars []corev1.EnvVar
 	var isEnterprise bool
+	provider := workspaceProvider(workspace)
 	effectiveRemotes := effectiveWorkspaceRemotes(workspace)
 	if workspace != nil {
 		host, _, _ := parseGitHubRepo(workspace.Repo)
-		isEnterprise = host != "" && host != "github.com"
+		isEnterprise = provider == kelosv1alpha1.GitProviderGitHub && host != "" && host != "github.com"
 
 		if isEnterprise {
 			// Set GH_HOST for GitHub Enterprise so that gh CLI targets the correct host.
@@ -310,7 +441,17 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		}
 	}
 
-	if workspace != nil && workspace.SecretRef != nil {
+	if workspace != nil && workspace.SecretRef != nil && provider != kelosv1alpha1.GitProviderGitHub {
+		providerEnv := providerEnvVars(provider, workspace.SecretRef.Name, workspace.Repo)
+		envVars = append(envVars, providerEnv...)
+		workspaceEnvVars = append(workspaceEnvVars, providerEnv...)
+		if provider == kelosv1alpha1.GitProviderGitLab {
+			envVars = append(envVars, corev1.EnvVar{
+				Name:  "GLAB_CONFIG_DIR",
+				Value: GLabConfigDir,
+			})
+		}
+	} else if workspace != nil && workspace.SecretRef != nil {
 		secretKeyRef := &corev1.SecretKeySelector{
 			LocalObjectReference: corev1.LocalObjectReference{
 				Name: workspace.SecretRef.Name,
@@ -345,6 +486,12 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		})
 	}
 
+	if workspace != nil && workspace.SSHKeySecretRef != nil {
+		sshEnv := corev1.EnvVar{Name: "GIT_SSH_COMMAND", Value: gitSSHCommand(SSHDir)}
+		envVars = append(envVars, sshEnv)
+		workspaceEnvVars = append(workspaceEnvVars, sshEnv)
+	}
+
 	backoffLimit := int32(1)
 	agentUID := AgentUID
 
@@ -379,42 +526,137 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 			MountPath: WorkspaceMountPath,
 		}
 
+		// The git cache holds the objects that the clone borrows through
+		// its alternates file, so every container using the repository
+		// mounts it at the same path.
+		var gitCacheMounts []corev1.VolumeMount
+		if workspace.Cache != nil && task.Spec.WorkspaceRef != nil {
+			volumes = append(volumes, corev1.Volume{
+				Name: GitCacheVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: GitCacheClaimName(task.Spec.WorkspaceRef.Name),
+						ReadOnly:  true,
+					},
+				},
+			})
+			gitCacheMounts = append(gitCacheMounts, corev1.VolumeMount{
+				Name:      GitCacheVolumeName,
+				MountPath: GitCacheMountPath,
+				ReadOnly:  true,
+			})
+		}
+
+		depth := int32(1)
+		if workspace.Depth != nil {
+			depth = *workspace.Depth
+		}
+
 		cloneArgs := []string{"clone"}
 		if workspace.Ref != "" {
 			cloneArgs = append(cloneArgs, "--branch", workspace.Ref)
 		}
-		cloneArgs = append(cloneArgs, "--no-single-branch", "--depth", "1", "--", workspace.Repo, WorkspaceMountPath+"/repo")
+		if len(gitCacheMounts) > 0 {
+			// Falls back to a plain clone until the first cache Job has
+			// populated the mirror.
+			cloneArgs = append(cloneArgs, "--reference-if-able", GitCacheMirrorPath)
+		}
+		if len(workspace.SparseCheckout) > 0 {
+			// Only download the blobs of the sparse paths when they are
+			// checked out.
+			cloneArgs = append(cloneArgs, "--sparse", "--filter=blob:none")
+		}
+		cloneArgs = append(cloneArgs, "--no-single-branch")
+		if depth > 0 {
+			cloneArgs = append(cloneArgs, "--depth", strconv.Itoa(int(depth)))
+		}
+		cloneArgs = append(cloneArgs, "--", workspace.Repo, WorkspaceMountPath+"/repo")
+
+		// gitCmd runs git with the workspace credentials. Configuration
+		// passed with -c is inherited by the git processes that clone
+		// submodules and fetch LFS objects.
+		gitCmd := "git"
+		auth := providerGitAuth(provider)
+		credentialHelper := auth.credentialHelper(auth.tokenKey, auth.usernameKey)
+		if workspace.SecretRef != nil {
+			gitCmd = fmt.Sprintf(`git -c credential.helper= -c credential.helper='%s'`, credentialHelper)
+		}
+
+		postCloneSteps, err := buildPostCloneSteps(workspace, gitCmd, depth)
+		if err != nil {
+			return nil, err
+		}
 
 		initContainer := corev1.Container{
 			Name:         "git-clone",
 			Image:        GitCloneImage,
 			Args:         cloneArgs,
 			Env:          workspaceEnvVars,
-			VolumeMounts: []corev1.VolumeMount{volumeMount},
+			VolumeMounts: append([]corev1.VolumeMount{volumeMount}, gitCacheMounts...),
 			SecurityContext: &corev1.SecurityContext{
 				RunAsUser: &agentUID,
 			},
 		}
+		if workspace.SSHKeySecretRef != nil {
+			volumes = append(volumes, sshKeyVolume(workspace.SSHKeySecretRef.Name))
+			initContainer.VolumeMounts = append(initContainer.VolumeMounts, corev1.VolumeMount{
+				Name:      SSHKeyVolumeName,
+				MountPath: SSHKeyMountPath,
+				ReadOnly:  true,
+			})
+		}
 
-		if workspace.SecretRef != nil {
-			credentialHelper := `!f() { echo "username=x-access-token"; echo "password=$GITHUB_TOKEN"; }; f`
-			// Clear inherited credential helpers with an empty -c credential.helper=
-			// before setting the workspace helper, then persist the same
-			// configuration into the repo so the agent container is
-			// independent from global/system helpers.
-			initContainer.Command = []string{"sh", "-c",
-				fmt.Sprintf(
-					`git -c credential.helper= -c credential.helper='%s' "$@" && { `+
+		if workspace.SecretRef != nil || workspace.SSHKeySecretRef != nil || len(postCloneSteps) > 0 {
+			script := `git "$@"`
+			if workspace.SecretRef != nil {
+				// Clear inherited credential helpers with an empty -c credential.helper=
+				// before setting the workspace helper, then persist the same
+				// configuration into the repo so the agent container is
+				// independent from global/system helpers.
+				script = fmt.Sprintf(
+					`%s "$@" && { `+
 						`git -C %s/repo config --unset-all credential.helper 2>/dev/null || true; `+
 						`git -C %s/repo config --add credential.helper '%s'; }`,
-					credentialHelper, WorkspaceMountPath, WorkspaceMountPath, credentialHelper,
-				),
+					gitCmd, WorkspaceMountPath, WorkspaceMountPath, credentialHelper,
+				)
+			}
+			if len(postCloneSteps) > 0 {
+				script += fmt.Sprintf(" && cd %s/repo && %s", WorkspaceMountPath, strings.Join(postCloneSteps, " && "))
+			}
+			if workspace.SSHKeySecretRef != nil {
+				// Later containers find the key on the workspace volume.
+				script = sshKeySetupScript(SSHDir) + " && " + script
 			}
+			initContainer.Command = []string{"sh", "-c", script}
 			initContainer.Args = append([]string{"--"}, cloneArgs...)
 		}
 
 		initContainers = append(initContainers, initContainer)
 
+		for _, repo := range workspace.Repos {
+			repoEnv, repoHelper := additionalRepoCredentials(workspace, repo)
+			mainContainer.Env = append(mainContainer.Env, repoEnv...)
+			cloneEnv := workspaceEnvVars
+			if repo.SecretRef != nil {
+				cloneEnv = repoEnv
+				if workspace.SSHKeySecretRef != nil {
+					cloneEnv = append(cloneEnv, corev1.EnvVar{Name: "GIT_SSH_COMMAND", Value: gitSSHCommand(SSHDir)})
+				}
+			}
+			initContainers = append(initContainers, buildAdditionalRepoCloneContainer(
+				repo, cloneEnv, repoHelper, depth, append([]corev1.VolumeMount{volumeMount}, gitCacheMounts...), agentUID))
+		}
+		if len(workspace.Repos) > 0 {
+			reposJSON, err := additionalReposJSON(workspace.Repos)
+			if err != nil {
+				return nil, err
+			}
+			mainContainer.Env = append(mainContainer.Env, corev1.EnvVar{
+				Name:  "KELOS_ADDITIONAL_REPOS",
+				Value: reposJSON,
+			})
+		}
+
 		if len(effectiveRemotes) > 0 {
 			var parts []string
 			parts = append(parts, fmt.Sprintf("cd %s/repo", WorkspaceMountPath))
@@ -434,7 +676,7 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 				Name:         "remote-setup",
 				Image:        GitCloneImage,
 				Command:      []string{"sh", "-c", strings.Join(parts, " && ")},
-				VolumeMounts: []corev1.VolumeMount{volumeMount},
+				VolumeMounts: append([]corev1.VolumeMount{volumeMount}, gitCacheMounts...),
 				SecurityContext: &corev1.SecurityContext{
 					RunAsUser: &agentUID,
 				},
@@ -443,11 +685,11 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		}
 
 		if task.Spec.Branch != "" {
-			fetchCmd := `git fetch origin "$KELOS_BRANCH":"$KELOS_BRANCH" 2>/dev/null`
-			if workspace.SecretRef != nil {
-				credHelper := `!f() { echo "username=x-access-token"; echo "password=$GITHUB_TOKEN"; }; f`
-				fetchCmd = fmt.Sprintf(`git -c credential.helper= -c credential.helper='%s' fetch origin "$KELOS_BRANCH":"$KELOS_BRANCH" 2>/dev/null`, credHelper)
+			fetchArgs := ""
+			if workspace.Depth != nil && depth > 0 {
+				fetchArgs = fmt.Sprintf(" --depth %d", depth)
 			}
+			fetchCmd := fmt.Sprintf(`%s fetch%s origin "$KELOS_BRANCH":"$KELOS_BRANCH" 2>/dev/null`, gitCmd, fetchArgs)
 			branchSetupScript := fmt.Sprintf(
 				`cd %s/repo && %s; `+
 					`if git rev-parse --verify refs/heads/"$KELOS_BRANCH" >/dev/null 2>&1; then `+
@@ -455,8 +697,23 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 					`else git checkout -b "$KELOS_BRANCH"; fi`,
 				WorkspaceMountPath, fetchCmd,
 			)
+			if workspace.Submodules {
+				// The branch may pin different submodule commits.
+				branchSetupScript += " && " + submoduleUpdateCmd(gitCmd, depth)
+			}
 			branchEnv := make([]corev1.EnvVar, len(workspaceEnvVars), len(workspaceEnvVars)+1)
 			copy(branchEnv, workspaceEnvVars)
+			for _, repo := range workspace.Repos {
+				repoEnv, repoHelper := additionalRepoCredentials(workspace, repo)
+				branchEnv = append(branchEnv, repoEnv...)
+				branchSetupScript += fmt.Sprintf(
+					` && cd %s/%s && %s fetch%s origin "$KELOS_BRANCH":"$KELOS_BRANCH" 2>/dev/null; `+
+						`if git rev-parse --verify refs/heads/"$KELOS_BRANCH" >/dev/null 2>&1; then `+
+						`git checkout "$KELOS_BRANCH"; `+
+						`else git checkout -b "$KELOS_BRANCH"; fi`,
+					WorkspaceMountPath, repo.Name, repoGitCmd(repoHelper), fetchArgs,
+				)
+			}
 			branchEnv = append(branchEnv, corev1.EnvVar{
 				Name:  "KELOS_BRANCH",
 				Value: task.Spec.Branch,
@@ -466,7 +723,7 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 				Image:        GitCloneImage,
 				Command:      []string{"sh", "-c", branchSetupScript},
 				Env:          branchEnv,
-				VolumeMounts: []corev1.VolumeMount{volumeMount},
+				VolumeMounts: append([]corev1.VolumeMount{volumeMount}, gitCacheMounts...),
 				SecurityContext: &corev1.SecurityContext{
 					RunAsUser: &agentUID,
 				},
@@ -475,16 +732,17 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		}
 
 		if len(workspace.Files) > 0 {
-			injectionScript, err := buildWorkspaceFileInjectionScript(workspace.Files)
+			injectionScript, fileVolumes, fileMounts, err := buildWorkspaceFileInjection(workspace.Files)
 			if err != nil {
 				return nil, err
 			}
+			volumes = append(volumes, fileVolumes...)
 
 			injectionContainer := corev1.Container{
 				Name:         "workspace-files",
 				Image:        GitCloneImage,
 				Command:      []string{"sh", "-c", injectionScript},
-				VolumeMounts: []corev1.VolumeMount{volumeMount},
+				VolumeMounts: append([]corev1.VolumeMount{volumeMount}, fileMounts...),
 				SecurityContext: &corev1.SecurityContext{
 					RunAsUser: &agentUID,
 				},
@@ -492,8 +750,66 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 			initContainers = append(initContainers, injectionContainer)
 		}
 
-		mainContainer.VolumeMounts = []corev1.VolumeMount{volumeMount}
+		mainContainer.VolumeMounts = append([]corev1.VolumeMount{volumeMount}, gitCacheMounts...)
 		mainContainer.WorkingDir = WorkspaceMountPath + "/repo"
+
+		if workspace.DependencyCache != nil && task.Spec.WorkspaceRef != nil {
+			volumes = append(volumes, corev1.Volume{
+				Name: DependencyCacheVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: DependencyCacheClaimName(task.Spec.WorkspaceRef.Name),
+					},
+				},
+			})
+			mainContainer.VolumeMounts = append(mainContainer.VolumeMounts,
+				corev1.VolumeMount{Name: DependencyCacheVolumeName, MountPath: DependencyCacheMountPath})
+			mainContainer.Env = append(mainContainer.Env, dependencyCacheEnvVars()...)
+		}
+
+		if setup := workspace.Setup; setup != nil && len(setup.Commands) > 0 {
+			setupImage := setup.Image
+			setupPullPolicy := corev1.PullPolicy("")
+			if setupImage == "" {
+				setupImage = image
+				setupPullPolicy = pullPolicy
+			}
+			timeoutSeconds := DefaultWorkspaceSetupTimeoutSeconds
+			if setup.TimeoutSeconds != nil {
+				timeoutSeconds = *setup.TimeoutSeconds
+			}
+			// Setup commands see the same repository, credentials and
+			// caches as the agent.
+			var setupEnv []corev1.EnvVar
+			setupEnv = append(setupEnv, workspaceEnvVars...)
+			var setupMounts []corev1.VolumeMount
+			for _, m := range mainContainer.VolumeMounts {
+				if m.Name == DependencyCacheVolumeName {
+					setupEnv = append(setupEnv, dependencyCacheEnvVars()...)
+				}
+				setupMounts = append(setupMounts, m)
+			}
+			setupEnv = append(setupEnv, setup.Env...)
+			initContainers = append(initContainers, corev1.Container{
+				Name:            WorkspaceSetupContainerName,
+				Image:           setupImage,
+				ImagePullPolicy: setupPullPolicy,
+				Command: []string{"timeout", strconv.FormatInt(timeoutSeconds, 10),
+					"sh", "-c", "set -e\n" + strings.Join(setup.Commands, "\n")},
+				WorkingDir:   WorkspaceMountPath + "/repo",
+				Env:          setupEnv,
+				VolumeMounts: setupMounts,
+				SecurityContext: &corev1.SecurityContext{
+					RunAsUser: &agentUID,
+				},
+			})
+		}
+
+		if len(gitCacheMounts) > 0 || (workspace.DependencyCache != nil && task.Spec.WorkspaceRef != nil) {
+			// Avoid changing the ownership of every cached file on each
+			// pod start.
+			podSecurityContext.FSGroupChangePolicy = ptr(corev1.FSGroupChangeOnRootMismatch)
+		}
 	}
 
 	// Inject AgentConfig: agentsMD env var and plugin volume/init container.
@@ -505,6 +821,20 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 			})
 		}
 
+		// Init containers always populate the plugin volume at
+		// PluginMountPath; an AgentType may mount it elsewhere in the
+		// agent container.
+		agentPluginPath := PluginMountPath
+		skillsAgent := task.Spec.Type
+		if agentType != nil && agentType.Plugins != nil {
+			if agentType.Plugins.MountPath != "" {
+				agentPluginPath = agentType.Plugins.MountPath
+			}
+			if agentType.Plugins.SkillsAgent != "" {
+				skillsAgent = agentType.Plugins.SkillsAgent
+			}
+		}
+
 		needsPluginVolume := len(agentConfig.Plugins) > 0 || len(agentConfig.Skills) > 0
 		if needsPluginVolume {
 			volumes = append(volumes, corev1.Volume{
@@ -512,10 +842,10 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
 			})
 			mainContainer.VolumeMounts = append(mainContainer.VolumeMounts,
-				corev1.VolumeMount{Name: PluginVolumeName, MountPath: PluginMountPath})
+				corev1.VolumeMount{Name: PluginVolumeName, MountPath: agentPluginPath})
 			mainContainer.Env = append(mainContainer.Env, corev1.EnvVar{
 				Name:  "KELOS_PLUGIN_DIR",
-				Value: PluginMountPath,
+				Value: agentPluginPath,
 			})
 		}
 
@@ -536,7 +866,7 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		}
 
 		if len(agentConfig.Skills) > 0 {
-			script, err := buildSkillsInstallScript(agentConfig.Skills, task.Spec.Type)
+			script, err := buildSkillsInstallScript(agentConfig.Skills, skillsAgent)
 			if err != nil {
 				return nil, fmt.Errorf("invalid skills configuration: %w", err)
 			}
@@ -562,7 +892,124 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 				Name:  "KELOS_MCP_SERVERS",
 				Value: mcpJSON,
 			})
+
+			if agentType != nil && agentType.MCP != nil {
+				content := mcpJSON
+				if agentType.MCP.Format == kelosv1alpha1.MCPConfigFormatTOML {
+					content = buildMCPServersTOML(agentConfig.MCPServers)
+				}
+				volumes = append(volumes, corev1.Volume{
+					Name:         MCPConfigVolumeName,
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				})
+				initContainers = append(initContainers, corev1.Container{
+					Name:  "mcp-config",
+					Image: GitCloneImage,
+					Command: []string{"sh", "-c", fmt.Sprintf("printf '%%s' %s | base64 -d > %s/%s",
+						shellQuote(base64.StdEncoding.EncodeToString([]byte(content))), MCPConfigMountPath, mcpConfigFileName)},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: MCPConfigVolumeName, MountPath: MCPConfigMountPath},
+					},
+					SecurityContext: &corev1.SecurityContext{RunAsUser: &agentUID},
+				})
+				// Mount only the file so the rest of the agent's
+				// configuration directory stays intact.
+				mainContainer.VolumeMounts = append(mainContainer.VolumeMounts, corev1.VolumeMount{
+					Name:      MCPConfigVolumeName,
+					MountPath: agentType.MCP.ConfigPath,
+					SubPath:   mcpConfigFileName,
+				})
+			}
+		}
+	}
+
+	// Mount the session claim so the agent can save its session on exit and
+	// restore the session of the Task it resumes.
+	if session := task.Spec.Session; session != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: SessionVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: session.PersistentVolumeClaimName,
+				},
+			},
+		})
+		mainContainer.VolumeMounts = append(mainContainer.VolumeMounts,
+			corev1.VolumeMount{Name: SessionVolumeName, MountPath: SessionMountPath})
+		mainContainer.Env = append(mainContainer.Env, corev1.EnvVar{
+			Name:  "KELOS_SESSION_DIR",
+			Value: path.Join(SessionMountPath, task.Name),
+		})
+		if task.Spec.ResumeFrom != "" {
+			mainContainer.Env = append(mainContainer.Env, corev1.EnvVar{
+				Name:  "KELOS_RESUME_SESSION_DIR",
+				Value: path.Join(SessionMountPath, task.Spec.ResumeFrom),
+			})
+		}
+		if podSecurityContext == nil {
+			podSecurityContext = &corev1.PodSecurityContext{
+				FSGroup: &agentUID,
+			}
+		}
+	}
+
+	// Configure kelos-capture to archive the transcript when the agent
+	// exits. For ConfigMap archives it prints the transcript to the log and
+	// the controller writes the ConfigMap.
+	if archive := task.Spec.TranscriptArchive; archive != nil {
+		env := []corev1.EnvVar{
+			{Name: "KELOS_TASK_NAME", Value: task.Name},
+			{Name: "KELOS_POD_NAME", ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			}},
+			{Name: "KELOS_POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+			}},
+		}
+		switch {
+		case archive.PersistentVolumeClaim != nil:
+			volumes = append(volumes, corev1.Volume{
+				Name: TranscriptVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: archive.PersistentVolumeClaim.ClaimName,
+					},
+				},
+			})
+			mainContainer.VolumeMounts = append(mainContainer.VolumeMounts,
+				corev1.VolumeMount{Name: TranscriptVolumeName, MountPath: TranscriptMountPath})
+			env = append(env,
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_STORE", Value: "pvc"},
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_ROOT", Value: archive.PersistentVolumeClaim.ClaimName},
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_DIR", Value: TranscriptMountPath},
+			)
+			if podSecurityContext == nil {
+				podSecurityContext = &corev1.PodSecurityContext{
+					FSGroup: &agentUID,
+				}
+			}
+		case archive.S3 != nil:
+			secretKey := func(key string) *corev1.EnvVarSource {
+				return &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: archive.S3.SecretRef.Name},
+						Key:                  key,
+					},
+				}
+			}
+			env = append(env,
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_STORE", Value: "s3"},
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_ROOT", Value: archive.S3.Bucket},
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_PREFIX", Value: strings.Trim(archive.S3.Prefix, "/")},
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_S3_ENDPOINT", Value: archive.S3.Endpoint},
+				corev1.EnvVar{Name: "KELOS_TRANSCRIPT_S3_REGION", Value: archive.S3.Region},
+				corev1.EnvVar{Name: "AWS_ACCESS_KEY_ID", ValueFrom: secretKey("AWS_ACCESS_KEY_ID")},
+				corev1.EnvVar{Name: "AWS_SECRET_ACCESS_KEY", ValueFrom: secretKey("AWS_SECRET_ACCESS_KEY")},
+			)
+		case archive.ConfigMap != nil:
+			env = append(env, corev1.EnvVar{Name: "KELOS_TRANSCRIPT_STORE", Value: "configmap"})
 		}
+		mainContainer.Env = append(mainContainer.Env, env...)
 	}
 
 	// Apply PodOverrides before constructing the Job so all overrides
@@ -570,6 +1017,18 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 	var serviceAccountName string
 	var activeDeadlineSeconds *int64
 	var nodeSelector map[string]string
+	podLabels := map[string]string{
+		"kelos.dev/name":       "kelos",
+		"kelos.dev/component":  "task",
+		"kelos.dev/managed-by": "kelos-controller",
+		"kelos.dev/task":       task.Name,
+	}
+	var podAnnotations map[string]string
+	var tolerations []corev1.Toleration
+	var affinity *corev1.Affinity
+	var priorityClassName string
+	var runtimeClassName *string
+	var imagePullSecrets []corev1.LocalObjectReference
 
 	if po := task.Spec.PodOverrides; po != nil {
 		if po.Resources != nil {
@@ -601,8 +1060,61 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 		if po.ServiceAccountName != "" {
 			serviceAccountName = po.ServiceAccountName
 		}
+
+		tolerations = po.Tolerations
+		affinity = po.Affinity
+		priorityClassName = po.PriorityClassName
+		runtimeClassName = po.RuntimeClassName
+		imagePullSecrets = po.ImagePullSecrets
+
+		for k, v := range po.Labels {
+			if _, builtin := podLabels[k]; !builtin {
+				podLabels[k] = v
+			}
+		}
+		if len(po.Annotations) > 0 {
+			podAnnotations = make(map[string]string, len(po.Annotations))
+			for k, v := range po.Annotations {
+				podAnnotations[k] = v
+			}
+		}
+
+		if len(po.Volumes) > 0 {
+			reserved := make(map[string]struct{}, len(volumes))
+			for _, v := range volumes {
+				reserved[v.Name] = struct{}{}
+			}
+			for _, name := range []string{WorkspaceVolumeName, PluginVolumeName, SessionVolumeName, TranscriptVolumeName, GitCacheVolumeName, DependencyCacheVolumeName, MCPConfigVolumeName, SSHKeyVolumeName} {
+				reserved[name] = struct{}{}
+			}
+			for _, v := range po.Volumes {
+				if _, exists := reserved[v.Name]; exists {
+					return nil, fmt.Errorf("podOverrides volume name %q is reserved", v.Name)
+				}
+			}
+			volumes = append(volumes, po.Volumes...)
+		}
+
+		// Volume mounts and the security context apply to every container
+		// so that init containers see the same files and run under the
+		// same constraints as the agent.
+		for i := range initContainers {
+			initContainers[i].VolumeMounts = append(initContainers[i].VolumeMounts, po.VolumeMounts...)
+			initContainers[i].SecurityContext = mergeSecurityContext(initContainers[i].SecurityContext, po.SecurityContext)
+		}
+		mainContainer.VolumeMounts = append(mainContainer.VolumeMounts, po.VolumeMounts...)
+		mainContainer.SecurityContext = mergeSecurityContext(mainContainer.SecurityContext, po.SecurityContext)
 	}
 
+	// Sidecars start after the other init containers so that they only
+	// run while the agent does. PodOverrides do not apply to them; each
+	// sidecar is a complete container definition.
+	sidecars, err := buildSidecars(task, agentConfig, mainContainer, initContainers)
+	if err != nil {
+		return nil, err
+	}
+	initContainers = append(initContainers, sidecars...)
+
 	// PodFailurePolicy ensures only pod disruptions (e.g. node scale-down,
 	// preemption) consume the backoff budget while application crashes fail the
 	// Job immediately.
@@ -644,12 +1156,8 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 			ActiveDeadlineSeconds: activeDeadlineSeconds,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"kelos.dev/name":       "kelos",
-						"kelos.dev/component":  "task",
-						"kelos.dev/managed-by": "kelos-controller",
-						"kelos.dev/task":       task.Name,
-					},
+					Labels:      podLabels,
+					Annotations: podAnnotations,
 				},
 				Spec: corev1.PodSpec{
 					RestartPolicy:      corev1.RestartPolicyNever,
@@ -659,6 +1167,11 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 					Volumes:            volumes,
 					Containers:         []corev1.Container{mainContainer},
 					NodeSelector:       nodeSelector,
+					Tolerations:        tolerations,
+					Affinity:           affinity,
+					PriorityClassName:  priorityClassName,
+					RuntimeClassName:   runtimeClassName,
+					ImagePullSecrets:   imagePullSecrets,
 				},
 			},
 		},
@@ -667,26 +1180,474 @@ func (b *JobBuilder) buildAgentJob(task *kelosv1alpha1.Task, workspace *kelosv1a
 	return job, nil
 }
 
-func buildWorkspaceFileInjectionScript(files []kelosv1alpha1.WorkspaceFile) (string, error) {
+// buildPostCloneSteps returns the shell commands run in the repository after
+// cloning to apply the sparse checkout, submodule and LFS options of a
+// Workspace.
+func buildPostCloneSteps(workspace *kelosv1alpha1.WorkspaceSpec, gitCmd string, depth int32) ([]string, error) {
+	var steps []string
+	if len(workspace.SparseCheckout) > 0 {
+		paths := make([]string, 0, len(workspace.SparseCheckout))
+		for _, p := range workspace.SparseCheckout {
+			cleanPath, err := sanitizeWorkspaceFilePath(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sparse checkout path %q: %w", p, err)
+			}
+			paths = append(paths, shellQuote(cleanPath))
+		}
+		steps = append(steps, "git sparse-checkout set -- "+strings.Join(paths, " "))
+	}
+	if workspace.Submodules {
+		steps = append(steps, submoduleUpdateCmd(gitCmd, depth))
+	}
+	if workspace.LFS {
+		steps = append(steps, "git lfs install --local", gitCmd+" lfs pull")
+	}
+	return steps, nil
+}
+
+// submoduleUpdateCmd returns the command that checks out the submodules of
+// the repository, with the clone depth of the Workspace.
+func submoduleUpdateCmd(gitCmd string, depth int32) string {
+	cmd := gitCmd + " submodule update --init --recursive"
+	if depth > 0 {
+		cmd += fmt.Sprintf(" --depth %d", depth)
+	}
+	return cmd
+}
+
+// gitAuth describes how the Secret of a git provider authenticates git
+// over HTTPS.
+type gitAuth struct {
+	// tokenKey is the Secret key holding the token or password.
+	tokenKey string
+	// username is sent as the user name when usernameKey is empty.
+	username string
+	// usernameKey is the Secret key holding the user name, for providers
+	// whose passwords are tied to an account.
+	usernameKey string
+}
+
+// providerGitAuth returns the git authentication of a provider.
+func providerGitAuth(provider kelosv1alpha1.GitProvider) gitAuth {
+	switch provider {
+	case kelosv1alpha1.GitProviderGitLab:
+		return gitAuth{tokenKey: "GITLAB_TOKEN", username: "oauth2"}
+	case kelosv1alpha1.GitProviderBitbucket:
+		return gitAuth{tokenKey: "BITBUCKET_APP_PASSWORD", usernameKey: "BITBUCKET_USERNAME"}
+	case kelosv1alpha1.GitProviderGitea:
+		// Gitea accepts an access token as the password of any user name.
+		return gitAuth{tokenKey: "GITEA_TOKEN", username: "kelos"}
+	default:
+		return gitAuth{tokenKey: "GITHUB_TOKEN", username: "x-access-token"}
+	}
+}
+
+// credentialHelper returns a git credential helper that answers with the
+// token in tokenEnv and, for providers with a usernameKey, the user name in
+// usernameEnv.
+func (a gitAuth) credentialHelper(tokenEnv, usernameEnv string) string {
+	username := a.username
+	if a.usernameKey != "" {
+		username = "$" + usernameEnv
+	}
+	return fmt.Sprintf(`!f() { echo "username=%s"; echo "password=$%s"; }; f`, username, tokenEnv)
+}
+
+// workspaceProvider returns the git provider of a workspace, defaulting to
+// GitHub.
+func workspaceProvider(workspace *kelosv1alpha1.WorkspaceSpec) kelosv1alpha1.GitProvider {
+	if workspace == nil || workspace.Provider == "" {
+		return kelosv1alpha1.GitProviderGitHub
+	}
+	return workspace.Provider
+}
+
+// secretEnvVar returns an environment variable read from a Secret key.
+func secretEnvVar(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// providerEnvVars returns the credentials of a non-GitHub provider, under
+// the names their git credential helper and CLI read. GitLab hosts other
+// than gitlab.com are passed to glab as GITLAB_HOST.
+func providerEnvVars(provider kelosv1alpha1.GitProvider, secretName, repoURL string) []corev1.EnvVar {
+	auth := providerGitAuth(provider)
+	var env []corev1.EnvVar
+	if auth.usernameKey != "" {
+		env = append(env, secretEnvVar(auth.usernameKey, secretName, auth.usernameKey))
+	}
+	env = append(env, secretEnvVar(auth.tokenKey, secretName, auth.tokenKey))
+	if provider == kelosv1alpha1.GitProviderGitLab {
+		if host := gitRepoHost(repoURL); host != "" && host != "gitlab.com" {
+			env = append(env, corev1.EnvVar{Name: "GITLAB_HOST", Value: host})
+		}
+	}
+	return env
+}
+
+// gitRepoHost returns the host name of an HTTPS, ssh:// or scp-style git
+// URL.
+func gitRepoHost(repoURL string) string {
+	if host, _, _ := parseGitHubRepo(repoURL); host != "" {
+		return host
+	}
+	if u, err := url.Parse(repoURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// sshKeyVolume returns the volume holding the Workspace SSH key Secret.
+func sshKeyVolume(secretName string) corev1.Volume {
+	return corev1.Volume{
+		Name: SSHKeyVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+				Items: []corev1.KeyToPath{
+					{Key: "ssh-privatekey", Path: "ssh-privatekey"},
+					{Key: "known_hosts", Path: "known_hosts"},
+				},
+				DefaultMode: ptr(int32(0440)),
+			},
+		},
+	}
+}
+
+// sshKeySetupScript copies the mounted SSH key into dir. ssh refuses keys
+// that other users can read, which mounted Secret files are with an
+// fsGroup.
+func sshKeySetupScript(dir string) string {
+	return fmt.Sprintf(
+		"mkdir -p %[1]s && cp %[2]s/ssh-privatekey %[1]s/id && cp %[2]s/known_hosts %[1]s/known_hosts && "+
+			"chmod 700 %[1]s && chmod 600 %[1]s/id",
+		dir, SSHKeyMountPath,
+	)
+}
+
+// gitSSHCommand returns the GIT_SSH_COMMAND that authenticates with the
+// key in dir and only trusts its known hosts.
+func gitSSHCommand(dir string) string {
+	return fmt.Sprintf("ssh -i %[1]s/id -o IdentitiesOnly=yes -o UserKnownHostsFile=%[1]s/known_hosts -o StrictHostKeyChecking=yes", dir)
+}
+
+// additionalRepoCredentials returns the environment that carries the
+// credentials of an additional workspace repository and the credential
+// helper that reads them. Repositories without their own secretRef use the
+// Workspace credentials, and the helper is empty when the repository is
+// cloned without HTTPS credentials.
+func additionalRepoCredentials(workspace *kelosv1alpha1.WorkspaceSpec, repo kelosv1alpha1.WorkspaceRepo) ([]corev1.EnvVar, string) {
+	auth := providerGitAuth(workspaceProvider(workspace))
+	if repo.SecretRef == nil {
+		if workspace.SecretRef != nil {
+			return nil, auth.credentialHelper(auth.tokenKey, auth.usernameKey)
+		}
+		return nil, ""
+	}
+	suffix := additionalRepoEnvSuffix(repo.Name)
+	tokenEnv := "KELOS_GIT_TOKEN_" + suffix
+	usernameEnv := "KELOS_GIT_USERNAME_" + suffix
+	var env []corev1.EnvVar
+	if auth.usernameKey != "" {
+		env = append(env, secretEnvVar(usernameEnv, repo.SecretRef.Name, auth.usernameKey))
+	}
+	env = append(env, secretEnvVar(tokenEnv, repo.SecretRef.Name, auth.tokenKey))
+	return env, auth.credentialHelper(tokenEnv, usernameEnv)
+}
+
+// additionalRepoEnvSuffix returns the suffix of the environment variables
+// that carry the credentials of the named additional repository.
+func additionalRepoEnvSuffix(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// repoGitCmd returns the git command for an additional repository, with
+// only its credential helper configured.
+func repoGitCmd(credentialHelper string) string {
+	if credentialHelper == "" {
+		return "git"
+	}
+	return fmt.Sprintf(`git -c credential.helper= -c credential.helper='%s'`, credentialHelper)
+}
+
+// buildAdditionalRepoCloneContainer returns the init container that clones
+// an additional workspace repository into /workspace/<name>.
+func buildAdditionalRepoCloneContainer(repo kelosv1alpha1.WorkspaceRepo, env []corev1.EnvVar, credentialHelper string, depth int32, mounts []corev1.VolumeMount, uid int64) corev1.Container {
+	path := WorkspaceMountPath + "/" + repo.Name
+	cloneArgs := []string{"clone"}
+	if repo.Ref != "" {
+		cloneArgs = append(cloneArgs, "--branch", repo.Ref)
+	}
+	cloneArgs = append(cloneArgs, "--no-single-branch")
+	if depth > 0 {
+		cloneArgs = append(cloneArgs, "--depth", strconv.Itoa(int(depth)))
+	}
+	cloneArgs = append(cloneArgs, "--", repo.Repo, path)
+
+	container := corev1.Container{
+		Name:         "git-clone-" + repo.Name,
+		Image:        GitCloneImage,
+		Args:         cloneArgs,
+		Env:          env,
+		VolumeMounts: mounts,
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser: &uid,
+		},
+	}
+	if credentialHelper != "" {
+		// Persist the helper so the agent pushes with the same token.
+		container.Command = []string{"sh", "-c", fmt.Sprintf(
+			`%s "$@" && { `+
+				`git -C %s config --unset-all credential.helper 2>/dev/null || true; `+
+				`git -C %s config --add credential.helper '%s'; }`,
+			repoGitCmd(credentialHelper), path, path, credentialHelper,
+		)}
+		container.Args = append([]string{"--"}, cloneArgs...)
+	}
+	return container
+}
+
+// additionalReposJSON describes the additional workspace repositories for
+// kelos-capture as a JSON array of name, path and [host/]owner/repo. Repos
+// with their own secretRef also name the variable holding their token, so
+// their pull requests are looked up with it.
+func additionalReposJSON(repos []kelosv1alpha1.WorkspaceRepo) (string, error) {
+	type additionalRepo struct {
+		Name     string `json:"name"`
+		Path     string `json:"path"`
+		Repo     string `json:"repo,omitempty"`
+		TokenEnv string `json:"tokenEnv,omitempty"`
+	}
+	entries := make([]additionalRepo, 0, len(repos))
+	for _, repo := range repos {
+		entry := additionalRepo{Name: repo.Name, Path: WorkspaceMountPath + "/" + repo.Name}
+		if repo.SecretRef != nil {
+			entry.TokenEnv = "KELOS_GIT_TOKEN_" + additionalRepoEnvSuffix(repo.Name)
+		}
+		if host, owner, name := parseGitHubRepo(repo.Repo); owner != "" && !strings.HasPrefix(name, "unknown-repo-") {
+			entry.Repo = owner + "/" + name
+			if host != "" && host != "github.com" {
+				entry.Repo = host + "/" + entry.Repo
+			}
+		}
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", fmt.Errorf("encoding additional repositories: %w", err)
+	}
+	return string(data), nil
+}
+
+// dependencyCacheEnvVars points the caches of common package managers at
+// subdirectories of the dependency cache volume.
+func dependencyCacheEnvVars() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "GOMODCACHE", Value: DependencyCacheMountPath + "/go/mod"},
+		{Name: "GOCACHE", Value: DependencyCacheMountPath + "/go/build"},
+		{Name: "npm_config_cache", Value: DependencyCacheMountPath + "/npm"},
+		{Name: "YARN_CACHE_FOLDER", Value: DependencyCacheMountPath + "/yarn"},
+		{Name: "PIP_CACHE_DIR", Value: DependencyCacheMountPath + "/pip"},
+	}
+}
+
+// buildSidecars returns the sidecar containers of a Task as native sidecar
+// init containers: those of its AgentConfig followed by its own, where a
+// Task sidecar replaces an AgentConfig sidecar with the same name. A
+// sidecar's readinessProbe doubles as its startupProbe when none is set, so
+// that the agent only starts once the service is ready.
+func buildSidecars(task *kelosv1alpha1.Task, agentConfig *kelosv1alpha1.AgentConfigSpec, mainContainer corev1.Container, initContainers []corev1.Container) ([]corev1.Container, error) {
+	var defined []corev1.Container
+	if agentConfig != nil {
+		defined = append(defined, agentConfig.Sidecars...)
+	}
+	for _, c := range task.Spec.Sidecars {
+		replaced := false
+		for i := range defined {
+			if defined[i].Name == c.Name {
+				defined[i] = c
+				replaced = true
+			}
+		}
+		if !replaced {
+			defined = append(defined, c)
+		}
+	}
+	if len(defined) == 0 {
+		return nil, nil
+	}
+
+	reserved := map[string]struct{}{mainContainer.Name: {}}
+	for _, c := range initContainers {
+		reserved[c.Name] = struct{}{}
+	}
+
+	always := corev1.ContainerRestartPolicyAlways
+	sidecars := make([]corev1.Container, 0, len(defined))
+	for _, c := range defined {
+		if _, exists := reserved[c.Name]; exists {
+			return nil, fmt.Errorf("sidecar name %q is reserved", c.Name)
+		}
+		sidecar := *c.DeepCopy()
+		sidecar.RestartPolicy = &always
+		if sidecar.StartupProbe == nil && sidecar.ReadinessProbe != nil {
+			sidecar.StartupProbe = sidecar.ReadinessProbe.DeepCopy()
+		}
+		sidecars = append(sidecars, sidecar)
+	}
+	return sidecars, nil
+}
+
+// mergeSecurityContext returns base with the fields set in override
+// replacing those of base. It returns base unchanged when override is nil.
+func mergeSecurityContext(base, override *corev1.SecurityContext) *corev1.SecurityContext {
+	if override == nil {
+		return base
+	}
+	merged := &corev1.SecurityContext{}
+	if base != nil {
+		merged = base.DeepCopy()
+	}
+	o := override.DeepCopy()
+	if o.Capabilities != nil {
+		merged.Capabilities = o.Capabilities
+	}
+	if o.Privileged != nil {
+		merged.Privileged = o.Privileged
+	}
+	if o.SELinuxOptions != nil {
+		merged.SELinuxOptions = o.SELinuxOptions
+	}
+	if o.WindowsOptions != nil {
+		merged.WindowsOptions = o.WindowsOptions
+	}
+	if o.RunAsUser != nil {
+		merged.RunAsUser = o.RunAsUser
+	}
+	if o.RunAsGroup != nil {
+		merged.RunAsGroup = o.RunAsGroup
+	}
+	if o.RunAsNonRoot != nil {
+		merged.RunAsNonRoot = o.RunAsNonRoot
+	}
+	if o.ReadOnlyRootFilesystem != nil {
+		merged.ReadOnlyRootFilesystem = o.ReadOnlyRootFilesystem
+	}
+	if o.AllowPrivilegeEscalation != nil {
+		merged.AllowPrivilegeEscalation = o.AllowPrivilegeEscalation
+	}
+	if o.ProcMount != nil {
+		merged.ProcMount = o.ProcMount
+	}
+	if o.SeccompProfile != nil {
+		merged.SeccompProfile = o.SeccompProfile
+	}
+	if o.AppArmorProfile != nil {
+		merged.AppArmorProfile = o.AppArmorProfile
+	}
+	return merged
+}
+
+// buildWorkspaceFileInjection returns the script that writes the files of a
+// Workspace into the repository, along with the volumes and mounts that
+// expose the ConfigMaps and Secrets the files are read from.
+func buildWorkspaceFileInjection(files []kelosv1alpha1.WorkspaceFile) (string, []corev1.Volume, []corev1.VolumeMount, error) {
 	lines := []string{"set -eu"}
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
 
-	for _, file := range files {
+	for i, file := range files {
 		relativePath, err := sanitizeWorkspaceFilePath(file.Path)
 		if err != nil {
-			return "", fmt.Errorf("invalid workspace file path %q: %w", file.Path, err)
+			return "", nil, nil, fmt.Errorf("invalid workspace file path %q: %w", file.Path, err)
 		}
 
 		targetPath := WorkspaceMountPath + "/repo/" + relativePath
-		contentBase64 := base64.StdEncoding.EncodeToString([]byte(file.Content))
 
+		if file.ValueFrom == nil && file.DirectoryFrom == nil {
+			contentBase64 := base64.StdEncoding.EncodeToString([]byte(file.Content))
+			lines = append(lines,
+				"target="+shellQuote(targetPath),
+				`mkdir -p "$(dirname "$target")"`,
+				fmt.Sprintf("printf '%%s' %s | base64 -d > \"$target\"", shellQuote(contentBase64)),
+			)
+			continue
+		}
+
+		volume, err := workspaceFileVolume(fmt.Sprintf("kelos-file-%d", i), file)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("invalid workspace file %q: %w", file.Path, err)
+		}
+		sourcePath := path.Join(workspaceFileSourcePath, volume.Name)
+		volumes = append(volumes, volume)
+		mounts = append(mounts, corev1.VolumeMount{Name: volume.Name, MountPath: sourcePath, ReadOnly: true})
+
+		if file.ValueFrom != nil {
+			// Optional keys that are missing leave no file behind.
+			lines = append(lines,
+				"target="+shellQuote(targetPath),
+				"source="+shellQuote(sourcePath+"/content"),
+				`if [ -e "$source" ]; then mkdir -p "$(dirname "$target")"; cp -L "$source" "$target"; fi`,
+			)
+			continue
+		}
+
+		// Skip the "..data" links that Kubernetes adds to the volume.
 		lines = append(lines,
 			"target="+shellQuote(targetPath),
-			`mkdir -p "$(dirname "$target")"`,
-			fmt.Sprintf("printf '%%s' %s | base64 -d > \"$target\"", shellQuote(contentBase64)),
+			"source="+shellQuote(sourcePath),
+			`mkdir -p "$target"`,
+			`for f in "$source"/* "$source"/.[!.]*; do if [ -e "$f" ]; then cp -L "$f" "$target/"; fi; done`,
 		)
 	}
 
-	return strings.Join(lines, "\n"), nil
+	return strings.Join(lines, "\n"), volumes, mounts, nil
+}
+
+// workspaceFileVolume returns the volume that exposes the ConfigMap or
+// Secret of a WorkspaceFile. A single key is projected to a file named
+// "content".
+func workspaceFileVolume(name string, file kelosv1alpha1.WorkspaceFile) (corev1.Volume, error) {
+	volume := corev1.Volume{Name: name}
+	switch {
+	case file.ValueFrom != nil && file.DirectoryFrom != nil:
+		return volume, fmt.Errorf("valueFrom and directoryFrom are mutually exclusive")
+	case file.ValueFrom != nil && file.ValueFrom.ConfigMapKeyRef != nil:
+		ref := file.ValueFrom.ConfigMapKeyRef
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: ref.LocalObjectReference,
+			Items:                []corev1.KeyToPath{{Key: ref.Key, Path: "content"}},
+			Optional:             ref.Optional,
+		}
+	case file.ValueFrom != nil && file.ValueFrom.SecretKeyRef != nil:
+		ref := file.ValueFrom.SecretKeyRef
+		volume.Secret = &corev1.SecretVolumeSource{
+			SecretName: ref.Name,
+			Items:      []corev1.KeyToPath{{Key: ref.Key, Path: "content"}},
+			Optional:   ref.Optional,
+		}
+	case file.DirectoryFrom != nil && file.DirectoryFrom.ConfigMapRef != nil:
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: *file.DirectoryFrom.ConfigMapRef,
+			Optional:             file.DirectoryFrom.Optional,
+		}
+	case file.DirectoryFrom != nil && file.DirectoryFrom.SecretRef != nil:
+		volume.Secret = &corev1.SecretVolumeSource{
+			SecretName: file.DirectoryFrom.SecretRef.Name,
+			Optional:   file.DirectoryFrom.Optional,
+		}
+	default:
+		return volume, fmt.Errorf("a ConfigMap or Secret reference is required")
+	}
+	return volume, nil
 }
 
 func sanitizeWorkspaceFilePath(filePath string) (string, error) {
@@ -840,3 +1801,51 @@ func buildMCPServersJSON(servers []kelosv1alpha1.MCPServerSpec) (string, error)
 	}
 	return string(data), nil
 }
+
+// buildMCPServersTOML converts MCPServerSpec entries into TOML with an
+// [mcp_servers.<name>] table per server, the format of the Codex
+// config.toml. The servers must have been validated by buildMCPServersJSON.
+// Strings are written as JSON strings, which are valid TOML basic strings.
+func buildMCPServersTOML(servers []kelosv1alpha1.MCPServerSpec) string {
+	var b strings.Builder
+	quote := func(v string) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	inlineTable := func(m map[string]string) string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, quote(k)+" = "+quote(m[k]))
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	}
+	for _, s := range servers {
+		fmt.Fprintf(&b, "[mcp_servers.%s]\n", quote(s.Name))
+		if s.Command != "" {
+			fmt.Fprintf(&b, "command = %s\n", quote(s.Command))
+		}
+		if len(s.Args) > 0 {
+			args := make([]string, 0, len(s.Args))
+			for _, a := range s.Args {
+				args = append(args, quote(a))
+			}
+			fmt.Fprintf(&b, "args = [%s]\n", strings.Join(args, ", "))
+		}
+		if s.URL != "" {
+			fmt.Fprintf(&b, "url = %s\n", quote(s.URL))
+		}
+		if len(s.Headers) > 0 {
+			fmt.Fprintf(&b, "http_headers = %s\n", inlineTable(s.Headers))
+		}
+		if len(s.Env) > 0 {
+			fmt.Fprintf(&b, "env = %s\n", inlineTable(s.Env))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
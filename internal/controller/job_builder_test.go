@@ -3,6 +3,7 @@ package controller
 import (
 	"encoding/base64"
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -156,6 +157,70 @@ func TestBuildClaudeCodeJob_NoModel(t *testing.T) {
 	}
 }
 
+func TestBuildClaudeCodeJob_SessionResume(t *testing.T) {
+	builder := NewJobBuilder()
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "follow-up",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Now add tests",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			Session:    &kelosv1alpha1.SessionSpec{PersistentVolumeClaimName: "agent-sessions"},
+			ResumeFrom: "first",
+		},
+	}
+
+	job, err := builder.Build(task, nil, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	podSpec := job.Spec.Template.Spec
+	var found bool
+	for _, v := range podSpec.Volumes {
+		if v.Name == SessionVolumeName {
+			found = true
+			if v.PersistentVolumeClaim == nil || v.PersistentVolumeClaim.ClaimName != "agent-sessions" {
+				t.Errorf("Expected session volume to use claim agent-sessions, got %+v", v.VolumeSource)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Expected session volume")
+	}
+	if podSpec.SecurityContext == nil || podSpec.SecurityContext.FSGroup == nil || *podSpec.SecurityContext.FSGroup != AgentUID {
+		t.Errorf("Expected FSGroup %d for session volume, got %+v", AgentUID, podSpec.SecurityContext)
+	}
+
+	container := podSpec.Containers[0]
+	var mounted bool
+	for _, m := range container.VolumeMounts {
+		if m.Name == SessionVolumeName && m.MountPath == SessionMountPath {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("Expected session volume mounted at %s, got %v", SessionMountPath, container.VolumeMounts)
+	}
+
+	env := make(map[string]string)
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+	if got := env["KELOS_SESSION_DIR"]; got != "/kelos/session/follow-up" {
+		t.Errorf("KELOS_SESSION_DIR = %q, want %q", got, "/kelos/session/follow-up")
+	}
+	if got := env["KELOS_RESUME_SESSION_DIR"]; got != "/kelos/session/first" {
+		t.Errorf("KELOS_RESUME_SESSION_DIR = %q, want %q", got, "/kelos/session/first")
+	}
+}
+
 func TestBuildClaudeCodeJob_WorkspaceWithRef(t *testing.T) {
 	builder := NewJobBuilder()
 	task := &kelosv1alpha1.Task{
@@ -331,6 +396,142 @@ func TestBuildClaudeCodeJob_WorkspaceWithInjectedFilesInvalidPath(t *testing.T)
 	}
 }
 
+func TestBuildClaudeCodeJob_WorkspaceFilesFromConfigMapsAndSecrets(t *testing.T) {
+	builder := NewJobBuilder()
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-workspace-files-from",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Inject shared files",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+		},
+	}
+
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo: "https://github.com/example/repo.git",
+		Files: []kelosv1alpha1.WorkspaceFile{
+			{
+				Path:    "AGENTS.md",
+				Content: "inline",
+			},
+			{
+				Path: ".npmrc",
+				ValueFrom: &kelosv1alpha1.WorkspaceFileSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "npm-auth"},
+						Key:                  "npmrc",
+					},
+				},
+			},
+			{
+				Path: "CLAUDE.md",
+				ValueFrom: &kelosv1alpha1.WorkspaceFileSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "team-docs"},
+						Key:                  "claude",
+						Optional:             ptr(true),
+					},
+				},
+			},
+			{
+				Path: ".claude/skills/reviewer/",
+				DirectoryFrom: &kelosv1alpha1.WorkspaceDirectorySource{
+					ConfigMapRef: &corev1.LocalObjectReference{Name: "reviewer-skill"},
+				},
+			},
+		},
+	}
+
+	job, err := builder.Build(task, workspace, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	volumes := map[string]corev1.Volume{}
+	for _, v := range job.Spec.Template.Spec.Volumes {
+		volumes[v.Name] = v
+	}
+	if _, ok := volumes["kelos-file-0"]; ok {
+		t.Error("Expected no volume for inline content")
+	}
+	if v := volumes["kelos-file-1"]; v.Secret == nil || v.Secret.SecretName != "npm-auth" ||
+		!reflect.DeepEqual(v.Secret.Items, []corev1.KeyToPath{{Key: "npmrc", Path: "content"}}) {
+		t.Errorf("Expected secret key volume for .npmrc, got %+v", v)
+	}
+	if v := volumes["kelos-file-2"]; v.ConfigMap == nil || v.ConfigMap.Name != "team-docs" ||
+		v.ConfigMap.Optional == nil || !*v.ConfigMap.Optional {
+		t.Errorf("Expected optional ConfigMap key volume for CLAUDE.md, got %+v", v)
+	}
+	if v := volumes["kelos-file-3"]; v.ConfigMap == nil || v.ConfigMap.Name != "reviewer-skill" || len(v.ConfigMap.Items) != 0 {
+		t.Errorf("Expected whole ConfigMap volume for the skill directory, got %+v", v)
+	}
+
+	injection := job.Spec.Template.Spec.InitContainers[1]
+	var mounts []string
+	for _, m := range injection.VolumeMounts {
+		if m.Name != WorkspaceVolumeName && !m.ReadOnly {
+			t.Errorf("Expected source mount %q to be read-only", m.Name)
+		}
+		mounts = append(mounts, m.Name+"="+m.MountPath)
+	}
+	wantMounts := []string{
+		WorkspaceVolumeName + "=" + WorkspaceMountPath,
+		"kelos-file-1=/kelos/files/kelos-file-1",
+		"kelos-file-2=/kelos/files/kelos-file-2",
+		"kelos-file-3=/kelos/files/kelos-file-3",
+	}
+	if !reflect.DeepEqual(mounts, wantMounts) {
+		t.Errorf("workspace-files mounts = %v, want %v", mounts, wantMounts)
+	}
+
+	script := injection.Command[2]
+	for _, want := range []string{
+		"target='/workspace/repo/.npmrc'\nsource='/kelos/files/kelos-file-1/content'\n",
+		`cp -L "$source" "$target"`,
+		"target='/workspace/repo/.claude/skills/reviewer'\nsource='/kelos/files/kelos-file-3'\nmkdir -p \"$target\"\n",
+		`for f in "$source"/* "$source"/.[!.]*; do`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got script:\n%s", want, script)
+		}
+	}
+}
+
+func TestBuildClaudeCodeJob_WorkspaceFileWithoutSource(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-workspace-files-no-source",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Inject shared files",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo: "https://github.com/example/repo.git",
+		Files: []kelosv1alpha1.WorkspaceFile{{
+			Path:      "CLAUDE.md",
+			ValueFrom: &kelosv1alpha1.WorkspaceFileSource{},
+		}},
+	}
+
+	_, err := NewJobBuilder().Build(task, workspace, nil, task.Spec.Prompt)
+	if err == nil || !strings.Contains(err.Error(), `invalid workspace file "CLAUDE.md"`) {
+		t.Errorf("Expected invalid workspace file error, got %v", err)
+	}
+}
+
 func TestBuildClaudeCodeJob_CustomImageWithWorkspace(t *testing.T) {
 	builder := NewJobBuilder()
 	task := &kelosv1alpha1.Task{
@@ -1837,25 +2038,619 @@ func TestBuildJob_PodOverridesAllFields(t *testing.T) {
 		t.Errorf("Expected memory limit 4Gi, got %s", memLimit.String())
 	}
 
-	// ActiveDeadlineSeconds
-	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 3600 {
-		t.Errorf("Expected ActiveDeadlineSeconds 3600, got %v", job.Spec.ActiveDeadlineSeconds)
+	// ActiveDeadlineSeconds
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 3600 {
+		t.Errorf("Expected ActiveDeadlineSeconds 3600, got %v", job.Spec.ActiveDeadlineSeconds)
+	}
+
+	// Env
+	envMap := map[string]string{}
+	for _, env := range container.Env {
+		if env.Value != "" {
+			envMap[env.Name] = env.Value
+		}
+	}
+	if envMap["HTTPS_PROXY"] != "http://proxy:8080" {
+		t.Errorf("Expected HTTPS_PROXY=http://proxy:8080, got %q", envMap["HTTPS_PROXY"])
+	}
+
+	// NodeSelector
+	if job.Spec.Template.Spec.NodeSelector["pool"] != "agents" {
+		t.Errorf("Expected nodeSelector pool=agents, got %q", job.Spec.Template.Spec.NodeSelector["pool"])
+	}
+}
+
+func TestBuildJob_PodOverridesScheduling(t *testing.T) {
+	builder := NewJobBuilder()
+	gvisor := "gvisor"
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scheduling",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			PodOverrides: &kelosv1alpha1.PodOverrides{
+				Tolerations: []corev1.Toleration{{
+					Key:      "dedicated",
+					Operator: corev1.TolerationOpEqual,
+					Value:    "agents",
+					Effect:   corev1.TaintEffectNoSchedule,
+				}},
+				Affinity: &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+								MatchExpressions: []corev1.NodeSelectorRequirement{{
+									Key:      "pool",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"agents"},
+								}},
+							}},
+						},
+					},
+				},
+				PriorityClassName: "agent-low",
+				RuntimeClassName:  &gvisor,
+				ImagePullSecrets:  []corev1.LocalObjectReference{{Name: "registry-creds"}},
+			},
+		},
+	}
+
+	job, err := builder.Build(task, nil, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	spec := job.Spec.Template.Spec
+	if len(spec.Tolerations) != 1 || spec.Tolerations[0].Key != "dedicated" {
+		t.Errorf("Expected dedicated toleration, got %v", spec.Tolerations)
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil {
+		t.Error("Expected node affinity to be set")
+	}
+	if spec.PriorityClassName != "agent-low" {
+		t.Errorf("Expected priorityClassName agent-low, got %q", spec.PriorityClassName)
+	}
+	if spec.RuntimeClassName == nil || *spec.RuntimeClassName != "gvisor" {
+		t.Errorf("Expected runtimeClassName gvisor, got %v", spec.RuntimeClassName)
+	}
+	if len(spec.ImagePullSecrets) != 1 || spec.ImagePullSecrets[0].Name != "registry-creds" {
+		t.Errorf("Expected imagePullSecrets registry-creds, got %v", spec.ImagePullSecrets)
+	}
+}
+
+func TestBuildJob_PodOverridesVolumesAndSecurityContext(t *testing.T) {
+	builder := NewJobBuilder()
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-volumes",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			PodOverrides: &kelosv1alpha1.PodOverrides{
+				Volumes: []corev1.Volume{{
+					Name: "ca-bundle",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "corp-ca"},
+						},
+					},
+				}},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "ca-bundle",
+					MountPath: "/etc/ssl/certs/corp-ca.crt",
+					SubPath:   "ca.crt",
+					ReadOnly:  true,
+				}},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: boolPtr(false),
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				},
+				Labels:      map[string]string{"team": "platform", "kelos.dev/task": "spoofed"},
+				Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+			},
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo: "https://github.com/example/repo.git",
+		Ref:  "main",
+	}
+
+	job, err := builder.Build(task, workspace, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	spec := job.Spec.Template.Spec
+	var found bool
+	for _, v := range spec.Volumes {
+		if v.Name == "ca-bundle" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected ca-bundle volume to be added")
+	}
+
+	containers := append([]corev1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	if len(containers) < 2 {
+		t.Fatalf("Expected init and agent containers, got %d", len(containers))
+	}
+	for _, c := range containers {
+		var mounted bool
+		for _, m := range c.VolumeMounts {
+			if m.Name == "ca-bundle" && m.MountPath == "/etc/ssl/certs/corp-ca.crt" {
+				mounted = true
+			}
+		}
+		if !mounted {
+			t.Errorf("Expected ca-bundle mounted in container %s", c.Name)
+		}
+		sc := c.SecurityContext
+		if sc == nil || sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			t.Errorf("Expected allowPrivilegeEscalation=false in container %s", c.Name)
+		}
+		if sc == nil || sc.Capabilities == nil || len(sc.Capabilities.Drop) != 1 {
+			t.Errorf("Expected dropped capabilities in container %s", c.Name)
+		}
+	}
+
+	// The git-clone container keeps the shared workspace UID.
+	gitClone := spec.InitContainers[0]
+	if gitClone.SecurityContext.RunAsUser == nil || *gitClone.SecurityContext.RunAsUser != AgentUID {
+		t.Errorf("Expected git-clone to keep runAsUser %d, got %v", AgentUID, gitClone.SecurityContext.RunAsUser)
+	}
+
+	labels := job.Spec.Template.Labels
+	if labels["team"] != "platform" {
+		t.Errorf("Expected pod label team=platform, got %v", labels)
+	}
+	if labels["kelos.dev/task"] != "test-volumes" {
+		t.Errorf("Expected built-in kelos.dev/task label to take precedence, got %q", labels["kelos.dev/task"])
+	}
+	if job.Spec.Template.Annotations["sidecar.istio.io/inject"] != "false" {
+		t.Errorf("Expected pod annotation, got %v", job.Spec.Template.Annotations)
+	}
+}
+
+func TestBuildJob_PodOverridesReservedVolumeName(t *testing.T) {
+	builder := NewJobBuilder()
+	for _, name := range []string{WorkspaceVolumeName, MCPConfigVolumeName, SSHKeyVolumeName} {
+		task := &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-reserved-volume",
+				Namespace: "default",
+			},
+			Spec: kelosv1alpha1.TaskSpec{
+				Type:   AgentTypeClaudeCode,
+				Prompt: "Fix issue",
+				Credentials: kelosv1alpha1.Credentials{
+					Type:      kelosv1alpha1.CredentialTypeAPIKey,
+					SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+				},
+				PodOverrides: &kelosv1alpha1.PodOverrides{
+					Volumes: []corev1.Volume{{
+						Name:         name,
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+				},
+			},
+		}
+
+		_, err := builder.Build(task, nil, nil, task.Spec.Prompt)
+		if err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("Expected reserved volume name error for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestBuildJob_WorkspaceCloneOptions(t *testing.T) {
+	newTask := func() *kelosv1alpha1.Task {
+		return &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-clone-options",
+				Namespace: "default",
+			},
+			Spec: kelosv1alpha1.TaskSpec{
+				Type:   AgentTypeClaudeCode,
+				Prompt: "Fix issue",
+				Credentials: kelosv1alpha1.Credentials{
+					Type:      kelosv1alpha1.CredentialTypeAPIKey,
+					SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+				},
+				Branch: "feature",
+			},
+		}
+	}
+	depth50 := int32(50)
+	depth0 := int32(0)
+
+	tests := []struct {
+		name              string
+		workspace         *kelosv1alpha1.WorkspaceSpec
+		wantArgs          []string
+		notWantArgs       []string
+		wantScript        []string
+		wantBranchScript  []string
+		notWantBranchArgs []string
+	}{
+		{
+			name: "defaults keep a shallow clone",
+			workspace: &kelosv1alpha1.WorkspaceSpec{
+				Repo: "https://github.com/example/repo.git",
+			},
+			wantArgs:          []string{"--depth 1"},
+			notWantArgs:       []string{"--sparse", "--filter"},
+			notWantBranchArgs: []string{"--depth", "submodule"},
+		},
+		{
+			name: "full history",
+			workspace: &kelosv1alpha1.WorkspaceSpec{
+				Repo:  "https://github.com/example/repo.git",
+				Depth: &depth0,
+			},
+			notWantArgs:       []string{"--depth"},
+			notWantBranchArgs: []string{"--depth"},
+		},
+		{
+			name: "sparse submodules and lfs",
+			workspace: &kelosv1alpha1.WorkspaceSpec{
+				Repo:           "https://github.com/example/repo.git",
+				Depth:          &depth50,
+				SparseCheckout: []string{"services/api", "libs/shared/"},
+				Submodules:     true,
+				LFS:            true,
+				SecretRef:      &kelosv1alpha1.SecretReference{Name: "github-token"},
+			},
+			wantArgs: []string{"--sparse --filter=blob:none", "--depth 50"},
+			wantScript: []string{
+				"cd /workspace/repo && git sparse-checkout set -- 'services/api' 'libs/shared'",
+				"credential.helper='!f() { echo \"username=x-access-token\"; echo \"password=$GITHUB_TOKEN\"; }; f' submodule update --init --recursive --depth 50",
+				"git lfs install --local && git -c credential.helper= ",
+				"lfs pull",
+			},
+			wantBranchScript: []string{
+				"fetch --depth 50 origin",
+				"fi && git -c credential.helper= ",
+				"submodule update --init --recursive --depth 50",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := NewJobBuilder().Build(newTask(), tt.workspace, nil, "Fix issue")
+			if err != nil {
+				t.Fatalf("Build() returned error: %v", err)
+			}
+			initContainers := job.Spec.Template.Spec.InitContainers
+			clone := initContainers[0]
+			args := strings.Join(clone.Args, " ")
+			for _, want := range tt.wantArgs {
+				if !strings.Contains(args, want) {
+					t.Errorf("Expected clone args to contain %q, got %q", want, args)
+				}
+			}
+			for _, notWant := range tt.notWantArgs {
+				if strings.Contains(args, notWant) {
+					t.Errorf("Expected clone args not to contain %q, got %q", notWant, args)
+				}
+			}
+			var script string
+			if len(clone.Command) == 3 {
+				script = clone.Command[2]
+			}
+			for _, want := range tt.wantScript {
+				if !strings.Contains(script, want) {
+					t.Errorf("Expected clone script to contain %q, got %q", want, script)
+				}
+			}
+
+			var branchScript string
+			for _, c := range initContainers {
+				if c.Name == "branch-setup" {
+					branchScript = c.Command[2]
+				}
+			}
+			for _, want := range tt.wantBranchScript {
+				if !strings.Contains(branchScript, want) {
+					t.Errorf("Expected branch-setup script to contain %q, got %q", want, branchScript)
+				}
+			}
+			for _, notWant := range tt.notWantBranchArgs {
+				if strings.Contains(branchScript, notWant) {
+					t.Errorf("Expected branch-setup script not to contain %q, got %q", notWant, branchScript)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildJob_WorkspaceSparseCheckoutInvalidPath(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-sparse-invalid",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:           "https://github.com/example/repo.git",
+		SparseCheckout: []string{"../outside"},
+	}
+
+	_, err := NewJobBuilder().Build(task, workspace, nil, task.Spec.Prompt)
+	if err == nil || !strings.Contains(err.Error(), "invalid sparse checkout path") {
+		t.Errorf("Expected invalid sparse checkout path error, got %v", err)
+	}
+}
+
+func TestBuildJob_WorkspaceSetup(t *testing.T) {
+	builder := NewJobBuilder()
+	timeout := int64(900)
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-setup",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			WorkspaceRef: &kelosv1alpha1.WorkspaceReference{Name: "app"},
+			Branch:       "feature",
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:            "https://github.com/example/app.git",
+		SecretRef:       &kelosv1alpha1.SecretReference{Name: "github-token"},
+		Files:           []kelosv1alpha1.WorkspaceFile{{Path: "CLAUDE.md", Content: "Be brief."}},
+		DependencyCache: &kelosv1alpha1.DependencyCache{},
+		Setup: &kelosv1alpha1.WorkspaceSetup{
+			Commands:       []string{"npm ci", "make deps"},
+			TimeoutSeconds: &timeout,
+			Env:            []corev1.EnvVar{{Name: "CI", Value: "true"}},
+		},
+	}
+
+	job, err := builder.Build(task, workspace, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	initContainers := job.Spec.Template.Spec.InitContainers
+	var names []string
+	for _, c := range initContainers {
+		names = append(names, c.Name)
+	}
+	if want := []string{"git-clone", "branch-setup", "workspace-files", WorkspaceSetupContainerName}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected init containers %v, got %v", want, names)
+	}
+
+	setup := initContainers[3]
+	if setup.Image != ClaudeCodeImage {
+		t.Errorf("Expected setup to default to the agent image, got %q", setup.Image)
+	}
+	wantCommand := []string{"timeout", "900", "sh", "-c", "set -e\nnpm ci\nmake deps"}
+	if !reflect.DeepEqual(setup.Command, wantCommand) {
+		t.Errorf("setup command = %q, want %q", setup.Command, wantCommand)
+	}
+	if setup.WorkingDir != WorkspaceMountPath+"/repo" {
+		t.Errorf("setup working dir = %q, want %q", setup.WorkingDir, WorkspaceMountPath+"/repo")
+	}
+	env := map[string]string{}
+	for _, e := range setup.Env {
+		env[e.Name] = e.Value
+	}
+	if _, ok := env["GITHUB_TOKEN"]; !ok {
+		t.Error("Expected setup to have the workspace credentials")
+	}
+	if env["CI"] != "true" || env["npm_config_cache"] != DependencyCacheMountPath+"/npm" {
+		t.Errorf("Expected setup env and dependency cache env, got %v", env)
+	}
+	var mounts []string
+	for _, m := range setup.VolumeMounts {
+		mounts = append(mounts, m.Name)
+	}
+	if want := []string{WorkspaceVolumeName, DependencyCacheVolumeName}; !reflect.DeepEqual(mounts, want) {
+		t.Errorf("setup mounts = %v, want %v", mounts, want)
+	}
+}
+
+func TestBuildJob_WorkspaceCaches(t *testing.T) {
+	builder := NewJobBuilder()
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cache",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			WorkspaceRef: &kelosv1alpha1.WorkspaceReference{Name: "monorepo"},
+			Branch:       "feature",
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:            "https://github.com/example/monorepo.git",
+		Ref:             "main",
+		Cache:           &kelosv1alpha1.WorkspaceCache{},
+		DependencyCache: &kelosv1alpha1.DependencyCache{},
+	}
+
+	job, err := builder.Build(task, workspace, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+	podSpec := job.Spec.Template.Spec
+
+	claims := map[string]corev1.PersistentVolumeClaimVolumeSource{}
+	for _, v := range podSpec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims[v.Name] = *v.PersistentVolumeClaim
+		}
+	}
+	if got := claims[GitCacheVolumeName]; got.ClaimName != "monorepo-git-cache" || !got.ReadOnly {
+		t.Errorf("Expected read-only git cache claim monorepo-git-cache, got %+v", got)
+	}
+	if got := claims[DependencyCacheVolumeName]; got.ClaimName != "monorepo-dependency-cache" || got.ReadOnly {
+		t.Errorf("Expected writable dependency cache claim monorepo-dependency-cache, got %+v", got)
+	}
+
+	clone := podSpec.InitContainers[0]
+	if !strings.Contains(strings.Join(clone.Args, " "), "--reference-if-able "+GitCacheMirrorPath) {
+		t.Errorf("Expected git-clone to reference the mirror, got args %v", clone.Args)
+	}
+	for _, c := range append(podSpec.InitContainers, podSpec.Containers...) {
+		found := false
+		for _, m := range c.VolumeMounts {
+			if m.Name == GitCacheVolumeName && m.MountPath == GitCacheMountPath && m.ReadOnly {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected container %q to mount the git cache read-only, got %v", c.Name, c.VolumeMounts)
+		}
+	}
+
+	env := map[string]string{}
+	for _, e := range podSpec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	if env["GOMODCACHE"] != DependencyCacheMountPath+"/go/mod" || env["npm_config_cache"] != DependencyCacheMountPath+"/npm" {
+		t.Errorf("Expected package manager caches on the dependency cache volume, got GOMODCACHE=%q npm_config_cache=%q", env["GOMODCACHE"], env["npm_config_cache"])
+	}
+	if p := podSpec.SecurityContext.FSGroupChangePolicy; p == nil || *p != corev1.FSGroupChangeOnRootMismatch {
+		t.Errorf("Expected fsGroupChangePolicy OnRootMismatch, got %v", p)
+	}
+}
+
+func TestBuildJob_Sidecars(t *testing.T) {
+	builder := NewJobBuilder()
+	readiness := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"pg_isready"}},
+		},
+		PeriodSeconds: 2,
+	}
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-sidecars",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			PodOverrides: &kelosv1alpha1.PodOverrides{
+				VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+				Volumes: []corev1.Volume{{
+					Name:         "cache",
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				}},
+			},
+			Sidecars: []corev1.Container{
+				{Name: "redis", Image: "redis:7.2"},
+				{Name: "postgres", Image: "postgres:16", ReadinessProbe: readiness},
+			},
+		},
+	}
+	agentConfig := &kelosv1alpha1.AgentConfigSpec{
+		Sidecars: []corev1.Container{
+			{Name: "postgres", Image: "postgres:15"},
+			{Name: "registry", Image: "registry:2"},
+		},
+	}
+
+	job, err := builder.Build(task, nil, agentConfig, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	initContainers := job.Spec.Template.Spec.InitContainers
+	if len(initContainers) != 3 {
+		t.Fatalf("Expected 3 init containers, got %d", len(initContainers))
+	}
+	var names []string
+	for _, c := range initContainers {
+		names = append(names, c.Name)
+		if c.RestartPolicy == nil || *c.RestartPolicy != corev1.ContainerRestartPolicyAlways {
+			t.Errorf("Expected sidecar %q to have restartPolicy Always, got %v", c.Name, c.RestartPolicy)
+		}
+		if len(c.VolumeMounts) != 0 {
+			t.Errorf("Expected PodOverrides volume mounts not to apply to sidecar %q, got %v", c.Name, c.VolumeMounts)
+		}
+	}
+	if want := []string{"postgres", "registry", "redis"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected sidecars %v, got %v", want, names)
 	}
 
-	// Env
-	envMap := map[string]string{}
-	for _, env := range container.Env {
-		if env.Value != "" {
-			envMap[env.Name] = env.Value
-		}
+	postgres := initContainers[0]
+	if postgres.Image != "postgres:16" {
+		t.Errorf("Expected Task sidecar to replace AgentConfig sidecar, got image %q", postgres.Image)
 	}
-	if envMap["HTTPS_PROXY"] != "http://proxy:8080" {
-		t.Errorf("Expected HTTPS_PROXY=http://proxy:8080, got %q", envMap["HTTPS_PROXY"])
+	if !reflect.DeepEqual(postgres.StartupProbe, readiness) {
+		t.Errorf("Expected readinessProbe to be used as startupProbe, got %v", postgres.StartupProbe)
+	}
+	if initContainers[1].StartupProbe != nil {
+		t.Errorf("Expected no startupProbe without a readinessProbe, got %v", initContainers[1].StartupProbe)
 	}
+	if task.Spec.Sidecars[1].RestartPolicy != nil || task.Spec.Sidecars[1].StartupProbe != nil {
+		t.Error("Expected Task sidecars not to be modified")
+	}
+}
 
-	// NodeSelector
-	if job.Spec.Template.Spec.NodeSelector["pool"] != "agents" {
-		t.Errorf("Expected nodeSelector pool=agents, got %q", job.Spec.Template.Spec.NodeSelector["pool"])
+func TestBuildJob_SidecarReservedName(t *testing.T) {
+	builder := NewJobBuilder()
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-sidecar-reserved",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			Sidecars: []corev1.Container{{Name: AgentTypeClaudeCode, Image: "redis:7.2"}},
+		},
+	}
+
+	_, err := builder.Build(task, nil, nil, task.Spec.Prompt)
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("Expected reserved sidecar name error, got %v", err)
 	}
 }
 
@@ -4356,3 +5151,536 @@ func TestBuildJob_NoneCredentials_ServiceAccountName(t *testing.T) {
 		t.Errorf("AWS_REGION = %q, want %q", env.Value, "us-west-2")
 	}
 }
+
+func TestBuildJob_TranscriptArchive(t *testing.T) {
+	newTask := func(archive *kelosv1alpha1.TranscriptArchive) *kelosv1alpha1.Task {
+		return &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+			Spec: kelosv1alpha1.TaskSpec{
+				Type:   AgentTypeCodex,
+				Prompt: "Fix the bug",
+				Credentials: kelosv1alpha1.Credentials{
+					Type:      kelosv1alpha1.CredentialTypeAPIKey,
+					SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+				},
+				TranscriptArchive: archive,
+			},
+		}
+	}
+	envByName := func(c corev1.Container) map[string]corev1.EnvVar {
+		env := make(map[string]corev1.EnvVar)
+		for _, e := range c.Env {
+			env[e.Name] = e
+		}
+		return env
+	}
+
+	t.Run("pvc", func(t *testing.T) {
+		task := newTask(&kelosv1alpha1.TranscriptArchive{
+			PersistentVolumeClaim: &kelosv1alpha1.PVCTranscriptStore{ClaimName: "transcripts"},
+		})
+		job, err := NewJobBuilder().Build(task, nil, nil, task.Spec.Prompt)
+		if err != nil {
+			t.Fatalf("Build() returned error: %v", err)
+		}
+		podSpec := job.Spec.Template.Spec
+
+		var found bool
+		for _, v := range podSpec.Volumes {
+			if v.Name == TranscriptVolumeName && v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == "transcripts" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected transcript volume for claim transcripts, got %v", podSpec.Volumes)
+		}
+
+		container := podSpec.Containers[0]
+		var mounted bool
+		for _, m := range container.VolumeMounts {
+			if m.Name == TranscriptVolumeName && m.MountPath == TranscriptMountPath {
+				mounted = true
+			}
+		}
+		if !mounted {
+			t.Errorf("Expected transcript volume mounted at %s", TranscriptMountPath)
+		}
+
+		env := envByName(container)
+		if env["KELOS_TRANSCRIPT_STORE"].Value != "pvc" || env["KELOS_TRANSCRIPT_ROOT"].Value != "transcripts" || env["KELOS_TRANSCRIPT_DIR"].Value != TranscriptMountPath {
+			t.Errorf("Unexpected transcript env: %v", container.Env)
+		}
+		if env["KELOS_TASK_NAME"].Value != "task-1" {
+			t.Errorf("KELOS_TASK_NAME = %q", env["KELOS_TASK_NAME"].Value)
+		}
+		if ref := env["KELOS_POD_NAME"].ValueFrom; ref == nil || ref.FieldRef == nil || ref.FieldRef.FieldPath != "metadata.name" {
+			t.Errorf("Expected KELOS_POD_NAME from the downward API, got %+v", env["KELOS_POD_NAME"])
+		}
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		task := newTask(&kelosv1alpha1.TranscriptArchive{
+			S3: &kelosv1alpha1.S3TranscriptStore{
+				Endpoint:  "http://minio.minio:9000",
+				Bucket:    "agents",
+				Prefix:    "/kelos/",
+				SecretRef: kelosv1alpha1.SecretReference{Name: "s3-creds"},
+			},
+		})
+		job, err := NewJobBuilder().Build(task, nil, nil, task.Spec.Prompt)
+		if err != nil {
+			t.Fatalf("Build() returned error: %v", err)
+		}
+		container := job.Spec.Template.Spec.Containers[0]
+		env := envByName(container)
+		if env["KELOS_TRANSCRIPT_STORE"].Value != "s3" || env["KELOS_TRANSCRIPT_ROOT"].Value != "agents" ||
+			env["KELOS_TRANSCRIPT_PREFIX"].Value != "kelos" || env["KELOS_TRANSCRIPT_S3_ENDPOINT"].Value != "http://minio.minio:9000" {
+			t.Errorf("Unexpected transcript env: %v", container.Env)
+		}
+		ref := env["AWS_SECRET_ACCESS_KEY"].ValueFrom
+		if ref == nil || ref.SecretKeyRef == nil || ref.SecretKeyRef.Name != "s3-creds" || ref.SecretKeyRef.Key != "AWS_SECRET_ACCESS_KEY" {
+			t.Errorf("Expected AWS_SECRET_ACCESS_KEY from secret s3-creds, got %+v", env["AWS_SECRET_ACCESS_KEY"])
+		}
+	})
+
+	t.Run("configmap", func(t *testing.T) {
+		task := newTask(&kelosv1alpha1.TranscriptArchive{ConfigMap: &kelosv1alpha1.ConfigMapTranscriptStore{}})
+		job, err := NewJobBuilder().Build(task, nil, nil, task.Spec.Prompt)
+		if err != nil {
+			t.Fatalf("Build() returned error: %v", err)
+		}
+		env := envByName(job.Spec.Template.Spec.Containers[0])
+		if env["KELOS_TRANSCRIPT_STORE"].Value != "configmap" {
+			t.Errorf("Expected KELOS_TRANSCRIPT_STORE=configmap, got %+v", env["KELOS_TRANSCRIPT_STORE"])
+		}
+		if len(job.Spec.Template.Spec.Volumes) != 0 {
+			t.Errorf("Expected no transcript volume, got %+v", job.Spec.Template.Spec.Volumes)
+		}
+	})
+}
+
+func TestBuildJob_WorkspaceAdditionalRepos(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-multi-repo",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Update the API and its client",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			Branch: "feature",
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:      "https://github.com/example/repo.git",
+		SecretRef: &kelosv1alpha1.SecretReference{Name: "github-token"},
+		Repos: []kelosv1alpha1.WorkspaceRepo{
+			{
+				Name: "client",
+				Repo: "https://github.com/example/client.git",
+				Ref:  "develop",
+			},
+			{
+				Name:      "infra-config",
+				Repo:      "https://github.example.com/ops/config.git",
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "ghe-token"},
+			},
+		},
+	}
+
+	job, err := NewJobBuilder().Build(task, workspace, nil, task.Spec.Prompt)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	initContainers := job.Spec.Template.Spec.InitContainers
+	var names []string
+	for _, c := range initContainers {
+		names = append(names, c.Name)
+	}
+	wantNames := []string{"git-clone", "git-clone-client", "git-clone-infra-config", "branch-setup"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("Init containers = %v, want %v", names, wantNames)
+	}
+
+	client := initContainers[1]
+	wantArgs := []string{"--", "clone", "--branch", "develop", "--no-single-branch", "--depth", "1",
+		"--", "https://github.com/example/client.git", "/workspace/client"}
+	if !reflect.DeepEqual(client.Args, wantArgs) {
+		t.Errorf("client clone args = %v, want %v", client.Args, wantArgs)
+	}
+	if !strings.Contains(client.Command[2], `password=$GITHUB_TOKEN`) {
+		t.Errorf("Expected client clone to use the workspace token, got %q", client.Command[2])
+	}
+	if !envHasSecretRef(client.Env, "GITHUB_TOKEN", "github-token") {
+		t.Errorf("Expected client clone to get GITHUB_TOKEN from the workspace secret, got %v", client.Env)
+	}
+
+	infra := initContainers[2]
+	script := infra.Command[2]
+	if !strings.Contains(script, `password=$KELOS_GIT_TOKEN_INFRA_CONFIG`) || strings.Contains(script, "$GITHUB_TOKEN") {
+		t.Errorf("Expected infra-config clone to use its own token, got %q", script)
+	}
+	if !strings.Contains(script, "git -C /workspace/infra-config config --add credential.helper") {
+		t.Errorf("Expected infra-config credential helper to be persisted, got %q", script)
+	}
+	if !envHasSecretRef(infra.Env, "KELOS_GIT_TOKEN_INFRA_CONFIG", "ghe-token") || envHasSecretRef(infra.Env, "GITHUB_TOKEN", "github-token") {
+		t.Errorf("Expected infra-config clone to only get its own token, got %v", infra.Env)
+	}
+
+	branchSetup := initContainers[3]
+	for _, want := range []string{
+		"cd /workspace/client && git -c credential.helper= ",
+		"cd /workspace/infra-config && git -c credential.helper= -c credential.helper='!f() { echo \"username=x-access-token\"; echo \"password=$KELOS_GIT_TOKEN_INFRA_CONFIG\"; }; f' fetch origin",
+	} {
+		if !strings.Contains(branchSetup.Command[2], want) {
+			t.Errorf("Expected branch setup script to contain %q, got %q", want, branchSetup.Command[2])
+		}
+	}
+	if !envHasSecretRef(branchSetup.Env, "KELOS_GIT_TOKEN_INFRA_CONFIG", "ghe-token") {
+		t.Errorf("Expected branch setup to get the infra-config token, got %v", branchSetup.Env)
+	}
+
+	agent := job.Spec.Template.Spec.Containers[0]
+	if !envHasSecretRef(agent.Env, "KELOS_GIT_TOKEN_INFRA_CONFIG", "ghe-token") {
+		t.Errorf("Expected agent to get the infra-config token, got %v", agent.Env)
+	}
+	var reposEnv string
+	for _, e := range agent.Env {
+		if e.Name == "KELOS_ADDITIONAL_REPOS" {
+			reposEnv = e.Value
+		}
+	}
+	wantRepos := `[{"name":"client","path":"/workspace/client","repo":"example/client"},` +
+		`{"name":"infra-config","path":"/workspace/infra-config","repo":"github.example.com/ops/config","tokenEnv":"KELOS_GIT_TOKEN_INFRA_CONFIG"}]`
+	if reposEnv != wantRepos {
+		t.Errorf("KELOS_ADDITIONAL_REPOS = %s, want %s", reposEnv, wantRepos)
+	}
+}
+
+func envHasSecretRef(env []corev1.EnvVar, name, secretName string) bool {
+	for _, e := range env {
+		if e.Name == name && e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil &&
+			e.ValueFrom.SecretKeyRef.Name == secretName {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBuildJob_WorkspaceProviders(t *testing.T) {
+	newTask := func() *kelosv1alpha1.Task {
+		return &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-provider",
+				Namespace: "default",
+			},
+			Spec: kelosv1alpha1.TaskSpec{
+				Type:   AgentTypeClaudeCode,
+				Prompt: "Fix issue",
+				Credentials: kelosv1alpha1.Credentials{
+					Type:      kelosv1alpha1.CredentialTypeAPIKey,
+					SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		workspace  *kelosv1alpha1.WorkspaceSpec
+		wantHelper string
+		wantEnv    map[string]string
+		wantValues map[string]string
+		notWantEnv []string
+	}{
+		{
+			name: "gitlab",
+			workspace: &kelosv1alpha1.WorkspaceSpec{
+				Repo:      "https://gitlab.example.com/group/repo.git",
+				Provider:  kelosv1alpha1.GitProviderGitLab,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "gitlab-token"},
+			},
+			wantHelper: `!f() { echo "username=oauth2"; echo "password=$GITLAB_TOKEN"; }; f`,
+			wantEnv:    map[string]string{"GITLAB_TOKEN": "GITLAB_TOKEN"},
+			wantValues: map[string]string{"GITLAB_HOST": "gitlab.example.com", "GLAB_CONFIG_DIR": GLabConfigDir},
+			notWantEnv: []string{"GITHUB_TOKEN", "GH_TOKEN", "GH_HOST", "GH_CONFIG_DIR"},
+		},
+		{
+			name: "bitbucket",
+			workspace: &kelosv1alpha1.WorkspaceSpec{
+				Repo:      "https://bitbucket.org/team/repo.git",
+				Provider:  kelosv1alpha1.GitProviderBitbucket,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "bitbucket-creds"},
+			},
+			wantHelper: `!f() { echo "username=$BITBUCKET_USERNAME"; echo "password=$BITBUCKET_APP_PASSWORD"; }; f`,
+			wantEnv:    map[string]string{"BITBUCKET_USERNAME": "BITBUCKET_USERNAME", "BITBUCKET_APP_PASSWORD": "BITBUCKET_APP_PASSWORD"},
+			notWantEnv: []string{"GITHUB_TOKEN", "GH_HOST"},
+		},
+		{
+			name: "gitea",
+			workspace: &kelosv1alpha1.WorkspaceSpec{
+				Repo:      "https://gitea.example.com/org/repo.git",
+				Provider:  kelosv1alpha1.GitProviderGitea,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "gitea-token"},
+			},
+			wantHelper: `!f() { echo "username=kelos"; echo "password=$GITEA_TOKEN"; }; f`,
+			wantEnv:    map[string]string{"GITEA_TOKEN": "GITEA_TOKEN"},
+			notWantEnv: []string{"GITHUB_TOKEN", "GH_HOST"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := NewJobBuilder().Build(newTask(), tt.workspace, nil, "Fix issue")
+			if err != nil {
+				t.Fatalf("Build() returned error: %v", err)
+			}
+			clone := job.Spec.Template.Spec.InitContainers[0]
+			if !strings.Contains(clone.Command[2], "config --add credential.helper '"+tt.wantHelper+"'") {
+				t.Errorf("Expected clone to persist helper %q, got %q", tt.wantHelper, clone.Command[2])
+			}
+			agent := job.Spec.Template.Spec.Containers[0]
+			for _, c := range []corev1.Container{clone, agent} {
+				env := map[string]corev1.EnvVar{}
+				for _, e := range c.Env {
+					env[e.Name] = e
+				}
+				for name, key := range tt.wantEnv {
+					ref := env[name].ValueFrom
+					if ref == nil || ref.SecretKeyRef.Name != tt.workspace.SecretRef.Name || ref.SecretKeyRef.Key != key {
+						t.Errorf("%s: expected %s from key %s of the workspace secret, got %v", c.Name, name, key, env[name])
+					}
+				}
+				for _, name := range tt.notWantEnv {
+					if _, ok := env[name]; ok {
+						t.Errorf("%s: unexpected env var %s", c.Name, name)
+					}
+				}
+			}
+			for name, value := range tt.wantValues {
+				var got string
+				for _, e := range agent.Env {
+					if e.Name == name {
+						got = e.Value
+					}
+				}
+				if got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildJob_WorkspaceSSHKey(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-ssh",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   AgentTypeClaudeCode,
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "my-secret"},
+			},
+			Branch: "feature",
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:            "git@gitlab.example.com:platform/internal.git",
+		Provider:        kelosv1alpha1.GitProviderGitLab,
+		SSHKeySecretRef: &kelosv1alpha1.SecretReference{Name: "deploy-key"},
+		Remotes: []kelosv1alpha1.GitRemote{
+			{Name: "upstream", URL: "ssh://git@gitlab.example.com:2222/platform/upstream.git"},
+		},
+	}
+
+	job, err := NewJobBuilder().Build(task, workspace, nil, "Fix issue")
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	podSpec := job.Spec.Template.Spec
+	var keyVolume *corev1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == SSHKeyVolumeName {
+			keyVolume = &podSpec.Volumes[i]
+		}
+	}
+	if keyVolume == nil || keyVolume.Secret == nil || keyVolume.Secret.SecretName != "deploy-key" {
+		t.Fatalf("Expected the SSH key secret volume, got %v", podSpec.Volumes)
+	}
+
+	clone := podSpec.InitContainers[0]
+	wantScript := "mkdir -p /workspace/.ssh && cp /kelos/ssh-key/ssh-privatekey /workspace/.ssh/id && " +
+		"cp /kelos/ssh-key/known_hosts /workspace/.ssh/known_hosts && chmod 700 /workspace/.ssh && chmod 600 /workspace/.ssh/id && git \"$@\""
+	if len(clone.Command) != 3 || clone.Command[2] != wantScript {
+		t.Errorf("clone command = %v, want script %q", clone.Command, wantScript)
+	}
+	var mounted bool
+	for _, m := range clone.VolumeMounts {
+		if m.Name == SSHKeyVolumeName && m.MountPath == SSHKeyMountPath && m.ReadOnly {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("Expected the SSH key to be mounted read-only in git-clone, got %v", clone.VolumeMounts)
+	}
+
+	wantSSH := "ssh -i /workspace/.ssh/id -o IdentitiesOnly=yes -o UserKnownHostsFile=/workspace/.ssh/known_hosts -o StrictHostKeyChecking=yes"
+	for _, c := range []corev1.Container{clone, podSpec.InitContainers[2], podSpec.Containers[0]} {
+		var got string
+		for _, e := range c.Env {
+			if e.Name == "GIT_SSH_COMMAND" {
+				got = e.Value
+			}
+		}
+		if got != wantSSH {
+			t.Errorf("%s: GIT_SSH_COMMAND = %q, want %q", c.Name, got, wantSSH)
+		}
+	}
+	if podSpec.InitContainers[2].Name != "branch-setup" {
+		t.Errorf("Expected branch-setup as the third init container, got %s", podSpec.InitContainers[2].Name)
+	}
+	for _, e := range podSpec.Containers[0].Env {
+		if e.Name == "GITLAB_TOKEN" || e.Name == "GITHUB_TOKEN" {
+			t.Errorf("Unexpected token env %s without a secretRef", e.Name)
+		}
+	}
+}
+
+func TestBuildJob_AgentType(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-agent-type",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "goose",
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "goose-secret"},
+			},
+		},
+	}
+	agentType := &kelosv1alpha1.AgentTypeSpec{
+		Image:           "example.com/goose:1.0",
+		ImagePullPolicy: corev1.PullAlways,
+		Credentials:     kelosv1alpha1.AgentTypeCredentials{APIKeyEnv: "OPENAI_API_KEY"},
+		MCP: &kelosv1alpha1.AgentTypeMCP{
+			ConfigPath: "/home/agent/.config/goose/mcp.toml",
+			Format:     kelosv1alpha1.MCPConfigFormatTOML,
+		},
+		Plugins: &kelosv1alpha1.AgentTypePlugins{
+			MountPath:   "/home/agent/.config/goose/plugins",
+			SkillsAgent: "goose-cli",
+		},
+		UsageFormat: "codex",
+	}
+	agentConfig := &kelosv1alpha1.AgentConfigSpec{
+		Skills: []kelosv1alpha1.SkillsShSpec{{Source: "org/skills"}},
+		MCPServers: []kelosv1alpha1.MCPServerSpec{{
+			Name:    "search",
+			Type:    "stdio",
+			Command: "search-mcp",
+			Args:    []string{"--verbose"},
+			Env:     map[string]string{"TOKEN": "x"},
+		}},
+	}
+
+	if _, err := NewJobBuilder().Build(task, nil, agentConfig, "Fix issue"); err == nil {
+		t.Fatal("Expected Build() to reject an agent type without an AgentType")
+	}
+
+	job, err := NewJobBuilder().BuildWithAgentType(task, agentType, nil, agentConfig, "Fix issue")
+	if err != nil {
+		t.Fatalf("BuildWithAgentType() returned error: %v", err)
+	}
+
+	podSpec := job.Spec.Template.Spec
+	agent := podSpec.Containers[0]
+	if agent.Name != "goose" || agent.Image != "example.com/goose:1.0" || agent.ImagePullPolicy != corev1.PullAlways {
+		t.Errorf("agent container = %s %s %s, want goose example.com/goose:1.0 Always", agent.Name, agent.Image, agent.ImagePullPolicy)
+	}
+	if !envHasSecretRef(agent.Env, "OPENAI_API_KEY", "goose-secret") {
+		t.Errorf("Expected OPENAI_API_KEY from the credentials secret, got %v", agent.Env)
+	}
+	env := map[string]string{}
+	for _, e := range agent.Env {
+		env[e.Name] = e.Value
+	}
+	if env["KELOS_USAGE_FORMAT"] != "codex" {
+		t.Errorf("KELOS_USAGE_FORMAT = %q, want codex", env["KELOS_USAGE_FORMAT"])
+	}
+	if env["KELOS_PLUGIN_DIR"] != "/home/agent/.config/goose/plugins" {
+		t.Errorf("KELOS_PLUGIN_DIR = %q, want the AgentType plugin path", env["KELOS_PLUGIN_DIR"])
+	}
+
+	mounts := map[string]corev1.VolumeMount{}
+	for _, m := range agent.VolumeMounts {
+		mounts[m.Name] = m
+	}
+	if m := mounts[PluginVolumeName]; m.MountPath != "/home/agent/.config/goose/plugins" {
+		t.Errorf("plugin mount = %v, want the AgentType plugin path", m)
+	}
+	if m := mounts[MCPConfigVolumeName]; m.MountPath != "/home/agent/.config/goose/mcp.toml" || m.SubPath != "config" {
+		t.Errorf("MCP config mount = %v, want the config file at the AgentType path", m)
+	}
+
+	var skillsScript, mcpScript string
+	for _, c := range podSpec.InitContainers {
+		switch c.Name {
+		case "skills-install":
+			skillsScript = c.Command[2]
+		case "mcp-config":
+			mcpScript = c.Command[2]
+		}
+	}
+	if !strings.Contains(skillsScript, "-a 'goose-cli'") {
+		t.Errorf("Expected skills to be installed for goose-cli, got %q", skillsScript)
+	}
+	wantTOML := "[mcp_servers.\"search\"]\ncommand = \"search-mcp\"\nargs = [\"--verbose\"]\nenv = { \"TOKEN\" = \"x\" }\n\n"
+	if !strings.Contains(mcpScript, base64.StdEncoding.EncodeToString([]byte(wantTOML))) {
+		t.Errorf("Expected the MCP config init container to write %q, got %q", wantTOML, mcpScript)
+	}
+}
+
+func TestBuildJob_AgentTypeDefaultCredentials(t *testing.T) {
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-agent-type-defaults", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "aider",
+			Prompt: "Fix issue",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeOAuth,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "aider-secret"},
+			},
+		},
+	}
+	job, err := NewJobBuilder().BuildWithAgentType(task, &kelosv1alpha1.AgentTypeSpec{Image: "example.com/aider"}, nil, nil, "Fix issue")
+	if err != nil {
+		t.Fatalf("BuildWithAgentType() returned error: %v", err)
+	}
+	if !envHasSecretRef(job.Spec.Template.Spec.Containers[0].Env, "KELOS_OAUTH_TOKEN", "aider-secret") {
+		t.Errorf("Expected KELOS_OAUTH_TOKEN for an AgentType without a mapping, got %v", job.Spec.Template.Spec.Containers[0].Env)
+	}
+
+	// A built-in type keeps its credential mapping when an AgentType only
+	// overrides its image.
+	task.Spec.Type = AgentTypeCodex
+	job, err = NewJobBuilder().BuildWithAgentType(task, &kelosv1alpha1.AgentTypeSpec{Image: "example.com/codex"}, nil, nil, "Fix issue")
+	if err != nil {
+		t.Fatalf("BuildWithAgentType() returned error: %v", err)
+	}
+	if c := job.Spec.Template.Spec.Containers[0]; c.Image != "example.com/codex" || !envHasSecretRef(c.Env, "CODEX_AUTH_JSON", "aider-secret") {
+		t.Errorf("Expected the overridden codex image with CODEX_AUTH_JSON, got %s %v", c.Image, c.Env)
+	}
+}
@@ -3,30 +3,42 @@ package controller
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
 	"strconv"
+	"strings"
 	"text/template"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
 	"github.com/kelos-dev/kelos/internal/githubapp"
+	"github.com/kelos-dev/kelos/internal/source"
+	"github.com/kelos-dev/kelos/internal/transcript"
+	"github.com/kelos-dev/kelos/internal/usage"
 )
 
 const (
@@ -38,6 +50,18 @@ const (
 
 	// outputRetryInterval is the delay between output capture retries.
 	outputRetryInterval = 5 * time.Second
+
+	// workspaceSetupLogLines is the number of setup log lines included in
+	// the WorkspaceSetup condition when setup fails.
+	workspaceSetupLogLines = 20
+
+	// maxConditionLogBytes bounds the log excerpt in a condition message.
+	maxConditionLogBytes = 4096
+
+	// agentTypeWaitTimeout is how long after its creation a Task of a custom
+	// type waits for its AgentType to be registered before it fails. This
+	// lets a Task and its AgentType be applied together in any order.
+	agentTypeWaitTimeout = 5 * time.Minute
 )
 
 // TaskReconciler reconciles a Task object.
@@ -49,17 +73,40 @@ type TaskReconciler struct {
 	TokenClient  *githubapp.TokenClient
 	Recorder     record.EventRecorder
 	BranchLocker *BranchLocker
+
+	// NewFileFetcher returns a FileFetcher for the given GitHub repository,
+	// used to load promptFrom.repoFile. Defaults to the GitHub contents API.
+	NewFileFetcher func(owner, repo, token, apiBaseURL string) source.FileFetcher
+
+	// UsageRetention is how long usage ledgers are kept. Zero keeps them
+	// forever.
+	UsageRetention time.Duration
 }
 
+// errPromptKeyNotFound is returned when a promptFrom ConfigMap does not
+// (yet) contain the referenced key.
+var errPromptKeyNotFound = errors.New("prompt key not found")
+
+// errPromptSourceUnsupported is returned when spec.promptFrom cannot be
+// used with the Task's Workspace. Retrying does not help, so the Task
+// fails.
+var errPromptSourceUnsupported = errors.New("unsupported prompt source")
+
+// promptConfigMapIndex indexes Tasks by the name of the ConfigMap their
+// prompt is loaded from.
+const promptConfigMapIndex = "spec.promptFrom.configMapKeyRef.name"
+
 // +kubebuilder:rbac:groups=kelos.dev,resources=tasks,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kelos.dev,resources=tasks/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=kelos.dev,resources=tasks/finalizers,verbs=update
 // +kubebuilder:rbac:groups=kelos.dev,resources=workspaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=kelos.dev,resources=agentconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kelos.dev,resources=agenttypes,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile handles Task reconciliation.
@@ -91,6 +138,11 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Honor cancellation requests before creating or tracking the Job.
+	if reason, ok := task.Annotations[kelosv1alpha1.AnnotationCancelRequested]; ok {
+		return r.handleCancellation(ctx, &task, reason)
+	}
+
 	// Check if Job already exists
 	var job batchv1.Job
 	jobExists := true
@@ -105,6 +157,12 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 
 	// Create Job if it doesn't exist
 	if !jobExists {
+		// A Task that finished without a Job, e.g. because its AgentType
+		// never appeared, must not be started by a later reconcile.
+		if task.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || task.Status.Phase == kelosv1alpha1.TaskPhaseFailed {
+			return r.reconcileTTL(ctx, &task, ctrl.Result{})
+		}
+
 		if len(task.Spec.DependsOn) > 0 {
 			ready, result, err := r.checkDependencies(ctx, &task)
 			if err != nil || !ready {
@@ -112,6 +170,13 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 			}
 		}
 
+		if task.Spec.ResumeFrom != "" {
+			ready, result, err := r.checkResumeFrom(ctx, &task)
+			if err != nil || !ready {
+				return result, err
+			}
+		}
+
 		if task.Spec.Branch != "" {
 			if task.Spec.WorkspaceRef == nil {
 				logger.Info("Branch is set without workspaceRef, branch checkout will not happen", "task", task.Name, "branch", task.Spec.Branch)
@@ -145,11 +210,18 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return result, err
 	}
 
-	// Check TTL expiration for finished Tasks
-	if expired, requeueAfter := r.ttlExpired(&task); expired {
+	return r.reconcileTTL(ctx, &task, result)
+}
+
+// reconcileTTL deletes a finished Task whose TTL has expired, or adjusts the
+// result so the Task is requeued when the TTL is due.
+func (r *TaskReconciler) reconcileTTL(ctx context.Context, task *kelosv1alpha1.Task, result ctrl.Result) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if expired, requeueAfter := r.ttlExpired(task); expired {
 		logger.Info("Deleting Task due to TTL expiration", "task", task.Name)
-		r.recordEvent(&task, corev1.EventTypeNormal, "TaskExpired", "Deleting Task due to TTL expiration")
-		if err := r.Delete(ctx, &task); err != nil {
+		r.recordEvent(task, corev1.EventTypeNormal, "TaskExpired", "Deleting Task due to TTL expiration")
+		if err := r.Delete(ctx, task); err != nil {
 			if apierrors.IsNotFound(err) {
 				return ctrl.Result{}, nil
 			}
@@ -167,6 +239,69 @@ func (r *TaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	return result, nil
 }
 
+// handleCancellation stops a Task that carries the cancel-requested
+// annotation. The Job is deleted and the Task is marked Failed with the
+// annotation value as the reason. Tasks that already finished are left as-is.
+func (r *TaskReconciler) handleCancellation(ctx context.Context, task *kelosv1alpha1.Task, reason string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if task.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || task.Status.Phase == kelosv1alpha1.TaskPhaseFailed {
+		return r.reconcileTTL(ctx, task, ctrl.Result{})
+	}
+
+	if err := r.deleteJob(ctx, task); err != nil {
+		logger.Error(err, "unable to delete Job")
+		return ctrl.Result{}, err
+	}
+
+	if task.Spec.Branch != "" {
+		r.BranchLocker.Release(branchLockKey(task), task.Name)
+	}
+
+	message := "Task cancelled"
+	if reason != "" {
+		message = fmt.Sprintf("Task cancelled: %s", reason)
+	}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if getErr := r.Get(ctx, client.ObjectKeyFromObject(task), task); getErr != nil {
+			return getErr
+		}
+		task.Status.Phase = kelosv1alpha1.TaskPhaseFailed
+		task.Status.Message = message
+		now := metav1.Now()
+		task.Status.CompletionTime = &now
+		return r.Status().Update(ctx, task)
+	}); err != nil {
+		logger.Error(err, "Unable to update Task status")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Cancelled Task", "task", task.Name, "reason", reason)
+	r.recordEvent(task, corev1.EventTypeNormal, "TaskCancelled", "%s", message)
+	r.recordUsage(ctx, task)
+	taskCompletedTotal.WithLabelValues(task.Namespace, task.Spec.Type, string(kelosv1alpha1.TaskPhaseFailed)).Inc()
+
+	return r.reconcileTTL(ctx, task, ctrl.Result{})
+}
+
+// deleteJob deletes the Task's Job if it exists.
+func (r *TaskReconciler) deleteJob(ctx context.Context, task *kelosv1alpha1.Task) error {
+	var job batchv1.Job
+	if err := r.Get(ctx, client.ObjectKey{Namespace: task.Namespace, Name: task.Name}, &job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	propagationPolicy := metav1.DeletePropagationBackground
+	if err := r.Delete(ctx, &job, &client.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // handleDeletion handles Task deletion.
 func (r *TaskReconciler) handleDeletion(ctx context.Context, task *kelosv1alpha1.Task) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -178,17 +313,15 @@ func (r *TaskReconciler) handleDeletion(ctx context.Context, task *kelosv1alpha1
 		}
 
 		// Delete the Job if it exists
-		var job batchv1.Job
-		if err := r.Get(ctx, client.ObjectKey{Namespace: task.Namespace, Name: task.Name}, &job); err == nil {
-			propagationPolicy := metav1.DeletePropagationBackground
-			if err := r.Delete(ctx, &job, &client.DeleteOptions{
-				PropagationPolicy: &propagationPolicy,
-			}); err != nil && !apierrors.IsNotFound(err) {
-				logger.Error(err, "unable to delete Job")
-				return ctrl.Result{}, err
-			}
+		if err := r.deleteJob(ctx, task); err != nil {
+			logger.Error(err, "unable to delete Job")
+			return ctrl.Result{}, err
 		}
 
+		// Record Tasks that finished without passing through updateStatus,
+		// e.g. because a dependency failed, before they are gone.
+		r.recordUsage(ctx, task)
+
 		// Remove finalizer
 		controllerutil.RemoveFinalizer(task, taskFinalizer)
 		if err := r.Update(ctx, task); err != nil {
@@ -221,7 +354,7 @@ func (r *TaskReconciler) createJob(ctx context.Context, task *kelosv1alpha1.Task
 		workspace = &ws.Spec
 
 		// Handle GitHub App authentication
-		if workspace.SecretRef != nil {
+		if workspace.SecretRef != nil || len(workspace.Repos) > 0 {
 			resolvedWorkspace, err := r.resolveGitHubAppToken(ctx, task, workspace)
 			if err != nil {
 				logger.Error(err, "Unable to resolve GitHub App token")
@@ -244,20 +377,24 @@ func (r *TaskReconciler) createJob(ctx context.Context, task *kelosv1alpha1.Task
 	}
 
 	var agentConfig *kelosv1alpha1.AgentConfigSpec
-	if task.Spec.AgentConfigRef != nil {
-		var ac kelosv1alpha1.AgentConfig
-		if err := r.Get(ctx, client.ObjectKey{
-			Namespace: task.Namespace,
-			Name:      task.Spec.AgentConfigRef.Name,
-		}, &ac); err != nil {
-			if apierrors.IsNotFound(err) {
-				logger.Info("AgentConfig not found yet, requeuing", "agentConfig", task.Spec.AgentConfigRef.Name)
-				return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	if refs := agentConfigRefs(&task.Spec); len(refs) > 0 {
+		specs := make([]kelosv1alpha1.AgentConfigSpec, 0, len(refs))
+		for _, ref := range refs {
+			var ac kelosv1alpha1.AgentConfig
+			if err := r.Get(ctx, client.ObjectKey{
+				Namespace: task.Namespace,
+				Name:      ref.Name,
+			}, &ac); err != nil {
+				if apierrors.IsNotFound(err) {
+					logger.Info("AgentConfig not found yet, requeuing", "agentConfig", ref.Name)
+					return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+				}
+				logger.Error(err, "Unable to fetch AgentConfig", "agentConfig", ref.Name)
+				return ctrl.Result{}, err
 			}
-			logger.Error(err, "Unable to fetch AgentConfig", "agentConfig", task.Spec.AgentConfigRef.Name)
-			return ctrl.Result{}, err
+			specs = append(specs, ac.Spec)
 		}
-		agentConfig = &ac.Spec
+		agentConfig = mergeAgentConfigs(specs)
 
 		if len(agentConfig.MCPServers) > 0 {
 			resolved, err := r.resolveMCPServerSecrets(ctx, task.Namespace, agentConfig.MCPServers)
@@ -281,9 +418,71 @@ func (r *TaskReconciler) createJob(ctx context.Context, task *kelosv1alpha1.Task
 		}
 	}
 
-	resolvedPrompt := r.resolvePromptTemplate(ctx, task)
+	agentType, err := r.getAgentType(ctx, task.Spec.Type)
+	if err != nil {
+		logger.Error(err, "Unable to fetch AgentType", "agentType", task.Spec.Type)
+		return ctrl.Result{}, err
+	}
+	if agentType == nil && !isBuiltinAgentType(task.Spec.Type) {
+		if agentTypeWaitExpired(task, time.Now()) {
+			msg := fmt.Sprintf("AgentType %q not found", task.Spec.Type)
+			logger.Info("AgentType not found, failing Task", "agentType", task.Spec.Type)
+			r.recordEvent(task, corev1.EventTypeWarning, "AgentTypeNotFound", "AgentType %q not found", task.Spec.Type)
+			updateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				if getErr := r.Get(ctx, client.ObjectKeyFromObject(task), task); getErr != nil {
+					return getErr
+				}
+				task.Status.Phase = kelosv1alpha1.TaskPhaseFailed
+				task.Status.Message = msg
+				now := metav1.Now()
+				task.Status.CompletionTime = &now
+				return r.Status().Update(ctx, task)
+			})
+			if updateErr != nil {
+				logger.Error(updateErr, "Unable to update Task status")
+			}
+			return ctrl.Result{}, nil
+		}
+		logger.Info("AgentType not found yet, requeuing", "agentType", task.Spec.Type)
+		r.setWaitingPhase(ctx, task, fmt.Sprintf("Waiting for AgentType %q", task.Spec.Type))
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	prompt := task.Spec.Prompt
+	if task.Spec.PromptFrom != nil {
+		loaded, err := r.loadPromptFrom(ctx, task, workspace)
+		if err != nil {
+			if apierrors.IsNotFound(err) || errors.Is(err, errPromptKeyNotFound) || errors.Is(err, source.ErrFileNotFound) {
+				logger.Info("Prompt source not available yet, requeuing", "error", err)
+				r.setWaitingPhase(ctx, task, fmt.Sprintf("Waiting for prompt: %v", err))
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+			logger.Error(err, "Unable to load prompt")
+			r.recordEvent(task, corev1.EventTypeWarning, "PromptLoadFailed", "Failed to load prompt: %v", err)
+			if errors.Is(err, errPromptSourceUnsupported) {
+				updateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					if getErr := r.Get(ctx, client.ObjectKeyFromObject(task), task); getErr != nil {
+						return getErr
+					}
+					task.Status.Phase = kelosv1alpha1.TaskPhaseFailed
+					task.Status.Message = fmt.Sprintf("Failed to load prompt: %v", err)
+					now := metav1.Now()
+					task.Status.CompletionTime = &now
+					return r.Status().Update(ctx, task)
+				})
+				if updateErr != nil {
+					logger.Error(updateErr, "Unable to update Task status")
+				}
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		prompt = loaded
+	}
+
+	resolvedPrompt := r.resolvePromptTemplate(ctx, task, prompt)
 
-	job, err := r.JobBuilder.Build(task, workspace, agentConfig, resolvedPrompt)
+	job, err := r.JobBuilder.BuildWithAgentType(task, agentType, workspace, agentConfig, resolvedPrompt)
 	if err != nil {
 		logger.Error(err, "unable to build Job")
 		r.recordEvent(task, corev1.EventTypeWarning, "JobBuildFailed", "Failed to build Job: %v", err)
@@ -312,6 +511,24 @@ func (r *TaskReconciler) createJob(ctx context.Context, task *kelosv1alpha1.Task
 			return ctrl.Result{Requeue: true}, nil
 		}
 		logger.Error(err, "unable to create Job")
+		if apierrors.IsInvalid(err) {
+			// Retrying cannot fix a pod spec the API server rejects, such
+			// as an invalid podOverrides.affinity, which the CRD schema
+			// does not validate.
+			r.recordEvent(task, corev1.EventTypeWarning, "JobCreateFailed", "Failed to create Job: %v", err)
+			updateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				if getErr := r.Get(ctx, client.ObjectKeyFromObject(task), task); getErr != nil {
+					return getErr
+				}
+				task.Status.Phase = kelosv1alpha1.TaskPhaseFailed
+				task.Status.Message = fmt.Sprintf("Failed to create Job: %v", err)
+				return r.Status().Update(ctx, task)
+			})
+			if updateErr != nil {
+				logger.Error(updateErr, "Unable to update Task status")
+			}
+			return ctrl.Result{}, nil
+		}
 		return ctrl.Result{}, err
 	}
 
@@ -335,16 +552,89 @@ func (r *TaskReconciler) createJob(ctx context.Context, task *kelosv1alpha1.Task
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// agentTypeWaitExpired reports whether a Task has waited longer than
+// agentTypeWaitTimeout for its AgentType.
+func agentTypeWaitExpired(task *kelosv1alpha1.Task, now time.Time) bool {
+	return now.Sub(task.CreationTimestamp.Time) >= agentTypeWaitTimeout
+}
+
+// getAgentType returns the spec of the AgentType with the given name, or
+// nil when there is none. Clusters without the AgentType CRD have none.
+func (r *TaskReconciler) getAgentType(ctx context.Context, name string) (*kelosv1alpha1.AgentTypeSpec, error) {
+	var at kelosv1alpha1.AgentType
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, &at); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &at.Spec, nil
+}
+
 // resolveGitHubAppToken checks if the workspace secret is a GitHub App secret,
 // and if so, generates an installation token and creates a new secret with
 // the GITHUB_TOKEN key. Returns a modified workspace spec pointing to the
-// generated secret.
+// generated secret. The secrets of additional repositories are resolved the
+// same way, each into its own generated secret.
 func (r *TaskReconciler) resolveGitHubAppToken(ctx context.Context, task *kelosv1alpha1.Task, workspace *kelosv1alpha1.WorkspaceSpec) (*kelosv1alpha1.WorkspaceSpec, error) {
+	if workspaceProvider(workspace) != kelosv1alpha1.GitProviderGitHub {
+		return workspace, nil
+	}
+	resolved := workspace
+	if workspace.SecretRef != nil {
+		var err error
+		resolved, err = resolveWorkspaceGitHubAppToken(ctx, r.Client, r.Scheme, r.TokenClient, task, task.Name+"-github-token", workspace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var repos []kelosv1alpha1.WorkspaceRepo
+	for i, repo := range workspace.Repos {
+		if repo.SecretRef == nil {
+			continue
+		}
+		repoResolved, err := resolveWorkspaceGitHubAppToken(ctx, r.Client, r.Scheme, r.TokenClient, task,
+			repoTokenSecretName(task, repo.Name),
+			&kelosv1alpha1.WorkspaceSpec{Repo: repo.Repo, SecretRef: repo.SecretRef})
+		if err != nil {
+			return nil, fmt.Errorf("repo %q: %w", repo.Name, err)
+		}
+		if repoResolved.SecretRef.Name == repo.SecretRef.Name {
+			continue
+		}
+		if repos == nil {
+			repos = append([]kelosv1alpha1.WorkspaceRepo(nil), workspace.Repos...)
+		}
+		repos[i].SecretRef = repoResolved.SecretRef
+	}
+	if repos != nil {
+		copied := *resolved
+		copied.Repos = repos
+		resolved = &copied
+	}
+	return resolved, nil
+}
+
+// repoTokenSecretName returns the name of the generated token secret for an
+// additional repository of the Task. The suffix is derived from the Task UID
+// and the repository name, so the name cannot collide with the main token
+// secret of another Task, which always ends in "-github-token".
+func repoTokenSecretName(task *kelosv1alpha1.Task, repoName string) string {
+	sum := sha256.Sum256([]byte(string(task.UID) + "/" + repoName))
+	return task.Name + "-github-token-" + hex.EncodeToString(sum[:])[:10]
+}
+
+// resolveWorkspaceGitHubAppToken implements resolveGitHubAppToken for any
+// owner of the generated secret, such as a Task or a Workspace. The secret
+// is named tokenSecretName and lives in the owner's namespace. An existing
+// secret of that name is only updated when the owner controls it.
+func resolveWorkspaceGitHubAppToken(ctx context.Context, c client.Client, scheme *runtime.Scheme, tokenClient *githubapp.TokenClient, owner client.Object, tokenSecretName string, workspace *kelosv1alpha1.WorkspaceSpec) (*kelosv1alpha1.WorkspaceSpec, error) {
 	logger := log.FromContext(ctx)
 
 	var secret corev1.Secret
-	if err := r.Get(ctx, client.ObjectKey{
-		Namespace: task.Namespace,
+	if err := c.Get(ctx, client.ObjectKey{
+		Namespace: owner.GetNamespace(),
 		Name:      workspace.SecretRef.Name,
 	}, &secret); err != nil {
 		return nil, fmt.Errorf("fetching workspace secret %q: %w", workspace.SecretRef.Name, err)
@@ -354,7 +644,7 @@ func (r *TaskReconciler) resolveGitHubAppToken(ctx context.Context, task *kelosv
 		return workspace, nil
 	}
 
-	if r.TokenClient == nil {
+	if tokenClient == nil {
 		return nil, fmt.Errorf("GitHub App secret detected but TokenClient is not configured")
 	}
 
@@ -368,8 +658,8 @@ func (r *TaskReconciler) resolveGitHubAppToken(ctx context.Context, task *kelosv
 	// Use a per-call TokenClient so that concurrent reconciles with different
 	// hosts do not race on the shared r.TokenClient.BaseURL.
 	tc := &githubapp.TokenClient{
-		BaseURL: r.TokenClient.BaseURL,
-		Client:  r.TokenClient.Client,
+		BaseURL: tokenClient.BaseURL,
+		Client:  tokenClient.Client,
 	}
 	if workspace.Repo != "" {
 		host, _, _ := parseGitHubRepo(workspace.Repo)
@@ -383,33 +673,35 @@ func (r *TaskReconciler) resolveGitHubAppToken(ctx context.Context, task *kelosv
 		return nil, fmt.Errorf("generating installation token: %w", err)
 	}
 
-	// Create a new secret with the generated token, owned by the Task
-	tokenSecretName := task.Name + "-github-token"
+	// Create a new secret with the generated token, owned by the owner
 	tokenSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      tokenSecretName,
-			Namespace: task.Namespace,
+			Namespace: owner.GetNamespace(),
 		},
 		StringData: map[string]string{
 			"GITHUB_TOKEN": tokenResp.Token,
 		},
 	}
 
-	if err := controllerutil.SetControllerReference(task, tokenSecret, r.Scheme); err != nil {
+	if err := controllerutil.SetControllerReference(owner, tokenSecret, scheme); err != nil {
 		return nil, fmt.Errorf("setting owner reference on token secret: %w", err)
 	}
 
-	if err := r.Create(ctx, tokenSecret); err != nil {
+	if err := c.Create(ctx, tokenSecret); err != nil {
 		if !apierrors.IsAlreadyExists(err) {
 			return nil, fmt.Errorf("creating token secret: %w", err)
 		}
 		// Update existing secret
 		existing := &corev1.Secret{}
-		if err := r.Get(ctx, client.ObjectKey{Name: tokenSecretName, Namespace: task.Namespace}, existing); err != nil {
+		if err := c.Get(ctx, client.ObjectKey{Name: tokenSecretName, Namespace: owner.GetNamespace()}, existing); err != nil {
 			return nil, fmt.Errorf("fetching existing token secret: %w", err)
 		}
+		if !metav1.IsControlledBy(existing, owner) {
+			return nil, fmt.Errorf("token secret %q already exists and is not owned by %s", tokenSecretName, owner.GetName())
+		}
 		existing.StringData = tokenSecret.StringData
-		if err := r.Update(ctx, existing); err != nil {
+		if err := c.Update(ctx, existing); err != nil {
 			return nil, fmt.Errorf("updating token secret: %w", err)
 		}
 	}
@@ -422,6 +714,57 @@ func (r *TaskReconciler) resolveGitHubAppToken(ctx context.Context, task *kelosv
 	return &resolved, nil
 }
 
+// loadPromptFrom reads the Task prompt from the ConfigMap key or repository
+// file referenced by spec.promptFrom.
+func (r *TaskReconciler) loadPromptFrom(ctx context.Context, task *kelosv1alpha1.Task, workspace *kelosv1alpha1.WorkspaceSpec) (string, error) {
+	from := task.Spec.PromptFrom
+
+	if ref := from.ConfigMapKeyRef; ref != nil {
+		var cm corev1.ConfigMap
+		if err := r.Get(ctx, client.ObjectKey{Namespace: task.Namespace, Name: ref.Name}, &cm); err != nil {
+			return "", fmt.Errorf("fetching prompt ConfigMap %q: %w", ref.Name, err)
+		}
+		value, ok := cm.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("%w: key %q in ConfigMap %q", errPromptKeyNotFound, ref.Key, ref.Name)
+		}
+		return value, nil
+	}
+
+	if from.RepoFile == nil {
+		return "", fmt.Errorf("promptFrom has no source configured")
+	}
+	if workspace == nil {
+		return "", fmt.Errorf("%w: promptFrom.repoFile requires a workspaceRef", errPromptSourceUnsupported)
+	}
+	if provider := workspaceProvider(workspace); provider != kelosv1alpha1.GitProviderGitHub {
+		return "", fmt.Errorf("%w: promptFrom.repoFile is only supported for github Workspaces, not %s; use promptFrom.configMapKeyRef instead", errPromptSourceUnsupported, provider)
+	}
+
+	var token string
+	if workspace.SecretRef != nil {
+		var secret corev1.Secret
+		if err := r.Get(ctx, client.ObjectKey{Namespace: task.Namespace, Name: workspace.SecretRef.Name}, &secret); err != nil {
+			return "", fmt.Errorf("fetching workspace secret %q: %w", workspace.SecretRef.Name, err)
+		}
+		token = string(secret.Data["GITHUB_TOKEN"])
+	}
+
+	host, owner, repo := parseGitHubRepo(workspace.Repo)
+	newFetcher := r.NewFileFetcher
+	if newFetcher == nil {
+		newFetcher = func(owner, repo, token, apiBaseURL string) source.FileFetcher {
+			return &source.GitHubFileFetcher{Owner: owner, Repo: repo, Token: token, BaseURL: apiBaseURL}
+		}
+	}
+
+	ref := from.RepoFile.Ref
+	if ref == "" {
+		ref = workspace.Ref
+	}
+	return newFetcher(owner, repo, token, gitHubAPIBaseURL(host)).FetchFile(ctx, from.RepoFile.Path, ref)
+}
+
 func (r *TaskReconciler) resolveMCPServerSecrets(ctx context.Context, namespace string, servers []kelosv1alpha1.MCPServerSpec) ([]kelosv1alpha1.MCPServerSpec, error) {
 	resolved := make([]kelosv1alpha1.MCPServerSpec, len(servers))
 	for i, server := range servers {
@@ -516,13 +859,24 @@ func (r *TaskReconciler) updateStatus(ctx context.Context, task *kelosv1alpha1.T
 	podNameChanged := podListSucceeded && task.Status.PodName != podName
 	phaseChanged := newPhase != ""
 
+	setupCondition := r.workspaceSetupCondition(ctx, task, pods.Items, podName)
+	if newPhase == kelosv1alpha1.TaskPhaseFailed {
+		failedSetup := setupCondition
+		if failedSetup == nil {
+			failedSetup = meta.FindStatusCondition(task.Status.Conditions, kelosv1alpha1.TaskConditionWorkspaceSetup)
+		}
+		if failedSetup != nil && failedSetup.Status == metav1.ConditionFalse {
+			newMessage = strings.SplitN(failedSetup.Message, "\n", 2)[0]
+		}
+	}
+
 	// Check if we should retry capturing outputs for an already-completed task
 	retryOutputs := !phaseChanged &&
 		len(task.Status.Outputs) == 0 && len(task.Status.Results) == 0 &&
 		task.Status.CompletionTime != nil &&
 		time.Since(task.Status.CompletionTime.Time) < outputRetryWindow
 
-	if !phaseChanged && !podNameChanged && !retryOutputs {
+	if !phaseChanged && !podNameChanged && !retryOutputs && setupCondition == nil {
 		return ctrl.Result{}, nil
 	}
 
@@ -539,6 +893,27 @@ func (r *TaskReconciler) updateStatus(ctx context.Context, task *kelosv1alpha1.T
 		outputs, results = r.readOutputs(ctx, task.Namespace, effectivePodName, containerName)
 	}
 
+	// Archive the transcript to a ConfigMap while the pod still exists.
+	// Other stores are written by kelos-capture and reported in results;
+	// their location is recorded in a ConfigMap so that it outlives the
+	// Task.
+	transcriptURI := results["transcript"]
+	if archive := task.Spec.TranscriptArchive; archive != nil {
+		if archive.ConfigMap != nil {
+			if setCompletionTime {
+				effectivePodName := podName
+				if effectivePodName == "" {
+					effectivePodName = task.Status.PodName
+				}
+				transcriptURI = r.archiveTranscriptToConfigMap(ctx, task, effectivePodName)
+			}
+		} else if transcriptURI != "" {
+			if err := r.writeTranscriptConfigMap(ctx, task, transcriptURI, nil, false); err != nil {
+				logger.Error(err, "Unable to record transcript location", "task", task.Name)
+			}
+		}
+	}
+
 	// When retrying output capture, skip the status update if we still
 	// have nothing — just requeue to try again later.
 	if retryOutputs && outputs == nil && results == nil {
@@ -552,6 +927,9 @@ func (r *TaskReconciler) updateStatus(ctx context.Context, task *kelosv1alpha1.T
 		if podNameChanged {
 			task.Status.PodName = podName
 		}
+		if setupCondition != nil {
+			meta.SetStatusCondition(&task.Status.Conditions, *setupCondition)
+		}
 		if phaseChanged {
 			task.Status.Phase = newPhase
 			task.Status.Message = newMessage
@@ -563,11 +941,15 @@ func (r *TaskReconciler) updateStatus(ctx context.Context, task *kelosv1alpha1.T
 				task.Status.CompletionTime = &now
 				task.Status.Outputs = outputs
 				task.Status.Results = results
+				task.Status.Transcript = transcriptURI
 			}
 		}
 		if retryOutputs && (outputs != nil || results != nil) {
 			task.Status.Outputs = outputs
 			task.Status.Results = results
+			if transcriptURI != "" {
+				task.Status.Transcript = transcriptURI
+			}
 		}
 		return r.Status().Update(ctx, task)
 	}); err != nil {
@@ -591,6 +973,11 @@ func (r *TaskReconciler) updateStatus(ctx context.Context, task *kelosv1alpha1.T
 	if (setCompletionTime || retryOutputs) && results != nil {
 		RecordCostTokenMetrics(task, results)
 	}
+	// Every finished Task is recorded, and recorded again once late
+	// results arrive.
+	if setCompletionTime || (retryOutputs && results != nil) {
+		r.recordUsage(ctx, task)
+	}
 
 	if setCompletionTime && (outputs != nil || results != nil) {
 		r.recordEvent(task, corev1.EventTypeNormal, "OutputsCaptured", "Captured %d outputs and %d results from agent", len(outputs), len(results))
@@ -604,6 +991,84 @@ func (r *TaskReconciler) updateStatus(ctx context.Context, task *kelosv1alpha1.T
 	return ctrl.Result{}, nil
 }
 
+// workspaceSetupCondition returns the WorkspaceSetup condition for the
+// setup container of the named pod once it has terminated, or nil when
+// there is nothing new to report. Failed setups include the end of the
+// setup logs in the condition message.
+func (r *TaskReconciler) workspaceSetupCondition(ctx context.Context, task *kelosv1alpha1.Task, pods []corev1.Pod, podName string) *metav1.Condition {
+	var terminated *corev1.ContainerStateTerminated
+	for i := range pods {
+		if pods[i].Name != podName {
+			continue
+		}
+		for _, cs := range pods[i].Status.InitContainerStatuses {
+			if cs.Name == WorkspaceSetupContainerName {
+				terminated = cs.State.Terminated
+			}
+		}
+	}
+	if terminated == nil {
+		return nil
+	}
+
+	condition := &metav1.Condition{
+		Type:               kelosv1alpha1.TaskConditionWorkspaceSetup,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Succeeded",
+		Message:            "Workspace setup commands succeeded",
+		ObservedGeneration: task.Generation,
+	}
+	if terminated.ExitCode != 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Failed"
+		condition.Message = fmt.Sprintf("Workspace setup commands failed with exit code %d", terminated.ExitCode)
+		// timeout exits with 124 when the commands run out of time.
+		if terminated.ExitCode == 124 {
+			condition.Reason = "TimedOut"
+			condition.Message = "Workspace setup commands timed out"
+		}
+	}
+
+	if existing := meta.FindStatusCondition(task.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status && existing.Reason == condition.Reason {
+		return nil
+	}
+
+	if condition.Status == metav1.ConditionFalse {
+		if logs := r.readLogTail(ctx, task.Namespace, podName, WorkspaceSetupContainerName, workspaceSetupLogLines); logs != "" {
+			condition.Message += "\n" + logs
+		}
+	}
+	return condition
+}
+
+// readLogTail returns the last lines of a container's log, or "" if it
+// cannot be read.
+func (r *TaskReconciler) readLogTail(ctx context.Context, namespace, podName, container string, lines int64) string {
+	if r.Clientset == nil || podName == "" {
+		return ""
+	}
+	req := r.Clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &lines,
+	})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("Unable to read Pod logs", "pod", podName, "container", container, "error", err)
+		return ""
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return ""
+	}
+	if len(data) > maxConditionLogBytes {
+		data = data[len(data)-maxConditionLogBytes:]
+	}
+	return strings.TrimRight(string(data), "\n")
+}
+
 func latestTaskPodName(pods []corev1.Pod) string {
 	if len(pods) == 0 {
 		return ""
@@ -674,6 +1139,136 @@ func (r *TaskReconciler) readOutputs(ctx context.Context, namespace, podName, co
 	return outputs, ResultsFromOutputs(outputs)
 }
 
+// archiveTranscriptToConfigMap stores the transcript that kelos-capture
+// printed to the agent container log in a ConfigMap named after the Task
+// and returns its reference, or "" if no transcript can be read or stored.
+func (r *TaskReconciler) archiveTranscriptToConfigMap(ctx context.Context, task *kelosv1alpha1.Task, podName string) string {
+	if r.Clientset == nil || podName == "" {
+		return ""
+	}
+	logger := log.FromContext(ctx)
+
+	stream, err := r.Clientset.CoreV1().Pods(task.Namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: task.Spec.Type,
+	}).Stream(ctx)
+	if err != nil {
+		logger.Info("Unable to read Pod logs for transcript archive", "pod", podName, "error", err)
+		return ""
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		logger.Info("Unable to read Pod log stream for transcript archive", "pod", podName, "error", err)
+		return ""
+	}
+	compressed, truncated, found, err := transcript.ParseBlock(data)
+	if err != nil {
+		logger.Error(err, "Unable to read transcript from Pod logs", "pod", podName)
+		return ""
+	}
+	if !found {
+		logger.Info("Agent container did not print a transcript", "pod", podName)
+		return ""
+	}
+	uri := transcript.URI(transcript.SchemeConfigMap, transcript.ConfigMapName(task.Name), "")
+	if err := r.writeTranscriptConfigMap(ctx, task, uri, compressed, truncated); err != nil {
+		logger.Error(err, "Unable to archive transcript", "task", task.Name)
+		return ""
+	}
+	if truncated {
+		r.recordEvent(task, corev1.EventTypeWarning, "TranscriptTruncated", "Transcript exceeds the ConfigMap size limit; only its end was archived")
+	}
+	return uri
+}
+
+// writeTranscriptConfigMap writes the <task>-transcript ConfigMap of a
+// finished Task. It records the transcript reference, and how to read it,
+// so that kelos logs finds the transcript once the Task is deleted; for the
+// ConfigMap store it also holds the compressed transcript. The ConfigMap is
+// not owned by the Task so that it survives Task deletion, and is deleted
+// after the retention of the archive by pruneTranscriptConfigMaps.
+func (r *TaskReconciler) writeTranscriptConfigMap(ctx context.Context, task *kelosv1alpha1.Task, uri string, compressed []byte, truncated bool) error {
+	archive := task.Spec.TranscriptArchive
+	retention := transcript.DefaultRetention
+	if archive != nil && archive.Retention != nil {
+		retention = archive.Retention.Duration
+	}
+	now := time.Now()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      transcript.ConfigMapName(task.Name),
+			Namespace: task.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		if cm.Labels == nil {
+			cm.Labels = make(map[string]string)
+		}
+		cm.Labels["kelos.dev/task"] = task.Name
+		cm.Labels[transcript.Label] = "true"
+		if cm.Annotations == nil {
+			cm.Annotations = make(map[string]string)
+		}
+		// The agent type selects the log parser once the Task is gone.
+		cm.Annotations[transcript.AgentTypeAnnotation] = task.Spec.Type
+		cm.Annotations[transcript.URIAnnotation] = uri
+		cm.Annotations[transcript.ExpiresAtAnnotation] = now.Add(retention).UTC().Format(time.RFC3339)
+		delete(cm.Annotations, transcript.TruncatedAnnotation)
+		if truncated {
+			cm.Annotations[transcript.TruncatedAnnotation] = "true"
+		}
+		delete(cm.Annotations, transcript.S3EndpointAnnotation)
+		delete(cm.Annotations, transcript.S3RegionAnnotation)
+		delete(cm.Annotations, transcript.S3SecretAnnotation)
+		if archive != nil && archive.S3 != nil {
+			cm.Annotations[transcript.S3EndpointAnnotation] = archive.S3.Endpoint
+			cm.Annotations[transcript.S3RegionAnnotation] = archive.S3.Region
+			cm.Annotations[transcript.S3SecretAnnotation] = archive.S3.SecretRef.Name
+		}
+		cm.BinaryData = nil
+		if compressed != nil {
+			cm.BinaryData = map[string][]byte{transcript.ConfigMapKey: compressed}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("writing transcript ConfigMap: %w", err)
+	}
+	if err := r.pruneTranscriptConfigMaps(ctx, task.Namespace, now); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to prune transcript ConfigMaps", "namespace", task.Namespace)
+	}
+	return nil
+}
+
+// pruneTranscriptConfigMaps deletes the transcript ConfigMaps of a
+// namespace whose retention has expired. ConfigMaps without an expiry are
+// kept for transcript.DefaultRetention after their creation.
+func (r *TaskReconciler) pruneTranscriptConfigMaps(ctx context.Context, namespace string, now time.Time) error {
+	var cms corev1.ConfigMapList
+	if err := r.List(ctx, &cms, client.InNamespace(namespace), client.MatchingLabels{transcript.Label: "true"}); err != nil {
+		return fmt.Errorf("listing transcript ConfigMaps: %w", err)
+	}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		expiresAt := cm.CreationTimestamp.Add(transcript.DefaultRetention)
+		if v, ok := cm.Annotations[transcript.ExpiresAtAnnotation]; ok {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				continue
+			}
+			expiresAt = t
+		}
+		if now.Before(expiresAt) {
+			continue
+		}
+		if err := r.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting transcript ConfigMap %s: %w", cm.Name, err)
+		}
+	}
+	return nil
+}
+
 // recordEvent records a Kubernetes Event on the given object if a Recorder is configured.
 func (r *TaskReconciler) recordEvent(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
 	if r.Recorder != nil {
@@ -751,6 +1346,29 @@ func (r *TaskReconciler) checkDependencies(ctx context.Context, task *kelosv1alp
 	return true, ctrl.Result{}, nil
 }
 
+// checkResumeFrom verifies that the Task whose session is resumed has
+// finished, so that its session has been saved. A missing Task does not block
+// the resume because its session outlives it on the session claim.
+func (r *TaskReconciler) checkResumeFrom(ctx context.Context, task *kelosv1alpha1.Task) (bool, ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var prev kelosv1alpha1.Task
+	if err := r.Get(ctx, client.ObjectKey{Namespace: task.Namespace, Name: task.Spec.ResumeFrom}, &prev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, ctrl.Result{}, nil
+		}
+		return false, ctrl.Result{}, err
+	}
+
+	if prev.Status.Phase != kelosv1alpha1.TaskPhaseSucceeded && prev.Status.Phase != kelosv1alpha1.TaskPhaseFailed {
+		logger.Info("Resumed task has not finished", "resumeFrom", prev.Name, "phase", prev.Status.Phase)
+		r.setWaitingPhase(ctx, task, fmt.Sprintf("Waiting for Task %q to finish before resuming its session", prev.Name))
+		return false, ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	return true, ctrl.Result{}, nil
+}
+
 // detectCycle walks the dependency graph from the given task and returns an
 // error if a cycle is detected.
 func (r *TaskReconciler) detectCycle(ctx context.Context, task *kelosv1alpha1.Task) error {
@@ -848,11 +1466,11 @@ func (r *TaskReconciler) setWaitingPhase(ctx context.Context, task *kelosv1alpha
 
 // resolvePromptTemplate resolves Go template references in the prompt using
 // dependency outputs. Falls back to the raw prompt on any error.
-func (r *TaskReconciler) resolvePromptTemplate(ctx context.Context, task *kelosv1alpha1.Task) string {
+func (r *TaskReconciler) resolvePromptTemplate(ctx context.Context, task *kelosv1alpha1.Task, prompt string) string {
 	logger := log.FromContext(ctx)
 
 	if len(task.Spec.DependsOn) == 0 {
-		return task.Spec.Prompt
+		return prompt
 	}
 
 	deps := make(map[string]interface{})
@@ -862,7 +1480,7 @@ func (r *TaskReconciler) resolvePromptTemplate(ctx context.Context, task *kelosv
 			Namespace: task.Namespace, Name: depName,
 		}, &depTask); err != nil {
 			logger.Info("Failed to fetch dependency for prompt template, using raw prompt", "dependency", depName, "error", err)
-			return task.Spec.Prompt
+			return prompt
 		}
 		deps[depName] = map[string]interface{}{
 			"Outputs": depTask.Status.Outputs,
@@ -871,16 +1489,16 @@ func (r *TaskReconciler) resolvePromptTemplate(ctx context.Context, task *kelosv
 		}
 	}
 
-	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(task.Spec.Prompt)
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(prompt)
 	if err != nil {
 		logger.Info("Failed to parse prompt template, using raw prompt", "error", err)
-		return task.Spec.Prompt
+		return prompt
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, map[string]interface{}{"Deps": deps}); err != nil {
 		logger.Info("Failed to execute prompt template, using raw prompt", "error", err)
-		return task.Spec.Prompt
+		return prompt
 	}
 	return buf.String()
 }
@@ -909,6 +1527,19 @@ func RecordCostTokenMetrics(task *kelosv1alpha1.Task, results map[string]string)
 	}
 }
 
+// recordUsage appends the Task's usage to the durable usage ledger so that
+// kelos report cost still covers the Task after it is deleted. Unfinished
+// Tasks are skipped. Failures are logged and do not fail the reconcile.
+func (r *TaskReconciler) recordUsage(ctx context.Context, task *kelosv1alpha1.Task) {
+	record, ok := usage.RecordFromTask(task)
+	if !ok {
+		return
+	}
+	if err := usage.Append(ctx, r.Client, record, r.UsageRetention); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to record Task usage", "task", task.Name)
+	}
+}
+
 // isJobFailed checks whether the Job has permanently failed by looking for a
 // JobFailed condition with status True. Unlike checking job.Status.Failed > 0,
 // this correctly handles Jobs with backoffLimit > 0 where intermediate pod
@@ -924,17 +1555,72 @@ func isJobFailed(job *batchv1.Job) bool {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *TaskReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &kelosv1alpha1.Task{}, promptConfigMapIndex, indexPromptConfigMap); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kelosv1alpha1.Task{}).
 		Owns(&batchv1.Job{}).
 		Watches(&kelosv1alpha1.Task{}, handler.EnqueueRequestsFromMapFunc(r.enqueueDependentTasks)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueTasksForConfigMap),
+			builder.WithPredicates(promptConfigMapPredicate())).
 		Complete(r)
 }
 
+// indexPromptConfigMap returns the name of the ConfigMap a Task loads its
+// prompt from, for promptConfigMapIndex.
+func indexPromptConfigMap(obj client.Object) []string {
+	task, ok := obj.(*kelosv1alpha1.Task)
+	if !ok || task.Spec.PromptFrom == nil || task.Spec.PromptFrom.ConfigMapKeyRef == nil {
+		return nil
+	}
+	return []string{task.Spec.PromptFrom.ConfigMapKeyRef.Name}
+}
+
+// promptConfigMapPredicate passes the ConfigMap events that can unblock a
+// Task waiting for its prompt: creation and changes to the data.
+func promptConfigMapPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return true },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCM, okOld := e.ObjectOld.(*corev1.ConfigMap)
+			newCM, okNew := e.ObjectNew.(*corev1.ConfigMap)
+			return okOld && okNew && !equality.Semantic.DeepEqual(oldCM.Data, newCM.Data)
+		},
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// enqueueTasksForConfigMap returns reconcile requests for Tasks that load
+// their prompt from the given ConfigMap and have neither started a Job nor
+// finished, so that they pick up the ConfigMap as soon as it is created or
+// updated.
+func (r *TaskReconciler) enqueueTasksForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	var taskList kelosv1alpha1.TaskList
+	if err := r.List(ctx, &taskList,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{promptConfigMapIndex: obj.GetName()},
+	); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range taskList.Items {
+		t := &taskList.Items[i]
+		if t.Status.JobName != "" || t.Status.Phase == kelosv1alpha1.TaskPhaseSucceeded || t.Status.Phase == kelosv1alpha1.TaskPhaseFailed {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(t)})
+	}
+	return requests
+}
+
 // enqueueDependentTasks returns reconcile requests for tasks that depend on the
-// given task or are waiting for the same branch. This ensures dependent and
-// branch-queued tasks are reconciled immediately when a task reaches a terminal
-// phase, instead of waiting for a requeue timer.
+// given task, resume its session or are waiting for the same branch. This
+// ensures dependent, resuming and branch-queued tasks are reconciled
+// immediately when a task reaches a terminal phase, instead of waiting for a
+// requeue timer.
 func (r *TaskReconciler) enqueueDependentTasks(ctx context.Context, obj client.Object) []reconcile.Request {
 	task, ok := obj.(*kelosv1alpha1.Task)
 	if !ok {
@@ -967,6 +1653,13 @@ func (r *TaskReconciler) enqueueDependentTasks(ctx context.Context, obj client.O
 				break
 			}
 		}
+		// Re-enqueue tasks resuming this task's session
+		if !seen[t.Name] && t.Spec.ResumeFrom == task.Name {
+			seen[t.Name] = true
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&t),
+			})
+		}
 		// Re-enqueue tasks waiting for the same workspace+branch
 		if !seen[t.Name] && task.Spec.Branch != "" && t.Spec.Branch != "" &&
 			branchLockKey(&t) == branchLockKey(task) &&
@@ -7,23 +7,34 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
 	"github.com/kelos-dev/kelos/internal/githubapp"
+	"github.com/kelos-dev/kelos/internal/source"
+	"github.com/kelos-dev/kelos/internal/transcript"
+	"github.com/kelos-dev/kelos/internal/usage"
 )
 
 func TestTTLExpired(t *testing.T) {
@@ -648,6 +659,120 @@ func TestUpdateStatusRefreshesPodName(t *testing.T) {
 	}
 }
 
+func TestUpdateStatusReportsWorkspaceSetup(t *testing.T) {
+	tests := []struct {
+		name        string
+		exitCode    int32
+		jobFailed   bool
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+		wantTaskMsg string
+	}{
+		{
+			name:        "setup succeeded",
+			exitCode:    0,
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  "Succeeded",
+			wantMessage: "Workspace setup commands succeeded",
+		},
+		{
+			name:        "setup failed",
+			exitCode:    2,
+			jobFailed:   true,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "Failed",
+			wantMessage: "Workspace setup commands failed with exit code 2\nfake logs",
+			wantTaskMsg: "Workspace setup commands failed with exit code 2",
+		},
+		{
+			name:        "setup timed out",
+			exitCode:    124,
+			jobFailed:   true,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "TimedOut",
+			wantMessage: "Workspace setup commands timed out\nfake logs",
+			wantTaskMsg: "Workspace setup commands timed out",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+			utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+			task := &kelosv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-1",
+					Namespace: "default",
+				},
+				Spec: kelosv1alpha1.TaskSpec{
+					Type:   "codex",
+					Prompt: "test",
+					Credentials: kelosv1alpha1.Credentials{
+						Type:      kelosv1alpha1.CredentialTypeAPIKey,
+						SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+					},
+				},
+				Status: kelosv1alpha1.TaskStatus{
+					Phase:   kelosv1alpha1.TaskPhaseRunning,
+					PodName: "task-pod",
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-pod",
+					Namespace: "default",
+					Labels:    map[string]string{"kelos.dev/task": "task-1"},
+				},
+				Status: corev1.PodStatus{
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "git-clone", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+						{Name: WorkspaceSetupContainerName, State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{ExitCode: tt.exitCode},
+						}},
+					},
+				},
+			}
+			job := &batchv1.Job{}
+			if tt.jobFailed {
+				job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+			} else {
+				job.Status.Active = 1
+			}
+
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(task).
+				WithObjects(task, pod).
+				Build()
+			r := &TaskReconciler{Client: cl, Scheme: scheme, Clientset: k8sfake.NewSimpleClientset(pod)}
+			if _, err := r.updateStatus(context.Background(), task, job); err != nil {
+				t.Fatalf("updateStatus() error: %v", err)
+			}
+
+			updated := &kelosv1alpha1.Task{}
+			if err := cl.Get(context.Background(), client.ObjectKeyFromObject(task), updated); err != nil {
+				t.Fatalf("getting updated task: %v", err)
+			}
+			if len(updated.Status.Conditions) != 1 {
+				t.Fatalf("Expected 1 condition, got %v", updated.Status.Conditions)
+			}
+			c := updated.Status.Conditions[0]
+			if c.Type != kelosv1alpha1.TaskConditionWorkspaceSetup || c.Status != tt.wantStatus || c.Reason != tt.wantReason {
+				t.Errorf("condition = %s/%s/%s, want %s/%s/%s", c.Type, c.Status, c.Reason, kelosv1alpha1.TaskConditionWorkspaceSetup, tt.wantStatus, tt.wantReason)
+			}
+			if c.Message != tt.wantMessage {
+				t.Errorf("condition message = %q, want %q", c.Message, tt.wantMessage)
+			}
+			if tt.wantTaskMsg != "" && updated.Status.Message != tt.wantTaskMsg {
+				t.Errorf("task message = %q, want %q", updated.Status.Message, tt.wantTaskMsg)
+			}
+		})
+	}
+}
+
 func TestUpdateStatusClearsStalePodNameWhenNoLivePodsRemain(t *testing.T) {
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
@@ -693,3 +818,1047 @@ func TestUpdateStatusClearsStalePodNameWhenNoLivePodsRemain(t *testing.T) {
 		t.Fatalf("task.Status.PodName = %q, want empty", updated.Status.PodName)
 	}
 }
+
+func TestHandleCancellation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "task-1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				kelosv1alpha1.AnnotationCancelRequested: "source item closed",
+			},
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "claude-code",
+			Prompt: "test",
+			Branch: "feature",
+			WorkspaceRef: &kelosv1alpha1.WorkspaceReference{
+				Name: "ws",
+			},
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+			},
+		},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase: kelosv1alpha1.TaskPhaseRunning,
+		},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "task-1",
+			Namespace: "default",
+		},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(task).
+		WithObjects(task, job).
+		Build()
+
+	locker := NewBranchLocker()
+	locker.TryAcquire(branchLockKey(task), task.Name)
+
+	r := &TaskReconciler{Client: cl, Scheme: scheme, BranchLocker: locker}
+	if _, err := r.handleCancellation(context.Background(), task, "source item closed"); err != nil {
+		t.Fatalf("handleCancellation() error: %v", err)
+	}
+
+	updated := &kelosv1alpha1.Task{}
+	if err := cl.Get(context.Background(), client.ObjectKeyFromObject(task), updated); err != nil {
+		t.Fatalf("Getting updated task: %v", err)
+	}
+	if updated.Status.Phase != kelosv1alpha1.TaskPhaseFailed {
+		t.Errorf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseFailed)
+	}
+	if updated.Status.Message != "Task cancelled: source item closed" {
+		t.Errorf("Message = %q, want %q", updated.Status.Message, "Task cancelled: source item closed")
+	}
+	if updated.Status.CompletionTime == nil {
+		t.Error("Expected CompletionTime to be set")
+	}
+
+	var remaining batchv1.JobList
+	if err := cl.List(context.Background(), &remaining, client.InNamespace("default")); err != nil {
+		t.Fatalf("Listing jobs: %v", err)
+	}
+	if len(remaining.Items) != 0 {
+		t.Errorf("Expected Job to be deleted, found %d", len(remaining.Items))
+	}
+
+	if acquired, holder := locker.TryAcquire(branchLockKey(task), "other"); !acquired {
+		t.Errorf("Expected branch lock to be released, still held by %q", holder)
+	}
+}
+
+func TestHandleCancellationLeavesFinishedTask(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "task-1",
+			Namespace: "default",
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "claude-code",
+			Prompt: "test",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+			},
+		},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase:   kelosv1alpha1.TaskPhaseSucceeded,
+			Message: "Task completed successfully",
+		},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(task).
+		WithObjects(task).
+		Build()
+
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+	if _, err := r.handleCancellation(context.Background(), task, "source item closed"); err != nil {
+		t.Fatalf("handleCancellation() error: %v", err)
+	}
+
+	updated := &kelosv1alpha1.Task{}
+	if err := cl.Get(context.Background(), client.ObjectKeyFromObject(task), updated); err != nil {
+		t.Fatalf("Getting updated task: %v", err)
+	}
+	if updated.Status.Phase != kelosv1alpha1.TaskPhaseSucceeded {
+		t.Errorf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseSucceeded)
+	}
+}
+
+type fakeFileFetcher struct {
+	files map[string]string
+	refs  []string
+}
+
+func (f *fakeFileFetcher) FetchFile(_ context.Context, path, ref string) (string, error) {
+	f.refs = append(f.refs, ref)
+	content, ok := f.files[path]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", source.ErrFileNotFound, path)
+	}
+	return content, nil
+}
+
+func TestLoadPromptFromConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type: "claude-code",
+			PromptFrom: &kelosv1alpha1.PromptSource{
+				ConfigMapKeyRef: &kelosv1alpha1.ConfigMapKeyReference{Name: "prompts", Key: "review"},
+			},
+		},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(task).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+
+	_, err := r.loadPromptFrom(context.Background(), task, nil)
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("Expected NotFound error for missing ConfigMap, got %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "prompts", Namespace: "default"},
+		Data:       map[string]string{"other": "x"},
+	}
+	if err := cl.Create(context.Background(), cm); err != nil {
+		t.Fatalf("Creating ConfigMap: %v", err)
+	}
+	_, err = r.loadPromptFrom(context.Background(), task, nil)
+	if !errors.Is(err, errPromptKeyNotFound) {
+		t.Fatalf("Expected errPromptKeyNotFound, got %v", err)
+	}
+
+	cm.Data["review"] = "Review the latest changes"
+	if err := cl.Update(context.Background(), cm); err != nil {
+		t.Fatalf("Updating ConfigMap: %v", err)
+	}
+	got, err := r.loadPromptFrom(context.Background(), task, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "Review the latest changes" {
+		t.Errorf("Prompt = %q, want %q", got, "Review the latest changes")
+	}
+}
+
+func TestLoadPromptFromRepoFile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-token", Namespace: "default"},
+		Data:       map[string][]byte{"GITHUB_TOKEN": []byte("secret-token")},
+	}
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type: "claude-code",
+			PromptFrom: &kelosv1alpha1.PromptSource{
+				RepoFile: &kelosv1alpha1.RepoFileReference{Path: "prompts/update.md"},
+			},
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:      "https://github.com/kelos-dev/kelos.git",
+		Ref:       "main",
+		SecretRef: &kelosv1alpha1.SecretReference{Name: "github-token"},
+	}
+
+	files := &fakeFileFetcher{files: map[string]string{"prompts/update.md": "Update the docs"}}
+	var gotOwner, gotRepo, gotToken, gotBaseURL string
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(task, secret).Build()
+	r := &TaskReconciler{
+		Client: cl,
+		Scheme: scheme,
+		NewFileFetcher: func(owner, repo, token, apiBaseURL string) source.FileFetcher {
+			gotOwner, gotRepo, gotToken, gotBaseURL = owner, repo, token, apiBaseURL
+			return files
+		},
+	}
+
+	got, err := r.loadPromptFrom(context.Background(), task, workspace)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "Update the docs" {
+		t.Errorf("Prompt = %q, want %q", got, "Update the docs")
+	}
+	if gotOwner != "kelos-dev" || gotRepo != "kelos" || gotToken != "secret-token" || gotBaseURL != "" {
+		t.Errorf("Fetcher created with (%q, %q, %q, %q)", gotOwner, gotRepo, gotToken, gotBaseURL)
+	}
+	if len(files.refs) != 1 || files.refs[0] != "main" {
+		t.Errorf("Expected file to be read at the workspace ref, got %v", files.refs)
+	}
+
+	if _, err := r.loadPromptFrom(context.Background(), task, nil); !errors.Is(err, errPromptSourceUnsupported) {
+		t.Errorf("Expected errPromptSourceUnsupported when repoFile is used without a workspace, got %v", err)
+	}
+
+	gitlab := workspace.DeepCopy()
+	gitlab.Repo = "https://gitlab.com/kelos-dev/kelos.git"
+	gitlab.Provider = kelosv1alpha1.GitProviderGitLab
+	if _, err := r.loadPromptFrom(context.Background(), task, gitlab); !errors.Is(err, errPromptSourceUnsupported) {
+		t.Errorf("Expected errPromptSourceUnsupported for a gitlab workspace, got %v", err)
+	}
+	if len(files.refs) != 1 {
+		t.Errorf("Expected no GitHub API call for a gitlab workspace, got refs %v", files.refs)
+	}
+}
+
+func TestReconcileWaitsForMissingPromptRepoFile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	workspace := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws", Namespace: "default"},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo: "https://github.com/kelos-dev/kelos.git",
+			Ref:  "main",
+		},
+	}
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "task-1",
+			Namespace:  "default",
+			Finalizers: []string{taskFinalizer},
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type: "claude-code",
+			PromptFrom: &kelosv1alpha1.PromptSource{
+				RepoFile: &kelosv1alpha1.RepoFileReference{Path: "prompts/missing.md"},
+			},
+			WorkspaceRef: &kelosv1alpha1.WorkspaceReference{Name: "ws"},
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+			},
+		},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(task).
+		WithObjects(task, workspace).
+		Build()
+	r := &TaskReconciler{
+		Client:       cl,
+		Scheme:       scheme,
+		JobBuilder:   NewJobBuilder(),
+		BranchLocker: NewBranchLocker(),
+		NewFileFetcher: func(owner, repo, token, apiBaseURL string) source.FileFetcher {
+			return &fakeFileFetcher{}
+		},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(task)}
+
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("Expected the Task to be requeued while the prompt file is missing")
+	}
+
+	updated := &kelosv1alpha1.Task{}
+	if err := cl.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Getting updated task: %v", err)
+	}
+	if updated.Status.Phase != kelosv1alpha1.TaskPhaseWaiting {
+		t.Errorf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseWaiting)
+	}
+	if !strings.Contains(updated.Status.Message, "prompts/missing.md") {
+		t.Errorf("Expected the message to name the missing file, got %q", updated.Status.Message)
+	}
+}
+
+func TestReconcileFailsTaskWithUnsupportedPromptSource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "task-1",
+			Namespace:  "default",
+			Finalizers: []string{taskFinalizer},
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type: "claude-code",
+			PromptFrom: &kelosv1alpha1.PromptSource{
+				RepoFile: &kelosv1alpha1.RepoFileReference{Path: "prompts/update.md"},
+			},
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+			},
+		},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(task).
+		WithObjects(task).
+		Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme, JobBuilder: NewJobBuilder(), BranchLocker: NewBranchLocker()}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(task)}
+
+	// The second reconcile stands in for a resync or a ConfigMap event
+	// and must leave the failed Task alone.
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error: %v", err)
+		}
+	}
+
+	updated := &kelosv1alpha1.Task{}
+	if err := cl.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Getting updated task: %v", err)
+	}
+	if updated.Status.Phase != kelosv1alpha1.TaskPhaseFailed {
+		t.Errorf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseFailed)
+	}
+	if updated.Status.CompletionTime == nil {
+		t.Error("Expected CompletionTime to be set")
+	}
+	var jobs batchv1.JobList
+	if err := cl.List(context.Background(), &jobs, client.InNamespace("default")); err != nil {
+		t.Fatalf("Listing jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("Expected no Job for the failed Task, found %d", len(jobs.Items))
+	}
+}
+
+func TestEnqueueTasksForConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	promptFrom := func(name string) *kelosv1alpha1.PromptSource {
+		return &kelosv1alpha1.PromptSource{
+			ConfigMapKeyRef: &kelosv1alpha1.ConfigMapKeyReference{Name: name, Key: "prompt"},
+		}
+	}
+	waiting := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "waiting", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code", PromptFrom: promptFrom("prompts")},
+	}
+	started := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "started", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code", PromptFrom: promptFrom("prompts")},
+		Status:     kelosv1alpha1.TaskStatus{JobName: "started"},
+	}
+	failed := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code", PromptFrom: promptFrom("prompts")},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseFailed},
+	}
+	other := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "claude-code", PromptFrom: promptFrom("other-prompts")},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(waiting, started, failed, other).
+		WithIndex(&kelosv1alpha1.Task{}, promptConfigMapIndex, indexPromptConfigMap).
+		Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "prompts", Namespace: "default"}}
+	requests := r.enqueueTasksForConfigMap(context.Background(), cm)
+	if len(requests) != 1 || requests[0].Name != "waiting" {
+		t.Errorf("Expected only the waiting task to be enqueued, got %v", requests)
+	}
+}
+
+func TestPromptConfigMapPredicate(t *testing.T) {
+	p := promptConfigMapPredicate()
+	oldCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "prompts", Namespace: "default"},
+		Data:       map[string]string{"prompt": "v1"},
+	}
+
+	if !p.Create(event.CreateEvent{Object: oldCM}) {
+		t.Error("Expected ConfigMap creation to pass")
+	}
+
+	relabeled := oldCM.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "a"}
+	if p.Update(event.UpdateEvent{ObjectOld: oldCM, ObjectNew: relabeled}) {
+		t.Error("Expected a metadata-only update to be filtered out")
+	}
+
+	changed := oldCM.DeepCopy()
+	changed.Data["prompt"] = "v2"
+	if !p.Update(event.UpdateEvent{ObjectOld: oldCM, ObjectNew: changed}) {
+		t.Error("Expected a data change to pass")
+	}
+
+	if p.Delete(event.DeleteEvent{Object: oldCM}) {
+		t.Error("Expected ConfigMap deletion to be filtered out")
+	}
+}
+
+func TestCheckResumeFrom(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	newTask := func(resumeFrom string) *kelosv1alpha1.Task {
+		return &kelosv1alpha1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "follow-up", Namespace: "default"},
+			Spec: kelosv1alpha1.TaskSpec{
+				Type:       "claude-code",
+				Prompt:     "Now add tests",
+				Session:    &kelosv1alpha1.SessionSpec{PersistentVolumeClaimName: "sessions"},
+				ResumeFrom: resumeFrom,
+			},
+		}
+	}
+	running := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default"},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseRunning},
+	}
+	done := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "done", Namespace: "default"},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseSucceeded},
+	}
+
+	tests := []struct {
+		name       string
+		resumeFrom string
+		wantReady  bool
+	}{
+		{"finished", "done", true},
+		{"running", "running", false},
+		{"deleted", "gone", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := newTask(tt.resumeFrom)
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(task).
+				WithObjects(task, running.DeepCopy(), done.DeepCopy()).
+				Build()
+			r := &TaskReconciler{Client: cl, Scheme: scheme}
+
+			ready, _, err := r.checkResumeFrom(context.Background(), task)
+			if err != nil {
+				t.Fatalf("checkResumeFrom() error: %v", err)
+			}
+			if ready != tt.wantReady {
+				t.Errorf("ready = %v, want %v", ready, tt.wantReady)
+			}
+			if !tt.wantReady {
+				updated := &kelosv1alpha1.Task{}
+				if err := cl.Get(context.Background(), client.ObjectKeyFromObject(task), updated); err != nil {
+					t.Fatalf("Getting updated task: %v", err)
+				}
+				if updated.Status.Phase != kelosv1alpha1.TaskPhaseWaiting {
+					t.Errorf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseWaiting)
+				}
+			}
+		})
+	}
+}
+
+func TestEnqueueDependentTasksResumeFrom(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	prev := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "prev", Namespace: "default"},
+		Status:     kelosv1alpha1.TaskStatus{Phase: kelosv1alpha1.TaskPhaseSucceeded},
+	}
+	resuming := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "resuming", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:       "claude-code",
+			Session:    &kelosv1alpha1.SessionSpec{PersistentVolumeClaimName: "sessions"},
+			ResumeFrom: "prev",
+		},
+	}
+	unrelated := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(prev, resuming, unrelated).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+
+	requests := r.enqueueDependentTasks(context.Background(), prev)
+	if len(requests) != 1 || requests[0].Name != "resuming" {
+		t.Errorf("Expected only the resuming task to be enqueued, got %v", requests)
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	completed := metav1.NewTime(time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC))
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "task-1",
+			Namespace: "default",
+			UID:       "uid-1",
+			Labels:    map[string]string{"kelos.dev/taskspawner": "fixer"},
+		},
+		Spec: kelosv1alpha1.TaskSpec{Type: "codex", Prompt: "test"},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase:          kelosv1alpha1.TaskPhaseSucceeded,
+			CompletionTime: &completed,
+			Results:        map[string]string{"cost-usd": "0.75", "input-tokens": "100"},
+		},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+	r.recordUsage(context.Background(), task)
+
+	records, err := usage.List(context.Background(), cl, client.InNamespace("default"))
+	if err != nil {
+		t.Fatalf("listing usage: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 usage record, got %d", len(records))
+	}
+	if records[0].Spawner != "fixer" || records[0].CostUSD != 0.75 || records[0].InputTokens != 100 {
+		t.Errorf("unexpected usage record: %+v", records[0])
+	}
+}
+
+func TestRecordUsageWithoutResults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	completed := metav1.NewTime(time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC))
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default", UID: "uid-1"},
+		Spec:       kelosv1alpha1.TaskSpec{Type: "codex", Prompt: "test"},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase:          kelosv1alpha1.TaskPhaseFailed,
+			Message:        "Task cancelled: source item closed",
+			CompletionTime: &completed,
+		},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+	r.recordUsage(context.Background(), task)
+
+	records, err := usage.List(context.Background(), cl, client.InNamespace("default"))
+	if err != nil {
+		t.Fatalf("listing usage: %v", err)
+	}
+	if len(records) != 1 || records[0].Phase != "Failed" || records[0].CostUSD != 0 {
+		t.Errorf("expected a Failed record without usage, got %+v", records)
+	}
+}
+
+func TestArchiveTranscriptToConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:              "codex",
+			Prompt:            "test",
+			TranscriptArchive: &kelosv1alpha1.TranscriptArchive{ConfigMap: &kelosv1alpha1.ConfigMapTranscriptStore{}},
+		},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "task-1-pod", Namespace: "default"}}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme, Clientset: k8sfake.NewSimpleClientset(pod)}
+
+	// The fake clientset returns "fake logs", which hold no transcript
+	// block, so nothing is archived.
+	if got := r.archiveTranscriptToConfigMap(context.Background(), task, "task-1-pod"); got != "" {
+		t.Errorf("expected no archive without a transcript block, got %q", got)
+	}
+	if got := r.archiveTranscriptToConfigMap(context.Background(), task, ""); got != "" {
+		t.Errorf("expected no archive without a pod, got %q", got)
+	}
+
+	body := []byte(`{"type":"result"}` + "\n")
+	compressed, _, err := transcript.Compress(body, transcript.MaxConfigMapBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.writeTranscriptConfigMap(context.Background(), task, "configmap://task-1-transcript", compressed, false); err != nil {
+		t.Fatalf("writeTranscriptConfigMap() error: %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := cl.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "task-1-transcript"}, &cm); err != nil {
+		t.Fatalf("getting transcript ConfigMap: %v", err)
+	}
+	if len(cm.OwnerReferences) != 0 {
+		t.Error("transcript ConfigMap must not be owned by the Task")
+	}
+	data, err := transcript.Decompress(cm.BinaryData[transcript.ConfigMapKey])
+	if err != nil {
+		t.Fatalf("decompressing transcript: %v", err)
+	}
+	if string(data) != string(body) {
+		t.Errorf("archived transcript = %q, want %q", data, body)
+	}
+	if cm.Annotations[transcript.URIAnnotation] != "configmap://task-1-transcript" {
+		t.Errorf("transcript reference = %q", cm.Annotations[transcript.URIAnnotation])
+	}
+}
+
+func TestWriteTranscriptConfigMapRecordsS3Location(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "task-1", Namespace: "default"},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "claude-code",
+			Prompt: "test",
+			TranscriptArchive: &kelosv1alpha1.TranscriptArchive{
+				S3: &kelosv1alpha1.S3TranscriptStore{
+					Endpoint:  "http://minio.minio:9000",
+					Bucket:    "agents",
+					Region:    "eu-west-1",
+					SecretRef: kelosv1alpha1.SecretReference{Name: "s3-creds"},
+				},
+				Retention: &metav1.Duration{Duration: time.Hour},
+			},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+
+	uri := "s3://agents/default/task-1/task-1-abc.jsonl"
+	if err := r.writeTranscriptConfigMap(context.Background(), task, uri, nil, false); err != nil {
+		t.Fatalf("writeTranscriptConfigMap() error: %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := cl.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "task-1-transcript"}, &cm); err != nil {
+		t.Fatalf("getting transcript ConfigMap: %v", err)
+	}
+	if len(cm.BinaryData) != 0 {
+		t.Error("expected no transcript data for an S3 archive")
+	}
+	for key, want := range map[string]string{
+		transcript.URIAnnotation:        uri,
+		transcript.S3EndpointAnnotation: "http://minio.minio:9000",
+		transcript.S3RegionAnnotation:   "eu-west-1",
+		transcript.S3SecretAnnotation:   "s3-creds",
+	} {
+		if got := cm.Annotations[key]; got != want {
+			t.Errorf("annotation %s = %q, want %q", key, got, want)
+		}
+	}
+	expiresAt, err := time.Parse(time.RFC3339, cm.Annotations[transcript.ExpiresAtAnnotation])
+	if err != nil {
+		t.Fatalf("parsing expiry: %v", err)
+	}
+	if d := time.Until(expiresAt); d <= 0 || d > time.Hour {
+		t.Errorf("expiry in %s, want within the 1h retention", d)
+	}
+}
+
+func TestPruneTranscriptConfigMaps(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	transcriptCM := func(name string, annotations map[string]string, created time.Time) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            map[string]string{transcript.Label: "true"},
+			Annotations:       annotations,
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+	}
+	expired := transcriptCM("expired-transcript", map[string]string{
+		transcript.ExpiresAtAnnotation: now.Add(-time.Minute).Format(time.RFC3339),
+	}, now.Add(-time.Hour))
+	current := transcriptCM("current-transcript", map[string]string{
+		transcript.ExpiresAtAnnotation: now.Add(time.Minute).Format(time.RFC3339),
+	}, now.Add(-time.Hour))
+	legacy := transcriptCM("legacy-transcript", nil, now.Add(-transcript.DefaultRetention-time.Hour))
+	unrelated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(expired, current, legacy, unrelated).Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+	if err := r.pruneTranscriptConfigMaps(context.Background(), "default", now); err != nil {
+		t.Fatalf("pruneTranscriptConfigMaps() error: %v", err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := cl.List(context.Background(), &cms); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, cm := range cms.Items {
+		names = append(names, cm.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"current-transcript", "settings"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("remaining ConfigMaps = %v, want %v", names, want)
+	}
+}
+
+func TestResolveGitHubAppToken_AdditionalRepos(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      "ghs_test_token",
+			"expires_at": time.Now().Add(1 * time.Hour).UTC().Format(time.RFC3339),
+		})
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pat-secret", Namespace: "default"},
+				Data:       map[string][]byte{"GITHUB_TOKEN": []byte("ghp_test")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "github-app-creds", Namespace: "default"},
+				Data: map[string][]byte{
+					"appID":          []byte("12345"),
+					"installationID": []byte("67890"),
+					"privateKey":     keyPEM,
+				},
+			},
+		).
+		Build()
+
+	r := &TaskReconciler{
+		Client: cl,
+		Scheme: scheme,
+		TokenClient: &githubapp.TokenClient{
+			BaseURL: server.URL,
+			Client:  server.Client(),
+		},
+	}
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-task",
+			Namespace: "default",
+			UID:       "test-uid",
+		},
+	}
+	workspace := &kelosv1alpha1.WorkspaceSpec{
+		Repo:      "https://github.com/kelos-dev/kelos.git",
+		SecretRef: &kelosv1alpha1.SecretReference{Name: "pat-secret"},
+		Repos: []kelosv1alpha1.WorkspaceRepo{
+			{Name: "docs", Repo: "https://github.com/kelos-dev/docs.git"},
+			{
+				Name:      "client",
+				Repo:      "https://github.com/kelos-dev/client.git",
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "github-app-creds"},
+			},
+		},
+	}
+
+	result, err := r.resolveGitHubAppToken(context.Background(), task, workspace)
+	if err != nil {
+		t.Fatalf("resolveGitHubAppToken() error: %v", err)
+	}
+
+	if result.SecretRef.Name != "pat-secret" {
+		t.Errorf("workspace secret = %q, want %q", result.SecretRef.Name, "pat-secret")
+	}
+	if result.Repos[0].SecretRef != nil {
+		t.Errorf("docs secret = %v, want nil", result.Repos[0].SecretRef)
+	}
+	// The name must not be the main token secret name of a Task called
+	// "test-task-client".
+	wantName := repoTokenSecretName(task, "client")
+	if wantName == "test-task-client-github-token" {
+		t.Fatalf("repoTokenSecretName() = %q collides with another Task's token secret", wantName)
+	}
+	if got := result.Repos[1].SecretRef.Name; got != wantName {
+		t.Errorf("client secret = %q, want %q", got, wantName)
+	}
+	if workspace.Repos[1].SecretRef.Name != "github-app-creds" {
+		t.Errorf("Expected the Workspace spec to be left unchanged, got %q", workspace.Repos[1].SecretRef.Name)
+	}
+
+	var tokenSecret corev1.Secret
+	if err := cl.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: wantName}, &tokenSecret); err != nil {
+		t.Fatalf("Getting generated token secret: %v", err)
+	}
+	if !metav1.IsControlledBy(&tokenSecret, task) {
+		t.Errorf("Expected the token secret to be controlled by the Task, got owners %v", tokenSecret.OwnerReferences)
+	}
+}
+
+func TestResolveWorkspaceGitHubAppToken_SecretOwnedByAnotherTask(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      "ghs_test_token",
+			"expires_at": time.Now().Add(1 * time.Hour).UTC().Format(time.RFC3339),
+		})
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	isController := true
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-sdk-github-token",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: kelosv1alpha1.GroupVersion.String(),
+				Kind:       "Task",
+				Name:       "api-sdk",
+				UID:        "api-sdk-uid",
+				Controller: &isController,
+			}},
+		},
+		Data: map[string][]byte{"GITHUB_TOKEN": []byte("ghs_other_token")},
+	}
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			foreign,
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "github-app-creds", Namespace: "default"},
+				Data: map[string][]byte{
+					"appID":          []byte("12345"),
+					"installationID": []byte("67890"),
+					"privateKey":     keyPEM,
+				},
+			},
+		).
+		Build()
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", UID: "api-uid"},
+	}
+	tokenClient := &githubapp.TokenClient{BaseURL: server.URL, Client: server.Client()}
+	_, err = resolveWorkspaceGitHubAppToken(context.Background(), cl, scheme, tokenClient, task, "api-sdk-github-token",
+		&kelosv1alpha1.WorkspaceSpec{SecretRef: &kelosv1alpha1.SecretReference{Name: "github-app-creds"}})
+	if err == nil {
+		t.Fatal("Expected an error for a token secret owned by another Task")
+	}
+
+	var got corev1.Secret
+	if err := cl.Get(context.Background(), client.ObjectKeyFromObject(foreign), &got); err != nil {
+		t.Fatalf("Getting token secret: %v", err)
+	}
+	if string(got.Data["GITHUB_TOKEN"]) != "ghs_other_token" || len(got.StringData) != 0 {
+		t.Errorf("Expected the other Task's token secret to be left unchanged, got %v", got)
+	}
+}
+
+func TestGetAgentType(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&kelosv1alpha1.AgentType{
+			ObjectMeta: metav1.ObjectMeta{Name: "aider"},
+			Spec:       kelosv1alpha1.AgentTypeSpec{Image: "example.com/aider:latest"},
+		}).
+		Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme}
+
+	spec, err := r.getAgentType(context.Background(), "aider")
+	if err != nil {
+		t.Fatalf("getAgentType() error: %v", err)
+	}
+	if spec == nil || spec.Image != "example.com/aider:latest" {
+		t.Errorf("getAgentType(aider) = %v, want the aider spec", spec)
+	}
+
+	spec, err = r.getAgentType(context.Background(), "claude-code")
+	if err != nil {
+		t.Fatalf("getAgentType() error: %v", err)
+	}
+	if spec != nil {
+		t.Errorf("getAgentType(claude-code) = %v, want nil", spec)
+	}
+}
+
+func TestAgentTypeWaitExpired(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+	}
+	if agentTypeWaitExpired(task, created.Add(time.Minute)) {
+		t.Error("expected a new Task to keep waiting for its AgentType")
+	}
+	if !agentTypeWaitExpired(task, created.Add(agentTypeWaitTimeout)) {
+		t.Error("expected the wait to expire after agentTypeWaitTimeout")
+	}
+}
+
+func TestReconcileDoesNotStartTaskFailedForMissingAgentType(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+
+	task := &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "task-1",
+			Namespace:         "default",
+			Finalizers:        []string{taskFinalizer},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * agentTypeWaitTimeout)),
+		},
+		Spec: kelosv1alpha1.TaskSpec{
+			Type:   "aider",
+			Prompt: "test",
+			Credentials: kelosv1alpha1.Credentials{
+				Type:      kelosv1alpha1.CredentialTypeAPIKey,
+				SecretRef: &kelosv1alpha1.SecretReference{Name: "creds"},
+			},
+		},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(task).
+		WithObjects(task).
+		Build()
+	r := &TaskReconciler{Client: cl, Scheme: scheme, JobBuilder: NewJobBuilder(), BranchLocker: NewBranchLocker()}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(task)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	updated := &kelosv1alpha1.Task{}
+	if err := cl.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Getting updated task: %v", err)
+	}
+	if updated.Status.Phase != kelosv1alpha1.TaskPhaseFailed {
+		t.Fatalf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseFailed)
+	}
+	if updated.Status.CompletionTime == nil {
+		t.Error("Expected CompletionTime to be set")
+	}
+
+	// The AgentType showing up later must not start the failed Task.
+	if err := cl.Create(context.Background(), &kelosv1alpha1.AgentType{
+		ObjectMeta: metav1.ObjectMeta{Name: "aider"},
+		Spec:       kelosv1alpha1.AgentTypeSpec{Image: "example.com/aider:latest"},
+	}); err != nil {
+		t.Fatalf("Creating AgentType: %v", err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+
+	var jobs batchv1.JobList
+	if err := cl.List(context.Background(), &jobs, client.InNamespace("default")); err != nil {
+		t.Fatalf("Listing jobs: %v", err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("Expected no Job for the failed Task, found %d", len(jobs.Items))
+	}
+	if err := cl.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Getting updated task: %v", err)
+	}
+	if updated.Status.Phase != kelosv1alpha1.TaskPhaseFailed {
+		t.Errorf("Phase = %q, want %q", updated.Status.Phase, kelosv1alpha1.TaskPhaseFailed)
+	}
+}
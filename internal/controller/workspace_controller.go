@@ -0,0 +1,393 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	"github.com/kelos-dev/kelos/internal/githubapp"
+)
+
+const (
+	// defaultGitCacheSize is the size of the git cache claim when
+	// spec.cache.volume.size is not set.
+	defaultGitCacheSize = "20Gi"
+
+	// defaultDependencyCacheSize is the size of the dependency cache claim
+	// when spec.dependencyCache.volume.size is not set.
+	defaultDependencyCacheSize = "10Gi"
+
+	// defaultGitCacheRefreshInterval is how often the mirror is refreshed
+	// when spec.cache.refreshInterval is not set.
+	defaultGitCacheRefreshInterval = time.Hour
+
+	// gitCacheComponent is the kelos.dev/component label of cache Jobs.
+	gitCacheComponent = "git-cache"
+
+	// gitCacheSSHDir holds the copy of the Workspace SSH key in cache Jobs.
+	gitCacheSSHDir = "/tmp/kelos-ssh"
+)
+
+// GitCacheClaimName returns the name of the PersistentVolumeClaim holding
+// the repository mirror of a Workspace.
+func GitCacheClaimName(workspace string) string {
+	return workspace + "-git-cache"
+}
+
+// DependencyCacheClaimName returns the name of the PersistentVolumeClaim
+// holding the dependency caches of a Workspace.
+func DependencyCacheClaimName(workspace string) string {
+	return workspace + "-dependency-cache"
+}
+
+// WorkspaceReconciler reconciles the caches of a Workspace object.
+type WorkspaceReconciler struct {
+	client.Client
+	Scheme      *runtime.Scheme
+	TokenClient *githubapp.TokenClient
+	Recorder    record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=kelos.dev,resources=workspaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile creates the cache claims of a Workspace and runs the Job that
+// keeps its repository mirror up to date.
+func (r *WorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var ws kelosv1alpha1.Workspace
+	if err := r.Get(ctx, req.NamespacedName, &ws); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Unable to fetch Workspace")
+		reconcileErrorsTotal.WithLabelValues("workspace").Inc()
+		return ctrl.Result{}, err
+	}
+
+	if !ws.DeletionTimestamp.IsZero() {
+		// Owner references clean up the claims and Jobs.
+		return ctrl.Result{}, nil
+	}
+
+	if dc := ws.Spec.DependencyCache; dc != nil {
+		if err := r.ensureCacheClaim(ctx, &ws, DependencyCacheClaimName(ws.Name), dc.Volume, defaultDependencyCacheSize); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := r.deleteCacheClaim(ctx, &ws, DependencyCacheClaimName(ws.Name)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if ws.Spec.Cache == nil {
+		if err := r.deleteGitCacheJobs(ctx, &ws, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.deleteCacheClaim(ctx, &ws, GitCacheClaimName(ws.Name))
+	}
+
+	if err := r.ensureCacheClaim(ctx, &ws, GitCacheClaimName(ws.Name), ws.Spec.Cache.Volume, defaultGitCacheSize); err != nil {
+		return ctrl.Result{}, err
+	}
+	return r.reconcileGitCacheJob(ctx, &ws)
+}
+
+// ensureCacheClaim creates the named cache claim of a Workspace if it does
+// not exist. Existing claims are left untouched because most of their spec
+// is immutable.
+func (r *WorkspaceReconciler) ensureCacheClaim(ctx context.Context, ws *kelosv1alpha1.Workspace, name string, volume kelosv1alpha1.CacheVolume, defaultSize string) error {
+	var existing corev1.PersistentVolumeClaim
+	err := r.Get(ctx, client.ObjectKey{Namespace: ws.Namespace, Name: name}, &existing)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("fetching cache claim %q: %w", name, err)
+	}
+
+	pvc := buildCacheClaim(ws, name, volume, defaultSize)
+	if err := controllerutil.SetControllerReference(ws, pvc, r.Scheme); err != nil {
+		return fmt.Errorf("setting owner reference on cache claim: %w", err)
+	}
+	if err := r.Create(ctx, pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("creating cache claim %q: %w", name, err)
+	}
+	r.recordEvent(ws, corev1.EventTypeNormal, "CacheClaimCreated", "Created cache PersistentVolumeClaim %s", name)
+	return nil
+}
+
+// deleteCacheClaim deletes the named cache claim if it was created for the
+// Workspace. Kubernetes keeps the claim until no pod uses it.
+func (r *WorkspaceReconciler) deleteCacheClaim(ctx context.Context, ws *kelosv1alpha1.Workspace, name string) error {
+	var pvc corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, client.ObjectKey{Namespace: ws.Namespace, Name: name}, &pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("fetching cache claim %q: %w", name, err)
+	}
+	if !metav1.IsControlledBy(&pvc, ws) {
+		return nil
+	}
+	if err := r.Delete(ctx, &pvc); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting cache claim %q: %w", name, err)
+	}
+	return nil
+}
+
+// reconcileGitCacheJob starts a cache Job when none is running and the
+// last one finished more than the refresh interval ago, and removes
+// finished Jobs other than the latest.
+func (r *WorkspaceReconciler) reconcileGitCacheJob(ctx context.Context, ws *kelosv1alpha1.Workspace) (ctrl.Result, error) {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(ws.Namespace), client.MatchingLabels{
+		"kelos.dev/component": gitCacheComponent,
+		"kelos.dev/workspace": ws.Name,
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("listing cache jobs: %w", err)
+	}
+
+	var latest *batchv1.Job
+	if len(jobs.Items) > 0 {
+		sort.Slice(jobs.Items, func(i, j int) bool {
+			return jobs.Items[i].Name > jobs.Items[j].Name
+		})
+		latest = &jobs.Items[0]
+		if err := r.deleteGitCacheJobs(ctx, ws, latest); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	interval := defaultGitCacheRefreshInterval
+	if ri := ws.Spec.Cache.RefreshInterval; ri != nil && ri.Duration > 0 {
+		interval = ri.Duration
+	}
+
+	now := time.Now()
+	if latest != nil {
+		finishedAt, finished := jobFinishedAt(latest)
+		if !finished {
+			// The Job is still running; its completion triggers a reconcile.
+			return ctrl.Result{}, nil
+		}
+		if next := finishedAt.Add(interval); now.Before(next) {
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	workspace := &ws.Spec
+	if workspace.SecretRef != nil && workspaceProvider(workspace) == kelosv1alpha1.GitProviderGitHub {
+		resolved, err := resolveWorkspaceGitHubAppToken(ctx, r.Client, r.Scheme, r.TokenClient, ws, ws.Name+"-git-cache-github-token", workspace)
+		if err != nil {
+			r.recordEvent(ws, corev1.EventTypeWarning, "CacheJobFailed", "Failed to resolve git credentials: %v", err)
+			return ctrl.Result{}, err
+		}
+		workspace = resolved
+	}
+
+	job := buildGitCacheJob(ws, workspace, now)
+	if err := controllerutil.SetControllerReference(ws, job, r.Scheme); err != nil {
+		return ctrl.Result{}, fmt.Errorf("setting owner reference on cache job: %w", err)
+	}
+	if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, fmt.Errorf("creating cache job: %w", err)
+	}
+	r.recordEvent(ws, corev1.EventTypeNormal, "CacheJobCreated", "Created cache Job %s", job.Name)
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// deleteGitCacheJobs deletes the finished cache Jobs of a Workspace except
+// keep. When keep is nil, running Jobs are deleted as well.
+func (r *WorkspaceReconciler) deleteGitCacheJobs(ctx context.Context, ws *kelosv1alpha1.Workspace, keep *batchv1.Job) error {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(ws.Namespace), client.MatchingLabels{
+		"kelos.dev/component": gitCacheComponent,
+		"kelos.dev/workspace": ws.Name,
+	}); err != nil {
+		return fmt.Errorf("listing cache jobs: %w", err)
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if keep != nil {
+			if job.Name == keep.Name {
+				continue
+			}
+			if _, finished := jobFinishedAt(job); !finished {
+				continue
+			}
+		}
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting cache job %q: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// jobFinishedAt returns when a Job completed or failed.
+func jobFinishedAt(job *batchv1.Job) (time.Time, bool) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// buildCacheClaim returns the cache claim of a Workspace.
+func buildCacheClaim(ws *kelosv1alpha1.Workspace, name string, volume kelosv1alpha1.CacheVolume, defaultSize string) *corev1.PersistentVolumeClaim {
+	size := resource.MustParse(defaultSize)
+	if volume.Size != nil {
+		size = *volume.Size
+	}
+	accessMode := volume.AccessMode
+	if accessMode == "" {
+		accessMode = corev1.ReadWriteOnce
+	}
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ws.Namespace,
+			Labels: map[string]string{
+				"kelos.dev/name":       "kelos",
+				"kelos.dev/component":  "workspace-cache",
+				"kelos.dev/managed-by": "kelos-controller",
+				"kelos.dev/workspace":  ws.Name,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{accessMode},
+			StorageClassName: volume.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+}
+
+// buildGitCacheJob returns a Job that clones a bare mirror of the Workspace
+// repository onto its git cache claim, or fetches into the mirror when it
+// already exists. The mirror is only cloned to a temporary directory and
+// renamed when complete so that Tasks never reference a partial mirror,
+// and it is never garbage collected because Task clones borrow its objects.
+func buildGitCacheJob(ws *kelosv1alpha1.Workspace, workspace *kelosv1alpha1.WorkspaceSpec, now time.Time) *batchv1.Job {
+	gitCmd := "git"
+	var env []corev1.EnvVar
+	env = append(env, corev1.EnvVar{Name: "KELOS_REPO", Value: workspace.Repo})
+	if workspace.SecretRef != nil {
+		auth := providerGitAuth(workspaceProvider(workspace))
+		credentialHelper := auth.credentialHelper(auth.tokenKey, auth.usernameKey)
+		gitCmd = fmt.Sprintf("git -c credential.helper= -c credential.helper='%s'", credentialHelper)
+		if auth.usernameKey != "" {
+			env = append(env, secretEnvVar(auth.usernameKey, workspace.SecretRef.Name, auth.usernameKey))
+		}
+		env = append(env, secretEnvVar(auth.tokenKey, workspace.SecretRef.Name, auth.tokenKey))
+	}
+
+	volumes := []corev1.Volume{{
+		Name: GitCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: GitCacheClaimName(ws.Name),
+			},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{
+		Name:      GitCacheVolumeName,
+		MountPath: GitCacheMountPath,
+	}}
+	sshSetup := ""
+	if workspace.SSHKeySecretRef != nil {
+		volumes = append(volumes, sshKeyVolume(workspace.SSHKeySecretRef.Name))
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      SSHKeyVolumeName,
+			MountPath: SSHKeyMountPath,
+			ReadOnly:  true,
+		})
+		env = append(env, corev1.EnvVar{Name: "GIT_SSH_COMMAND", Value: gitSSHCommand(gitCacheSSHDir)})
+		sshSetup = sshKeySetupScript(gitCacheSSHDir) + "\n"
+	}
+
+	script := fmt.Sprintf(`set -e
+%smirror=%s
+if [ -f "$mirror/HEAD" ]; then
+  %s -C "$mirror" fetch --prune --tags origin
+else
+  rm -rf "$mirror.tmp"
+  %s clone --bare -- "$KELOS_REPO" "$mirror.tmp"
+  git -C "$mirror.tmp" config remote.origin.fetch '+refs/heads/*:refs/heads/*'
+  git -C "$mirror.tmp" config gc.auto 0
+  mv "$mirror.tmp" "$mirror"
+fi`, sshSetup, GitCacheMirrorPath, gitCmd, gitCmd)
+
+	labels := map[string]string{
+		"kelos.dev/name":       "kelos",
+		"kelos.dev/component":  gitCacheComponent,
+		"kelos.dev/managed-by": "kelos-controller",
+		"kelos.dev/workspace":  ws.Name,
+	}
+	agentUID := AgentUID
+	fsGroupChangePolicy := corev1.FSGroupChangeOnRootMismatch
+	backoffLimit := int32(2)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-git-cache-%d", ws.Name, now.Unix()),
+			Namespace: ws.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					SecurityContext: &corev1.PodSecurityContext{
+						FSGroup:             &agentUID,
+						FSGroupChangePolicy: &fsGroupChangePolicy,
+					},
+					Containers: []corev1.Container{{
+						Name:            "git-cache",
+						Image:           GitCloneImage,
+						Command:         []string{"sh", "-c", script},
+						Env:             env,
+						VolumeMounts:    mounts,
+						SecurityContext: &corev1.SecurityContext{RunAsUser: &agentUID},
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+func (r *WorkspaceReconciler) recordEvent(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kelosv1alpha1.Workspace{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Complete(r)
+}
@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func newWorkspaceTestReconciler(objs ...client.Object) *WorkspaceReconciler {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &WorkspaceReconciler{Client: cl, Scheme: scheme}
+}
+
+func finishedCacheJob(ws *kelosv1alpha1.Workspace, name string, finishedAt time.Time) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ws.Namespace,
+			Labels: map[string]string{
+				"kelos.dev/component": gitCacheComponent,
+				"kelos.dev/workspace": ws.Name,
+			},
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{
+				Type:               batchv1.JobComplete,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(finishedAt),
+			}},
+		},
+	}
+}
+
+func listCacheJobs(t *testing.T, r *WorkspaceReconciler, ws *kelosv1alpha1.Workspace) []batchv1.Job {
+	t.Helper()
+	var jobs batchv1.JobList
+	if err := r.List(context.Background(), &jobs, client.InNamespace(ws.Namespace), client.MatchingLabels{
+		"kelos.dev/component": gitCacheComponent,
+		"kelos.dev/workspace": ws.Name,
+	}); err != nil {
+		t.Fatalf("listing cache jobs: %v", err)
+	}
+	return jobs.Items
+}
+
+func TestWorkspaceReconcile_CreatesCaches(t *testing.T) {
+	size := resource.MustParse("50Gi")
+	storageClass := "efs"
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "monorepo", Namespace: "default", UID: "ws-uid"},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo: "https://github.com/example/monorepo.git",
+			Cache: &kelosv1alpha1.WorkspaceCache{
+				Volume: kelosv1alpha1.CacheVolume{Size: &size, StorageClassName: &storageClass},
+			},
+			DependencyCache: &kelosv1alpha1.DependencyCache{
+				Volume: kelosv1alpha1.CacheVolume{AccessMode: corev1.ReadWriteOnce},
+			},
+		},
+	}
+	r := newWorkspaceTestReconciler(ws)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ws)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+
+	var gitPVC corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "monorepo-git-cache"}, &gitPVC); err != nil {
+		t.Fatalf("fetching git cache claim: %v", err)
+	}
+	if got := gitPVC.Spec.Resources.Requests[corev1.ResourceStorage]; got.Cmp(size) != 0 {
+		t.Errorf("git cache size = %s, want %s", got.String(), size.String())
+	}
+	if gitPVC.Spec.StorageClassName == nil || *gitPVC.Spec.StorageClassName != "efs" {
+		t.Errorf("git cache storage class = %v, want efs", gitPVC.Spec.StorageClassName)
+	}
+	if len(gitPVC.Spec.AccessModes) != 1 || gitPVC.Spec.AccessModes[0] != corev1.ReadWriteOnce {
+		t.Errorf("git cache access modes = %v, want [ReadWriteOnce]", gitPVC.Spec.AccessModes)
+	}
+	if !metav1.IsControlledBy(&gitPVC, ws) {
+		t.Error("Expected git cache claim to be owned by the Workspace")
+	}
+
+	var depsPVC corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "monorepo-dependency-cache"}, &depsPVC); err != nil {
+		t.Fatalf("fetching dependency cache claim: %v", err)
+	}
+	if got := depsPVC.Spec.Resources.Requests[corev1.ResourceStorage]; got.String() != defaultDependencyCacheSize {
+		t.Errorf("dependency cache size = %s, want %s", got.String(), defaultDependencyCacheSize)
+	}
+	if len(depsPVC.Spec.AccessModes) != 1 || depsPVC.Spec.AccessModes[0] != corev1.ReadWriteOnce {
+		t.Errorf("dependency cache access modes = %v, want [ReadWriteOnce]", depsPVC.Spec.AccessModes)
+	}
+
+	jobs := listCacheJobs(t, r, ws)
+	if len(jobs) != 1 {
+		t.Fatalf("Expected 1 cache job, got %d", len(jobs))
+	}
+
+	// A running cache Job is not duplicated.
+	result, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("Expected no requeue while the cache job runs, got %v", result.RequeueAfter)
+	}
+	if jobs := listCacheJobs(t, r, ws); len(jobs) != 1 {
+		t.Errorf("Expected 1 cache job, got %d", len(jobs))
+	}
+}
+
+func TestWorkspaceReconcile_RefreshInterval(t *testing.T) {
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "monorepo", Namespace: "default", UID: "ws-uid"},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo: "https://github.com/example/monorepo.git",
+			Cache: &kelosv1alpha1.WorkspaceCache{
+				RefreshInterval: &metav1.Duration{Duration: 30 * time.Minute},
+			},
+		},
+	}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ws)}
+
+	t.Run("recent job is kept", func(t *testing.T) {
+		r := newWorkspaceTestReconciler(ws,
+			finishedCacheJob(ws, "monorepo-git-cache-100", time.Now().Add(-2*time.Hour)),
+			finishedCacheJob(ws, "monorepo-git-cache-200", time.Now().Add(-10*time.Minute)),
+		)
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile() error: %v", err)
+		}
+		if result.RequeueAfter <= 15*time.Minute || result.RequeueAfter > 20*time.Minute {
+			t.Errorf("Expected requeue in about 20m, got %v", result.RequeueAfter)
+		}
+		jobs := listCacheJobs(t, r, ws)
+		if len(jobs) != 1 || jobs[0].Name != "monorepo-git-cache-200" {
+			t.Errorf("Expected only the latest cache job to be kept, got %v", jobs)
+		}
+	})
+
+	t.Run("stale job is refreshed", func(t *testing.T) {
+		r := newWorkspaceTestReconciler(ws,
+			finishedCacheJob(ws, "monorepo-git-cache-100", time.Now().Add(-time.Hour)),
+		)
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile() error: %v", err)
+		}
+		if result.RequeueAfter != 30*time.Minute {
+			t.Errorf("Expected requeue after the refresh interval, got %v", result.RequeueAfter)
+		}
+		jobs := listCacheJobs(t, r, ws)
+		if len(jobs) != 2 {
+			t.Fatalf("Expected a new cache job next to the previous one, got %d jobs", len(jobs))
+		}
+	})
+}
+
+func TestWorkspaceReconcile_CacheRemoved(t *testing.T) {
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "monorepo", Namespace: "default", UID: "ws-uid"},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo: "https://github.com/example/monorepo.git",
+		},
+	}
+	owned := buildCacheClaim(ws, GitCacheClaimName(ws.Name), kelosv1alpha1.CacheVolume{}, defaultGitCacheSize)
+	owned.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: kelosv1alpha1.GroupVersion.String(),
+		Kind:       "Workspace",
+		Name:       ws.Name,
+		UID:        ws.UID,
+		Controller: ptr(true),
+	}}
+	unowned := buildCacheClaim(ws, DependencyCacheClaimName(ws.Name), kelosv1alpha1.CacheVolume{}, defaultDependencyCacheSize)
+	r := newWorkspaceTestReconciler(ws, owned, unowned,
+		finishedCacheJob(ws, "monorepo-git-cache-100", time.Now()),
+	)
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ws)}); err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+
+	err := r.Get(ctx, client.ObjectKeyFromObject(owned), &corev1.PersistentVolumeClaim{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expected owned git cache claim to be deleted, got %v", err)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(unowned), &corev1.PersistentVolumeClaim{}); err != nil {
+		t.Errorf("Expected claim not created by Kelos to be kept, got %v", err)
+	}
+	if jobs := listCacheJobs(t, r, ws); len(jobs) != 0 {
+		t.Errorf("Expected cache jobs to be deleted, got %d", len(jobs))
+	}
+}
+
+func TestBuildGitCacheJob(t *testing.T) {
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "monorepo", Namespace: "default"},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo:      "https://github.com/example/monorepo.git",
+			SecretRef: &kelosv1alpha1.SecretReference{Name: "github-token"},
+		},
+	}
+
+	job := buildGitCacheJob(ws, &ws.Spec, time.Unix(1700000000, 0))
+
+	if job.Name != "monorepo-git-cache-1700000000" {
+		t.Errorf("job name = %q, want %q", job.Name, "monorepo-git-cache-1700000000")
+	}
+	podSpec := job.Spec.Template.Spec
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].PersistentVolumeClaim.ClaimName != "monorepo-git-cache" {
+		t.Errorf("Expected the git cache claim to be mounted, got %v", podSpec.Volumes)
+	}
+	c := podSpec.Containers[0]
+	script := c.Command[2]
+	for _, want := range []string{
+		"mirror=" + GitCacheMirrorPath,
+		`fetch --prune --tags origin`,
+		`clone --bare -- "$KELOS_REPO" "$mirror.tmp"`,
+		"config gc.auto 0",
+		`mv "$mirror.tmp" "$mirror"`,
+		"credential.helper=",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected cache script to contain %q, got:\n%s", want, script)
+		}
+	}
+	env := map[string]corev1.EnvVar{}
+	for _, e := range c.Env {
+		env[e.Name] = e
+	}
+	if env["KELOS_REPO"].Value != ws.Spec.Repo {
+		t.Errorf("KELOS_REPO = %q, want %q", env["KELOS_REPO"].Value, ws.Spec.Repo)
+	}
+	if ref := env["GITHUB_TOKEN"].ValueFrom; ref == nil || ref.SecretKeyRef.Name != "github-token" {
+		t.Errorf("Expected GITHUB_TOKEN from the workspace secret, got %v", env["GITHUB_TOKEN"])
+	}
+}
+
+func TestBuildGitCacheJob_ProviderCredentials(t *testing.T) {
+	ws := &kelosv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "default"},
+		Spec: kelosv1alpha1.WorkspaceSpec{
+			Repo:            "git@gitlab.example.com:platform/internal.git",
+			Provider:        kelosv1alpha1.GitProviderGitLab,
+			SecretRef:       &kelosv1alpha1.SecretReference{Name: "gitlab-token"},
+			SSHKeySecretRef: &kelosv1alpha1.SecretReference{Name: "deploy-key"},
+		},
+	}
+
+	job := buildGitCacheJob(ws, &ws.Spec, time.Unix(1700000000, 0))
+
+	podSpec := job.Spec.Template.Spec
+	if len(podSpec.Volumes) != 2 || podSpec.Volumes[1].Secret == nil || podSpec.Volumes[1].Secret.SecretName != "deploy-key" {
+		t.Errorf("Expected the SSH key secret to be mounted, got %v", podSpec.Volumes)
+	}
+	c := podSpec.Containers[0]
+	script := c.Command[2]
+	for _, want := range []string{
+		"cp " + SSHKeyMountPath + "/ssh-privatekey /tmp/kelos-ssh/id",
+		`echo "username=oauth2"; echo "password=$GITLAB_TOKEN"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected cache script to contain %q, got:\n%s", want, script)
+		}
+	}
+	env := map[string]corev1.EnvVar{}
+	for _, e := range c.Env {
+		env[e.Name] = e
+	}
+	if ref := env["GITLAB_TOKEN"].ValueFrom; ref == nil || ref.SecretKeyRef.Name != "gitlab-token" || ref.SecretKeyRef.Key != "GITLAB_TOKEN" {
+		t.Errorf("Expected GITLAB_TOKEN from the workspace secret, got %v", env["GITLAB_TOKEN"])
+	}
+	if _, ok := env["GITHUB_TOKEN"]; ok {
+		t.Errorf("Expected no GITHUB_TOKEN for a GitLab workspace")
+	}
+	if got := env["GIT_SSH_COMMAND"].Value; !strings.Contains(got, "-i /tmp/kelos-ssh/id") {
+		t.Errorf("GIT_SSH_COMMAND = %q, want the copied key", got)
+	}
+}
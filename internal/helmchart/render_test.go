@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/kelos-dev/kelos/internal/manifests"
+	"github.com/kelos-dev/kelos/internal/manifests/manifeststest"
 	sigyaml "sigs.k8s.io/yaml"
 )
 
@@ -73,7 +74,7 @@ func TestRender_VersionOverride(t *testing.T) {
 		t.Fatalf("rendering chart: %v", err)
 	}
 	output := string(data)
-	if strings.Contains(output, ":latest") {
+	if manifeststest.LatestImageTag.MatchString(output) {
 		t.Error("expected no :latest tags in rendered output")
 	}
 	if !strings.Contains(output, ":v1.2.3") {
@@ -0,0 +1,36 @@
+package manifests
+
+import (
+	"bytes"
+	"testing"
+
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	sigyaml "sigs.k8s.io/yaml"
+)
+
+// TestInstallCRDFitsLastAppliedAnnotation guards against CRDs that
+// client-side "kubectl apply" cannot install because their
+// kubectl.kubernetes.io/last-applied-configuration annotation would exceed
+// the annotation size limit.
+func TestInstallCRDFitsLastAppliedAnnotation(t *testing.T) {
+	for _, doc := range bytes.Split(InstallCRD, []byte("\n---\n")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		data, err := sigyaml.YAMLToJSON(doc)
+		if err != nil {
+			t.Fatalf("converting CRD to JSON: %v", err)
+		}
+		var crd struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}
+		if err := sigyaml.Unmarshal(data, &crd); err != nil {
+			t.Fatalf("decoding CRD: %v", err)
+		}
+		if len(data) > apivalidation.TotalAnnotationSizeLimitB {
+			t.Errorf("CRD %s is %d bytes, want at most %d", crd.Metadata.Name, len(data), apivalidation.TotalAnnotationSizeLimitB)
+		}
+	}
+}
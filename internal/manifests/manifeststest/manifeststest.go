@@ -0,0 +1,10 @@
+// Package manifeststest provides helpers for tests of the rendered Kelos
+// manifests.
+package manifeststest
+
+import "regexp"
+
+// LatestImageTag matches an image reference with the latest tag. CRD
+// descriptions copied from Kubernetes types mention ":latest" on its own,
+// which is not an image.
+var LatestImageTag = regexp.MustCompile(`\S:latest\b`)
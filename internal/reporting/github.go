@@ -137,17 +137,22 @@ func (r *GitHubReporter) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 }
 
+// StatusCommentHeader is the first line of every status comment posted by the
+// reporter. Consumers use it to tell Kelos status comments apart from human
+// discussion.
+const StatusCommentHeader = "🤖 **Kelos Task Status**"
+
 // FormatAcceptedComment returns the comment body for an accepted task.
 func FormatAcceptedComment(taskName string) string {
-	return fmt.Sprintf("🤖 **Kelos Task Status**\n\nTask `%s` has been **accepted** and is being processed.", taskName)
+	return fmt.Sprintf("%s\n\nTask `%s` has been **accepted** and is being processed.", StatusCommentHeader, taskName)
 }
 
 // FormatSucceededComment returns the comment body for a succeeded task.
 func FormatSucceededComment(taskName string) string {
-	return fmt.Sprintf("🤖 **Kelos Task Status**\n\nTask `%s` has **succeeded**. ✅", taskName)
+	return fmt.Sprintf("%s\n\nTask `%s` has **succeeded**. ✅", StatusCommentHeader, taskName)
 }
 
 // FormatFailedComment returns the comment body for a failed task.
 func FormatFailedComment(taskName string) string {
-	return fmt.Sprintf("🤖 **Kelos Task Status**\n\nTask `%s` has **failed**. ❌", taskName)
+	return fmt.Sprintf("%s\n\nTask `%s` has **failed**. ❌", StatusCommentHeader, taskName)
 }
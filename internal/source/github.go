@@ -43,6 +43,8 @@ type GitHubSource struct {
 	AllowedTeams      []string
 	MinimumPermission string
 	PriorityLabels    []string
+
+	filtered []FilteredItem
 }
 
 type githubIssue struct {
@@ -81,6 +83,7 @@ func (s *GitHubSource) httpClient() *http.Client {
 
 // Discover fetches issues from GitHub and returns them as WorkItems.
 func (s *GitHubSource) Discover(ctx context.Context) ([]WorkItem, error) {
+	s.filtered = nil
 	issues, err := s.fetchAllIssues(ctx)
 	if err != nil {
 		return nil, err
@@ -125,6 +128,7 @@ func (s *GitHubSource) Discover(ctx context.Context) ([]WorkItem, error) {
 				return nil, fmt.Errorf("evaluating comment policy for issue #%d: %w", issue.Number, err)
 			}
 			if !commentAllowed {
+				s.filtered = append(s.filtered, FilteredItem{ID: strconv.Itoa(issue.Number), Reason: commentPolicyFilterReason})
 				continue
 			}
 			triggerTime = resolvedTriggerTime
@@ -136,14 +140,15 @@ func (s *GitHubSource) Discover(ctx context.Context) ([]WorkItem, error) {
 		}
 
 		item := WorkItem{
-			ID:       strconv.Itoa(issue.Number),
-			Number:   issue.Number,
-			Title:    issue.Title,
-			Body:     issue.Body,
-			URL:      issue.HTMLURL,
-			Labels:   labels,
-			Comments: comments,
-			Kind:     kind,
+			ID:                 strconv.Itoa(issue.Number),
+			Number:             issue.Number,
+			Title:              issue.Title,
+			Body:               issue.Body,
+			URL:                issue.HTMLURL,
+			Labels:             labels,
+			Comments:           comments,
+			Kind:               kind,
+			DiscussionComments: discussionComments(rawComments, policy),
 		}
 
 		// Record the timestamp of the most recent trigger comment so the
@@ -158,6 +163,25 @@ func (s *GitHubSource) Discover(ctx context.Context) ([]WorkItem, error) {
 	return items, nil
 }
 
+// discussionComments returns the bodies of comments that do not contain a
+// trigger or exclude command from the given policy.
+func discussionComments(comments []githubComment, policy githubCommentPolicy) []string {
+	var commands []string
+	if policy.TriggerComment != "" {
+		commands = append(commands, policy.TriggerComment)
+	}
+	commands = append(commands, policy.ExcludeComments...)
+
+	var bodies []string
+	for _, c := range comments {
+		if containsAnyCommand(c.Body, commands) {
+			continue
+		}
+		bodies = append(bodies, c.Body)
+	}
+	return bodies
+}
+
 // containsAnyCommand reports whether body contains any of the given commands.
 func containsAnyCommand(body string, cmds []string) bool {
 	for _, cmd := range cmds {
@@ -214,20 +238,41 @@ func (s *GitHubSource) filterItems(issues []githubIssue) []githubIssue {
 		}
 
 		// Exclude-label filtering
-		skip := false
-		for _, l := range issue.Labels {
-			if _, ok := excluded[l.Name]; ok {
-				skip = true
-				break
-			}
-		}
-		if !skip {
-			filtered = append(filtered, issue)
+		if label, ok := excludedLabel(issue.Labels, excluded); ok {
+			s.filtered = append(s.filtered, FilteredItem{ID: strconv.Itoa(issue.Number), Reason: excludeLabelFilterReason(label)})
+			continue
 		}
+		filtered = append(filtered, issue)
 	}
 	return filtered
 }
 
+// FilteredItems returns the issues the most recent Discover call dropped
+// because of an exclude label or the comment policy.
+func (s *GitHubSource) FilteredItems() []FilteredItem {
+	return s.filtered
+}
+
+// commentPolicyFilterReason is the FilteredItem reason for items dropped by
+// the trigger or exclude comment policy.
+const commentPolicyFilterReason = "Filtered out by comment policy"
+
+// excludeLabelFilterReason returns the FilteredItem reason for an item
+// dropped because it carries the given exclude label.
+func excludeLabelFilterReason(label string) string {
+	return fmt.Sprintf("Filtered out by exclude label %q", label)
+}
+
+// excludedLabel returns the first of labels that is in excluded.
+func excludedLabel(labels []githubLabel, excluded map[string]struct{}) (string, bool) {
+	for _, l := range labels {
+		if _, ok := excluded[l.Name]; ok {
+			return l.Name, true
+		}
+	}
+	return "", false
+}
+
 func (s *GitHubSource) fetchAllIssues(ctx context.Context) ([]githubIssue, error) {
 	var allIssues []githubIssue
 
@@ -304,6 +349,52 @@ func (s *GitHubSource) fetchIssuesPage(ctx context.Context, pageURL string) ([]g
 	return issues, nextURL, nil
 }
 
+// IsClosed reports whether the issue with the given number is closed.
+func (s *GitHubSource) IsClosed(ctx context.Context, id string) (bool, error) {
+	number, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid issue number %q: %w", id, err)
+	}
+	itemURL := fmt.Sprintf("%s/repos/%s/%s/issues/%d", s.baseURL(), s.Owner, s.Repo, number)
+	return fetchGitHubItemClosed(ctx, s.httpClient(), itemURL, s.Token)
+}
+
+// fetchGitHubItemClosed fetches a single issue or pull request and reports
+// whether it is closed. Items that were deleted are reported as closed.
+func fetchGitHubItemClosed(ctx context.Context, client *http.Client, itemURL, token string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, itemURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("fetching item state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var item struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return false, fmt.Errorf("decoding item state: %w", err)
+	}
+	return item.State == "closed", nil
+}
+
 func (s *GitHubSource) fetchComments(ctx context.Context, issueNumber int) ([]githubComment, error) {
 	var allComments []githubComment
 
@@ -0,0 +1,93 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxFileBytes limits the size of a file fetched from a repository.
+const maxFileBytes = 1024 * 1024
+
+// ErrFileNotFound is returned by FetchFile when the file does not exist at
+// the requested ref.
+var ErrFileNotFound = errors.New("file not found")
+
+// FileFetcher reads files from a repository.
+type FileFetcher interface {
+	// FetchFile returns the contents of the file at path. When ref is
+	// empty, the repository's default branch is used.
+	FetchFile(ctx context.Context, path, ref string) (string, error)
+}
+
+// GitHubFileFetcher reads files from a GitHub repository through the
+// contents API.
+type GitHubFileFetcher struct {
+	Owner   string
+	Repo    string
+	Token   string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (f *GitHubFileFetcher) baseURL() string {
+	if f.BaseURL != "" {
+		return f.BaseURL
+	}
+	return defaultBaseURL
+}
+
+func (f *GitHubFileFetcher) httpClient() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return http.DefaultClient
+}
+
+// FetchFile returns the raw contents of the file at path.
+func (f *GitHubFileFetcher) FetchFile(ctx context.Context, path, ref string) (string, error) {
+	var escaped []string
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	u := fmt.Sprintf("%s/repos/%s/%s/contents/%s", f.baseURL(), f.Owner, f.Repo, strings.Join(escaped, "/"))
+	if ref != "" {
+		u += "?" + url.Values{"ref": {ref}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	if f.Token != "" {
+		req.Header.Set("Authorization", "token "+f.Token)
+	}
+	req.Header.Set("Accept", "application/vnd.github.raw")
+
+	resp, err := f.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching file %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s", ErrFileNotFound, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("GitHub API returned status %d for file %s: %s", resp.StatusCode, path, string(body))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFileBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("reading file %s: %w", path, err)
+	}
+	if len(data) > maxFileBytes {
+		return "", fmt.Errorf("file %s exceeds %d bytes", path, maxFileBytes)
+	}
+	return string(data), nil
+}
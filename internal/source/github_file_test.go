@@ -0,0 +1,54 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubFileFetcherFetchFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/contents/prompts/fix issue.md" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("ref"); got != "main" {
+			t.Errorf("ref = %q, want %q", got, "main")
+		}
+		if got := r.Header.Get("Accept"); got != "application/vnd.github.raw" {
+			t.Errorf("Accept = %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Write([]byte("Fix {{.Title}}"))
+	}))
+	defer server.Close()
+
+	f := &GitHubFileFetcher{Owner: "owner", Repo: "repo", Token: "secret", BaseURL: server.URL}
+	got, err := f.FetchFile(context.Background(), "prompts/fix issue.md", "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Fix {{.Title}}" {
+		t.Errorf("FetchFile() = %q", got)
+	}
+}
+
+func TestGitHubFileFetcherNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("expected no query without ref, got %q", r.URL.RawQuery)
+		}
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	f := &GitHubFileFetcher{Owner: "owner", Repo: "repo", BaseURL: server.URL}
+	_, err := f.FetchFile(context.Background(), "missing.md", "")
+	if !errors.Is(err, ErrFileNotFound) || !strings.Contains(err.Error(), "missing.md") {
+		t.Fatalf("expected ErrFileNotFound for missing.md, got %v", err)
+	}
+}
@@ -37,6 +37,8 @@ type GitHubPullRequestSource struct {
 	MinimumPermission string
 	Draft             *bool
 	PriorityLabels    []string
+
+	filtered []FilteredItem
 }
 
 type githubUser struct {
@@ -77,6 +79,7 @@ type githubPullRequestComment struct {
 }
 
 func (s *GitHubPullRequestSource) Discover(ctx context.Context) ([]WorkItem, error) {
+	s.filtered = nil
 	pullRequests, err := s.fetchAllPullRequests(ctx)
 	if err != nil {
 		return nil, err
@@ -117,6 +120,14 @@ func (s *GitHubPullRequestSource) Discover(ctx context.Context) ([]WorkItem, err
 
 		reviewState, triggerTime := aggregatePullRequestReviewState(reviews, pr.Head.SHA)
 		if !matchesDesiredReviewState(s.resolvedReviewState(), reviewState) {
+			got := reviewState
+			if got == "" {
+				got = "none"
+			}
+			s.filtered = append(s.filtered, FilteredItem{
+				ID:     strconv.Itoa(pr.Number),
+				Reason: fmt.Sprintf("Filtered out by review state: want %s, got %s", s.resolvedReviewState(), got),
+			})
 			continue
 		}
 
@@ -139,6 +150,7 @@ func (s *GitHubPullRequestSource) Discover(ctx context.Context) ([]WorkItem, err
 				return nil, fmt.Errorf("evaluating comment policy for pull request #%d: %w", pr.Number, err)
 			}
 			if !commentAllowed {
+				s.filtered = append(s.filtered, FilteredItem{ID: strconv.Itoa(pr.Number), Reason: commentPolicyFilterReason})
 				continue
 			}
 			commentTriggerTime = resolvedTriggerTime
@@ -152,17 +164,19 @@ func (s *GitHubPullRequestSource) Discover(ctx context.Context) ([]WorkItem, err
 		}
 
 		item := WorkItem{
-			ID:             strconv.Itoa(pr.Number),
-			Number:         pr.Number,
-			Title:          pr.Title,
-			Body:           pr.Body,
-			URL:            pr.HTMLURL,
-			Labels:         labels,
-			Comments:       concatCommentBodies(conversationComments),
-			Kind:           "PR",
-			Branch:         pr.Head.Ref,
-			ReviewState:    reviewState,
-			ReviewComments: concatPullRequestReviewComments(reviewComments),
+			ID:                 strconv.Itoa(pr.Number),
+			Number:             pr.Number,
+			Title:              pr.Title,
+			Body:               pr.Body,
+			URL:                pr.HTMLURL,
+			Labels:             labels,
+			Comments:           concatCommentBodies(conversationComments),
+			Kind:               "PR",
+			Branch:             pr.Head.Ref,
+			ReviewState:        reviewState,
+			ReviewComments:     concatPullRequestReviewComments(reviewComments),
+			HeadSHA:            pr.Head.SHA,
+			DiscussionComments: discussionComments(conversationComments, policy),
 		}
 
 		item.TriggerTime = s.resolveTriggerTime(triggerTime, commentTriggerTime)
@@ -173,6 +187,13 @@ func (s *GitHubPullRequestSource) Discover(ctx context.Context) ([]WorkItem, err
 	return items, nil
 }
 
+// FilteredItems returns the pull requests the most recent Discover call
+// dropped because of an exclude label, the review state or the comment
+// policy.
+func (s *GitHubPullRequestSource) FilteredItems() []FilteredItem {
+	return s.filtered
+}
+
 func (s *GitHubPullRequestSource) resolvedReviewState() string {
 	if s.ReviewState == "" {
 		return reviewStateAny
@@ -207,16 +228,14 @@ func (s *GitHubPullRequestSource) filterPullRequests(pullRequests []githubPullRe
 			continue
 		}
 
+		if label, ok := excludedLabel(pr.Labels, excludedLabels); ok {
+			s.filtered = append(s.filtered, FilteredItem{ID: strconv.Itoa(pr.Number), Reason: excludeLabelFilterReason(label)})
+			continue
+		}
+
 		labelSet := make(map[string]struct{}, len(pr.Labels))
-		skip := false
 		for _, label := range pr.Labels {
 			labelSet[label.Name] = struct{}{}
-			if _, ok := excludedLabels[label.Name]; ok {
-				skip = true
-			}
-		}
-		if skip {
-			continue
 		}
 
 		missingLabel := false
@@ -317,6 +336,17 @@ func (s *GitHubPullRequestSource) fetchPullRequestComments(ctx context.Context,
 	return allComments, nil
 }
 
+// IsClosed reports whether the pull request with the given number is
+// closed or merged.
+func (s *GitHubPullRequestSource) IsClosed(ctx context.Context, id string) (bool, error) {
+	number, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid pull request number %q: %w", id, err)
+	}
+	itemURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", s.baseURL(), s.Owner, s.Repo, number)
+	return fetchGitHubItemClosed(ctx, s.httpClient(), itemURL, s.Token)
+}
+
 func (s *GitHubPullRequestSource) fetchGitHubPage(ctx context.Context, pageURL string, out interface{}) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
 	if err != nil {
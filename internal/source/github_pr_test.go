@@ -936,3 +936,29 @@ func TestResolvePullRequestTriggerTime(t *testing.T) {
 		t.Errorf("resolveTriggerTime() with reviewState=any = %v, want %v", got, commentTime)
 	}
 }
+
+func TestGitHubPullRequestSourceIsClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/pulls/1":
+			w.Write([]byte(`{"number": 1, "state": "open"}`))
+		case "/repos/owner/repo/pulls/2":
+			w.Write([]byte(`{"number": 2, "state": "closed", "merged": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := &GitHubPullRequestSource{Owner: "owner", Repo: "repo", BaseURL: server.URL}
+
+	for id, want := range map[string]bool{"1": false, "2": true} {
+		got, err := s.IsClosed(context.Background(), id)
+		if err != nil {
+			t.Fatalf("IsClosed(%s): unexpected error: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("IsClosed(%s) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -432,6 +432,11 @@ func TestDiscoverExcludeLabels(t *testing.T) {
 	if items[1].Number != 3 {
 		t.Errorf("expected issue #3 second, got #%d", items[1].Number)
 	}
+
+	filtered := s.FilteredItems()
+	if len(filtered) != 1 || filtered[0].ID != "2" || filtered[0].Reason != `Filtered out by exclude label "kelos/needs-input"` {
+		t.Errorf("expected issue #2 to be reported as filtered by its exclude label, got %+v", filtered)
+	}
 }
 
 func TestDiscoverExcludeLabelsNoMatch(t *testing.T) {
@@ -906,6 +911,30 @@ func TestContainsCommand(t *testing.T) {
 	}
 }
 
+func TestDiscussionCommentsDropsCommands(t *testing.T) {
+	comments := []githubComment{
+		{Body: "Looks good"},
+		{Body: "/kelos pick-up"},
+		{Body: "Please also handle the edge case"},
+		{Body: "/kelos needs-input"},
+	}
+	policy := githubCommentPolicy{
+		TriggerComment:  "/kelos pick-up",
+		ExcludeComments: []string{"/kelos needs-input"},
+	}
+
+	got := discussionComments(comments, policy)
+	want := []string{"Looks good", "Please also handle the edge case"}
+	if len(got) != len(want) {
+		t.Fatalf("discussionComments() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("discussionComments()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
 func TestDiscoverSetsTriggerTime(t *testing.T) {
 	triggerTS := "2026-01-15T10:30:00Z"
 
@@ -1182,3 +1211,41 @@ func TestConcatCommentBodiesAllFit(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, result)
 	}
 }
+
+func TestGitHubSourceIsClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token test-token" {
+			t.Errorf("Authorization = %q, want %q", got, "token test-token")
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues/1":
+			fmt.Fprint(w, `{"number": 1, "state": "open"}`)
+		case "/repos/owner/repo/issues/2":
+			fmt.Fprint(w, `{"number": 2, "state": "closed"}`)
+		case "/repos/owner/repo/issues/3":
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	s := &GitHubSource{Owner: "owner", Repo: "repo", Token: "test-token", BaseURL: server.URL}
+
+	for id, want := range map[string]bool{"1": false, "2": true, "3": true} {
+		got, err := s.IsClosed(context.Background(), id)
+		if err != nil {
+			t.Fatalf("IsClosed(%s): unexpected error: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("IsClosed(%s) = %v, want %v", id, got, want)
+		}
+	}
+
+	if _, err := s.IsClosed(context.Background(), "4"); err == nil {
+		t.Error("Expected error for a failed lookup")
+	}
+	if _, err := s.IsClosed(context.Background(), "not-a-number"); err == nil {
+		t.Error("Expected error for an invalid issue number")
+	}
+}
@@ -53,7 +53,12 @@ type jiraIssueFields struct {
 }
 
 type jiraStatus struct {
-	Name string `json:"name"`
+	Name           string              `json:"name"`
+	StatusCategory *jiraStatusCategory `json:"statusCategory,omitempty"`
+}
+
+type jiraStatusCategory struct {
+	Key string `json:"key"`
 }
 
 type jiraIssueType struct {
@@ -198,6 +203,51 @@ func (s *JiraSource) fetchIssuesPage(ctx context.Context, startAt int) (*jiraSea
 	return &result, nil
 }
 
+// IsClosed reports whether the issue with the given key is in a status of
+// the done category. Issues that were deleted are reported as closed.
+func (s *JiraSource) IsClosed(ctx context.Context, id string) (bool, error) {
+	u, err := url.Parse(strings.TrimRight(s.BaseURL, "/") + "/rest/api/2/issue/" + url.PathEscape(id))
+	if err != nil {
+		return false, fmt.Errorf("parsing base URL: %w", err)
+	}
+	u.RawQuery = url.Values{"fields": {"status"}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+
+	if s.Token != "" {
+		if s.User != "" {
+			req.SetBasicAuth(s.User, s.Token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+s.Token)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return false, fmt.Errorf("fetching issue %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("Jira API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var issue jiraIssue
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return false, fmt.Errorf("decoding response: %w", err)
+	}
+	status := issue.Fields.Status
+	return status != nil && status.StatusCategory != nil && status.StatusCategory.Key == "done", nil
+}
+
 // extractIssueNumber extracts the numeric part from a Jira issue key (e.g., "PROJ-42" -> 42).
 func extractIssueNumber(key string) int {
 	parts := strings.SplitN(key, "-", 2)
@@ -506,3 +506,32 @@ func TestExtractIssueNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestJiraSourceIsClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("fields"); got != "status" {
+			t.Errorf("fields = %q, want %q", got, "status")
+		}
+		switch r.URL.Path {
+		case "/rest/api/2/issue/PROJ-1":
+			w.Write([]byte(`{"key": "PROJ-1", "fields": {"status": {"name": "In Progress", "statusCategory": {"key": "indeterminate"}}}}`))
+		case "/rest/api/2/issue/PROJ-2":
+			w.Write([]byte(`{"key": "PROJ-2", "fields": {"status": {"name": "Resolved", "statusCategory": {"key": "done"}}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := &JiraSource{BaseURL: server.URL, Project: "PROJ", Token: "test-token"}
+
+	for id, want := range map[string]bool{"PROJ-1": false, "PROJ-2": true, "PROJ-3": true} {
+		got, err := s.IsClosed(context.Background(), id)
+		if err != nil {
+			t.Fatalf("IsClosed(%s): unexpected error: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("IsClosed(%s) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -19,11 +19,17 @@ Comments:
 // RenderPrompt renders a prompt for the given work item using the provided template.
 // If promptTemplate is empty, a default template is used.
 func RenderPrompt(promptTemplate string, item WorkItem) (string, error) {
+	return RenderPromptWithSnippets(promptTemplate, item, nil)
+}
+
+// RenderPromptWithSnippets is like RenderPrompt but makes the given snippets
+// available through the include function, e.g. {{include "guidelines" .}}.
+func RenderPromptWithSnippets(promptTemplate string, item WorkItem, snippets map[string]string) (string, error) {
 	tmplStr := promptTemplate
 	if tmplStr == "" {
 		tmplStr = defaultPromptTemplate
 	}
-	return RenderTemplate(tmplStr, item)
+	return renderTemplate(tmplStr, item, snippets)
 }
 
 // RenderTemplate renders a Go text/template string with the given work item's fields.
@@ -31,8 +37,14 @@ func RenderPrompt(promptTemplate string, item WorkItem) (string, error) {
 // GitHub issue/Jira sources: {{.Number}}, {{.Body}}, {{.URL}}, {{.Labels}}, {{.Comments}}
 // GitHub pull request sources additionally expose: {{.Branch}}, {{.ReviewState}}, {{.ReviewComments}}
 // Cron sources: {{.Time}}, {{.Schedule}}
+// Templates can use the functions documented in docs/reference.md, such as
+// slugify, truncate, default and regexReplace.
 func RenderTemplate(tmplStr string, item WorkItem) (string, error) {
-	tmpl, err := template.New("tmpl").Parse(tmplStr)
+	return renderTemplate(tmplStr, item, nil)
+}
+
+func renderTemplate(tmplStr string, item WorkItem, snippets map[string]string) (string, error) {
+	tmpl, err := template.New("tmpl").Funcs(templateFuncs(snippets)).Parse(tmplStr)
 	if err != nil {
 		return "", fmt.Errorf("parsing template: %w", err)
 	}
@@ -24,6 +24,13 @@ type WorkItem struct {
 	Time           string // Cron trigger time (RFC3339)
 	Schedule       string // Cron schedule expression
 
+	// HeadSHA is the pull request head commit SHA for GitHub PR sources.
+	HeadSHA string
+	// DiscussionComments holds the bodies of conversation comments that are
+	// not trigger or exclude commands, in chronological order. The spawner
+	// uses them to detect new discussion for content-based retriggering.
+	DiscussionComments []string
+
 	// TriggerTime is the source-provided re-engagement time for this work item.
 	// For GitHub issues it is the most recent matching trigger comment time.
 	// For GitHub pull requests it is the most recent qualifying review time or
@@ -38,6 +45,30 @@ type Source interface {
 	Discover(ctx context.Context) ([]WorkItem, error)
 }
 
+// ItemStateChecker is implemented by sources that can look up a single work
+// item in the external system. Items can be missing from Discover results
+// while still open, for example when they are past the pagination limit or
+// filtered out by labels or comment policy, so callers use IsClosed to
+// confirm that an item was closed before acting on it.
+type ItemStateChecker interface {
+	// IsClosed reports whether the work item with the given ID is closed
+	// or no longer exists.
+	IsClosed(ctx context.Context, id string) (bool, error)
+}
+
+// FilteredItem is a work item that Discover dropped, with the reason.
+type FilteredItem struct {
+	ID     string
+	Reason string
+}
+
+// FilterReporter is implemented by sources that report which work items
+// their most recent Discover call filtered out and why, for example because
+// of an exclude label or the comment policy.
+type FilterReporter interface {
+	FilteredItems() []FilteredItem
+}
+
 // SortByLabelPriority sorts items in place by the first matching label in
 // priorityLabels. Items whose labels match an earlier index are sorted first.
 // Items with no matching label are placed last. The sort is stable so items
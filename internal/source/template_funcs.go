@@ -0,0 +1,244 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxIncludeDepth bounds nested include calls so that a snippet that
+// includes itself fails instead of recursing forever.
+const maxIncludeDepth = 10
+
+var nonSlugCharRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// templateFuncs returns the functions available to branch, promptTemplate
+// and metadata templates. Arguments that are usually piped come last so that
+// functions compose in pipelines, e.g. {{.Title | slugify | truncate 40}}.
+//
+// snippets holds the templates available through include. Passing nil makes
+// include fail with a descriptive error.
+func templateFuncs(snippets map[string]string) template.FuncMap {
+	funcs := template.FuncMap{
+		"lower":        strings.ToLower,
+		"upper":        strings.ToUpper,
+		"title":        titleCase,
+		"kebabcase":    func(s string) string { return joinWords(s, "-") },
+		"snakecase":    func(s string) string { return joinWords(s, "_") },
+		"slugify":      slugify,
+		"trim":         strings.TrimSpace,
+		"trimPrefix":   func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":   func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"truncate":     truncate,
+		"replace":      func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":     func(substr, s string) bool { return strings.Contains(s, substr) },
+		"split":        split,
+		"join":         join,
+		"default":      defaultValue,
+		"regexReplace": regexReplace,
+		"regexMatch":   regexMatch,
+		"now":          time.Now,
+		"date":         formatDate,
+		"toJson":       toJSON,
+		"indent":       indent,
+	}
+
+	depth := 0
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		if snippets == nil {
+			return "", fmt.Errorf("include %q: no snippets configured", name)
+		}
+		snippet, ok := snippets[name]
+		if !ok {
+			return "", fmt.Errorf("include %q: snippet not found", name)
+		}
+		if depth >= maxIncludeDepth {
+			return "", fmt.Errorf("include %q: maximum include depth %d exceeded", name, maxIncludeDepth)
+		}
+		depth++
+		defer func() { depth-- }()
+
+		tmpl, err := template.New(name).Funcs(funcs).Parse(snippet)
+		if err != nil {
+			return "", fmt.Errorf("include %q: parsing snippet: %w", name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("include %q: %w", name, err)
+		}
+		return buf.String(), nil
+	}
+
+	return funcs
+}
+
+// words splits s into lowercase words at non-alphanumeric characters and
+// lower-to-upper case transitions.
+func words(s string) []string {
+	var result []string
+	var current []rune
+	var prev rune
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+		prev = r
+	}
+	flush()
+	return result
+}
+
+func joinWords(s, sep string) string {
+	return strings.Join(words(s), sep)
+}
+
+// titleCase upper-cases the first letter of each space-separated word.
+func titleCase(s string) string {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		r, size := utf8.DecodeRuneInString(f)
+		fields[i] = string(unicode.ToUpper(r)) + f[size:]
+	}
+	return strings.Join(fields, " ")
+}
+
+// slugify converts s into a lowercase string that is safe to use in branch
+// names and Kubernetes label values: runs of characters other than a-z and
+// 0-9 become a single "-", and leading and trailing dashes are removed.
+func slugify(s string) string {
+	return strings.Trim(nonSlugCharRe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// truncate shortens s to at most n runes. Trailing dashes and spaces left
+// by the cut are removed so truncated slugs stay valid.
+func truncate(n int, s string) string {
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimRight(string(runes[:n]), "- ")
+}
+
+func split(sep, s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, sep)
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// join joins a list of values with sep. A plain string is treated as a
+// comma-separated list so that join works on the Labels variable.
+func join(sep string, list interface{}) (string, error) {
+	switch v := list.(type) {
+	case string:
+		return strings.Join(split(",", v), sep), nil
+	case []string:
+		return strings.Join(v, sep), nil
+	}
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %T", list)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// defaultValue returns value unless it is empty, in which case def is
+// returned.
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if rv.Len() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return def
+		}
+	}
+	return value
+}
+
+func regexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("regexReplace: %w", err)
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+func regexMatch(pattern, s string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("regexMatch: %w", err)
+	}
+	return re.MatchString(s), nil
+}
+
+// formatDate formats a time.Time or an RFC 3339 string (such as the cron
+// .Time variable) using a Go time layout.
+func formatDate(layout string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("date: unsupported value of type %T", value)
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(b), nil
+}
+
+// indent prefixes every line of s with n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
@@ -0,0 +1,118 @@
+package source
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateFuncs(t *testing.T) {
+	item := WorkItem{
+		ID:     "42",
+		Number: 42,
+		Title:  "Fix: login/logout  broken on Safari!",
+		Body:   "line one\nline two",
+		Labels: []string{"bug", "priority/high"},
+		Time:   "2026-01-15T10:30:00Z",
+	}
+
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"lower", `{{.Title | lower}}`, "fix: login/logout  broken on safari!"},
+		{"upper", `{{"abc" | upper}}`, "ABC"},
+		{"title", `{{"fix the bug" | title}}`, "Fix The Bug"},
+		{"kebabcase", `{{"FixLoginBug now" | kebabcase}}`, "fix-login-bug-now"},
+		{"snakecase", `{{"Fix login-bug" | snakecase}}`, "fix_login_bug"},
+		{"slugify", `kelos-{{.Number}}-{{.Title | slugify}}`, "kelos-42-fix-login-logout-broken-on-safari"},
+		{"slugify and truncate", `{{.Title | slugify | truncate 10}}`, "fix-login"},
+		{"truncate short", `{{"abc" | truncate 10}}`, "abc"},
+		{"trim", `{{"  padded  " | trim}}`, "padded"},
+		{"trimPrefix", `{{"feat: add" | trimPrefix "feat: "}}`, "add"},
+		{"replace", `{{"a/b/c" | replace "/" "-"}}`, "a-b-c"},
+		{"contains", `{{if .Labels | contains "bug"}}yes{{end}}`, "yes"},
+		{"join labels", `{{.Labels | join " | "}}`, "bug | priority/high"},
+		{"join split", `{{split "," "a,b" | join "+"}}`, "a+b"},
+		{"default empty", `{{.Branch | default "main"}}`, "main"},
+		{"default set", `{{.ID | default "none"}}`, "42"},
+		{"regexReplace", `{{.Title | regexReplace "^Fix: " ""}}`, "login/logout  broken on Safari!"},
+		{"regexMatch", `{{if regexMatch "Safari" .Title}}safari{{end}}`, "safari"},
+		{"date", `{{.Time | date "2006-01-02"}}`, "2026-01-15"},
+		{"toJson", `{{.Title | toJson}}`, `"Fix: login/logout  broken on Safari!"`},
+		{"indent", `{{.Body | indent 2}}`, "  line one\n  line two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.tmpl, item)
+			if err != nil {
+				t.Fatalf("RenderTemplate(%q) error: %v", tt.tmpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateDateNow(t *testing.T) {
+	got, err := RenderTemplate(`{{now | date "2006"}}`, WorkItem{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 4 {
+		t.Errorf("expected a four-digit year, got %q", got)
+	}
+}
+
+func TestRenderTemplateFuncErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr string
+	}{
+		{"invalid regex", `{{.Title | regexReplace "(" ""}}`, "regexReplace"},
+		{"invalid date", `{{"yesterday" | date "2006"}}`, "date"},
+		{"include without snippets", `{{include "footer" .}}`, "no snippets configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderTemplate(tt.tmpl, WorkItem{Title: "x"})
+			if err == nil {
+				t.Fatalf("expected error for %q", tt.tmpl)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRenderPromptWithSnippets(t *testing.T) {
+	snippets := map[string]string{
+		"header":     "Work on #{{.Number}}.",
+		"guidelines": "{{include \"header\" .}} Keep the diff small.",
+		"loop":       "{{include \"loop\" .}}",
+	}
+	item := WorkItem{ID: "7", Number: 7, Title: "Add tests"}
+
+	got, err := RenderPromptWithSnippets(`{{include "guidelines" .}}
+Task: {{.Title}}`, item, snippets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Work on #7. Keep the diff small.\nTask: Add tests"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := RenderPromptWithSnippets(`{{include "missing" .}}`, item, snippets); err == nil || !strings.Contains(err.Error(), "snippet not found") {
+		t.Errorf("expected snippet not found error, got %v", err)
+	}
+
+	if _, err := RenderPromptWithSnippets(`{{include "loop" .}}`, item, snippets); err == nil || !strings.Contains(err.Error(), "maximum include depth") {
+		t.Errorf("expected include depth error, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package transcript archives agent transcripts so that they outlive the
+// agent pod, and reads them back.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemePVC, SchemeS3 and SchemeConfigMap are the URI schemes of
+	// archived transcripts recorded in TaskStatus.Transcript.
+	SchemePVC       = "pvc"
+	SchemeS3        = "s3"
+	SchemeConfigMap = "configmap"
+
+	// ConfigMapKey is the binaryData key holding the gzip-compressed
+	// transcript in a transcript ConfigMap.
+	ConfigMapKey = "transcript.jsonl.gz"
+
+	// MaxConfigMapBytes bounds the compressed transcript stored in a
+	// ConfigMap, leaving headroom below the 1 MiB object size limit.
+	MaxConfigMapBytes = 900 * 1024
+
+	// AgentTypeAnnotation records the agent type on a transcript ConfigMap
+	// so that it can be formatted after its Task is deleted.
+	AgentTypeAnnotation = "kelos.dev/agent-type"
+
+	// URIAnnotation records the transcript reference on a transcript
+	// ConfigMap, whichever store holds the transcript.
+	URIAnnotation = "kelos.dev/transcript-uri"
+
+	// TruncatedAnnotation marks a ConfigMap transcript of which only the
+	// end was kept.
+	TruncatedAnnotation = "kelos.dev/transcript-truncated"
+
+	// ExpiresAtAnnotation records, in RFC 3339, when a transcript
+	// ConfigMap may be deleted.
+	ExpiresAtAnnotation = "kelos.dev/transcript-expires-at"
+
+	// S3EndpointAnnotation, S3RegionAnnotation and S3SecretAnnotation
+	// record how to read an S3 transcript once its Task is deleted.
+	S3EndpointAnnotation = "kelos.dev/transcript-s3-endpoint"
+	S3RegionAnnotation   = "kelos.dev/transcript-s3-region"
+	S3SecretAnnotation   = "kelos.dev/transcript-s3-secret"
+
+	// Label marks transcript ConfigMaps.
+	Label = "kelos.dev/transcript"
+
+	// DefaultRetention is how long transcript ConfigMaps are kept when
+	// TranscriptArchive.Retention is not set.
+	DefaultRetention = 30 * 24 * time.Hour
+
+	// BlockStart and BlockEnd enclose the compressed transcript that
+	// kelos-capture prints to the agent container log for the controller
+	// to store in a ConfigMap. BlockStart is followed by " truncated" when
+	// only the end of the transcript fit.
+	BlockStart = "---KELOS_TRANSCRIPT_START---"
+	BlockEnd   = "---KELOS_TRANSCRIPT_END---"
+
+	// blockLineWidth is the length of the base64 lines of a block.
+	blockLineWidth = 76
+)
+
+// ObjectPath returns the path of a transcript relative to the root of a
+// store: <namespace>/<task>/<pod>.jsonl.
+func ObjectPath(namespace, task, pod string) string {
+	return path.Join(namespace, task, pod+".jsonl")
+}
+
+// ConfigMapName returns the name of the ConfigMap that archives the
+// transcript of the named Task, or records where it is archived.
+func ConfigMapName(task string) string {
+	return task + "-transcript"
+}
+
+// URI returns the reference to a transcript in a store, e.g.
+// s3://bucket/prefix/ns/task/pod.jsonl.
+func URI(scheme, root, p string) string {
+	if p == "" {
+		return scheme + "://" + root
+	}
+	return scheme + "://" + root + "/" + strings.TrimPrefix(p, "/")
+}
+
+// ParseURI splits a transcript reference into its scheme, root (the claim,
+// bucket or ConfigMap name) and path within the root.
+func ParseURI(uri string) (scheme, root, p string, err error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || rest == "" {
+		return "", "", "", fmt.Errorf("invalid transcript reference %q", uri)
+	}
+	switch scheme {
+	case SchemePVC, SchemeS3, SchemeConfigMap:
+	default:
+		return "", "", "", fmt.Errorf("unsupported transcript store %q in %q", scheme, uri)
+	}
+	root, p, _ = strings.Cut(rest, "/")
+	return scheme, root, p, nil
+}
+
+// Compress gzips a transcript for storage in a ConfigMap. When the result
+// exceeds limit bytes, the oldest lines are dropped until it fits, and
+// truncated is true.
+func Compress(data []byte, limit int) (out []byte, truncated bool, err error) {
+	for {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, false, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, false, err
+		}
+		if buf.Len() <= limit || len(data) == 0 {
+			return buf.Bytes(), truncated, nil
+		}
+
+		// Keep the share of the transcript that fits, rounded to a line
+		// boundary. The end holds the agent's result, which matters most.
+		keep := int(float64(len(data)) * float64(limit) / float64(buf.Len()) * 0.9)
+		data = data[len(data)-keep:]
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+		truncated = true
+	}
+}
+
+// Decompress reverses Compress.
+func Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading compressed transcript: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// WriteBlock prints a compressed transcript between BlockStart and
+// BlockEnd as base64 lines.
+func WriteBlock(w io.Writer, compressed []byte, truncated bool) error {
+	start := BlockStart
+	if truncated {
+		start += " truncated"
+	}
+	var b strings.Builder
+	b.WriteString(start + "\n")
+	encoded := base64.StdEncoding.EncodeToString(compressed)
+	for len(encoded) > 0 {
+		n := min(blockLineWidth, len(encoded))
+		b.WriteString(encoded[:n] + "\n")
+		encoded = encoded[n:]
+	}
+	b.WriteString(BlockEnd + "\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ParseBlock extracts the compressed transcript written by WriteBlock from
+// a container log. It reports false when the log has no complete block.
+func ParseBlock(log []byte) (compressed []byte, truncated, found bool, err error) {
+	var encoded strings.Builder
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(log))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, BlockStart):
+			inBlock = true
+			truncated = strings.TrimSpace(strings.TrimPrefix(line, BlockStart)) == "truncated"
+			encoded.Reset()
+		case !inBlock:
+		case line == BlockEnd:
+			compressed, err = base64.StdEncoding.DecodeString(encoded.String())
+			if err != nil {
+				return nil, false, true, fmt.Errorf("decoding transcript block: %w", err)
+			}
+			return compressed, truncated, true, nil
+		default:
+			encoded.WriteString(line)
+		}
+	}
+	return nil, false, false, scanner.Err()
+}
+
+// StripBlock returns a reader of r without the transcript block, so that
+// log formatters do not print it.
+func StripBlock(r io.Reader) io.Reader {
+	return &blockStripper{r: bufio.NewReader(r)}
+}
+
+type blockStripper struct {
+	r       *bufio.Reader
+	buf     []byte
+	inBlock bool
+	err     error
+}
+
+func (s *blockStripper) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		line, err := s.r.ReadBytes('\n')
+		s.err = err
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte(BlockStart)):
+			s.inBlock = true
+		case s.inBlock:
+			if bytes.Equal(trimmed, []byte(BlockEnd)) {
+				s.inBlock = false
+			}
+		default:
+			s.buf = line
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,137 @@
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestURIRoundTrip(t *testing.T) {
+	tests := []struct {
+		scheme, root, path string
+		want               string
+	}{
+		{scheme: SchemeS3, root: "bucket", path: "kelos/default/task-1/pod.jsonl", want: "s3://bucket/kelos/default/task-1/pod.jsonl"},
+		{scheme: SchemePVC, root: "transcripts", path: ObjectPath("default", "task-1", "pod"), want: "pvc://transcripts/default/task-1/pod.jsonl"},
+		{scheme: SchemeConfigMap, root: ConfigMapName("task-1"), want: "configmap://task-1-transcript"},
+	}
+	for _, tt := range tests {
+		uri := URI(tt.scheme, tt.root, tt.path)
+		if uri != tt.want {
+			t.Errorf("URI() = %q, want %q", uri, tt.want)
+		}
+		scheme, root, p, err := ParseURI(uri)
+		if err != nil {
+			t.Fatalf("ParseURI(%q) error: %v", uri, err)
+		}
+		if scheme != tt.scheme || root != tt.root || p != tt.path {
+			t.Errorf("ParseURI(%q) = %q, %q, %q", uri, scheme, root, p)
+		}
+	}
+
+	for _, bad := range []string{"", "bucket/key", "gs://bucket/key", "s3://"} {
+		if _, _, _, err := ParseURI(bad); err == nil {
+			t.Errorf("ParseURI(%q) expected error", bad)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data := []byte(strings.Repeat(`{"type":"assistant","message":"hello"}`+"\n", 100))
+	out, truncated, err := Compress(data, MaxConfigMapBytes)
+	if err != nil {
+		t.Fatalf("Compress() error: %v", err)
+	}
+	if truncated {
+		t.Error("small transcript should not be truncated")
+	}
+	got, err := Decompress(out)
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("round trip changed the transcript")
+	}
+}
+
+func TestCompressTruncatesToLimit(t *testing.T) {
+	// Random content compresses poorly, forcing truncation.
+	rng := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, `{"line":%d,"data":"%x"}`+"\n", i, rng.Int63())
+	}
+	b.WriteString(`{"type":"result"}` + "\n")
+	data := []byte(b.String())
+
+	out, truncated, err := Compress(data, 8*1024)
+	if err != nil {
+		t.Fatalf("Compress() error: %v", err)
+	}
+	if !truncated {
+		t.Fatal("expected truncation")
+	}
+	if len(out) > 8*1024 {
+		t.Errorf("compressed size %d exceeds limit", len(out))
+	}
+	got, err := Decompress(out)
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if !bytes.HasSuffix(got, []byte(`{"type":"result"}`+"\n")) {
+		t.Error("truncation should keep the end of the transcript")
+	}
+	if !bytes.HasPrefix(got, []byte(`{"line":`)) {
+		t.Errorf("truncation should start at a line boundary, got %q", got[:20])
+	}
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	transcript := []byte(strings.Repeat(`{"type":"assistant","message":{"content":[]}}`+"\n", 50))
+	compressed, _, err := Compress(transcript, MaxConfigMapBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log bytes.Buffer
+	log.WriteString(`{"type":"result"}` + "\n")
+	if err := WriteBlock(&log, compressed, true); err != nil {
+		t.Fatalf("WriteBlock() error: %v", err)
+	}
+	log.WriteString("---KELOS_OUTPUTS_START---\nbranch: main\n---KELOS_OUTPUTS_END---\n")
+
+	got, truncated, found, err := ParseBlock(log.Bytes())
+	if err != nil || !found {
+		t.Fatalf("ParseBlock() = found %v, error %v", found, err)
+	}
+	if !truncated {
+		t.Error("expected the truncated flag to round-trip")
+	}
+	data, err := Decompress(got)
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if !bytes.Equal(data, transcript) {
+		t.Error("transcript changed in the block round trip")
+	}
+
+	stripped, err := io.ReadAll(StripBlock(bytes.NewReader(log.Bytes())))
+	if err != nil {
+		t.Fatalf("reading stripped log: %v", err)
+	}
+	want := `{"type":"result"}` + "\n---KELOS_OUTPUTS_START---\nbranch: main\n---KELOS_OUTPUTS_END---\n"
+	if string(stripped) != want {
+		t.Errorf("StripBlock() = %q, want %q", stripped, want)
+	}
+}
+
+func TestParseBlockMissing(t *testing.T) {
+	for _, log := range []string{"", "plain log\n", BlockStart + "\nAAAA\n"} {
+		if _, _, found, err := ParseBlock([]byte(log)); found || err != nil {
+			t.Errorf("ParseBlock(%q) = found %v, error %v; want not found", log, found, err)
+		}
+	}
+}
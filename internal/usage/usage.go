@@ -0,0 +1,190 @@
+// Package usage keeps a durable summary of the cost and token usage of
+// finished Tasks. Task results are lost when a Task is deleted, for example
+// by ttlSecondsAfterFinished, so the controller also appends a record for
+// each finished Task to a per-day ledger ConfigMap in the Task's namespace.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+const (
+	// LedgerLabel marks ConfigMaps that hold usage records.
+	LedgerLabel = "kelos.dev/usage-ledger"
+
+	// DefaultRetention is how long ledger ConfigMaps are kept by default.
+	DefaultRetention = 90 * 24 * time.Hour
+
+	ledgerPrefix     = "kelos-usage-"
+	ledgerDateLayout = "20060102"
+)
+
+// Record is the usage of a single finished Task.
+type Record struct {
+	Task           string            `json:"task"`
+	Namespace      string            `json:"namespace"`
+	UID            string            `json:"uid"`
+	Spawner        string            `json:"spawner,omitempty"`
+	Type           string            `json:"type"`
+	Model          string            `json:"model,omitempty"`
+	Phase          string            `json:"phase"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	CompletionTime time.Time         `json:"completionTime"`
+	CostUSD        float64           `json:"costUSD,omitempty"`
+	InputTokens    int64             `json:"inputTokens,omitempty"`
+	OutputTokens   int64             `json:"outputTokens,omitempty"`
+}
+
+// Key returns the ConfigMap data key of the record. The UID is part of the
+// key so that a Task recreated with the same name gets its own record.
+func (r Record) Key() string {
+	return r.Task + "." + r.UID
+}
+
+// RecordFromTask builds a usage record from a finished Task, whether it
+// succeeded, failed or was cancelled, with or without results. Tasks that
+// failed before they started have no completion time and are recorded at
+// their creation time. It returns false when the Task has not finished.
+func RecordFromTask(task *kelosv1alpha1.Task) (Record, bool) {
+	completion := task.Status.CompletionTime
+	if completion == nil {
+		if task.Status.Phase != kelosv1alpha1.TaskPhaseSucceeded && task.Status.Phase != kelosv1alpha1.TaskPhaseFailed {
+			return Record{}, false
+		}
+		completion = &task.CreationTimestamp
+	}
+	r := Record{
+		Task:           task.Name,
+		Namespace:      task.Namespace,
+		UID:            string(task.UID),
+		Spawner:        task.Labels["kelos.dev/taskspawner"],
+		Type:           task.Spec.Type,
+		Model:          task.Spec.Model,
+		Phase:          string(task.Status.Phase),
+		Labels:         task.Labels,
+		CompletionTime: completion.UTC(),
+	}
+	if v, err := strconv.ParseFloat(task.Status.Results["cost-usd"], 64); err == nil {
+		r.CostUSD = v
+	}
+	if v, err := strconv.ParseFloat(task.Status.Results["input-tokens"], 64); err == nil {
+		r.InputTokens = int64(v)
+	}
+	if v, err := strconv.ParseFloat(task.Status.Results["output-tokens"], 64); err == nil {
+		r.OutputTokens = int64(v)
+	}
+	return r, true
+}
+
+// LedgerName returns the name of the ledger ConfigMap for the day of t in
+// UTC. One ConfigMap per day keeps each well below the ConfigMap size limit.
+func LedgerName(t time.Time) string {
+	return ledgerPrefix + t.UTC().Format(ledgerDateLayout)
+}
+
+// Append stores the record in the ledger ConfigMap for its completion day,
+// creating the ConfigMap if needed. Appending the same record again
+// overwrites it. Creating a day's ledger also deletes the ledgers older than
+// retention; a zero retention keeps them all.
+func Append(ctx context.Context, c client.Client, r Record, retention time.Duration) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encoding usage record: %w", err)
+	}
+	key := client.ObjectKey{Namespace: r.Namespace, Name: LedgerName(r.CompletionTime)}
+
+	created := false
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var cm corev1.ConfigMap
+		if err := c.Get(ctx, key, &cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			cm = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+					Labels:    map[string]string{LedgerLabel: "true"},
+				},
+				Data: map[string]string{r.Key(): string(data)},
+			}
+			err := c.Create(ctx, &cm)
+			if apierrors.IsAlreadyExists(err) {
+				// Another reconcile created it first; retry as an update.
+				return apierrors.NewConflict(corev1.Resource("configmaps"), key.Name, err)
+			}
+			created = err == nil
+			return err
+		}
+		if cm.Data[r.Key()] == string(data) {
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[r.Key()] = string(data)
+		return c.Update(ctx, &cm)
+	})
+	if err != nil || !created || retention <= 0 {
+		return err
+	}
+	return Prune(ctx, c, r.Namespace, time.Now().Add(-retention))
+}
+
+// Prune deletes the ledger ConfigMaps in namespace for days that ended
+// before cutoff.
+func Prune(ctx context.Context, c client.Client, namespace string, cutoff time.Time) error {
+	var cms corev1.ConfigMapList
+	if err := c.List(ctx, &cms, client.InNamespace(namespace), client.MatchingLabels{LedgerLabel: "true"}); err != nil {
+		return fmt.Errorf("listing usage ledgers: %w", err)
+	}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if !strings.HasPrefix(cm.Name, ledgerPrefix) {
+			continue
+		}
+		day, err := time.Parse(ledgerDateLayout, strings.TrimPrefix(cm.Name, ledgerPrefix))
+		if err != nil || !day.Add(24*time.Hour).Before(cutoff) {
+			continue
+		}
+		if err := c.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting usage ledger %s: %w", cm.Name, err)
+		}
+	}
+	return nil
+}
+
+// List returns all records stored in ledger ConfigMaps matching opts, e.g.
+// client.InNamespace. Entries that cannot be decoded are skipped.
+func List(ctx context.Context, c client.Reader, opts ...client.ListOption) ([]Record, error) {
+	var cms corev1.ConfigMapList
+	opts = append(opts, client.MatchingLabels{LedgerLabel: "true"})
+	if err := c.List(ctx, &cms, opts...); err != nil {
+		return nil, fmt.Errorf("listing usage ledgers: %w", err)
+	}
+
+	var records []Record
+	for _, cm := range cms.Items {
+		for _, v := range cm.Data {
+			var r Record
+			if err := json.Unmarshal([]byte(v), &r); err != nil {
+				continue
+			}
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
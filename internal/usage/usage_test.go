@@ -0,0 +1,180 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kelosv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+)
+
+func newTestScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(s))
+	utilruntime.Must(kelosv1alpha1.AddToScheme(s))
+	return s
+}
+
+func finishedTask(name, uid string, completed time.Time, results map[string]string) *kelosv1alpha1.Task {
+	ct := metav1.NewTime(completed)
+	return &kelosv1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID("uid-" + uid),
+			Labels:    map[string]string{"kelos.dev/taskspawner": "fixer", "team": "platform"},
+		},
+		Spec: kelosv1alpha1.TaskSpec{Type: "claude-code", Model: "opus"},
+		Status: kelosv1alpha1.TaskStatus{
+			Phase:          kelosv1alpha1.TaskPhaseSucceeded,
+			CompletionTime: &ct,
+			Results:        results,
+		},
+	}
+}
+
+func TestRecordFromTask(t *testing.T) {
+	completed := time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)
+	task := finishedTask("fixer-1", "a", completed, map[string]string{
+		"cost-usd":      "1.25",
+		"input-tokens":  "1000",
+		"output-tokens": "250",
+	})
+
+	r, ok := RecordFromTask(task)
+	if !ok {
+		t.Fatal("expected a record for a finished task")
+	}
+	if r.Spawner != "fixer" || r.Model != "opus" || r.Type != "claude-code" || r.Phase != "Succeeded" {
+		t.Errorf("unexpected record metadata: %+v", r)
+	}
+	if r.CostUSD != 1.25 || r.InputTokens != 1000 || r.OutputTokens != 250 {
+		t.Errorf("unexpected usage: %+v", r)
+	}
+	if !r.CompletionTime.Equal(completed) {
+		t.Errorf("CompletionTime = %v, want %v", r.CompletionTime, completed)
+	}
+
+	// A Task cancelled or failed before it started has no completion time
+	// and no results, and is still recorded.
+	created := completed.Add(-time.Hour)
+	task.CreationTimestamp = metav1.NewTime(created)
+	task.Status.Phase = kelosv1alpha1.TaskPhaseFailed
+	task.Status.CompletionTime = nil
+	task.Status.Results = nil
+	r, ok = RecordFromTask(task)
+	if !ok {
+		t.Fatal("expected a record for a failed task without a completion time")
+	}
+	if !r.CompletionTime.Equal(created) || r.Phase != "Failed" || r.CostUSD != 0 {
+		t.Errorf("unexpected record for a failed task: %+v", r)
+	}
+
+	task.Status.Phase = kelosv1alpha1.TaskPhaseRunning
+	if _, ok := RecordFromTask(task); ok {
+		t.Error("expected no record for an unfinished task")
+	}
+}
+
+func TestLedgerName(t *testing.T) {
+	loc := time.FixedZone("UTC-8", -8*60*60)
+	got := LedgerName(time.Date(2026, 3, 4, 20, 0, 0, 0, loc))
+	if got != "kelos-usage-20260305" {
+		t.Errorf("LedgerName = %q, want %q", got, "kelos-usage-20260305")
+	}
+}
+
+func TestAppendAndList(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+
+	day := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	r1, _ := RecordFromTask(finishedTask("fixer-1", "a", day, map[string]string{"cost-usd": "1"}))
+	r2, _ := RecordFromTask(finishedTask("fixer-2", "b", day.Add(time.Hour), map[string]string{"cost-usd": "2"}))
+	r3, _ := RecordFromTask(finishedTask("fixer-3", "c", day.Add(24*time.Hour), map[string]string{"cost-usd": "3"}))
+
+	for _, r := range []Record{r1, r2, r3, r1} {
+		if err := Append(ctx, cl, r, 0); err != nil {
+			t.Fatalf("Append(%s) error: %v", r.Task, err)
+		}
+	}
+
+	var cm corev1.ConfigMap
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "kelos-usage-20260304"}, &cm); err != nil {
+		t.Fatalf("getting ledger: %v", err)
+	}
+	if len(cm.Data) != 2 {
+		t.Errorf("expected 2 records in the first day's ledger, got %d", len(cm.Data))
+	}
+	if cm.Labels[LedgerLabel] != "true" {
+		t.Errorf("expected ledger label, got %v", cm.Labels)
+	}
+
+	// Unrelated ConfigMaps are ignored.
+	if err := cl.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Data:       map[string]string{"x": "not json"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := List(ctx, cl, client.InNamespace("default"))
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	var total float64
+	for _, r := range records {
+		total += r.CostUSD
+	}
+	if total != 6 {
+		t.Errorf("total cost = %v, want 6", total)
+	}
+}
+
+func TestAppendPrunesExpiredLedgers(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+
+	now := time.Now().UTC()
+	old, _ := RecordFromTask(finishedTask("fixer-1", "a", now.Add(-40*24*time.Hour), nil))
+	recent, _ := RecordFromTask(finishedTask("fixer-2", "b", now.Add(-10*24*time.Hour), nil))
+	for _, r := range []Record{old, recent} {
+		if err := Append(ctx, cl, r, 0); err != nil {
+			t.Fatalf("Append(%s) error: %v", r.Task, err)
+		}
+	}
+
+	today, _ := RecordFromTask(finishedTask("fixer-3", "c", now, nil))
+	if err := Append(ctx, cl, today, 30*24*time.Hour); err != nil {
+		t.Fatalf("Append(%s) error: %v", today.Task, err)
+	}
+
+	records, err := List(ctx, cl, client.InNamespace("default"))
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	var names []string
+	for _, r := range records {
+		names = append(names, r.Task)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected the expired ledger to be pruned, got records %v", names)
+	}
+	for _, r := range records {
+		if r.Task == "fixer-1" {
+			t.Errorf("expected fixer-1 to be pruned, got records %v", names)
+		}
+	}
+}
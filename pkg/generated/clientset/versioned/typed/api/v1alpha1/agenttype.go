@@ -0,0 +1,68 @@
+/*
+Copyright 2026 Gunju Kim
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	apiv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	scheme "github.com/kelos-dev/kelos/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// AgentTypesGetter has a method to return a AgentTypeInterface.
+// A group's client should implement this interface.
+type AgentTypesGetter interface {
+	AgentTypes() AgentTypeInterface
+}
+
+// AgentTypeInterface has methods to work with AgentType resources.
+type AgentTypeInterface interface {
+	Create(ctx context.Context, agentType *apiv1alpha1.AgentType, opts v1.CreateOptions) (*apiv1alpha1.AgentType, error)
+	Update(ctx context.Context, agentType *apiv1alpha1.AgentType, opts v1.UpdateOptions) (*apiv1alpha1.AgentType, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*apiv1alpha1.AgentType, error)
+	List(ctx context.Context, opts v1.ListOptions) (*apiv1alpha1.AgentTypeList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *apiv1alpha1.AgentType, err error)
+	AgentTypeExpansion
+}
+
+// agentTypes implements AgentTypeInterface
+type agentTypes struct {
+	*gentype.ClientWithList[*apiv1alpha1.AgentType, *apiv1alpha1.AgentTypeList]
+}
+
+// newAgentTypes returns a AgentTypes
+func newAgentTypes(c *ApiV1alpha1Client) *agentTypes {
+	return &agentTypes{
+		gentype.NewClientWithList[*apiv1alpha1.AgentType, *apiv1alpha1.AgentTypeList](
+			"agenttypes",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *apiv1alpha1.AgentType { return &apiv1alpha1.AgentType{} },
+			func() *apiv1alpha1.AgentTypeList { return &apiv1alpha1.AgentTypeList{} },
+		),
+	}
+}
@@ -29,6 +29,7 @@ import (
 type ApiV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	AgentConfigsGetter
+	AgentTypesGetter
 	TasksGetter
 	TaskSpawnersGetter
 	WorkspacesGetter
@@ -43,6 +44,10 @@ func (c *ApiV1alpha1Client) AgentConfigs(namespace string) AgentConfigInterface
 	return newAgentConfigs(c, namespace)
 }
 
+func (c *ApiV1alpha1Client) AgentTypes() AgentTypeInterface {
+	return newAgentTypes(c)
+}
+
 func (c *ApiV1alpha1Client) Tasks(namespace string) TaskInterface {
 	return newTasks(c, namespace)
 }
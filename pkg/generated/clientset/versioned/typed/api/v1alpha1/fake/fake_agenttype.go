@@ -0,0 +1,50 @@
+/*
+Copyright 2026 Gunju Kim
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	apiv1alpha1 "github.com/kelos-dev/kelos/pkg/generated/clientset/versioned/typed/api/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeAgentTypes implements AgentTypeInterface
+type fakeAgentTypes struct {
+	*gentype.FakeClientWithList[*v1alpha1.AgentType, *v1alpha1.AgentTypeList]
+	Fake *FakeApiV1alpha1
+}
+
+func newFakeAgentTypes(fake *FakeApiV1alpha1) apiv1alpha1.AgentTypeInterface {
+	return &fakeAgentTypes{
+		gentype.NewFakeClientWithList[*v1alpha1.AgentType, *v1alpha1.AgentTypeList](
+			fake.Fake,
+			"",
+			v1alpha1.SchemeGroupVersion.WithResource("agenttypes"),
+			v1alpha1.SchemeGroupVersion.WithKind("AgentType"),
+			func() *v1alpha1.AgentType { return &v1alpha1.AgentType{} },
+			func() *v1alpha1.AgentTypeList { return &v1alpha1.AgentTypeList{} },
+			func(dst, src *v1alpha1.AgentTypeList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.AgentTypeList) []*v1alpha1.AgentType { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1alpha1.AgentTypeList, items []*v1alpha1.AgentType) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
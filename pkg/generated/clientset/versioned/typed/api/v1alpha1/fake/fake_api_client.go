@@ -32,6 +32,10 @@ func (c *FakeApiV1alpha1) AgentConfigs(namespace string) v1alpha1.AgentConfigInt
 	return newFakeAgentConfigs(c, namespace)
 }
 
+func (c *FakeApiV1alpha1) AgentTypes() v1alpha1.AgentTypeInterface {
+	return newFakeAgentTypes(c)
+}
+
 func (c *FakeApiV1alpha1) Tasks(namespace string) v1alpha1.TaskInterface {
 	return newFakeTasks(c, namespace)
 }
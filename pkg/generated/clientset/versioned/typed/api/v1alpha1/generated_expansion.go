@@ -20,6 +20,8 @@ package v1alpha1
 
 type AgentConfigExpansion interface{}
 
+type AgentTypeExpansion interface{}
+
 type TaskExpansion interface{}
 
 type TaskSpawnerExpansion interface{}
@@ -0,0 +1,101 @@
+/*
+Copyright 2026 Gunju Kim
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+	time "time"
+
+	kelosapiv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	versioned "github.com/kelos-dev/kelos/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/kelos-dev/kelos/pkg/generated/informers/externalversions/internalinterfaces"
+	apiv1alpha1 "github.com/kelos-dev/kelos/pkg/generated/listers/api/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// AgentTypeInformer provides access to a shared informer and lister for
+// AgentTypes.
+type AgentTypeInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() apiv1alpha1.AgentTypeLister
+}
+
+type agentTypeInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewAgentTypeInformer constructs a new informer for AgentType type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewAgentTypeInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredAgentTypeInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredAgentTypeInformer constructs a new informer for AgentType type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredAgentTypeInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ApiV1alpha1().AgentTypes().List(context.Background(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ApiV1alpha1().AgentTypes().Watch(context.Background(), options)
+			},
+			ListWithContextFunc: func(ctx context.Context, options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ApiV1alpha1().AgentTypes().List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ApiV1alpha1().AgentTypes().Watch(ctx, options)
+			},
+		}, client),
+		&kelosapiv1alpha1.AgentType{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *agentTypeInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredAgentTypeInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *agentTypeInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&kelosapiv1alpha1.AgentType{}, f.defaultInformer)
+}
+
+func (f *agentTypeInformer) Lister() apiv1alpha1.AgentTypeLister {
+	return apiv1alpha1.NewAgentTypeLister(f.Informer().GetIndexer())
+}
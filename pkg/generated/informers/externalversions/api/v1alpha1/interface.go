@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// AgentConfigs returns a AgentConfigInformer.
 	AgentConfigs() AgentConfigInformer
+	// AgentTypes returns a AgentTypeInformer.
+	AgentTypes() AgentTypeInformer
 	// Tasks returns a TaskInformer.
 	Tasks() TaskInformer
 	// TaskSpawners returns a TaskSpawnerInformer.
@@ -50,6 +52,11 @@ func (v *version) AgentConfigs() AgentConfigInformer {
 	return &agentConfigInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// AgentTypes returns a AgentTypeInformer.
+func (v *version) AgentTypes() AgentTypeInformer {
+	return &agentTypeInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Tasks returns a TaskInformer.
 func (v *version) Tasks() TaskInformer {
 	return &taskInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
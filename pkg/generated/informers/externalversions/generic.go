@@ -55,6 +55,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=api, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("agentconfigs"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Api().V1alpha1().AgentConfigs().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("agenttypes"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Api().V1alpha1().AgentTypes().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("tasks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Api().V1alpha1().Tasks().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("taskspawners"):
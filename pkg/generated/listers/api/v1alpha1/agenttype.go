@@ -0,0 +1,48 @@
+/*
+Copyright 2026 Gunju Kim
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	apiv1alpha1 "github.com/kelos-dev/kelos/api/v1alpha1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// AgentTypeLister helps list AgentTypes.
+// All objects returned here must be treated as read-only.
+type AgentTypeLister interface {
+	// List lists all AgentTypes in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*apiv1alpha1.AgentType, err error)
+	// Get retrieves the AgentType from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*apiv1alpha1.AgentType, error)
+	AgentTypeListerExpansion
+}
+
+// agentTypeLister implements the AgentTypeLister interface.
+type agentTypeLister struct {
+	listers.ResourceIndexer[*apiv1alpha1.AgentType]
+}
+
+// NewAgentTypeLister returns a new AgentTypeLister.
+func NewAgentTypeLister(indexer cache.Indexer) AgentTypeLister {
+	return &agentTypeLister{listers.New[*apiv1alpha1.AgentType](indexer, apiv1alpha1.Resource("agenttype"))}
+}
@@ -26,6 +26,10 @@ type AgentConfigListerExpansion interface{}
 // AgentConfigNamespaceLister.
 type AgentConfigNamespaceListerExpansion interface{}
 
+// AgentTypeListerExpansion allows custom methods to be added to
+// AgentTypeLister.
+type AgentTypeListerExpansion interface{}
+
 // TaskListerExpansion allows custom methods to be added to
 // TaskLister.
 type TaskListerExpansion interface{}
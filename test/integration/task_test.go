@@ -665,6 +665,245 @@ var _ = Describe("Task Controller", func() {
 		})
 	})
 
+	Context("When the API server rejects the Job of a Task", func() {
+		It("Should fail the Task when the API server rejects its Job", func() {
+			By("Creating a namespace")
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-task-invalid-affinity",
+				},
+			}
+			Expect(k8sClient.Create(ctx, ns)).Should(Succeed())
+
+			By("Creating a Secret with API key")
+			apiSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "anthropic-api-key",
+					Namespace: ns.Name,
+				},
+				StringData: map[string]string{
+					"ANTHROPIC_API_KEY": "test-api-key",
+				},
+			}
+			Expect(k8sClient.Create(ctx, apiSecret)).Should(Succeed())
+
+			By("Creating a Task with an affinity term that has no selector terms")
+			task := &kelosv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-invalid-affinity",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.TaskSpec{
+					Type:   "claude-code",
+					Prompt: "Fail on invalid affinity",
+					Credentials: kelosv1alpha1.Credentials{
+						Type:      kelosv1alpha1.CredentialTypeAPIKey,
+						SecretRef: &kelosv1alpha1.SecretReference{Name: "anthropic-api-key"},
+					},
+					PodOverrides: &kelosv1alpha1.PodOverrides{
+						Affinity: &corev1.Affinity{
+							NodeAffinity: &corev1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Verifying the Task transitions to Failed")
+			taskLookupKey := types.NamespacedName{Name: task.Name, Namespace: ns.Name}
+			createdTask := &kelosv1alpha1.Task{}
+			Eventually(func() kelosv1alpha1.TaskPhase {
+				if err := k8sClient.Get(ctx, taskLookupKey, createdTask); err != nil {
+					return ""
+				}
+				return createdTask.Status.Phase
+			}, timeout, interval).Should(Equal(kelosv1alpha1.TaskPhaseFailed))
+			Expect(createdTask.Status.Message).To(ContainSubstring("Failed to create Job"))
+
+			By("Verifying the JobCreateFailed event is emitted")
+			Eventually(func() *corev1.Event {
+				return findEvent(ns.Name, task.Name, "JobCreateFailed")
+			}, timeout, interval).ShouldNot(BeNil())
+		})
+	})
+
+	Context("When creating a Task with multiple AgentConfigs", func() {
+		It("Should merge the AgentConfigs in order", func() {
+			By("Creating a namespace")
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-task-agentconfig-refs",
+				},
+			}
+			Expect(k8sClient.Create(ctx, ns)).Should(Succeed())
+
+			By("Creating a Secret with API key")
+			apiSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "anthropic-api-key",
+					Namespace: ns.Name,
+				},
+				StringData: map[string]string{
+					"ANTHROPIC_API_KEY": "test-api-key",
+				},
+			}
+			Expect(k8sClient.Create(ctx, apiSecret)).Should(Succeed())
+
+			By("Creating a baseline and a team AgentConfig")
+			baseline := &kelosv1alpha1.AgentConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "platform-baseline",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.AgentConfigSpec{
+					AgentsMD: "Never commit secrets.",
+					MCPServers: []kelosv1alpha1.MCPServerSpec{
+						{Name: "internal-docs", Type: "http", URL: "https://docs.example.com/mcp"},
+						{Name: "github", Type: "http", URL: "https://baseline.example.com/mcp"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, baseline)).Should(Succeed())
+			team := &kelosv1alpha1.AgentConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "team-config",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.AgentConfigSpec{
+					AgentsMD: "Use pnpm.",
+					MCPServers: []kelosv1alpha1.MCPServerSpec{
+						{Name: "github", Type: "http", URL: "https://team.example.com/mcp"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, team)).Should(Succeed())
+
+			By("Creating a Task referencing both AgentConfigs")
+			task := &kelosv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-agentconfig-refs",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.TaskSpec{
+					Type:   "claude-code",
+					Prompt: "Use the composed config",
+					Credentials: kelosv1alpha1.Credentials{
+						Type:      kelosv1alpha1.CredentialTypeAPIKey,
+						SecretRef: &kelosv1alpha1.SecretReference{Name: "anthropic-api-key"},
+					},
+					AgentConfigRefs: []kelosv1alpha1.AgentConfigReference{
+						{Name: "platform-baseline"},
+						{Name: "team-config"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, task)).Should(Succeed())
+
+			By("Verifying a Job is created")
+			createdJob := &batchv1.Job{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: task.Name, Namespace: ns.Name}, createdJob)
+				return err == nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Verifying the merged instructions and MCP servers")
+			env := map[string]string{}
+			for _, e := range createdJob.Spec.Template.Spec.Containers[0].Env {
+				env[e.Name] = e.Value
+			}
+			Expect(env["KELOS_AGENTS_MD"]).To(Equal("Never commit secrets.\n\nUse pnpm."))
+
+			var parsed struct {
+				MCPServers map[string]struct {
+					URL string `json:"url"`
+				} `json:"mcpServers"`
+			}
+			Expect(json.Unmarshal([]byte(env["KELOS_MCP_SERVERS"]), &parsed)).Should(Succeed())
+			Expect(parsed.MCPServers).To(HaveLen(2))
+			Expect(parsed.MCPServers["internal-docs"].URL).To(Equal("https://docs.example.com/mcp"))
+			Expect(parsed.MCPServers["github"].URL).To(Equal("https://team.example.com/mcp"))
+		})
+
+		It("Should reject setting both agentConfigRef and agentConfigRefs", func() {
+			By("Creating a namespace")
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-task-agentconfig-refs-exclusive",
+				},
+			}
+			Expect(k8sClient.Create(ctx, ns)).Should(Succeed())
+
+			task := &kelosv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-agentconfig-both",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.TaskSpec{
+					Type:   "claude-code",
+					Prompt: "Hello",
+					Credentials: kelosv1alpha1.Credentials{
+						Type: kelosv1alpha1.CredentialTypeNone,
+					},
+					AgentConfigRef:  &kelosv1alpha1.AgentConfigReference{Name: "a"},
+					AgentConfigRefs: []kelosv1alpha1.AgentConfigReference{{Name: "b"}},
+				},
+			}
+			err := k8sClient.Create(ctx, task)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("agentConfigRef and agentConfigRefs are mutually exclusive"))
+		})
+	})
+
+	Context("When validating prompt and promptFrom", func() {
+		It("Should accept an empty prompt and reject prompt combined with promptFrom", func() {
+			By("Creating a namespace")
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-task-prompt-validation",
+				},
+			}
+			Expect(k8sClient.Create(ctx, ns)).Should(Succeed())
+
+			By("Creating a Task with an empty prompt")
+			empty := &kelosv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-empty-prompt",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.TaskSpec{
+					Type: "claude-code",
+					Credentials: kelosv1alpha1.Credentials{
+						Type: kelosv1alpha1.CredentialTypeNone,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, empty)).Should(Succeed())
+
+			By("Creating a Task with both prompt and promptFrom")
+			both := &kelosv1alpha1.Task{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "task-prompt-and-prompt-from",
+					Namespace: ns.Name,
+				},
+				Spec: kelosv1alpha1.TaskSpec{
+					Type:   "claude-code",
+					Prompt: "Hello",
+					PromptFrom: &kelosv1alpha1.PromptSource{
+						ConfigMapKeyRef: &kelosv1alpha1.ConfigMapKeyReference{Name: "prompts", Key: "prompt"},
+					},
+					Credentials: kelosv1alpha1.Credentials{
+						Type: kelosv1alpha1.CredentialTypeNone,
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, both)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("prompt and promptFrom are mutually exclusive"))
+		})
+	})
+
 	Context("When creating a Task with workspace and ref", func() {
 		It("Should create a Job with init container and workspace volume", func() {
 			By("Creating a namespace")